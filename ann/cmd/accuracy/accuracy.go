@@ -3,11 +3,13 @@ package main
 /*
  Simulate the accuracy of a user's query (e.g without requiring PIR or encryptions)
  1) Sample hash functions randomly from the distribution
+    (lattice hashes for the euclidean metric, random hyperplanes for the angular metric)
  2) Hash all dataset points under each hash function and construct hash tables
  3) For each query in the testing set
 	a) Compute the NumProbes closest lattice points
 	b) Starting with the smallest radii and closest probe, find the first nonempty colliding bucket
-	c) Randomly choose an element if there are multiple to simulate capped buckets of size 1.
+	c) Randomly keep BucketSize elements if there are more to simulate capped buckets.
+	   With BucketSize > 1 the client re-ranks the retrieved elements and keeps the closest.
 	d) Compute the distance of the returned answer from the query
 	e) Compute the ratio with the distance to the query's true nearest neighbor
 	f) Return "Hit" if less than c=2
@@ -15,6 +17,7 @@ package main
 
 import (
 	"bufio"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -64,6 +67,12 @@ func main() {
 		SequenceType        string  `default:"normal2"`
 		Mode                string  `default:"train"`
 		HashSize            uint64  `default:"64"`
+		BucketSize          int     `default:"1"`
+		DistanceMetric      string  `default:"euclidean"` // euclidean or angular (cosine)
+		NumHyperplanes      int     `default:"16"`        // hyperplanes per hash function (angular metric only)
+
+		// store the dataset with uint8 instead of float32 coordinates (see ann.Matrix.Quantize)
+		Quantize bool `default:"false"`
 
 		// a value large enough such that any translation will be random
 		MaxCoordinateValue int `default:"1000"`
@@ -76,6 +85,9 @@ func main() {
 		MaxDistance float64 `default:"1500"`
 
 		Profiling bool `default:"false"`
+
+		// (hex) seed used to sample the hash functions; random if empty
+		Seed string
 	}
 	arg.MustParse(&args)
 	fmt.Printf("%+v\n", args)
@@ -96,7 +108,12 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
-	data, testData, n, err := ann.ReadDataset(dataset)
+	metric, err := ann.ParseDistanceMetric(args.DistanceMetric)
+	if err != nil {
+		panic(err)
+	}
+
+	data, testData, n, err := ann.ReadDatasetMatrix(dataset, metric, args.Quantize)
 	if err != nil {
 		panic(err)
 	}
@@ -143,11 +160,28 @@ func main() {
 	args.MinDistance = radii[0]
 	args.MaxDistance = radii[len(radii)-1]
 
-	inputDim := data[0].Size()
+	seed, err := hex.DecodeString(args.Seed)
+	if err != nil {
+		panic(err)
+	}
+	if len(seed) == 0 {
+		seed, err = hash.NewRandomSeed()
+		if err != nil {
+			panic(err)
+		}
+	}
+	rnd := hash.NewSeededRand(seed)
+
+	inputDim := data.Dimension
 	tables := make([]*ann.HashTable, numTables)
 	hashes := make([]hash.Hash, numTables)
 	for i := 0; i < len(tables); i++ {
-		hashes[i] = hash.NewMultiLatticeHash(inputDim, 2, radii[i], float64(args.MaxCoordinateValue))
+		switch metric {
+		case ann.Angular:
+			hashes[i] = hash.NewHyperplaneHash(rnd, inputDim, args.NumHyperplanes)
+		default:
+			hashes[i] = hash.NewMultiLatticeHash(rnd, inputDim, 2, radii[i], float64(args.MaxCoordinateValue))
+		}
 	}
 	fmt.Printf("Constructed hash functions\n")
 	for i := 0; i < len(tables); i++ {
@@ -168,7 +202,7 @@ func main() {
 			panic(err)
 		}
 		args.Probes = numProbes
-		buckets := ann.NewPBRBuckets(hash.Prime, uint64(float64(numProbes)*args.PartitionFactor))
+		buckets := ann.NewPartitions(int(float64(numProbes)*args.PartitionFactor), int(args.HashSize))
 		fmt.Printf("probes: %v partitions: %v\n", numProbes, buckets.NumBuckets)
 
 		results := make([]ThreadRes, numThreads)
@@ -181,7 +215,7 @@ func main() {
 					switch args.Mode {
 					case "train":
 						queryIndex = testIndexes[row]
-						query = data[queryIndex]
+						query = data.Vec(queryIndex)
 					case "test":
 						query = testData[row]
 						queryIndex = -1 // never reject collisions
@@ -193,7 +227,7 @@ func main() {
 							fmt.Printf("completed query %v of %v\n", row-sections[i][0], sections[i][1]-sections[i][0])
 						}
 					}
-					collisions, radius := SimulateQuery(tables, hashes, cache[row], query, queryIndex, numProbes, buckets)
+					collisions, radius := SimulateQuery(tables, hashes, cache[row], query, queryIndex, numProbes, buckets, args.BucketSize)
 					t.tableId = append(t.tableId, radius)
 					t.rawCollisions = append(t.rawCollisions, collisions)
 					if len(collisions) == 0 {
@@ -204,13 +238,17 @@ func main() {
 						continue
 					}
 					r := rand.Intn(len(collisions))
+					if args.BucketSize > 1 {
+						// the client re-ranks the retrieved elements locally
+						r = closest(metric, query, data, collisions)
+					}
 					res := collisions[r]
 					t.collisionId = append(t.collisionId, int(res))
 					ideal := collisions[0]
 
-					bestDist := vec.EuclideanDistance(query, data[testAnswers[row]])
-					resultDist := vec.EuclideanDistance(query, data[res])
-					idealDist := vec.EuclideanDistance(query, data[ideal])
+					bestDist := metric.Distance(query, data.Vec(testAnswers[row]))
+					resultDist := metric.Distance(query, data.Vec(int(res)))
+					idealDist := metric.Distance(query, data.Vec(int(ideal)))
 					approximationRatio := resultDist / bestDist
 					idealApproximationRatio := idealDist / bestDist
 					if approximationRatio < 5 {
@@ -308,6 +346,10 @@ Ideal %v
 			Lattice:               args.Lattice,
 			ApproximationFactor:   args.ApproximationFactor,
 			SequenceType:          args.SequenceType,
+			BucketSize:            args.BucketSize,
+			DistanceMetric:        metric.String(),
+			NumHyperplanes:        args.NumHyperplanes,
+			Quantize:              args.Quantize,
 			ProjectionWidthMean:   args.ProjectionWidthMean,
 			ProjectionWidthStddev: args.ProjectionWidthStddev,
 			MaxCoordinateValue:    args.MaxCoordinateValue,
@@ -336,7 +378,7 @@ Ideal %v
 	}
 }
 
-func SimulateQuery(tables []*ann.HashTable, hashes []hash.Hash, cache [][]uint64, query *vec.Vec, queryId int, probes int, buckets *ann.PBRBuckets) ([]uint32, int) {
+func SimulateQuery(tables []*ann.HashTable, hashes []hash.Hash, cache [][]uint64, query *vec.Vec, queryId int, probes int, buckets *ann.PBRBuckets, bucketSize int) ([]uint32, int) {
 	res := make([]uint32, 0)
 	for i := range tables {
 		bucketsUsed := make([]bool, buckets.NumBuckets)
@@ -351,19 +393,7 @@ func SimulateQuery(tables []*ann.HashTable, hashes []hash.Hash, cache [][]uint64
 			}
 			bucketsUsed[bucket] = true
 			collisions := tables[i].Get(h)
-			if len(collisions) > 1 {
-				// choose the random member kept from the capped bucket
-				r := rand.Intn(len(collisions))
-				if collisions[r] == uint32(queryId) {
-					// len(collisions) is at least two in this branch so there is always another choice
-					r = (r + 1) % len(collisions)
-				}
-				res = append(res, collisions[r])
-			} else if len(collisions) == 1 {
-				if collisions[0] != uint32(queryId) {
-					res = append(res, collisions[0])
-				}
-			}
+			res = append(res, capBucket(collisions, queryId, bucketSize)...)
 		}
 		if len(res) > 0 {
 			return res, i
@@ -372,6 +402,44 @@ func SimulateQuery(tables []*ann.HashTable, hashes []hash.Hash, cache [][]uint64
 	return res, len(tables)
 }
 
+// capBucket chooses the (at most) bucketSize random members kept from the capped bucket
+// never returning the query itself
+func capBucket(collisions []uint32, queryId int, bucketSize int) []uint32 {
+	kept := make([]uint32, 0, bucketSize)
+	if len(collisions) <= bucketSize {
+		for _, c := range collisions {
+			if c != uint32(queryId) {
+				kept = append(kept, c)
+			}
+		}
+		return kept
+	}
+
+	for _, r := range rand.Perm(len(collisions)) {
+		if len(kept) == bucketSize {
+			break
+		}
+		if collisions[r] != uint32(queryId) {
+			kept = append(kept, collisions[r])
+		}
+	}
+	return kept
+}
+
+// closest returns the index of the candidate closest to the query
+func closest(metric ann.DistanceMetric, query *vec.Vec, data *ann.Matrix, candidates []uint32) int {
+	best := 0
+	bestDist := math.Inf(1)
+	for i, c := range candidates {
+		d := metric.Distance(query, data.Vec(int(c)))
+		if d < bestDist {
+			best = i
+			bestDist = d
+		}
+	}
+	return best
+}
+
 func ReadTrainingTestData(datasetName string, numTests int) ([]int, []int, error) {
 	f, err := os.Open("../meanAndStd/" + datasetName + ".txt")
 	if err != nil {
@@ -441,6 +509,10 @@ type ResultsStruct struct {
 	Lattice             int
 	ApproximationFactor float64
 	SequenceType        string
+	BucketSize          int
+	DistanceMetric      string
+	NumHyperplanes      int
+	Quantize            bool
 	Time                time.Time
 
 	// a value large enough such that any translation will be random
@@ -11,6 +11,7 @@ package main
 
 import (
 	"bufio"
+	"encoding/hex"
 	"fmt"
 	"math"
 	"math/rand"
@@ -36,6 +37,7 @@ func main() {
 		Dataset   string `default:"../../../datasets/mnist"`
 		Samples   int    `default:"10000"`
 		Dimension int    `default:"0"`
+		Seed      string // (hex) seed for the dimensionality reduction; random if empty
 	}
 
 	arg.MustParse(&args)
@@ -56,7 +58,17 @@ func main() {
 
 	inputDim := data[0].Size()
 	if transformDim > 0 && transformDim <= inputDim {
-		r := hash.NewHashCommon(inputDim, transformDim, 0, true)
+		seed, err := hex.DecodeString(args.Seed)
+		if err != nil {
+			panic(err)
+		}
+		if len(seed) == 0 {
+			seed, err = hash.NewRandomSeed()
+			if err != nil {
+				panic(err)
+			}
+		}
+		r := hash.NewHashCommon(hash.NewSeededRand(seed), inputDim, transformDim, 0, true)
 		scaleFactor := math.Sqrt(float64(inputDim) / float64(transformDim))
 		spans := hash.Spans(len(data), numThreads)
 		for t := 0; t < numThreads; t++ {
@@ -11,7 +11,6 @@ import (
 	"github.com/gonum/stat/distuv"
 	"github.com/sachaservan/private-ann/hash"
 	"github.com/sachaservan/private-ann/pir/field"
-	"github.com/sachaservan/vec"
 )
 
 /*
@@ -102,26 +101,49 @@ type HashTable struct {
 }
 
 func NewHashTable(table int, numBits uint64) *HashTable {
-	mask := (uint64(1) << numBits) - uint64(1)
-	return &HashTable{table: table, hashes: make(map[uint64][]uint32), mask: mask}
+	return &HashTable{table: table, hashes: make(map[uint64][]uint32), mask: KeyMask(int(numBits))}
 }
 
-func ComputeHashes(n int, h hash.Hash, data []*vec.Vec, numBits uint64) ([]uint64, []field.FP) {
+// ComputeHashes hashes the data into the nth table and returns the keys and values of the table.
+// Each value is a bucket of (at most) bucketSize encoded ids; empty slots are zero (see EncodeID).
+// rnd is used to choose which elements are kept in each bucket and should be seeded
+// identically on all servers so that the resulting tables match.
+func ComputeHashes(rnd *rand.Rand, n int, h hash.Hash, data *Matrix, numBits uint64, bucketSize int) ([]uint64, [][]field.FP) {
 	table := NewHashTable(n, numBits)
 	table.AddAll(h, data)
-	return convertAndCap(table.hashes)
+	return convertAndCap(rnd, table.hashes, bucketSize)
 }
 
-func (t *HashTable) AddAll(h hash.Hash, data []*vec.Vec) {
+// MaxID is the largest id that EncodeID can encode
+// (its encoding must stay below the field modulus 2^31-1)
+const MaxID = 1<<31 - 3
+
+// EncodeID encodes a dataset id (at most MaxID) as a (non-zero) field element
+// so that empty bucket slots can be represented by zero
+func EncodeID(id uint32) field.FP {
+	return field.FP(id) + 1
+}
+
+// DecodeID returns the dataset id encoded in v and false if v is an empty slot
+func DecodeID(v field.FP) (int, bool) {
+	if v == 0 {
+		return 0, false
+	}
+	return int(v - 1), true
+}
+
+// AddAll hashes the rows of the matrix (see hash.Hash.HashRow) into the table
+func (t *HashTable) AddAll(h hash.Hash, data *Matrix) {
 	numThreads := runtime.NumCPU()
-	sections := hash.Spans(len(data), numThreads)
+	sections := hash.Spans(data.Rows, numThreads)
 	errs := make(chan error)
 	for i := 0; i < numThreads; i++ {
 		go func(i int) {
 			myHashes := make(map[uint64][]uint32)
+			// (decoded) coordinates of the rows of a quantized matrix
+			buf := make([]float32, data.Dimension)
 			for row := sections[i][0]; row < sections[i][1]; row++ {
-				v := data[row]
-				hash := h.Hash(v)
+				hash := h.HashRow(data.Row(row, buf))
 				hash = hash & t.mask
 				cur := myHashes[hash]
 				myHashes[hash] = append(cur, uint32(row))
@@ -156,17 +178,34 @@ func (t *HashTable) Len() int {
 	return len(t.hashes)
 }
 
-// Choose one element to keep from each bucket with multiple values
-func convertAndCap(hashTable map[uint64][]uint32) ([]uint64, []field.FP) {
-	keys := make([]uint64, 0)
-	values := make([]field.FP, 0)
-	for k, v := range hashTable {
-		r := 0
-		if len(v) > 1 {
-			r = rand.Intn(len(v))
-		}
+// Choose (at most) bucketSize elements to keep from each bucket
+// Buckets are visited in sorted order (and their elements sorted) so that
+// the choice only depends on rnd and not on map iteration or thread scheduling
+func convertAndCap(rnd *rand.Rand, hashTable map[uint64][]uint32, bucketSize int) ([]uint64, [][]field.FP) {
+	keys := make([]uint64, 0, len(hashTable))
+	for k := range hashTable {
 		keys = append(keys, k)
-		values = append(values, field.FP(v[r]))
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	values := make([][]field.FP, 0, len(keys))
+	for _, k := range keys {
+		v := hashTable[k]
+		if len(v) > bucketSize {
+			sort.Slice(v, func(i, j int) bool { return v[i] < v[j] })
+			// partial Fisher-Yates shuffle to sample bucketSize elements
+			for i := 0; i < bucketSize; i++ {
+				r := i + rnd.Intn(len(v)-i)
+				v[i], v[r] = v[r], v[i]
+			}
+			v = v[:bucketSize]
+		}
+
+		bucket := make([]field.FP, bucketSize)
+		for i := range v {
+			bucket[i] = EncodeID(v[i])
+		}
+		values = append(values, bucket)
 	}
 	return keys, values
 }
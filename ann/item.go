@@ -0,0 +1,73 @@
+package ann
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/sachaservan/vec"
+)
+
+// Item is the content associated with a dataset id: the training vector
+// and an (optional) opaque payload
+type Item struct {
+	ID      int
+	Vector  *vec.Vec
+	Payload []byte
+}
+
+// ItemRecordBytes returns the size of an encoded item record
+// coordinates are stored as float32 followed by the payload length and payload
+func ItemRecordBytes(dim, maxPayloadBytes int) int {
+	return 4*dim + 2 + maxPayloadBytes
+}
+
+// EncodeItem encodes the vector and payload into a fixed-size record
+func EncodeItem(v *vec.Vec, payload []byte, maxPayloadBytes int) ([]byte, error) {
+	row := make([]float32, v.Size())
+	for i := range row {
+		row[i] = float32(v.Coord(i))
+	}
+	return EncodeItemRow(row, payload, maxPayloadBytes)
+}
+
+// EncodeItemRow is EncodeItem for a row of a Matrix (see Matrix.Row)
+func EncodeItemRow(row []float32, payload []byte, maxPayloadBytes int) ([]byte, error) {
+	if len(payload) > maxPayloadBytes || maxPayloadBytes > math.MaxUint16 {
+		return nil, errors.New("payload too large")
+	}
+
+	record := make([]byte, ItemRecordBytes(len(row), maxPayloadBytes))
+	for i, c := range row {
+		binary.LittleEndian.PutUint32(record[4*i:], math.Float32bits(c))
+	}
+
+	offset := 4 * len(row)
+	binary.LittleEndian.PutUint16(record[offset:], uint16(len(payload)))
+	copy(record[offset+2:], payload)
+
+	return record, nil
+}
+
+// DecodeItem decodes a record produced by EncodeItem
+func DecodeItem(record []byte, dim int) (*vec.Vec, []byte, error) {
+	if len(record) < ItemRecordBytes(dim, 0) {
+		return nil, nil, errors.New("record too short")
+	}
+
+	coords := make([]float64, dim)
+	for i := range coords {
+		coords[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(record[4*i:])))
+	}
+
+	offset := 4 * dim
+	payloadLen := int(binary.LittleEndian.Uint16(record[offset:]))
+	if offset+2+payloadLen > len(record) {
+		return nil, nil, errors.New("invalid payload length")
+	}
+
+	payload := make([]byte, payloadLen)
+	copy(payload, record[offset+2:])
+
+	return vec.NewVec(coords), payload, nil
+}
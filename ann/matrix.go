@@ -0,0 +1,126 @@
+package ann
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/sachaservan/vec"
+)
+
+// Matrix stores vectors of the same dimension contiguously, one row per vector, with float32
+// coordinates or (once quantized, see Quantize) uint8 codes: a row takes 4 (or 1) bytes per
+// coordinate where a *vec.Vec takes 8 bytes per coordinate plus a pointer and a slice header.
+// Large datasets (e.g., deep1b) should be read with ReadMatrix rather than ReadVectors.
+type Matrix struct {
+	Rows      int
+	Dimension int
+
+	// coordinates of the rows, one row after the other (nil once quantized)
+	Data []float32
+
+	// quantized coordinates, one row after the other: coordinate j
+	// of row i is Min + Step*Codes[i*Dimension+j] (see Quantize)
+	Codes []uint8
+	Min   float32
+	Step  float32
+}
+
+// NewMatrix returns a matrix of the given size whose coordinates are all zero
+func NewMatrix(rows, dimension int) *Matrix {
+	return &Matrix{Rows: rows, Dimension: dimension, Data: make([]float32, rows*dimension)}
+}
+
+// MatrixFromVecs copies the vectors (which should have the same dimension) into a matrix
+func MatrixFromVecs(data []*vec.Vec) (*Matrix, error) {
+	if len(data) == 0 {
+		return &Matrix{}, nil
+	}
+
+	m := NewMatrix(len(data), data[0].Size())
+	for i, v := range data {
+		if v.Size() != m.Dimension {
+			return nil, fmt.Errorf("vector %v has %v coordinates (expected %v)", i, v.Size(), m.Dimension)
+		}
+		row := m.Data[i*m.Dimension : (i+1)*m.Dimension]
+		for j := range row {
+			row[j] = float32(v.Coord(j))
+		}
+	}
+	return m, nil
+}
+
+// Quantized returns true if the coordinates are stored as uint8 codes
+func (m *Matrix) Quantized() bool {
+	return m.Codes != nil
+}
+
+// Row returns the coordinates of row i; the rows of a quantized matrix are decoded
+// into buf (of length Dimension) so that reading a row never allocates memory
+func (m *Matrix) Row(i int, buf []float32) []float32 {
+	if !m.Quantized() {
+		return m.Data[i*m.Dimension : (i+1)*m.Dimension]
+	}
+
+	codes := m.Codes[i*m.Dimension : (i+1)*m.Dimension]
+	for j, c := range codes {
+		buf[j] = m.Min + m.Step*float32(c)
+	}
+	return buf
+}
+
+// Vec returns (a copy of) row i as a vector
+func (m *Matrix) Vec(i int) *vec.Vec {
+	coords := make([]float64, m.Dimension)
+	for j, c := range m.Row(i, make([]float32, m.Dimension)) {
+		coords[j] = float64(c)
+	}
+	return vec.NewVec(coords)
+}
+
+// Vecs returns (copies of) all the rows as vectors
+func (m *Matrix) Vecs() []*vec.Vec {
+	data := make([]*vec.Vec, m.Rows)
+	for i := range data {
+		data[i] = m.Vec(i)
+	}
+	return data
+}
+
+// Quantize replaces the coordinates by uint8 codes, dividing the range of the coordinates
+// into 255 steps, which divides the memory used by 4. Integer coordinates that span at most
+// 256 values (e.g., those of bvecs and u8bin files) are stored exactly.
+func (m *Matrix) Quantize() {
+	if m.Quantized() {
+		return
+	}
+
+	var min, max float32
+	if len(m.Data) > 0 {
+		min, max = m.Data[0], m.Data[0]
+	}
+	integers := true
+	for _, c := range m.Data {
+		if c < min {
+			min = c
+		}
+		if c > max {
+			max = c
+		}
+		integers = integers && c == float32(math.Round(float64(c)))
+	}
+
+	m.Min = min
+	m.Step = (max - min) / 255
+	if integers && max-min <= 255 {
+		m.Step = 1
+	}
+
+	m.Codes = make([]uint8, len(m.Data))
+	if m.Step > 0 {
+		for i, c := range m.Data {
+			code := math.Round(float64((c - min) / m.Step))
+			m.Codes[i] = uint8(math.Max(0, math.Min(255, code)))
+		}
+	}
+	m.Data = nil
+}
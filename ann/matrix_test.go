@@ -0,0 +1,164 @@
+package ann
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/sachaservan/vec"
+)
+
+func TestMatrix(t *testing.T) {
+	data := []*vec.Vec{vec.NewVec([]float64{1, 2, 3}), vec.NewVec([]float64{-4, 0.5, 6})}
+
+	m, err := MatrixFromVecs(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Rows != 2 || m.Dimension != 3 || m.Quantized() {
+		t.Fatalf("unexpected matrix %v x %v (quantized: %v)", m.Rows, m.Dimension, m.Quantized())
+	}
+
+	for i, v := range data {
+		if !reflect.DeepEqual(m.Vec(i), v) {
+			t.Fatalf("row %v is %v (expected %v)", i, m.Vec(i), v)
+		}
+	}
+	if !reflect.DeepEqual(m.Vecs(), data) {
+		t.Fatalf("vectors of the matrix do not match")
+	}
+
+	// rows of float matrices are not copied
+	m.Row(1, nil)[0] = 7
+	if m.Data[3] != 7 {
+		t.Fatalf("row is not a view of the matrix")
+	}
+
+	if _, err := MatrixFromVecs([]*vec.Vec{vec.NewVec([]float64{1}), vec.NewVec([]float64{1, 2})}); err == nil {
+		t.Fatalf("vectors of different dimensions were accepted")
+	}
+	if m, err := MatrixFromVecs(nil); err != nil || m.Rows != 0 {
+		t.Fatalf("empty matrix: %v rows, %v", m.Rows, err)
+	}
+}
+
+func TestQuantize(t *testing.T) {
+	// integers spanning at most 256 values are stored exactly
+	m := NewMatrix(3, 4)
+	for i := range m.Data {
+		m.Data[i] = float32(-100 + 23*i)
+	}
+	expected := append([]float32{}, m.Data...)
+
+	m.Quantize()
+	if !m.Quantized() || m.Data != nil {
+		t.Fatalf("matrix was not quantized")
+	}
+	buf := make([]float32, m.Dimension)
+	for i := 0; i < m.Rows; i++ {
+		if row := m.Row(i, buf); !reflect.DeepEqual(row, expected[i*m.Dimension:(i+1)*m.Dimension]) {
+			t.Fatalf("row %v is %v (expected %v)", i, row, expected[i*m.Dimension:(i+1)*m.Dimension])
+		}
+	}
+
+	// other coordinates are rounded to one of 256 values
+	m = NewMatrix(10, 10)
+	for i := range m.Data {
+		m.Data[i] = float32(math.Sin(float64(i)) * 1000)
+	}
+	expected = append([]float32{}, m.Data...)
+
+	m.Quantize()
+	buf = make([]float32, m.Dimension)
+	for i := 0; i < m.Rows; i++ {
+		for j, c := range m.Row(i, buf) {
+			if diff := math.Abs(float64(c - expected[i*m.Dimension+j])); diff > float64(m.Step)/2+1e-3 {
+				t.Fatalf("coordinate (%v, %v) is %v (expected %v ± %v)", i, j, c, expected[i*m.Dimension+j], m.Step/2)
+			}
+		}
+	}
+
+	// constant coordinates
+	m = NewMatrix(2, 2)
+	for i := range m.Data {
+		m.Data[i] = 0.25
+	}
+	m.Quantize()
+	if row := m.Row(1, buf[:2]); row[0] != 0.25 || row[1] != 0.25 {
+		t.Fatalf("constant row is %v", row)
+	}
+}
+
+func TestNormalizeMatrix(t *testing.T) {
+	data := []*vec.Vec{vec.NewVec([]float64{3, 4}), vec.NewVec([]float64{0, 0}), vec.NewVec([]float64{-2, 0})}
+	m, err := MatrixFromVecs(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Euclidean.NormalizeMatrix(m)
+	if !reflect.DeepEqual(m.Vecs(), data) {
+		t.Fatalf("euclidean metric changed the rows")
+	}
+
+	Angular.NormalizeMatrix(m)
+	expected := []float32{0.6, 0.8, 0, 0, -1, 0}
+	if !reflect.DeepEqual(m.Data, expected) {
+		t.Fatalf("normalized rows are %v (expected %v)", m.Data, expected)
+	}
+
+	// the rows are normalized like the vectors
+	Angular.Normalize(data)
+	for i, v := range data {
+		for j, c := range m.Row(i, nil) {
+			if math.Abs(float64(c)-v.Coord(j)) > 1e-6 {
+				t.Fatalf("row %v is %v (expected %v)", i, m.Row(i, nil), v)
+			}
+		}
+	}
+
+	m.Quantize()
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("quantized matrix was normalized")
+		}
+	}()
+	Angular.NormalizeMatrix(m)
+}
+
+func TestReadMatrix(t *testing.T) {
+	data := testVectors(6, 4)
+
+	for _, ext := range formatExtensions {
+		format, _ := ParseVectorFormat(ext)
+		fileName := writeTestFile(t, "vectors"+ext, encodeVectors(format, data))
+
+		for _, quantize := range []bool{false, true} {
+			m, err := ReadMatrix(fileName, quantize)
+			if err != nil {
+				t.Fatalf("%v: %v", format, err)
+			}
+			if m.Rows != len(data) || m.Dimension != 4 || m.Quantized() != quantize {
+				t.Fatalf("%v: unexpected matrix %v x %v (quantized: %v)", format, m.Rows, m.Dimension, m.Quantized())
+			}
+
+			// the test coordinates are integers in [10, 256), which are quantized exactly
+			for i, coords := range data {
+				if !reflect.DeepEqual(m.Vec(i).Coords, coords) {
+					t.Fatalf("%v: row %v is %v (expected %v)", format, i, m.Vec(i).Coords, coords)
+				}
+			}
+
+			// uint8 coordinates are read straight into codes
+			if quantize && format.coordinateBytes() == 1 && (m.Min != 0 || m.Step != 1) {
+				t.Fatalf("%v: uint8 coordinates were rescaled (min %v, step %v)", format, m.Min, m.Step)
+			}
+		}
+	}
+
+	contents := encodeVectors(Fbin, data)
+	fileName := writeTestFile(t, "vectors.fbin", contents[:len(contents)-4])
+	if _, err := ReadMatrix(fileName, false); err == nil {
+		t.Fatalf("truncated file was accepted")
+	}
+}
@@ -0,0 +1,97 @@
+package ann
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/sachaservan/vec"
+)
+
+// DistanceMetric is the distance used to compare dataset items
+type DistanceMetric int
+
+const (
+	// Euclidean (l2) distance, hashed with lattice LSH
+	Euclidean DistanceMetric = iota
+	// Angular distance (the angle between the items, equivalent to cosine similarity),
+	// hashed with random hyperplanes; items and queries are normalized to unit vectors
+	Angular
+)
+
+// ParseDistanceMetric returns the metric with the given name
+// ("euclidean", or "angular" / "cosine")
+func ParseDistanceMetric(name string) (DistanceMetric, error) {
+	switch name {
+	case "euclidean", "l2":
+		return Euclidean, nil
+	case "angular", "cosine":
+		return Angular, nil
+	default:
+		return Euclidean, fmt.Errorf("unknown distance metric %q", name)
+	}
+}
+
+func (m DistanceMetric) String() string {
+	switch m {
+	case Euclidean:
+		return "euclidean"
+	case Angular:
+		return "angular"
+	default:
+		return fmt.Sprintf("DistanceMetric(%d)", int(m))
+	}
+}
+
+// Distance returns the distance between p and q
+// (for the angular metric the angle in radians, in [0, pi])
+func (m DistanceMetric) Distance(p, q *vec.Vec) float64 {
+	if m == Angular {
+		cos := vec.CosineDistance(p, q)
+		if math.IsNaN(cos) {
+			// zero vectors are orthogonal to everything
+			return math.Pi / 2
+		}
+		// clamp to avoid NaN from rounding errors
+		return math.Acos(math.Max(-1, math.Min(1, cos)))
+	}
+	return vec.EuclideanDistance(p, q)
+}
+
+// Normalize maps the vectors (in place) to the space used for hashing:
+// unit vectors for the angular metric (zero vectors are left unchanged)
+// and the identity for the euclidean metric
+func (m DistanceMetric) Normalize(data []*vec.Vec) {
+	if m != Angular {
+		return
+	}
+	for _, v := range data {
+		norm, _ := v.Dot(v)
+		if norm > 0 {
+			v.Normalize()
+		}
+	}
+}
+
+// NormalizeMatrix is Normalize for the rows of the matrix,
+// which should be normalized before being quantized
+func (m DistanceMetric) NormalizeMatrix(data *Matrix) {
+	if m != Angular {
+		return
+	}
+	if data.Quantized() {
+		panic("quantized matrices cannot be normalized")
+	}
+	for i := 0; i < data.Rows; i++ {
+		row := data.Row(i, nil)
+		norm := 0.0
+		for _, c := range row {
+			norm += float64(c) * float64(c)
+		}
+		if norm > 0 {
+			norm = math.Sqrt(norm)
+			for j, c := range row {
+				row[j] = float32(float64(c) / norm)
+			}
+		}
+	}
+}
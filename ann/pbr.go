@@ -5,7 +5,6 @@ import (
 
 	"github.com/sachaservan/private-ann/hash"
 	"github.com/sachaservan/private-ann/pir/field"
-	"github.com/sachaservan/vec"
 )
 
 type PBRBuckets struct {
@@ -13,6 +12,32 @@ type PBRBuckets struct {
 	Size       uint64
 	Max        uint64
 	NumBuckets int
+	Mask       uint64 // hashes are masked (to the key range) before they are assigned to a bucket
+}
+
+// NewPartitions returns the numPartitions partitions of the keys of a table with keyBits-bit keys
+// (see Probing.Partitions)
+func NewPartitions(numPartitions, keyBits int) *PBRBuckets {
+	p := NewPBRBuckets(KeySpace(keyBits), uint64(numPartitions))
+	p.Mask = KeyMask(keyBits)
+	return p
+}
+
+// KeySpace returns the number of possible keys with keyBits bits
+// (keys are universal hashes, which are smaller than hash.Prime)
+func KeySpace(keyBits int) uint64 {
+	if keyBits >= 64 {
+		return hash.Prime
+	}
+	return uint64(1) << keyBits
+}
+
+// KeyMask returns the mask that truncates hashes to keyBits-bit keys
+func KeyMask(keyBits int) uint64 {
+	if keyBits >= 64 {
+		return ^uint64(0)
+	}
+	return (uint64(1) << keyBits) - 1
 }
 
 func NewPBRBuckets(max uint64, numBuckets uint64) *PBRBuckets {
@@ -34,10 +59,12 @@ func NewPBRBuckets(max uint64, numBuckets uint64) *PBRBuckets {
 		Size:       skip,
 		Max:        max,
 		NumBuckets: int(numBuckets),
+		Mask:       ^uint64(0),
 	}
 }
 
 func (p *PBRBuckets) FindBucket(hash uint64) uint64 {
+	hash &= p.Mask
 	guess := hash / p.Size
 	if guess >= uint64(len(p.Buckets)) || p.Buckets[guess][0] > hash {
 		guess--
@@ -49,27 +76,33 @@ func (p *PBRBuckets) FindBucket(hash uint64) uint64 {
 
 type sorter struct {
 	keys   []uint64
-	values []field.FP
+	values [][]field.FP
 }
 
-func ComputeBucketDivisions(numBuckets int, keys []uint64, values []field.FP, hashKeyBits int) ([]int, []int) {
+// ComputeBucketDivisions sorts the keys (and values) of a table and returns the range
+// [starts[b], stops[b]) of the (sorted) keys that fall in each partition b
+func ComputeBucketDivisions(p *PBRBuckets, keys []uint64, values [][]field.FP) ([]int, []int) {
 	// first sort data
 	s := sorter{keys, values}
 	sort.Sort(&s)
 
-	mod := uint64(1) << hashKeyBits
-	p := NewPBRBuckets(mod, uint64(numBuckets))
+	numBuckets := p.NumBuckets
 	starts := make([]int, numBuckets)
 	stops := make([]int, numBuckets)
 	// technically we could use binary search but a linear scan suffices
 	bucket := 0
 	for i := 0; i < len(keys); i++ {
-		if keys[i] >= p.Buckets[bucket][1] {
+		// skip the (empty) partitions preceding the key
+		for bucket < numBuckets-1 && keys[i] >= p.Buckets[bucket][1] {
 			stops[bucket] = i
 			starts[bucket+1] = i
 			bucket++
 		}
 	}
+	for ; bucket < numBuckets-1; bucket++ {
+		stops[bucket] = len(keys)
+		starts[bucket+1] = len(keys)
+	}
 	stops[numBuckets-1] = len(keys)
 	return starts, stops
 }
@@ -87,20 +120,133 @@ func (s *sorter) Swap(i, j int) {
 	s.values[i], s.values[j] = s.values[j], s.values[i]
 }
 
-func ComputeProbes(hashFunction hash.Hash, query *vec.Vec, numPartitions, numProbes int) []uint64 {
+// MaxPartitionSize returns the maximum number of keys in any of the partitions
+func MaxPartitionSize(buckets *PBRBuckets, keys []uint64) int {
+	sizes := make([]int, buckets.NumBuckets)
+	max := 0
+	for _, key := range keys {
+		b := buckets.FindBucket(key)
+		sizes[b]++
+		if sizes[b] > max {
+			max = sizes[b]
+		}
+	}
+	return max
+}
+
+// SlotChoices is the number of candidate slots of each key in its partition of a slot table
+// (cuckoo hashing); the client queries all of them (see ComputeSlotTable)
+const SlotChoices = 2
+
+// maxSlotEvictions bounds the keys evicted (and moved to their other slot)
+// to insert a key into a slot table before the key is rejected
+const maxSlotEvictions = 500
+
+// SlotIndex returns the candidate slot (choice in [0, SlotChoices)) of the key within the
+// region of the choice in its partition of a slot table (see ComputeSlotTable)
+func SlotIndex(key uint64, choice, slotsPerPartition int) int {
+	// keys are hashes but their low bits also select the partition: mix them (splitmix64)
+	h := key + uint64(choice+1)*0x9e3779b97f4a7c15
+	h = (h ^ (h >> 30)) * 0xbf58476d1ce4e5b9
+	h = (h ^ (h >> 27)) * 0x94d049bb133111eb
+	h ^= h >> 31
+	return int(h % uint64(slotsPerPartition))
+}
+
+// SlotRecord returns the index of candidate slot choice of the key in a slot table
+func SlotRecord(buckets *PBRBuckets, key uint64, choice, slotsPerPartition int) int {
+	region := int(buckets.FindBucket(key))*SlotChoices + choice
+	return region*slotsPerPartition + SlotIndex(key, choice, slotsPerPartition)
+}
+
+// SlotTagElements is the number of field elements at the end of each slot
+// table record that store the key of the slot (see SlotTag)
+const SlotTagElements = 3
 
-	output := make([]uint64, numPartitions)
-	hashes := hashFunction.MultiHash(query, numProbes)
+// slotTagBits is the number of bits of the key stored in each tag element
+const slotTagBits = 22
 
-	buckets := NewPBRBuckets(hash.Prime, uint64(numPartitions))
-	used := make([]bool, numPartitions)
-	// hashes (should be) in optimal order so first come first serve
-	for _, h := range hashes {
-		bucket := buckets.FindBucket(h)
-		if !used[bucket] {
-			used[bucket] = true
-			output[bucket] = h
+// SlotTag encodes the key of a slot as SlotTagElements field elements
+func SlotTag(key uint64) []field.FP {
+	tag := make([]field.FP, SlotTagElements)
+	for e := range tag {
+		tag[e] = field.FP(key & (1<<slotTagBits - 1))
+		key >>= slotTagBits
+	}
+	return tag
+}
+
+// SlotMatches returns true if the (recovered) slot table record stores the bucket of the key;
+// records of other keys that share the slot must be discarded (see ComputeSlotTable)
+func SlotMatches(record []field.FP, key uint64) bool {
+	if len(record) < SlotTagElements {
+		return false
+	}
+	tag := SlotTag(key)
+	for e, v := range record[len(record)-SlotTagElements:] {
+		if v != tag[e] {
+			return false
 		}
 	}
-	return output
+	return true
+}
+
+// ComputeSlotTable lays out a hash table for (single-server) index PIR queries:
+// partition b of buckets is stored in the SlotChoices regions of slotsPerPartition records
+// that start at record (b*SlotChoices+c)*slotsPerPartition (for each choice c) and each key
+// is stored in one of its candidate slots (SlotIndex(key, c) of region c, see SlotRecord).
+// The keys are inserted with cuckoo hashing: a key whose candidate slots are taken evicts
+// the key of one of them, which moves to its other candidate slot, and so on.
+// Each record holds the bucket of a single key followed by the key's tag (see SlotTag)
+// so that the client can discard the buckets of the other keys of its candidate slots.
+// Keys that do not fit (after maxSlotEvictions evictions) are rejected and their number
+// is returned; empty slots are all zero.
+func ComputeSlotTable(buckets *PBRBuckets, slotsPerPartition int, keys []uint64, values [][]field.FP, bucketSize int) ([][]field.FP, int) {
+	records := make([][]field.FP, buckets.NumBuckets*SlotChoices*slotsPerPartition)
+
+	// index of the key of each slot (-1: empty)
+	slots := make([]int, len(records))
+	for i := range slots {
+		slots[i] = -1
+	}
+
+	rejected := 0
+	for i := range keys {
+		key, choice := i, 0
+		for evictions := 0; ; evictions++ {
+			if evictions > maxSlotEvictions {
+				rejected++
+				break
+			}
+
+			slot := SlotRecord(buckets, keys[key], choice, slotsPerPartition)
+			if slots[slot] < 0 {
+				slots[slot] = key
+				break
+			}
+
+			// take the slot and move its key to the next of its candidate slots
+			// (the region of the slot is the choice of the slot for the key)
+			key, slots[slot] = slots[slot], key
+			choice = (slot/slotsPerPartition + 1) % SlotChoices
+		}
+	}
+
+	for slot := range records {
+		records[slot] = make([]field.FP, bucketSize+SlotTagElements)
+		i := slots[slot]
+		if i < 0 {
+			continue
+		}
+
+		for l, v := range values[i] {
+			if v == 0 || l == bucketSize {
+				break
+			}
+			records[slot][l] = v
+		}
+		copy(records[slot][bucketSize:], SlotTag(keys[i]))
+	}
+
+	return records, rejected
 }
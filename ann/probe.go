@@ -0,0 +1,91 @@
+package ann
+
+import (
+	"fmt"
+
+	"github.com/sachaservan/private-ann/hash"
+	"github.com/sachaservan/vec"
+)
+
+// ProbeStrategy selects the bucket that a query probes in each partition of a table
+type ProbeStrategy int
+
+const (
+	// ProbeClosest probes, in each partition, the bucket of the first (closest) of the
+	// query's multi-probe hashes that falls in the partition (no bucket if none does)
+	ProbeClosest ProbeStrategy = iota
+)
+
+// ParseProbeStrategy returns the probing strategy with the given name ("closest")
+func ParseProbeStrategy(name string) (ProbeStrategy, error) {
+	switch name {
+	case "closest":
+		return ProbeClosest, nil
+	default:
+		return ProbeClosest, fmt.Errorf("unknown probing strategy %q", name)
+	}
+}
+
+func (s ProbeStrategy) String() string {
+	switch s {
+	case ProbeClosest:
+		return "closest"
+	default:
+		return fmt.Sprintf("ProbeStrategy(%d)", int(s))
+	}
+}
+
+// Probing describes how the tables are partitioned and how a query probes them.
+// The servers batch the tables with the same partitions that the clients probe,
+// so both must use the same Probing (it is part of the session parameters)
+type Probing struct {
+	KeyBits       int // range (in bits) of the keys (the hash function range)
+	NumPartitions int // number of partitions of each table; one bucket is probed per partition
+	NumProbes     int // number of multi-probe hashes of the query
+	Strategy      ProbeStrategy
+}
+
+// Partitions returns the partitions of the keys of each table
+func (p Probing) Partitions() *PBRBuckets {
+	return NewPartitions(p.NumPartitions, p.KeyBits)
+}
+
+// Keys returns the key probed in each partition of the table of hashFunction for the
+// (normalized) query and whether a bucket is probed in the partition at all. A partition that
+// is not probed has the first key of another partition, for which keyword queries over the
+// partition are empty (keys are any value in the key range, including 0)
+func (p Probing) Keys(hashFunction hash.Hash, query *vec.Vec) ([]uint64, []bool) {
+
+	output := make([]uint64, p.NumPartitions)
+	hashes := hashFunction.MultiHash(query, p.NumProbes)
+
+	buckets := p.Partitions()
+	used := make([]bool, p.NumPartitions)
+	// hashes (should be) in optimal order so first come first serve
+	for _, h := range hashes {
+		bucket := buckets.FindBucket(h)
+		if !used[bucket] {
+			used[bucket] = true
+			output[bucket] = h & buckets.Mask
+		}
+	}
+
+	for b := range output {
+		if !used[b] {
+			output[b] = buckets.Buckets[(b+1)%buckets.NumBuckets][0]
+		}
+	}
+
+	return output, used
+}
+
+// QueryKeys returns the (len(hashFunctions), NumPartitions) matrices of keys probed
+// for the (normalized) query and of whether they are probed, one row per table (see Keys)
+func (p Probing) QueryKeys(hashFunctions []hash.Hash, query *vec.Vec) ([][]uint64, [][]bool) {
+	keys := make([][]uint64, len(hashFunctions))
+	probed := make([][]bool, len(hashFunctions))
+	for i := range keys {
+		keys[i], probed[i] = p.Keys(hashFunctions[i], query)
+	}
+	return keys, probed
+}
@@ -0,0 +1,53 @@
+package ann
+
+import (
+	"testing"
+
+	"github.com/sachaservan/private-ann/hash"
+)
+
+func TestProbingPartitions(t *testing.T) {
+	rnd := hash.NewSeededRand([]byte("test"))
+	matrix := NewMatrix(200, 8)
+	for j := range matrix.Data {
+		matrix.Data[j] = float32(rnd.NormFloat64())
+	}
+	data := matrix.Vecs()
+
+	for _, keyBits := range []int{20, 63, 64} {
+		probing := Probing{KeyBits: keyBits, NumPartitions: 6, NumProbes: 4, Strategy: ProbeClosest}
+		h := hash.NewHyperplaneHash(rnd, 8, 12)
+
+		keys, values := ComputeHashes(rnd, 0, h, matrix, uint64(keyBits), 2)
+		starts, stops := ComputeBucketDivisions(probing.Partitions(), keys, values)
+
+		// the first probe of each item is its own key, in the partition the server put it in
+		for i, v := range data {
+			probes, probed := probing.Keys(h, v)
+			if len(probes) != probing.NumPartitions || len(probed) != probing.NumPartitions {
+				t.Fatalf("expected %v probes but got %v", probing.NumPartitions, len(probes))
+			}
+
+			key := h.Hash(v) & KeyMask(keyBits)
+			b := probing.Partitions().FindBucket(key)
+			if probes[b] != key || !probed[b] {
+				t.Fatalf("%v-bit keys: item %v probes %v in partition %v (expected %v)", keyBits, i, probes[b], b, key)
+			}
+
+			// the keys of the partitions that are not probed are outside the partitions
+			for p := range probes {
+				if inside := probing.Partitions().FindBucket(probes[p]) == uint64(p); inside != probed[p] {
+					t.Fatalf("%v-bit keys: key %v of partition %v (probed: %v) is inside the partition: %v", keyBits, probes[p], p, probed[p], inside)
+				}
+			}
+
+			found := false
+			for j := starts[b]; j < stops[b]; j++ {
+				found = found || keys[j] == key
+			}
+			if !found {
+				t.Fatalf("%v-bit keys: key of item %v is not in partition %v of the table", keyBits, i, b)
+			}
+		}
+	}
+}
@@ -1,72 +1,92 @@
 package ann
 
 import (
-	"bufio"
+	"crypto/sha256"
+	"fmt"
 	"io"
 	"os"
-	"strconv"
-	"strings"
+	"path/filepath"
 
 	"github.com/sachaservan/vec"
 )
 
-const trainDatasetSuffix = "_train.csv"
-const testDatasetSuffix = "_test.csv"
-const neighborsDatasetSuffix = "_neighbors.csv"
+// files of a dataset are named after the dataset with one of these suffixes
+// followed by the extension of their format (e.g., sift_base.fvecs, see VectorFormat)
+var (
+	trainDatasetSuffixes     = []string{"_train", "_base"}
+	testDatasetSuffixes      = []string{"_test", "_query"}
+	neighborsDatasetSuffixes = []string{"_neighbors", "_groundtruth"}
+)
 
+// NewDatastream reads all the vectors of the file (in any format, see OpenVectors)
 func NewDatastream(fileName string) ([]*vec.Vec, error) {
-	file, err := os.Open(fileName)
-	if err != nil {
-		return nil, err
-	}
-	data := make([]*vec.Vec, 0)
-	reader := bufio.NewReader(file)
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil && err != io.EOF {
-			panic(err)
-		}
-		if len(line) > 1 {
-			tokens := strings.Split(line, ",")
-			valuesFloat := make([]float64, len(tokens))
-			for j, v := range tokens {
-				// note in particular the glove dataset uses float and not int values
-				f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
-				if err != nil {
-					panic(err)
-				}
-				valuesFloat[j] = f
+	return ReadVectors(fileName)
+}
+
+// datasetFile returns the first file of the dataset with one of the suffixes
+// and the extension of a supported format
+func datasetFile(datasetName string, suffixes []string) (string, error) {
+	for _, suffix := range suffixes {
+		for _, ext := range formatExtensions {
+			fileName := datasetName + suffix + ext
+			if _, err := os.Stat(fileName); err == nil {
+				return fileName, nil
 			}
-			data = append(data, vec.NewVec(valuesFloat))
-		}
-		if err == io.EOF {
-			break
 		}
 	}
-	file.Close()
-	return data, nil
+	return "", fmt.Errorf("dataset %v has no %v file (e.g., %v)", datasetName, suffixes[0][1:], datasetName+suffixes[0]+".csv")
 }
 
+// ReadDataset reads the training vectors, the test vectors and the indices of the
+// nearest neighbors of each test vector of the dataset (see datasetFile)
 func ReadDataset(datasetName string) ([]*vec.Vec, []*vec.Vec, [][]int, error) {
-	trainDataset := datasetName + trainDatasetSuffix
-	testDataset := datasetName + testDatasetSuffix
-	neighborsDataset := datasetName + neighborsDatasetSuffix
-
-	trainData, err := NewDatastream(trainDataset)
+	trainDataset, err := datasetFile(datasetName, trainDatasetSuffixes)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	testData, err := NewDatastream(testDataset)
+	testData, neighbors, err := readTestData(datasetName)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	neighborIndices, err := NewDatastream(neighborsDataset)
+	trainData, err := ReadVectors(trainDataset)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
+	if len(trainData) == 0 {
+		return nil, nil, nil, fmt.Errorf("dataset %v has no training vectors", datasetName)
+	}
+
+	return trainData, testData, neighbors, nil
+}
+
+// readTestData reads the test vectors and the indices of their nearest neighbors
+func readTestData(datasetName string) ([]*vec.Vec, [][]int, error) {
+	testDataset, err := datasetFile(datasetName, testDatasetSuffixes)
+	if err != nil {
+		return nil, nil, err
+	}
+	neighborsDataset, err := datasetFile(datasetName, neighborsDatasetSuffixes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	testData, err := ReadVectors(testDataset)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	neighborIndices, err := ReadVectors(neighborsDataset)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(testData) == 0 {
+		return nil, nil, fmt.Errorf("dataset %v has no test vectors", datasetName)
+	}
+
 	neighborIdxs := make([][]int, len(neighborIndices))
 	// somewhat of a hack: convert vec.Vec
 	for i, indexVector := range neighborIndices {
@@ -77,5 +97,114 @@ func ReadDataset(datasetName string) ([]*vec.Vec, []*vec.Vec, [][]int, error) {
 		neighborIdxs[i] = indexes
 	}
 
-	return trainData, testData, neighborIdxs, nil
+	return testData, neighborIdxs, nil
+}
+
+// ReadDatasetForMetric reads the dataset and normalizes the training
+// and test vectors for the metric (see DistanceMetric.Normalize)
+func ReadDatasetForMetric(datasetName string, metric DistanceMetric) ([]*vec.Vec, []*vec.Vec, [][]int, error) {
+	trainData, testData, neighbors, err := ReadDataset(datasetName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	metric.Normalize(trainData)
+	metric.Normalize(testData)
+
+	return trainData, testData, neighbors, nil
+}
+
+// ReadDatasetMatrix is ReadDatasetForMetric for large datasets: the training vectors
+// are read into a Matrix (see ReadMatrix), whose coordinates are quantized if quantize is set
+// (after normalization for the angular metric)
+func ReadDatasetMatrix(datasetName string, metric DistanceMetric, quantize bool) (*Matrix, []*vec.Vec, [][]int, error) {
+	trainDataset, err := datasetFile(datasetName, trainDatasetSuffixes)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	testData, neighbors, err := readTestData(datasetName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	trainData, err := ReadMatrix(trainDataset, quantize && metric == Euclidean)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if trainData.Rows == 0 {
+		return nil, nil, nil, fmt.Errorf("dataset %v has no training vectors", datasetName)
+	}
+
+	metric.NormalizeMatrix(trainData)
+	metric.Normalize(testData)
+	if quantize {
+		trainData.Quantize()
+	}
+
+	return trainData, testData, neighbors, nil
+}
+
+// number and size of the chunks of each dataset file hashed by DatasetChecksum
+const (
+	checksumChunks    = 64
+	checksumChunkSize = 64 << 10
+)
+
+// DatasetChecksum returns a digest of the training and test files of the dataset
+// (the files that determine the contents of the hash tables). To keep server starts
+// cheap on large datasets, only the format, the size and checksumChunks evenly spaced
+// chunks (including the first and the last) of each file are hashed: a file edited in place
+// without changing its size or the sampled chunks is not detected (remove the cache instead).
+func DatasetChecksum(datasetName string) ([32]byte, error) {
+	var checksum [32]byte
+	h := sha256.New()
+	for _, suffixes := range [][]string{trainDatasetSuffixes, testDatasetSuffixes} {
+		fileName, err := datasetFile(datasetName, suffixes)
+		if err != nil {
+			return checksum, err
+		}
+
+		if err := sampleFile(h, fileName); err != nil {
+			return checksum, err
+		}
+	}
+
+	copy(checksum[:], h.Sum(nil))
+	return checksum, nil
+}
+
+// sampleFile writes the extension, the size and the sampled chunks of the file to w
+func sampleFile(w io.Writer, fileName string) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+
+	fmt.Fprintf(w, "%v:%v:", filepath.Ext(fileName), size)
+
+	// small files are hashed entirely
+	if size <= checksumChunks*checksumChunkSize {
+		_, err = io.Copy(w, file)
+		return err
+	}
+
+	chunk := make([]byte, checksumChunkSize)
+	for i := int64(0); i < checksumChunks; i++ {
+		offset := i * (size - checksumChunkSize) / (checksumChunks - 1)
+		if _, err := file.ReadAt(chunk, offset); err != nil {
+			return err
+		}
+		w.Write(chunk)
+	}
+
+	return nil
 }
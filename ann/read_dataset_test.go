@@ -0,0 +1,55 @@
+package ann
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func TestDatasetChecksum(t *testing.T) {
+	dataset := filepath.Join(t.TempDir(), "test")
+
+	// the training file is sampled, the test file is hashed entirely
+	train := make([]byte, checksumChunks*checksumChunkSize+12345)
+	rand.Read(train)
+	test := []byte("1,2,3\n")
+
+	checksum := func() [32]byte {
+		if err := ioutil.WriteFile(dataset+"_train.fvecs", train, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(dataset+"_test.csv", test, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		checksum, err := DatasetChecksum(dataset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return checksum
+	}
+
+	original := checksum()
+	if checksum() != original {
+		t.Fatalf("checksum of the same dataset changed")
+	}
+
+	// each edit is undone by applying it again
+	for name, edit := range map[string]func(){
+		"first byte":     func() { train[0] ^= 1 },
+		"last byte":      func() { train[len(train)-1] ^= 1 },
+		"test file byte": func() { test[0] ^= 1 },
+	} {
+		edit()
+		if checksum() == original {
+			t.Fatalf("checksum did not change with the %v", name)
+		}
+		edit()
+	}
+
+	train = append(train, 0)
+	if checksum() == original {
+		t.Fatalf("checksum did not change with the size")
+	}
+}
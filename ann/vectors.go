@@ -0,0 +1,390 @@
+package ann
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sachaservan/vec"
+)
+
+// VectorFormat is the encoding of a file of vectors (given by the file extension)
+type VectorFormat int
+
+const (
+	// CSV files (.csv) hold one vector per line (comma-separated coordinates)
+	CSV VectorFormat = iota
+	// Fvecs, Bvecs and Ivecs files (.fvecs, .bvecs, .ivecs, e.g., SIFT) hold, for each vector,
+	// its dimension (little-endian int32) followed by its float32, uint8 or int32 coordinates
+	Fvecs
+	Bvecs
+	Ivecs
+	// Fbin, U8bin and Ibin files (.fbin, .u8bin, .ibin, e.g., deep1b) hold the number of
+	// vectors and their dimension (little-endian uint32) followed by the float32, uint8 or
+	// int32 coordinates of the vectors, one vector after the other
+	Fbin
+	U8bin
+	Ibin
+)
+
+// extensions of the supported formats, in the order in which dataset files are looked up
+var formatExtensions = []string{".csv", ".fvecs", ".bvecs", ".ivecs", ".fbin", ".u8bin", ".ibin"}
+
+// ParseVectorFormat returns the format of the file extension (e.g., ".fvecs")
+func ParseVectorFormat(extension string) (VectorFormat, error) {
+	for i, ext := range formatExtensions {
+		if ext == extension {
+			return VectorFormat(i), nil
+		}
+	}
+	return CSV, fmt.Errorf("unknown vector format %q", extension)
+}
+
+func (f VectorFormat) String() string {
+	if f < 0 || int(f) >= len(formatExtensions) {
+		return fmt.Sprintf("VectorFormat(%d)", int(f))
+	}
+	return formatExtensions[f][1:]
+}
+
+// coordinateBytes returns the size of each coordinate of a binary format
+func (f VectorFormat) coordinateBytes() int {
+	switch f {
+	case Bvecs, U8bin:
+		return 1
+	default:
+		return 4
+	}
+}
+
+// readChunkSize is the number of vectors read at a time by ReadVectors
+const readChunkSize = 1 << 14
+
+// VectorReader streams the vectors of a file (see OpenVectors)
+type VectorReader struct {
+	Format    VectorFormat
+	Dimension int // dimension of the vectors (0 until the first vector is read, except for bin files)
+
+	name      string
+	file      *os.File
+	reader    *bufio.Reader
+	remaining int    // (bin formats) number of vectors left to read
+	read      int    // number of vectors read so far
+	line      int    // (CSV) number of lines read so far
+	buf       []byte // coordinates of the current vector
+}
+
+// OpenVectors opens the file of vectors, whose format is given by its extension
+// (see VectorFormat); the vectors are then read in chunks (see VectorReader.Read)
+func OpenVectors(fileName string) (*VectorReader, error) {
+	format, err := ParseVectorFormat(filepath.Ext(fileName))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", fileName, err)
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &VectorReader{
+		Format: format,
+		name:   fileName,
+		file:   file,
+		reader: bufio.NewReaderSize(file, 1<<20),
+	}
+
+	if format == Fbin || format == U8bin || format == Ibin {
+		var header [8]byte
+		if _, err := io.ReadFull(r.reader, header[:]); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("%v: failed to read header: %v", fileName, err)
+		}
+		r.remaining = int(binary.LittleEndian.Uint32(header[0:4]))
+		r.Dimension = int(binary.LittleEndian.Uint32(header[4:8]))
+		if r.Dimension == 0 && r.remaining > 0 {
+			file.Close()
+			return nil, fmt.Errorf("%v: vectors should have a positive dimension", fileName)
+		}
+		if err := r.checkSize(); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("%v: %v", fileName, err)
+		}
+	}
+
+	return r, nil
+}
+
+// checkSize makes sure that the size of a bin file matches the number of vectors and the
+// dimension of its header (so that a corrupted header cannot make readers allocate memory
+// for vectors that are not in the file)
+func (r *VectorReader) checkSize() error {
+	info, err := r.file.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	size := info.Size() - 8
+	rowBytes := int64(r.Dimension) * int64(r.Format.coordinateBytes())
+	if r.remaining == 0 && size == 0 {
+		return nil
+	}
+	if rowBytes == 0 || size%rowBytes != 0 || size/rowBytes != int64(r.remaining) {
+		return fmt.Errorf("header announces %v vectors of dimension %v but the file holds %v bytes of coordinates", r.remaining, r.Dimension, size)
+	}
+	return nil
+}
+
+// Read returns the next (at most n) vectors of the file, or io.EOF once all the vectors were read
+func (r *VectorReader) Read(n int) ([]*vec.Vec, error) {
+	vectors := make([]*vec.Vec, 0, n)
+	for len(vectors) < n {
+		v, err := r.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, v)
+	}
+
+	if len(vectors) == 0 && n > 0 {
+		return nil, io.EOF
+	}
+	return vectors, nil
+}
+
+// Close closes the file
+func (r *VectorReader) Close() error {
+	return r.file.Close()
+}
+
+// next reads the next vector (io.EOF at the end of the file)
+func (r *VectorReader) next() (*vec.Vec, error) {
+	coords, err := r.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Format != CSV {
+		coords = make([]float64, r.Dimension)
+		for j := range coords {
+			coords[j] = r.coordinate(j)
+		}
+	}
+	return vec.NewVec(coords), nil
+}
+
+// scan reads the next vector (io.EOF at the end of the file): the coordinates of a CSV
+// line are returned while those of a binary vector are left encoded in r.buf (see coordinate)
+func (r *VectorReader) scan() ([]float64, error) {
+	var coords []float64
+	var err error
+	switch r.Format {
+	case CSV:
+		coords, err = r.nextLine()
+	case Fvecs, Bvecs, Ivecs:
+		err = r.nextVecs()
+	default:
+		err = r.nextBin()
+	}
+	if err == io.EOF {
+		return nil, err
+	}
+	if err != nil {
+		if r.Format == CSV {
+			return nil, fmt.Errorf("%v: line %v: %v", r.name, r.line, err)
+		}
+		return nil, fmt.Errorf("%v: vector %v: %v", r.name, r.read, err)
+	}
+
+	r.read++
+	return coords, nil
+}
+
+// nextLine parses the next non-empty line of a CSV file
+func (r *VectorReader) nextLine() ([]float64, error) {
+	for {
+		line, err := r.reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(line) > 0 {
+			r.line++
+		}
+
+		if line = strings.TrimSpace(line); line != "" {
+			tokens := strings.Split(line, ",")
+			coords := make([]float64, len(tokens))
+			for j, v := range tokens {
+				// note in particular the glove dataset uses float and not int values
+				f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+				if err != nil {
+					return nil, err
+				}
+				coords[j] = f
+			}
+			return coords, r.checkDimension(len(coords))
+		}
+
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+	}
+}
+
+// nextVecs reads the dimension and coordinates of the next vector of an fvecs/bvecs/ivecs file
+func (r *VectorReader) nextVecs() error {
+	var header [4]byte
+	if _, err := io.ReadFull(r.reader, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return errors.New("file is truncated")
+		}
+		return err
+	}
+
+	dimension := int(int32(binary.LittleEndian.Uint32(header[:])))
+	if err := r.checkDimension(dimension); err != nil {
+		return err
+	}
+
+	return r.readCoords()
+}
+
+// nextBin reads the coordinates of the next vector of an fbin/u8bin/ibin file
+func (r *VectorReader) nextBin() error {
+	if r.remaining == 0 {
+		return io.EOF
+	}
+
+	if err := r.readCoords(); err != nil {
+		return err
+	}
+	r.remaining--
+
+	return nil
+}
+
+// readCoords reads the Dimension (binary) coordinates of a vector into r.buf
+func (r *VectorReader) readCoords() error {
+	size := r.Format.coordinateBytes()
+	if len(r.buf) != r.Dimension*size {
+		r.buf = make([]byte, r.Dimension*size)
+	}
+	if _, err := io.ReadFull(r.reader, r.buf); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return errors.New("file is truncated")
+		}
+		return err
+	}
+	return nil
+}
+
+// coordinate decodes the jth coordinate of the binary vector read by readCoords
+func (r *VectorReader) coordinate(j int) float64 {
+	switch r.Format {
+	case Fvecs, Fbin:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(r.buf[4*j:])))
+	case Ivecs, Ibin:
+		return float64(int32(binary.LittleEndian.Uint32(r.buf[4*j:])))
+	default:
+		return float64(r.buf[j])
+	}
+}
+
+// checkDimension makes sure that all the vectors of the file have the same (positive) dimension
+func (r *VectorReader) checkDimension(dimension int) error {
+	if dimension <= 0 {
+		return fmt.Errorf("invalid dimension %v", dimension)
+	}
+	if r.Dimension == 0 {
+		r.Dimension = dimension
+	}
+	if dimension != r.Dimension {
+		return fmt.Errorf("expected %v coordinates (got %v)", r.Dimension, dimension)
+	}
+	return nil
+}
+
+// ReadVectors reads all the vectors of the file (see OpenVectors)
+func ReadVectors(fileName string) ([]*vec.Vec, error) {
+	r, err := OpenVectors(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var data []*vec.Vec
+	for {
+		vectors, err := r.Read(readChunkSize)
+		if err == io.EOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, vectors...)
+	}
+}
+
+// ReadMatrix reads all the vectors of the file (see OpenVectors) into a Matrix, without
+// allocating a vector per row; the coordinates are rounded to float32, or quantized if
+// quantize is set (see Matrix.Quantize), in which case the coordinates of bvecs and
+// u8bin files are read straight into uint8 codes
+func ReadMatrix(fileName string, quantize bool) (*Matrix, error) {
+	r, err := OpenVectors(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	// the size of bin files is known in advance (and matches the file size, see checkSize)
+	m := &Matrix{}
+	codes := quantize && r.Format.coordinateBytes() == 1
+	if codes {
+		m.Codes = make([]uint8, 0, r.remaining*r.Dimension)
+		m.Step = 1
+	} else if r.remaining > 0 {
+		m.Data = make([]float32, 0, r.remaining*r.Dimension)
+	}
+
+	for {
+		coords, err := r.scan()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if codes {
+			m.Codes = append(m.Codes, r.buf...)
+		} else if r.Format == CSV {
+			for _, c := range coords {
+				m.Data = append(m.Data, float32(c))
+			}
+		} else {
+			for j := 0; j < r.Dimension; j++ {
+				m.Data = append(m.Data, float32(r.coordinate(j)))
+			}
+		}
+		m.Rows++
+	}
+
+	m.Dimension = r.Dimension
+	if quantize {
+		m.Quantize()
+	}
+	return m, nil
+}
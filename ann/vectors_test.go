@@ -0,0 +1,181 @@
+package ann
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sachaservan/vec"
+)
+
+// encodeVectors encodes the vectors in the format (inverse of VectorReader)
+func encodeVectors(format VectorFormat, data [][]float64) []byte {
+	var buf bytes.Buffer
+	putCoordinate := func(c float64) {
+		switch format {
+		case Fvecs, Fbin:
+			binary.Write(&buf, binary.LittleEndian, math.Float32bits(float32(c)))
+		case Ivecs, Ibin:
+			binary.Write(&buf, binary.LittleEndian, int32(c))
+		default:
+			buf.WriteByte(uint8(c))
+		}
+	}
+
+	switch format {
+	case CSV:
+		for _, coords := range data {
+			tokens := make([]string, len(coords))
+			for j, c := range coords {
+				tokens[j] = fmt.Sprint(c)
+			}
+			buf.WriteString(strings.Join(tokens, ",") + "\n")
+		}
+	case Fvecs, Bvecs, Ivecs:
+		for _, coords := range data {
+			binary.Write(&buf, binary.LittleEndian, int32(len(coords)))
+			for _, c := range coords {
+				putCoordinate(c)
+			}
+		}
+	default:
+		binary.Write(&buf, binary.LittleEndian, uint32(len(data)))
+		binary.Write(&buf, binary.LittleEndian, uint32(len(data[0])))
+		for _, coords := range data {
+			for _, c := range coords {
+				putCoordinate(c)
+			}
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func writeTestFile(t *testing.T, name string, contents []byte) string {
+	fileName := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(fileName, contents, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
+
+// testVectors returns vectors whose coordinates are exactly represented in every format
+// (integers in [10, 256), so that the uint8 codes of a quantized matrix are not the coordinates)
+func testVectors(n, dim int) [][]float64 {
+	data := make([][]float64, n)
+	for i := range data {
+		data[i] = make([]float64, dim)
+		for j := range data[i] {
+			data[i][j] = float64(10 + (i*dim+j*7)%246)
+		}
+	}
+	return data
+}
+
+func TestReadVectorsFormats(t *testing.T) {
+	data := testVectors(10, 5)
+
+	for _, ext := range formatExtensions {
+		format, err := ParseVectorFormat(ext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fileName := writeTestFile(t, "vectors"+ext, encodeVectors(format, data))
+
+		vectors, err := ReadVectors(fileName)
+		if err != nil {
+			t.Fatalf("%v: %v", format, err)
+		}
+		if len(vectors) != len(data) {
+			t.Fatalf("%v: expected %v vectors but got %v", format, len(data), len(vectors))
+		}
+		for i, v := range vectors {
+			if !reflect.DeepEqual(v.Coords, data[i]) {
+				t.Fatalf("%v: vector %v is %v (expected %v)", format, i, v.Coords, data[i])
+			}
+		}
+
+		// read in chunks
+		r, err := OpenVectors(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var chunks []*vec.Vec
+		for {
+			chunk, err := r.Read(3)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%v: %v", format, err)
+			}
+			chunks = append(chunks, chunk...)
+		}
+		r.Close()
+
+		if !reflect.DeepEqual(chunks, vectors) || r.Dimension != 5 {
+			t.Fatalf("%v: chunked read does not match", format)
+		}
+	}
+}
+
+func TestReadVectorsInconsistentDimension(t *testing.T) {
+	data := [][]float64{{1, 2, 3}, {4, 5}}
+
+	for _, format := range []VectorFormat{CSV, Fvecs, Bvecs, Ivecs} {
+		fileName := writeTestFile(t, "vectors."+format.String(), encodeVectors(format, data))
+		if _, err := ReadVectors(fileName); err == nil {
+			t.Fatalf("%v: vectors of different dimensions were accepted", format)
+		}
+	}
+
+	fileName := writeTestFile(t, "vectors.fvecs", encodeVectors(Fvecs, [][]float64{{}}))
+	if _, err := ReadVectors(fileName); err == nil {
+		t.Fatalf("vector of dimension 0 was accepted")
+	}
+}
+
+func TestReadVectorsTruncated(t *testing.T) {
+	data := testVectors(4, 3)
+
+	for _, ext := range formatExtensions[1:] {
+		format, _ := ParseVectorFormat(ext)
+		contents := encodeVectors(format, data)
+
+		// cut the last coordinate
+		fileName := writeTestFile(t, "vectors"+ext, contents[:len(contents)-format.coordinateBytes()])
+		if _, err := ReadVectors(fileName); err == nil {
+			t.Fatalf("%v: truncated file was accepted", format)
+		}
+	}
+}
+
+func TestReadVectorsHeaderCount(t *testing.T) {
+	data := testVectors(4, 3)
+
+	for _, format := range []VectorFormat{Fbin, U8bin, Ibin} {
+		for _, count := range []uint32{3, 5, math.MaxUint32} {
+			contents := encodeVectors(format, data)
+			binary.LittleEndian.PutUint32(contents, count)
+
+			fileName := writeTestFile(t, "vectors."+format.String(), contents)
+			if _, err := OpenVectors(fileName); err == nil {
+				t.Fatalf("%v: header announcing %v vectors (instead of 4) was accepted", format, count)
+			}
+		}
+
+		// trailing bytes
+		contents := append(encodeVectors(format, data), 0)
+		fileName := writeTestFile(t, "vectors."+format.String(), contents)
+		if _, err := OpenVectors(fileName); err == nil {
+			t.Fatalf("%v: trailing bytes were accepted", format)
+		}
+	}
+}
@@ -2,16 +2,36 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/gob"
-	"log"
-	"net/rpc"
+	"errors"
+	"fmt"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/sachaservan/paillier"
+	"github.com/sachaservan/private-ann/ann"
+	"github.com/sachaservan/private-ann/hash"
 	"github.com/sachaservan/private-ann/pir"
+	"github.com/sachaservan/private-ann/pir/field"
+	"github.com/sachaservan/vec"
 
 	"github.com/sachaservan/private-ann/cmd/api"
 )
 
+// ErrTableMismatch is returned by InitSession when the servers do not hold identical tables
+// (e.g., they were built from different datasets or seeds); their answers would be wrong
+var ErrTableMismatch = errors.New("servers hold different tables")
+
+// ErrVersionMismatch is returned by InitSession when the servers opened the session on
+// different versions of their tables (e.g., while the tables are updated); retry later
+var ErrVersionMismatch = errors.New("servers opened the session on different versions of their tables")
+
+// ErrNoSession is returned by queries made before InitSession
+var ErrNoSession = errors.New("no open session (see InitSession)")
+
 // RuntimeExperiment captures all the information needed to
 // evaluate a two-server deployment
 type RuntimeExperiment struct {
@@ -30,6 +50,31 @@ type RuntimeExperiment struct {
 	QueryClientMS           []int64 `json:"query_client_ms"`
 }
 
+// RecordQuery adds the statistics of a query to the experiment (see Client.Stats)
+func (experiment *RuntimeExperiment) RecordQuery(stats *QueryStats) {
+	experiment.QueryUpBandwidthBytes = append(experiment.QueryUpBandwidthBytes, stats.UpBandwidthBytes)
+	experiment.QueryDownBandwidthBytes = append(experiment.QueryDownBandwidthBytes, stats.DownBandwidthBytes)
+	experiment.QueryServerMS = append(experiment.QueryServerMS, stats.ServerQueryMS)
+	experiment.QueryMaskingServerUS = append(experiment.QueryMaskingServerUS, stats.ServerMaskingUS)
+}
+
+// QueryStats are the statistics of a query for buckets of the hash tables
+type QueryStats struct {
+	UpBandwidthBytes   int64 // size of the requests to the servers
+	DownBandwidthBytes int64 // size of the responses of the servers
+	ServerQueryMS      int64 // time taken by server A to evaluate the query
+	ServerMaskingUS    int64 // time taken by server A to mask the results
+}
+
+// ServerInfo describes the dataset and deployment of the servers (see InitSession)
+type ServerInfo struct {
+	DatasetName     string
+	DatasetSize     int
+	NumFeatures     int
+	NumServerProcs  int
+	PreprocessingMS int64 // time taken by server A to build its tables
+}
+
 // ServerA is the ID (index) of the first server
 const ServerA int = 0
 
@@ -41,74 +86,289 @@ type Client struct {
 	ServerAddresses []string
 	ServerPorts     []string
 	SessionParams   *api.SessionParameters
+	ServerInfo      *ServerInfo
+
+	// query a single server (ServerA) with Paillier-encrypted selection vectors
+	// instead of secret-sharing the queries between two servers
+	SingleServer bool
+	SecurityBits int // size (in bits) of the Paillier modulus
+
+	// number of neighbors returned by Search (0 is treated as 1) and whether Search
+	// retrieves the items of the candidates (always the case for more than one neighbor)
+	NumNeighbors  int
+	RetrieveItems bool
+
+	secretKey *paillier.SecretKey // generated on the first single-server query
 
-	// all timing information collected during protocol execution
-	Experiment *RuntimeExperiment
+	sessionIDs [2]int64 // ID of the client's session on each server
+
+	// hash functions of the session and the hash functions of each bundle fetched
+	// from the servers (by digest); bundles are also cached in HashBundleDir (optional)
+	hashFunctions []hash.Hash
+	hashBundles   map[string]*hashBundle
+	HashBundleDir string
+
+	// wire protocol: api.TransportGRPC (default) or api.TransportRPC
+	Transport string
+
+	// each call to a server is bounded by Timeout (0: no timeout; WaitForExperimentStart
+	// is not bounded) and calls that fail to reach a server are retried up to Retries times,
+	// waiting RetryBackoff (doubled after each retry) in between; InitSession is not retried
+	Timeout      time.Duration
+	Retries      int
+	RetryBackoff time.Duration
+
+	conns   [2]*serverConn // long-lived connection to each server (see call)
+	connsMu sync.Mutex
+
+	// connect to the servers over TLS (nil: plaintext, see NewTLSConfig);
+	// ServerPins optionally pins the public key of each server (see PinServer)
+	TLSConfig  *tls.Config
+	ServerPins [2][]byte
+
+	// called with the statistics of each query for buckets (optional)
+	Stats func(*QueryStats)
 }
 
 // WaitForExperimentStart completes once the servers are ready
 // to start the experiment
-func (client *Client) WaitForExperimentStart() {
+func (client *Client) WaitForExperimentStart(ctx context.Context) error {
 	args := api.WaitForExperimentArgs{}
 	res := api.WaitForExperimentResponse{}
 
 	// wait for server A
-	if !client.call(ServerA, "Server.WaitForExperiment", &args, &res) {
-		panic("failed to make RPC call")
+	if err := client.callTimeout(ctx, 0, ServerA, "Server.WaitForExperiment", &args, &res); err != nil {
+		return err
 	}
 
-	// wait for server B
-	if !client.call(ServerB, "Server.WaitForExperiment", &args, &res) {
-		panic("failed to make RPC call")
+	if client.SingleServer {
+		return nil
 	}
+
+	// wait for server B
+	return client.callTimeout(ctx, 0, ServerB, "Server.WaitForExperiment", &args, &res)
 }
 
-// InitSession creates a new API session with the server
-func (client *Client) InitSession() {
+// InitSession creates a new API session with each server. The session is only used once
+// the servers agree on it; otherwise the sessions opened on the servers are terminated
+// (and the previous session, if any, is kept)
+func (client *Client) InitSession(ctx context.Context) error {
 
 	args := &api.InitSessionArgs{}
 	res := &api.InitSessionResponse{}
 
-	if !client.call(ServerA, "Server.InitSession", &args, &res) {
-		panic("failed to make RPC call")
+	// InitSession is not retried: a call that timed out may still open a session
+	if err := client.callOnce(ctx, ServerA, "Server.InitSession", &args, &res); err != nil {
+		return err
+	}
+
+	sessionIDs := [2]int64{ServerA: res.SessionID}
+	opened := []int{ServerA}
+
+	var resB *api.InitSessionResponse
+	if !client.SingleServer {
+		resB = &api.InitSessionResponse{}
+		if err := client.callOnce(ctx, ServerB, "Server.InitSession", &args, &resB); err != nil {
+			client.endSessions(ctx, sessionIDs, opened)
+			return err
+		}
+		sessionIDs[ServerB] = resB.SessionID
+		opened = append(opened, ServerB)
+	}
+
+	hashes, err := client.checkSessions(ctx, res, resB)
+	if err != nil {
+		client.endSessions(ctx, sessionIDs, opened)
+		return err
+	}
+
+	params := res.SessionParameters
+	client.SessionParams = &params
+	client.sessionIDs = sessionIDs
+	client.hashFunctions = hashes
+
+	client.ServerInfo = &ServerInfo{
+		DatasetName:     res.StatsDatasetName,
+		DatasetSize:     res.StatsDatasetSize,
+		NumFeatures:     res.StatsNumFeatures,
+		NumServerProcs:  res.StatsNumServerProcs,
+		PreprocessingMS: res.StatsPreprocessingTimeInMS,
+	}
+
+	return nil
+}
+
+// checkSessions checks that both servers (resB is nil with a single server) opened the
+// session on the same tables and returns the hash functions of the session
+func (client *Client) checkSessions(ctx context.Context, res, resB *api.InitSessionResponse) ([]hash.Hash, error) {
+	// a (malicious) server could otherwise make the client divide the key space by zero
+	if res.NumPartitions <= 0 {
+		return nil, errors.New("server returned an invalid number of partitions")
+	}
+
+	// the hash functions of the session hash the vectors of the dataset (and the test query)
+	dimension := res.StatsNumFeatures
+	if res.TestQuery != nil && res.TestQuery.Size() != dimension {
+		return nil, errors.New("server returned a test query of another dimension than the dataset")
+	}
+
+	if resB != nil {
+		if resB.NumTables != res.NumTables || resB.NumProbes != res.NumProbes || resB.StatsNumFeatures != dimension ||
+			resB.BucketSize != res.BucketSize || resB.Probing() != res.Probing() ||
+			!bytes.Equal(resB.HashBundleDigest, res.HashBundleDigest) {
+			return nil, errors.New("servers returned inconsistent session parameters")
+		}
+
+		// both servers answer the queries of the session with this version
+		if resB.Version != res.Version {
+			return nil, ErrVersionMismatch
+		}
+
+		if !sameTables(res.TableDigests, resB.TableDigests, res.NumTables) {
+			return nil, ErrTableMismatch
+		}
+	}
+
+	return client.hashFunctionsWithDigest(ctx, res.HashBundleDigest, dimension)
+}
+
+// endSessions terminates the sessions (with the given IDs) opened on the servers;
+// failures are ignored since the servers expire unused sessions anyway
+func (client *Client) endSessions(ctx context.Context, sessionIDs [2]int64, servers []int) {
+	for _, serverID := range servers {
+		args := api.TerminateSessionArgs{SessionID: sessionIDs[serverID]}
+		client.call(ctx, serverID, "Server.TerminateSession", &args, &api.TerminateSessionResponse{})
 	}
+}
 
-	// TODO: don't manually copy these? Is there a cleaner way?
-	client.SessionParams = &api.SessionParameters{
-		SessionID:           res.SessionID,
-		NumTables:           res.NumTables,
-		NumProbes:           res.NumProbes,
-		TestQuery:           res.TestQuery,
-		HashFunctions:       res.HashFunctions,
-		HashFunctionRange:   res.HashFunctionRange,
-		TableBucketMetadata: res.TableBucketMetadata,
+// sameTables reports whether the servers returned the same digest for each of the numTables tables
+func sameTables(digestsA, digestsB [][]byte, numTables int) bool {
+	if len(digestsA) != numTables || len(digestsB) != numTables {
+		return false
 	}
 
-	client.Experiment.NumProbes = res.NumProbes
-	client.Experiment.HashFunctionRange = res.HashFunctionRange
-	client.Experiment.NumTables = res.NumTables
-	client.Experiment.DatasetName = res.StatsDatasetName
-	client.Experiment.DatasetSize = res.StatsDatasetSize
-	client.Experiment.NumFeatures = res.StatsNumFeatures
-	client.Experiment.NumServerProcs = res.StatsDatasetSize
+	for i := range digestsA {
+		if !bytes.Equal(digestsA[i], digestsB[i]) {
+			return false
+		}
+	}
 
+	return true
 }
 
 // PrivateANNQuery privately retrieves the values in buckets with associated keys
-// keys from each table and returns the first non-zero candidate.
-// keys: (NumTables, NumProbes) array keys to probe in each table
-// keywordBits: size of each keyword (DPF bits)
-func (client *Client) PrivateANNQuery(keys [][]uint64) int {
+// keys from each table and returns the ids in the first non-empty bucket.
+// keys: (NumTables, NumPartitions) array keys to probe in each table and
+// probed: whether each key is probed (nil: all keys are probed, see QueryKeys)
+func (client *Client) PrivateANNQuery(ctx context.Context, keys [][]uint64, probed [][]bool) ([]int, error) {
+	return client.PrivateKNNCandidates(ctx, keys, probed, 1)
+}
 
-	var wg sync.WaitGroup
+// PrivateKNNQuery privately retrieves the k nearest neighbors of the query among
+// the candidates returned by PrivateKNNCandidates. The candidate vectors are retrieved with
+// PrivateItemQuery (the servers must serve items) and the candidates are ranked by their true
+// distance to the query. Returns (at most) k ids and their distances to the query.
+func (client *Client) PrivateKNNQuery(ctx context.Context, query *vec.Vec, keys [][]uint64, probed [][]bool, k int) ([]int, []float64, error) {
 
-	allQueriesA := make([]*pir.BatchQueryShare, len(keys))
-	allQueriesB := make([]*pir.BatchQueryShare, len(keys))
+	items, distances, err := client.privateKNNQuery(ctx, query, keys, probed, k)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]int, len(items))
+	for i := range items {
+		ids[i] = items[i].ID
+	}
+
+	return ids, distances, nil
+}
+
+// privateKNNQuery retrieves the items of the candidates in up to k non-empty buckets
+// and returns (at most) the k items nearest to the query (and their distances)
+func (client *Client) privateKNNQuery(ctx context.Context, query *vec.Vec, keys [][]uint64, probed [][]bool, k int) ([]*ann.Item, []float64, error) {
+
+	candidates, err := client.PrivateKNNCandidates(ctx, keys, probed, k)
+	if err != nil || len(candidates) == 0 {
+		return []*ann.Item{}, []float64{}, err
+	}
+
+	// pad to the maximum number of candidates so that the servers
+	// do not learn how many candidates were revealed
+	items, err := client.privateItemQuery(ctx, candidates, k*client.SessionParams.BucketSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vectors := make([]*vec.Vec, len(items))
+	byID := make(map[int]*ann.Item, len(items))
+	for i := range items {
+		vectors[i] = items[i].Vector
+		byID[items[i].ID] = items[i]
+	}
 
-	if len(keys) != client.SessionParams.NumTables || len(keys[0]) != client.SessionParams.NumProbes {
-		panic("keys should have shape (NumTables, NumProbes)")
+	ranked, distances := RankCandidates(client.SessionParams.DistanceMetric, query, candidates, vectors)
+	if len(ranked) > k {
+		ranked = ranked[:k]
+		distances = distances[:k]
 	}
 
+	for i := range ranked {
+		items[i] = byID[ranked[i]]
+	}
+
+	return items[:len(ranked)], distances, nil
+}
+
+// PrivateKNNCandidates privately retrieves the values in buckets with associated keys
+// from each table and returns the (deduplicated) ids contained in up to k non-empty buckets.
+// The servers only reveal the first k non-empty buckets in probe order
+// (table by table, see server.obliviousMasking).
+// keys: (NumTables, NumPartitions) array keys to probe in each table and
+// probed: whether each key is probed (nil: all keys are probed, see QueryKeys)
+func (client *Client) PrivateKNNCandidates(ctx context.Context, keys [][]uint64, probed [][]bool, k int) ([]int, error) {
+
+	if client.SessionParams == nil {
+		return nil, ErrNoSession
+	}
+
+	if k < 1 || k > client.SessionParams.NumTables*client.SessionParams.NumPartitions {
+		return nil, errors.New("k should be between 1 and the number of probed buckets")
+	}
+
+	if len(keys) != client.SessionParams.NumTables {
+		return nil, errors.New("keys should have shape (NumTables, NumPartitions)")
+	}
+	for _, tableKeys := range keys {
+		if len(tableKeys) != client.SessionParams.NumPartitions {
+			return nil, errors.New("keys should have shape (NumTables, NumPartitions)")
+		}
+	}
+
+	if probed == nil {
+		probed = make([][]bool, len(keys))
+		for i := range probed {
+			probed[i] = make([]bool, len(keys[i]))
+			for j := range probed[i] {
+				probed[i][j] = true
+			}
+		}
+	}
+	if len(probed) != len(keys) {
+		return nil, errors.New("probed should have the shape of keys")
+	}
+	for i := range probed {
+		if len(probed[i]) != len(keys[i]) {
+			return nil, errors.New("probed should have the shape of keys")
+		}
+	}
+
+	if client.SingleServer {
+		return client.privateEncryptedKNNCandidates(ctx, keys, probed, k)
+	}
+
+	allQueriesA := make([]*pir.BatchQueryShare, len(keys))
+	allQueriesB := make([]*pir.BatchQueryShare, len(keys))
+
 	for i := 0; i < client.SessionParams.NumTables; i++ {
 		tableIndex := i
 		// one query per "probe" in the ith table
@@ -117,7 +377,9 @@ func (client *Client) PrivateANNQuery(keys [][]uint64) int {
 
 		bucketDbmd := client.SessionParams.TableBucketMetadata[tableIndex]
 		for j, k := range keys[tableIndex] {
-			q := bucketDbmd.NewKeywordQueryShares(k, 2, uint(client.SessionParams.HashFunctionRange))
+			// the servers check the verifiable keys together before answering; the keys of
+			// the partitions that are not probed are outside the partitions (see ann.Probing.Keys)
+			q := bucketDbmd.NewVerifiableKeywordQueryShares(k, 2, uint(client.SessionParams.HashFunctionRange))
 			qA[j] = q[0]
 			qB[j] = q[1]
 		}
@@ -136,103 +398,338 @@ func (client *Client) PrivateANNQuery(keys [][]uint64) int {
 
 	// RPC both servers (in parallel)
 	argsA := &api.ANNQueryArgs{}
-	argsA.SessionID = client.SessionParams.SessionID
+	argsA.SessionID = client.sessionIDs[ServerA]
+	argsA.NumResults = k
 	argsA.SecretShared = allQueriesA
+	argsA.Version = client.SessionParams.Version
 
 	argsB := &api.ANNQueryArgs{}
-	argsB.SessionID = client.SessionParams.SessionID
+	argsB.SessionID = client.sessionIDs[ServerB]
+	argsB.NumResults = k
 	argsB.SecretShared = allQueriesB
+	argsB.Version = client.SessionParams.Version
 
 	resA := &api.ANNQueryResponse{}
 	resB := &api.ANNQueryResponse{}
 
-	wg.Add(2)
-	go func() {
-		defer wg.Done()
-		if !client.call(ServerA, "Server.PrivateANNQuery", &argsA, &resA) {
-			panic("failed to make RPC call")
+	if err := client.callBoth(ctx, "Server.PrivateANNQuery", &argsA, &argsB, &resA, &resB); err != nil {
+		return nil, err
+	}
+
+	total := client.SessionParams.NumTables * client.SessionParams.NumPartitions
+	if len(resA.ResSecretShared) != total || len(resB.ResSecretShared) != total {
+		return nil, errors.New("servers returned the wrong number of buckets")
+	}
+
+	// final candidate set (obliviously masked by the servers)
+	candidates := client.collectCandidates(k, func(i, c int) ([]field.FP, bool) {
+		bucket := pir.Recover([]*pir.SecretSharedQueryResult{resA.ResSecretShared[i], resB.ResSecretShared[i]})
+		return version(bucket, c, k), true
+	})
+
+	if client.Stats != nil {
+		client.Stats(&QueryStats{
+			UpBandwidthBytes:   getSizeInBytes(argsA) + getSizeInBytes(argsB),
+			DownBandwidthBytes: getSizeInBytes(resA) + getSizeInBytes(resB),
+			ServerQueryMS:      resA.StatsQueryTimeInMS,
+			ServerMaskingUS:    resA.StatsMaskingTimeInUS,
+		})
+	}
+
+	return candidates, nil
+}
+
+// privateEncryptedKNNCandidates is PrivateKNNCandidates for a single server: for each table,
+// the client sends an encrypted selection vector over the slots of each partition
+// (see server.SlotTables) and decrypts the (obliviously masked) buckets
+func (client *Client) privateEncryptedKNNCandidates(ctx context.Context, keys [][]uint64, probed [][]bool, k int) ([]int, error) {
+
+	slotParams := client.SessionParams.SlotTables
+	if slotParams == nil {
+		return nil, errors.New("server does not serve single-server queries")
+	}
+
+	sk, err := client.paillierKey()
+	if err != nil {
+		return nil, err
+	}
+	pk := &sk.PublicKey
+
+	var wg sync.WaitGroup
+
+	// encrypting the selection vectors dominates the cost of the query
+	allQueries := make([]*pir.EncryptedBatchQuery, len(keys))
+	wg.Add(len(keys))
+	for i := range keys {
+		go func(tableIndex int) {
+			defer wg.Done()
+
+			// one query per candidate slot of the key of each partition
+			batchQuery := &pir.EncryptedBatchQuery{Queries: make([]*pir.EncryptedQuery, ann.SlotChoices*len(keys[tableIndex]))}
+			for j, key := range keys[tableIndex] {
+				for c := 0; c < ann.SlotChoices; c++ {
+					if probed[tableIndex][j] {
+						slot := ann.SlotIndex(key, c, slotParams.SlotsPerPartition)
+						batchQuery.Queries[j*ann.SlotChoices+c] = slotParams.NewEncryptedIndexQuery(pk, slot, slotParams.SlotsPerPartition)
+					} else {
+						batchQuery.Queries[j*ann.SlotChoices+c] = slotParams.NewEncryptedEmptyQuery(pk, slotParams.SlotsPerPartition)
+					}
+				}
+			}
+			allQueries[tableIndex] = batchQuery
+		}(i)
+	}
+	wg.Wait()
+
+	args := &api.EncryptedANNQueryArgs{}
+	args.SessionID = client.sessionIDs[ServerA]
+	args.NumResults = k
+	args.PublicKey = pk
+	args.Encrypted = allQueries
+	args.Version = client.SessionParams.Version
+
+	res := &api.EncryptedANNQueryResponse{}
+	if err := client.call(ctx, ServerA, "Server.PrivateEncryptedANNQuery", &args, &res); err != nil {
+		return nil, err
+	}
+
+	total := client.SessionParams.NumTables * client.SessionParams.NumPartitions * ann.SlotChoices
+	if len(res.ResEncrypted) != total {
+		return nil, errors.New("server returned the wrong number of buckets")
+	}
+
+	// each record is tagged with the key of its slot, which can be
+	// another key than the probed key (see ann.ComputeSlotTable)
+	numSlots := client.SessionParams.NumPartitions * ann.SlotChoices
+	candidates := client.collectCandidates(k, func(i, c int) ([]field.FP, bool) {
+		versions := res.ResEncrypted[i].Ciphertexts
+		size := len(versions) / k
+		if len(versions) != size*k {
+			return nil, false
 		}
-	}()
+		record := pir.RecoverEncrypted(sk, &pir.EncryptedQueryResult{Ciphertexts: versions[c*size : (c+1)*size]}, slotParams.SlotSize)
+		return record, ann.SlotMatches(record, keys[i/numSlots][i%numSlots/ann.SlotChoices])
+	})
+
+	if client.Stats != nil {
+		client.Stats(&QueryStats{
+			UpBandwidthBytes:   getSizeInBytes(args),
+			DownBandwidthBytes: getSizeInBytes(res),
+			ServerQueryMS:      res.StatsQueryTimeInMS,
+			ServerMaskingUS:    res.StatsMaskingTimeInUS,
+		})
+	}
 
-	go func() {
-		defer wg.Done()
-		if !client.call(ServerB, "Server.PrivateANNQuery", &argsB, &resB) {
-			panic("failed to make RPC call")
+	return candidates, nil
+}
+
+// collectCandidates returns the (deduplicated) ids in the first k non-empty buckets (in probe
+// order); recoverBucket(i, c) returns version c of the ith (recovered) bucket and false if the
+// bucket is not the bucket of the probed key (its ids are then discarded but it still counts
+// as non-empty). The servers return k versions of each bucket and version c of a bucket is
+// only unmasked if exactly c non-empty buckets precede it (see server.obliviousMasking)
+func (client *Client) collectCandidates(k int, recoverBucket func(i, c int) ([]field.FP, bool)) []int {
+
+	candidates := make([]int, 0)
+	seen := make(map[int]bool)
+
+	// recover each bucket and convert its slots to values (IDs)
+	bucketSize := client.SessionParams.BucketSize
+	total := client.SessionParams.NumTables * client.SessionParams.NumPartitions
+	numFound := 0
+	for i := 0; i < total && numFound < k; i++ {
+		bucket, matches := recoverBucket(i, numFound)
+		for l := 0; l < bucketSize && l < len(bucket); l++ {
+			id, ok := ann.DecodeID(bucket[l])
+			if !ok {
+				break
+			}
+
+			if l == 0 {
+				numFound++
+			}
+			if !matches {
+				break
+			}
+
+			// the same id can appear in buckets of different tables
+			if !seen[id] {
+				seen[id] = true
+				candidates = append(candidates, id)
+			}
 		}
-	}()
+	}
 
-	wg.Wait()
+	return candidates
+}
 
-	// final candidate set (obliviously masked by the servers)
-	candidate := 0
-
-	// recover each slot and covert to a value (ID)
-	total := client.SessionParams.NumTables * client.SessionParams.NumProbes
-	for i := 0; i < total; i++ {
-		shareA := resA.ResSecretShared[i]
-		shareB := resB.ResSecretShared[i]
-		val := int(pir.Recover([]*pir.SecretSharedQueryResult{shareA, shareB}))
-		if val != 0 {
-			candidate = val
-			break
+// version returns version c of a bucket made of k (concatenated) versions
+// (nil if the bucket is not made of k versions)
+func version(bucket []field.FP, c, k int) []field.FP {
+	size := len(bucket) / k
+	if len(bucket) != size*k {
+		return nil
+	}
+	return bucket[c*size : (c+1)*size]
+}
+
+// PrivateItemQuery privately retrieves the items (vectors and payloads) for the ids
+// (e.g., returned by PrivateANNQuery). The request is always padded with dummy queries
+// to BucketSize items so that the servers do not learn how many ids were found.
+func (client *Client) PrivateItemQuery(ctx context.Context, ids []int) ([]*ann.Item, error) {
+	if client.SessionParams == nil {
+		return nil, ErrNoSession
+	}
+
+	return client.privateItemQuery(ctx, ids, client.SessionParams.BucketSize)
+}
+
+// privateItemQuery retrieves the items for the ids padding the request to numQueries items
+func (client *Client) privateItemQuery(ctx context.Context, ids []int, numQueries int) ([]*ann.Item, error) {
+
+	if client.SingleServer {
+		return nil, errors.New("retrieving items requires two servers")
+	}
+
+	itemParams := client.SessionParams.ItemDB
+	if itemParams == nil {
+		return nil, errors.New("servers do not serve items")
+	}
+
+	if len(ids) > numQueries {
+		numQueries = len(ids)
+	}
+
+	// the dummy indices must be unpredictable to the servers
+	seed, err := hash.NewRandomSeed()
+	if err != nil {
+		return nil, err
+	}
+	rnd := hash.NewSeededRand(seed)
+
+	queriesA := make([]*pir.QueryShare, numQueries)
+	queriesB := make([]*pir.QueryShare, numQueries)
+	for i := 0; i < numQueries; i++ {
+		// dummy query for padding
+		index := uint64(rnd.Intn(itemParams.DBSize))
+		if i < len(ids) {
+			if ids[i] < 0 || ids[i] >= itemParams.DBSize {
+				return nil, fmt.Errorf("item %v is not in the item database", ids[i])
+			}
+			index = uint64(ids[i])
 		}
+
+		q := itemParams.NewVerifiableIndexQueryShares(index, 2, uint(itemParams.IndexBits))
+		queriesA[i] = q[0]
+		queriesB[i] = q[1]
+	}
+
+	argsA := &api.ItemQueryArgs{SessionID: client.sessionIDs[ServerA], Queries: queriesA, Version: client.SessionParams.Version}
+	argsB := &api.ItemQueryArgs{SessionID: client.sessionIDs[ServerB], Queries: queriesB, Version: client.SessionParams.Version}
+	resA := &api.ItemQueryResponse{}
+	resB := &api.ItemQueryResponse{}
+
+	if err := client.callBoth(ctx, "Server.PrivateItemQuery", &argsA, &argsB, &resA, &resB); err != nil {
+		return nil, err
 	}
 
-	// update the experiment statistics
-	totalUploadBytes := getSizeInBytes(argsA) + getSizeInBytes(argsB)
-	totalDownloadBytes := getSizeInBytes(resA) + getSizeInBytes(resB)
-	servQuery := resA.StatsQueryTimeInMS
-	servMasking := resA.StatsMaskingTimeInUS
-	client.Experiment.QueryUpBandwidthBytes = append(client.Experiment.QueryUpBandwidthBytes, totalUploadBytes)
-	client.Experiment.QueryDownBandwidthBytes = append(client.Experiment.QueryDownBandwidthBytes, totalDownloadBytes)
-	client.Experiment.QueryServerMS = append(client.Experiment.QueryServerMS, servQuery)
-	client.Experiment.QueryMaskingServerUS = append(client.Experiment.QueryMaskingServerUS, servMasking)
+	if len(resA.ResSecretShared) != numQueries || len(resB.ResSecretShared) != numQueries {
+		return nil, errors.New("servers returned the wrong number of items")
+	}
+
+	items := make([]*ann.Item, len(ids))
+	for i := range items {
+		res := []*pir.SecretSharedQueryResult{resA.ResSecretShared[i], resB.ResSecretShared[i]}
+		record := pir.RecoverBytes(res, itemParams.RecordBytes)
+		v, payload, err := ann.DecodeItem(record, itemParams.Dimension)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = &ann.Item{ID: ids[i], Vector: v, Payload: payload}
+	}
+
+	return items, nil
+}
+
+// RankCandidates orders the candidate ids by their distance (under metric) to the query
+// vectors[i] is the vector associated with candidates[i]
+func RankCandidates(metric ann.DistanceMetric, query *vec.Vec, candidates []int, vectors []*vec.Vec) ([]int, []float64) {
+	c := &hash.Candidates{
+		Indexes:   make([]uint64, len(candidates)),
+		Distances: make([]float64, len(candidates)),
+	}
+	for i := range candidates {
+		c.Indexes[i] = uint64(candidates[i])
+		c.Distances[i] = metric.Distance(query, vectors[i])
+	}
+	sort.Sort(c)
 
-	return candidate
+	ranked := make([]int, len(candidates))
+	for i := range ranked {
+		ranked[i] = int(c.Indexes[i])
+	}
+	return ranked, c.Distances
 }
 
 // TerminateSessions ends the client session on both servers
-func (client *Client) TerminateSessions() {
-	args := api.TerminateSessionArgs{}
+func (client *Client) TerminateSessions(ctx context.Context) error {
 	res := api.TerminateSessionResponse{}
 
-	// kill server A
-	if !client.call(ServerA, "Server.TerminateSession", &args, &res) {
-		panic("failed to make RPC call in terminate session")
+	args := api.TerminateSessionArgs{SessionID: client.sessionIDs[ServerA]}
+	if err := client.call(ctx, ServerA, "Server.TerminateSession", &args, &res); err != nil {
+		return err
 	}
 
-	// kill server B
-	if !client.call(ServerB, "Server.TerminateSession", &args, &res) {
-		panic("failed to make RPC call in terminate session")
+	if client.SingleServer {
+		return nil
 	}
-}
 
-// send an RPC request to the master, wait for the response
-func (client *Client) call(serverID int, rpcname string, args interface{}, reply interface{}) bool {
+	args = api.TerminateSessionArgs{SessionID: client.sessionIDs[ServerB]}
+	return client.call(ctx, ServerB, "Server.TerminateSession", &args, &res)
+}
 
-	cli, err := rpc.DialHTTP("tcp", client.ServerAddresses[serverID]+":"+client.ServerPorts[serverID])
-	if err != nil {
-		log.Fatal("dialing:", err)
+// paillierKey returns the client's Paillier key (generated on first use)
+func (client *Client) paillierKey() (*paillier.SecretKey, error) {
+	if client.secretKey == nil {
+		// (KeyGen panics on invalid sizes)
+		if client.SecurityBits < 64 || client.SecurityBits%2 != 0 {
+			return nil, fmt.Errorf("invalid Paillier key size %v (expected an even number of bits, at least 64)", client.SecurityBits)
+		}
+		client.secretKey, _ = paillier.KeyGen(client.SecurityBits)
 	}
+	return client.secretKey, nil
+}
 
-	defer cli.Close()
+// callBoth makes the same call to both servers (in parallel) and returns the first error
+func (client *Client) callBoth(ctx context.Context, rpcname string, argsA, argsB, replyA, replyB interface{}) error {
+	var errA, errB error
 
-	err = cli.Call(rpcname, args, reply)
-	if err == nil {
-		return true
-	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		errA = client.call(ctx, ServerA, rpcname, argsA, replyA)
+	}()
 
-	log.Printf("[Client]: failed to call RPC with error %v", err)
+	go func() {
+		defer wg.Done()
+		errB = client.call(ctx, ServerB, rpcname, argsB, replyB)
+	}()
+
+	wg.Wait()
 
-	return false
+	if errA != nil {
+		return errA
+	}
+	return errB
 }
 
+// getSizeInBytes returns the size of the gob encoding of s (0 if s cannot be encoded)
 func getSizeInBytes(s interface{}) int64 {
 	var b bytes.Buffer        // Stand-in for a network connection
 	enc := gob.NewEncoder(&b) // Will write to network.
-	err := enc.Encode(s)
-	if err != nil {
-		panic(err)
+	if err := enc.Encode(s); err != nil {
+		return 0
 	}
 
 	return int64(len(b.Bytes()))
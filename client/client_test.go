@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sachaservan/private-ann/cmd/api"
+	"github.com/sachaservan/private-ann/hash"
+	"github.com/sachaservan/vec"
+)
+
+func TestInitSessionMismatch(t *testing.T) {
+	session := func(id int64, version int, digest byte) api.InitSessionResponse {
+		res := api.InitSessionResponse{TableDigests: [][]byte{{digest}, {2}}}
+		res.SessionID = id
+		res.NumTables = 2
+		res.NumProbes = 1
+		res.NumPartitions = 1
+		res.BucketSize = 1
+		res.Version = version
+		return res
+	}
+
+	tests := []struct {
+		a, b api.InitSessionResponse
+		err  error
+	}{
+		{session(11, 1, 1), session(12, 2, 1), ErrVersionMismatch},
+		{session(11, 1, 1), session(12, 1, 3), ErrTableMismatch},
+	}
+
+	for _, test := range tests {
+		a, b := newFakeServer(t), newFakeServer(t)
+		a.session, b.session = test.a, test.b
+		client := newTestClient(t, a, b)
+
+		if err := client.InitSession(context.Background()); err != test.err {
+			t.Fatalf("expected %v but got %v", test.err, err)
+		}
+
+		// the session is not used and is terminated on both servers
+		keys := [][]uint64{{0}, {0}}
+		if _, err := client.PrivateANNQuery(context.Background(), keys, nil); err != ErrNoSession {
+			t.Fatalf("query after a failed InitSession returned %v", err)
+		}
+		if !reflect.DeepEqual(a.terminatedSessions(), []int64{11}) || !reflect.DeepEqual(b.terminatedSessions(), []int64{12}) {
+			t.Fatalf("expected sessions 11 and 12 to be terminated but got %v and %v", a.terminatedSessions(), b.terminatedSessions())
+		}
+	}
+}
+
+func TestInitSessionNoPartitions(t *testing.T) {
+	fake, client := startFakeServer(t)
+	fake.session.SessionID = 13
+	fake.session.NumTables = 1
+	fake.session.NumProbes = 1
+	fake.session.TableDigests = [][]byte{{1}}
+	fake.setHashBundle(t, &hash.Bundle{Descriptions: []*hash.Description{
+		{Kind: hash.Hyperplane, Seed: []byte("test"), Dimension: 4, NumPlanes: 8},
+	}})
+
+	if err := client.InitSession(context.Background()); err == nil {
+		t.Fatalf("session without partitions was accepted")
+	}
+	if client.SessionParams != nil {
+		t.Fatalf("session without partitions is used")
+	}
+}
+
+func TestInitSessionChecksHashFunctions(t *testing.T) {
+	fake, client := startFakeServer(t)
+	fake.session.SessionID = 13
+	fake.session.NumTables = 1
+	fake.session.NumProbes = 1
+	fake.session.NumPartitions = 1
+	fake.session.TableDigests = [][]byte{{1}}
+	client.SingleServer = true
+
+	// the hash function is not sampled: its dimension is not the dimension of the dataset
+	fake.setHashBundle(t, &hash.Bundle{Descriptions: []*hash.Description{
+		{Kind: hash.Hyperplane, Seed: []byte("test"), Dimension: 1 << 40, NumPlanes: hash.MaxNumPlanes},
+	}})
+	fake.session.StatsNumFeatures = 4
+	if err := client.InitSession(context.Background()); err == nil {
+		t.Fatalf("hash function of another dimension was accepted")
+	}
+
+	// nor is a test query of another dimension
+	fake.setHashBundle(t, &hash.Bundle{Descriptions: []*hash.Description{
+		{Kind: hash.Hyperplane, Seed: []byte("test"), Dimension: 4, NumPlanes: 8},
+	}})
+	fake.session.TestQuery = vec.NewVec(make([]float64, 5))
+	if err := client.InitSession(context.Background()); err == nil {
+		t.Fatalf("test query of another dimension was accepted")
+	}
+
+	fake.session.TestQuery = vec.NewVec(make([]float64, 4))
+	if err := client.InitSession(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,296 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/rpc"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/sachaservan/private-ann/cmd/api"
+	"github.com/sachaservan/private-ann/cmd/api/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultRetryBackoff is the delay before the first retry of a call when RetryBackoff is not set
+const DefaultRetryBackoff = 100 * time.Millisecond
+
+// maxRetryBackoff bounds the (doubling) delay between retries
+const maxRetryBackoff = 10 * time.Second
+
+// DialTimeout bounds the connection (and the handshakes) to a server when
+// the call does not have an earlier deadline (e.g., when Timeout is not set)
+const DialTimeout = 30 * time.Second
+
+// serverConn is the long-lived connection of the client to a server;
+// it is dialed on first use and redialed after connection failures
+type serverConn struct {
+	mu         sync.Mutex
+	rpcClient  *rpc.Client      // net/rpc transport
+	grpcConn   *grpc.ClientConn // gRPC transport
+	grpcClient pb.PrivateANNClient
+}
+
+// conn returns the connection to the server (created on first use)
+func (client *Client) conn(serverID int) *serverConn {
+	client.connsMu.Lock()
+	defer client.connsMu.Unlock()
+
+	if client.conns[serverID] == nil {
+		client.conns[serverID] = &serverConn{}
+	}
+	return client.conns[serverID]
+}
+
+// Close closes the connections to the servers
+// (it does not terminate the sessions, see TerminateSessions)
+func (client *Client) Close() error {
+	client.connsMu.Lock()
+	defer client.connsMu.Unlock()
+
+	var err error
+	for i, conn := range client.conns {
+		if conn == nil {
+			continue
+		}
+		if cerr := conn.close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		client.conns[i] = nil
+	}
+
+	return err
+}
+
+// call sends an API request to the server and waits for the response.
+// Each attempt is bounded by client.Timeout; calls that fail because the server
+// cannot be reached are retried (up to client.Retries times) with exponential backoff
+func (client *Client) call(ctx context.Context, serverID int, rpcname string, args interface{}, reply interface{}) error {
+	return client.callTimeout(ctx, client.Timeout, serverID, rpcname, args, reply)
+}
+
+// callOnce is call without retries, for the calls that cannot be repeated
+// safely (e.g., InitSession, which opens a new session on each call)
+func (client *Client) callOnce(ctx context.Context, serverID int, rpcname string, args interface{}, reply interface{}) error {
+	if client.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.Timeout)
+		defer cancel()
+	}
+
+	_, err := client.attempt(ctx, serverID, rpcname, args, reply)
+	return err
+}
+
+// callTimeout is call with a different timeout for each attempt (0: no timeout)
+func (client *Client) callTimeout(ctx context.Context, timeout time.Duration, serverID int, rpcname string, args interface{}, reply interface{}) error {
+
+	backoff := client.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+
+		retry, err := client.attempt(attemptCtx, serverID, rpcname, args, reply)
+		cancel()
+		if err == nil || !retry || attempt >= client.Retries || ctx.Err() != nil {
+			return err
+		}
+
+		log.Printf("[Client]: failed to reach server %v (%v); retrying in %v", serverID, err, backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		backoff *= 2
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// attempt makes a single call to the server and reports whether
+// the call failed because the server could not be reached (and can be retried)
+func (client *Client) attempt(ctx context.Context, serverID int, rpcname string, args interface{}, reply interface{}) (bool, error) {
+	conn := client.conn(serverID)
+	address := client.ServerAddresses[serverID] + ":" + client.ServerPorts[serverID]
+
+	if client.Transport != api.TransportRPC {
+		cli, err := conn.dialGRPC(address, client.tlsConfig(serverID))
+		if err != nil {
+			return false, err
+		}
+
+		err = pb.Invoke(ctx, cli, rpcname, args, reply)
+		return status.Code(err) == codes.Unavailable, err
+	}
+
+	cli, err := conn.dialRPC(ctx, address, client.tlsConfig(serverID))
+	if err != nil {
+		return true, err
+	}
+
+	// the response is decoded into a reply of this attempt: a call that times out may still
+	// be decoding its response, which must not race with the caller (or the next attempt)
+	attemptReply := reflect.New(reflect.TypeOf(reply).Elem())
+	call := cli.Go(rpcname, args, attemptReply.Interface(), make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+	case <-ctx.Done():
+		// the response may still arrive on the connection: drop it and redial on the next call
+		conn.reset(cli)
+		return false, ctx.Err()
+	}
+
+	// errors returned by the server are not connection failures
+	if _, ok := call.Error.(rpc.ServerError); call.Error != nil && !ok {
+		conn.reset(cli)
+		return true, call.Error
+	}
+	if call.Error != nil {
+		return false, call.Error
+	}
+
+	reflect.ValueOf(reply).Elem().Set(attemptReply.Elem())
+	return false, nil
+}
+
+// dialRPC connects to a net/rpc server over HTTP (like rpc.DialHTTP), over TLS if config is set;
+// the connection and the handshakes are bounded by ctx and DialTimeout
+func dialRPC(ctx context.Context, address string, config *tls.Config) (*rpc.Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, DialTimeout)
+	defer cancel()
+
+	var conn net.Conn
+	var err error
+	if config == nil {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = (&tls.Dialer{Config: config}).DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// a server that accepts the connection but never answers does not block the caller
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	io.WriteString(conn, "CONNECT "+rpc.DefaultRPCPath+" HTTP/1.0\n\n")
+
+	// the server switches to the RPC protocol once it accepts the CONNECT request
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: "CONNECT"})
+	if err == nil && resp.Status != "200 Connected to Go RPC" {
+		err = errors.New("unexpected HTTP response: " + resp.Status)
+	}
+	if err == nil {
+		err = conn.SetDeadline(time.Time{})
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return rpc.NewClient(conn), nil
+}
+
+// dialRPC returns the net/rpc connection to the server, dialing it if needed;
+// the lock is not held while dialing so that close and reset do not wait for it
+func (conn *serverConn) dialRPC(ctx context.Context, address string, config *tls.Config) (*rpc.Client, error) {
+	conn.mu.Lock()
+	cli := conn.rpcClient
+	conn.mu.Unlock()
+	if cli != nil {
+		return cli, nil
+	}
+
+	cli, err := dialRPC(ctx, address, config)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	// another call connected in the meantime: its connection is shared
+	if conn.rpcClient != nil {
+		cli.Close()
+		return conn.rpcClient, nil
+	}
+	conn.rpcClient = cli
+
+	return cli, nil
+}
+
+// dialGRPC returns the gRPC client of the server; the connection is established
+// (and reestablished after failures) in the background by gRPC
+func (conn *serverConn) dialGRPC(address string, config *tls.Config) (pb.PrivateANNClient, error) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if conn.grpcClient == nil {
+		creds := grpc.WithInsecure()
+		if config != nil {
+			creds = grpc.WithTransportCredentials(credentials.NewTLS(config))
+		}
+
+		cc, err := grpc.Dial(address, creds)
+		if err != nil {
+			return nil, err
+		}
+		conn.grpcConn = cc
+		conn.grpcClient = pb.NewPrivateANNClient(cc)
+	}
+
+	return conn.grpcClient, nil
+}
+
+// reset drops the (failed) net/rpc connection so that the next call redials the server
+func (conn *serverConn) reset(cli *rpc.Client) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if conn.rpcClient == cli {
+		cli.Close()
+		conn.rpcClient = nil
+	}
+}
+
+// close closes the connection to the server
+func (conn *serverConn) close() error {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	var err error
+	if conn.rpcClient != nil {
+		err = conn.rpcClient.Close()
+		conn.rpcClient = nil
+	}
+	if conn.grpcConn != nil {
+		if cerr := conn.grpcConn.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		conn.grpcConn = nil
+		conn.grpcClient = nil
+	}
+
+	return err
+}
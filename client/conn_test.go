@@ -0,0 +1,343 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"net"
+	"net/http"
+	"net/rpc"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sachaservan/private-ann/cmd/api"
+	"github.com/sachaservan/private-ann/hash"
+	"github.com/sachaservan/private-ann/pir"
+	"github.com/sachaservan/private-ann/pir/field"
+)
+
+// EchoArgs and EchoReply are the arguments and reply of fakeServer.Echo
+// (exported so that net/rpc can register the method)
+type EchoArgs struct {
+	Value int
+}
+
+type EchoReply struct {
+	Value int
+}
+
+// fakeServer is a net/rpc server (registered as "Server") whose
+// calls can be delayed and whose connections can be dropped
+type fakeServer struct {
+	host, port string
+	listener   *trackingListener
+	stall      int64 // delay (in ns) of each Echo call
+	drops      int32 // number of Echo calls that drop the connection instead of replying
+
+	session    api.InitSessionResponse // returned by InitSession
+	bundle     []byte                  // returned by HashBundle (see setHashBundle)
+	buckets    [][]field.FP            // (shares of the) buckets returned by PrivateANNQuery
+	mu         sync.Mutex
+	terminated []int64             // IDs of the terminated sessions
+	queries    []*api.ANNQueryArgs // received PrivateANNQuery requests
+}
+
+// trackingListener counts the accepted connections and refuses (closes right after
+// accepting them) the first refusals connections
+type trackingListener struct {
+	net.Listener
+	mu       sync.Mutex
+	conns    []net.Conn
+	refusals int32
+}
+
+func (l *trackingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	if atomic.AddInt32(&l.refusals, -1) >= 0 {
+		conn.Close()
+	}
+
+	l.mu.Lock()
+	l.conns = append(l.conns, conn)
+	l.mu.Unlock()
+	return conn, nil
+}
+
+func (l *trackingListener) numAccepted() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.conns)
+}
+
+func (l *trackingListener) dropAll() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, conn := range l.conns {
+		conn.Close()
+	}
+}
+
+func (s *fakeServer) Echo(args *EchoArgs, reply *EchoReply) error {
+	if atomic.AddInt32(&s.drops, -1) >= 0 {
+		s.listener.dropAll()
+		return nil
+	}
+
+	time.Sleep(time.Duration(atomic.LoadInt64(&s.stall)))
+	reply.Value = args.Value
+	return nil
+}
+
+func (s *fakeServer) InitSession(args *api.InitSessionArgs, reply *api.InitSessionResponse) error {
+	*reply = s.session
+	return nil
+}
+
+func (s *fakeServer) HashBundle(args *api.HashBundleArgs, reply *api.HashBundleResponse) error {
+	reply.Bundle = s.bundle
+	return nil
+}
+
+// setHashBundle makes the sessions of the server use the hash functions of the bundle
+// (and a dataset of their dimension)
+func (s *fakeServer) setHashBundle(t *testing.T, bundle *hash.Bundle) {
+	data, err := hash.EncodeBundle(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(data)
+	s.bundle = data
+	s.session.HashBundleDigest = digest[:]
+	if len(bundle.Descriptions) > 0 {
+		s.session.StatsNumFeatures = bundle.Descriptions[0].Dimension
+	}
+}
+
+func (s *fakeServer) TerminateSession(args *api.TerminateSessionArgs, reply *api.TerminateSessionResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.terminated = append(s.terminated, args.SessionID)
+	return nil
+}
+
+func (s *fakeServer) PrivateANNQuery(args *api.ANNQueryArgs, reply *api.ANNQueryResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries = append(s.queries, args)
+
+	reply.SessionID = args.SessionID
+	for _, bucket := range s.buckets {
+		reply.ResSecretShared = append(reply.ResSecretShared, &pir.SecretSharedQueryResult{Shares: bucket})
+	}
+	return nil
+}
+
+func (s *fakeServer) receivedQueries() []*api.ANNQueryArgs {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries
+}
+
+func (s *fakeServer) terminatedSessions() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.terminated
+}
+
+// newFakeServer serves a fakeServer over HTTP (like the servers' net/rpc transport)
+func newFakeServer(t *testing.T) *fakeServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeServer{listener: &trackingListener{Listener: listener}}
+	rpcServer := rpc.NewServer()
+	if err := rpcServer.RegisterName("Server", fake); err != nil {
+		t.Fatal(err)
+	}
+	go http.Serve(fake.listener, rpcServer)
+	t.Cleanup(func() { listener.Close() })
+
+	fake.host, fake.port, _ = net.SplitHostPort(listener.Addr().String())
+	return fake
+}
+
+// newTestClient returns a client of the fake servers A and B
+func newTestClient(t *testing.T, a, b *fakeServer) *Client {
+	client := &Client{
+		ServerAddresses: []string{a.host, b.host},
+		ServerPorts:     []string{a.port, b.port},
+		Transport:       api.TransportRPC,
+		RetryBackoff:    10 * time.Millisecond,
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// startFakeServer starts a fake server and returns a client that uses it as both servers
+func startFakeServer(t *testing.T) (*fakeServer, *Client) {
+	fake := newFakeServer(t)
+	return fake, newTestClient(t, fake, fake)
+}
+
+func TestCallTimeoutReconnects(t *testing.T) {
+	fake, client := startFakeServer(t)
+	client.Timeout = 20 * time.Millisecond
+	atomic.StoreInt64(&fake.stall, int64(100*time.Millisecond))
+
+	reply := &EchoReply{}
+	err := client.call(context.Background(), ServerA, "Server.Echo", &EchoArgs{Value: 1}, reply)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected the call to time out but got %v", err)
+	}
+
+	// the late response is not decoded into the reply of the caller
+	time.Sleep(150 * time.Millisecond)
+	if reply.Value != 0 {
+		t.Fatalf("reply was written after the call timed out")
+	}
+
+	// the next call does not reuse the connection of the timed out call
+	atomic.StoreInt64(&fake.stall, 0)
+	if err := client.call(context.Background(), ServerA, "Server.Echo", &EchoArgs{Value: 2}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Value != 2 {
+		t.Fatalf("expected reply 2 but got %v", reply.Value)
+	}
+	if fake.listener.numAccepted() != 2 {
+		t.Fatalf("expected 2 connections but got %v", fake.listener.numAccepted())
+	}
+}
+
+func TestCallRetriesWithBackoff(t *testing.T) {
+	fake, client := startFakeServer(t)
+	client.Retries = 3
+	atomic.StoreInt32(&fake.listener.refusals, 2)
+
+	start := time.Now()
+	reply := &EchoReply{}
+	if err := client.call(context.Background(), ServerA, "Server.Echo", &EchoArgs{Value: 3}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Value != 3 {
+		t.Fatalf("expected reply 3 but got %v", reply.Value)
+	}
+
+	// waited 10ms then 20ms before the successful attempt
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("retries did not back off (took %v)", elapsed)
+	}
+	if fake.listener.numAccepted() != 3 {
+		t.Fatalf("expected 3 connections but got %v", fake.listener.numAccepted())
+	}
+
+	// the server stays unreachable for longer than the retries
+	client.Close()
+	client.Retries = 1
+	atomic.StoreInt32(&fake.listener.refusals, 2)
+	if err := client.call(context.Background(), ServerA, "Server.Echo", &EchoArgs{Value: 4}, reply); err == nil {
+		t.Fatalf("call succeeded although the server was unreachable")
+	}
+}
+
+func TestCallReconnectsAfterDrop(t *testing.T) {
+	fake, client := startFakeServer(t)
+	client.Retries = 1
+
+	reply := &EchoReply{}
+	if err := client.call(context.Background(), ServerA, "Server.Echo", &EchoArgs{Value: 5}, reply); err != nil {
+		t.Fatal(err)
+	}
+
+	// the connection is dropped during the call: the call is retried on a new connection
+	atomic.StoreInt32(&fake.drops, 1)
+	if err := client.call(context.Background(), ServerA, "Server.Echo", &EchoArgs{Value: 6}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Value != 6 {
+		t.Fatalf("expected reply 6 but got %v", reply.Value)
+	}
+	if fake.listener.numAccepted() != 2 {
+		t.Fatalf("expected 2 connections but got %v", fake.listener.numAccepted())
+	}
+
+	// server errors are not retried
+	if err := client.call(context.Background(), ServerA, "Server.Missing", &EchoArgs{}, reply); err == nil {
+		t.Fatalf("call to a missing method succeeded")
+	}
+	if fake.listener.numAccepted() != 2 {
+		t.Fatalf("server error caused a reconnection")
+	}
+}
+
+func TestDialTimeout(t *testing.T) {
+	// the server accepts connections but never answers the CONNECT request
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	fake := newFakeServer(t)
+	fake.host, fake.port, _ = net.SplitHostPort(listener.Addr().String())
+	client := newTestClient(t, fake, fake)
+	client.Timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	if err := client.call(context.Background(), ServerA, "Server.Echo", &EchoArgs{}, &EchoReply{}); err == nil {
+		t.Fatalf("call to an unresponsive server succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("dial was not bounded by the timeout (took %v)", elapsed)
+	}
+
+	// the connection can be closed while it is dialed
+	client.Timeout = 200 * time.Millisecond
+	done := make(chan struct{})
+	go func() {
+		client.call(context.Background(), ServerA, "Server.Echo", &EchoArgs{}, &EchoReply{})
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	closed := make(chan struct{})
+	go func() {
+		client.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-done:
+		t.Fatalf("dial completed before the connection was closed")
+	}
+	<-done
+}
+
+func TestInitSessionIsNotRetried(t *testing.T) {
+	fake, client := startFakeServer(t)
+	client.SingleServer = true
+	client.Retries = 3
+	atomic.StoreInt32(&fake.listener.refusals, 1)
+
+	if err := client.InitSession(context.Background()); err == nil {
+		t.Fatalf("InitSession succeeded although the server dropped the connection")
+	}
+	if fake.listener.numAccepted() != 1 {
+		t.Fatalf("InitSession was retried (%v connections)", fake.listener.numAccepted())
+	}
+}
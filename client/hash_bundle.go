@@ -0,0 +1,104 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+
+	"github.com/sachaservan/private-ann/cmd/api"
+	"github.com/sachaservan/private-ann/hash"
+)
+
+// hashFunctionsWithDigest returns the hash functions of the bundle with the digest,
+// which must hash vectors of the dimension (see hash.Bundle.CheckDimension).
+// Bundles are content-addressed: each bundle is only fetched (from server A) and
+// reconstructed once, and optionally cached in HashBundleDir for other clients
+func (client *Client) hashFunctionsWithDigest(ctx context.Context, digest []byte, dimension int) ([]hash.Hash, error) {
+	key := hex.EncodeToString(digest)
+	if bundle, ok := client.hashBundles[key]; ok {
+		if err := bundle.bundle.CheckDimension(dimension); err != nil {
+			return nil, err
+		}
+		return bundle.hashes, nil
+	}
+
+	data := client.readHashBundle(key)
+	if !hasDigest(data, digest) {
+		args := api.HashBundleArgs{Digest: digest}
+		res := api.HashBundleResponse{}
+		if err := client.call(ctx, ServerA, "Server.HashBundle", &args, &res); err != nil {
+			return nil, err
+		}
+
+		data = res.Bundle
+		if !hasDigest(data, digest) {
+			return nil, errors.New("server returned a hash bundle that does not match its digest")
+		}
+		client.writeHashBundle(key, data)
+	}
+
+	bundle, err := hash.DecodeBundle(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// the parameters of the hash functions are checked before they are sampled
+	if err := bundle.CheckDimension(dimension); err != nil {
+		return nil, err
+	}
+
+	hashes, err := bundle.HashFunctions()
+	if err != nil {
+		return nil, err
+	}
+
+	if client.hashBundles == nil {
+		client.hashBundles = make(map[string]*hashBundle)
+	}
+	client.hashBundles[key] = &hashBundle{bundle: bundle, hashes: hashes}
+
+	return hashes, nil
+}
+
+// hashBundle is a bundle fetched from the servers and its (reconstructed) hash functions
+type hashBundle struct {
+	bundle *hash.Bundle
+	hashes []hash.Hash
+}
+
+func hasDigest(data []byte, digest []byte) bool {
+	if data == nil {
+		return false
+	}
+	actual := sha256.Sum256(data)
+	return bytes.Equal(actual[:], digest)
+}
+
+// readHashBundle returns the bundle cached in HashBundleDir (nil if it is not cached)
+func (client *Client) readHashBundle(key string) []byte {
+	if client.HashBundleDir == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(client.HashBundleDir, key+".bundle"))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// writeHashBundle caches the bundle in HashBundleDir (if set)
+func (client *Client) writeHashBundle(key string, data []byte) {
+	if client.HashBundleDir == "" {
+		return
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(client.HashBundleDir, key+".bundle"), data, 0644); err != nil {
+		log.Printf("[Client]: failed to cache hash bundle: %v", err)
+	}
+}
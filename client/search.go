@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sachaservan/private-ann/ann"
+	"github.com/sachaservan/vec"
+)
+
+// Result is the outcome of a Search
+type Result struct {
+	// ids of the candidates revealed by the servers; when the items are retrieved,
+	// (at most) NumNeighbors candidates ordered by their distance to the query
+	IDs []int
+
+	// distance of each candidate to the query and its item (vector and payload)
+	// (only when the items are retrieved)
+	Distances []float64
+	Items     []*ann.Item
+}
+
+// Search privately searches for the (approximate) nearest neighbors of the query:
+// the query is hashed with the hash functions of the session, the buckets of the
+// resulting keys are retrieved from the servers and (when the client retrieves items)
+// the candidates are ranked by their distance to the query. InitSession must be called first.
+func (client *Client) Search(ctx context.Context, query *vec.Vec) (*Result, error) {
+	if client.SessionParams == nil {
+		return nil, ErrNoSession
+	}
+
+	if client.SessionParams.NumTables < 1 || len(client.hashFunctions) != client.SessionParams.NumTables {
+		return nil, errors.New("session should have one hash function per table")
+	}
+
+	// queries live in the same (normalized) space as the items
+	q := query.Copy()
+	client.SessionParams.DistanceMetric.Normalize([]*vec.Vec{q})
+
+	keys, probed := client.QueryKeys(q)
+
+	k := client.NumNeighbors
+	if k < 1 {
+		k = 1
+	}
+
+	if k == 1 && !client.RetrieveItems {
+		ids, err := client.PrivateANNQuery(ctx, keys, probed)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{IDs: ids}, nil
+	}
+
+	items, distances, err := client.privateKNNQuery(ctx, q, keys, probed, k)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, len(items))
+	for i := range items {
+		ids[i] = items[i].ID
+	}
+
+	return &Result{IDs: ids, Distances: distances, Items: items}, nil
+}
+
+// QueryKeys returns the keys of the buckets to probe for the (normalized) query
+// in each table, i.e., a (NumTables, NumPartitions) array, and whether each key is
+// probed; the servers partition their tables the same way (see ann.Probing)
+func (client *Client) QueryKeys(query *vec.Vec) ([][]uint64, [][]bool) {
+	return client.SessionParams.Probing().QueryKeys(client.hashFunctions, query)
+}
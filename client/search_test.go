@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sachaservan/private-ann/ann"
+	"github.com/sachaservan/private-ann/hash"
+	"github.com/sachaservan/private-ann/pir"
+	"github.com/sachaservan/private-ann/pir/field"
+	"github.com/sachaservan/vec"
+)
+
+// newSearchServers returns fake servers whose sessions have numTables tables
+// (of 2 partitions and buckets of 2 ids) and a client with a session on them
+func newSearchServers(t *testing.T, numTables int) (*fakeServer, *fakeServer, *Client) {
+	a, b := newFakeServer(t), newFakeServer(t)
+
+	descriptions := make([]*hash.Description, numTables)
+	digests := make([][]byte, numTables)
+	metadata := make([]*pir.DBMetadata, numTables)
+	for i := range descriptions {
+		descriptions[i] = &hash.Description{Kind: hash.Hyperplane, Seed: hash.DeriveSeed([]byte("test"), i), Dimension: 4, NumPlanes: 8}
+		digests[i] = []byte{byte(i)}
+		metadata[i] = &pir.DBMetadata{DBSize: 10, SlotSize: 2}
+	}
+
+	for i, fake := range []*fakeServer{a, b} {
+		fake.session.SessionID = int64(11 + i)
+		fake.session.NumTables = numTables
+		fake.session.NumProbes = 2
+		fake.session.NumPartitions = 2
+		fake.session.BucketSize = 2
+		fake.session.HashFunctionRange = 20
+		fake.session.TableBucketMetadata = metadata
+		fake.session.TableDigests = digests
+		fake.setHashBundle(t, &hash.Bundle{Descriptions: descriptions})
+	}
+
+	client := newTestClient(t, a, b)
+	if err := client.InitSession(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return a, b, client
+}
+
+func TestSearch(t *testing.T) {
+	a, b, client := newSearchServers(t, 2)
+
+	var stats []*QueryStats
+	client.Stats = func(s *QueryStats) { stats = append(stats, s) }
+
+	// server A holds the buckets and server B shares of zero: the first
+	// non-empty bucket is the second one (the third one is ignored)
+	empty := []field.FP{0, 0}
+	a.buckets = [][]field.FP{empty, {ann.EncodeID(7), ann.EncodeID(3)}, {ann.EncodeID(9), 0}, empty}
+	b.buckets = [][]field.FP{empty, empty, empty, empty}
+
+	res, err := client.Search(context.Background(), vec.NewVec([]float64{1, 2, 3, 4}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res.IDs, []int{7, 3}) || res.Items != nil {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if len(stats) != 1 || stats[0].UpBandwidthBytes == 0 || stats[0].DownBandwidthBytes == 0 {
+		t.Fatalf("statistics were not reported")
+	}
+
+	// each server receives its share of one query per partition of each table
+	for i, fake := range []*fakeServer{a, b} {
+		queries := fake.receivedQueries()
+		if len(queries) != 1 {
+			t.Fatalf("server %v received %v queries", i, len(queries))
+		}
+		args := queries[0]
+		if args.SessionID != int64(11+i) || args.NumResults != 1 || len(args.SecretShared) != 2 {
+			t.Fatalf("server %v received an unexpected query %+v", i, args)
+		}
+		for _, batchQuery := range args.SecretShared {
+			if len(batchQuery.Queries) != 2 {
+				t.Fatalf("server %v received %v queries for a table (expected 2)", i, len(batchQuery.Queries))
+			}
+		}
+	}
+
+	// the servers return the wrong number of buckets
+	a.buckets = a.buckets[:3]
+	if _, err := client.Search(context.Background(), vec.NewVec([]float64{1, 2, 3, 4})); err == nil {
+		t.Fatalf("wrong number of buckets was accepted")
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	fake, client := startFakeServer(t)
+	query := vec.NewVec([]float64{1, 2, 3, 4})
+
+	if _, err := client.Search(context.Background(), query); err != ErrNoSession {
+		t.Fatalf("expected %v but got %v", ErrNoSession, err)
+	}
+
+	// a session without tables yields no keys
+	fake.session.SessionID = 13
+	fake.session.NumPartitions = 1
+	fake.setHashBundle(t, &hash.Bundle{})
+	if err := client.InitSession(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Search(context.Background(), query); err == nil {
+		t.Fatalf("search without tables succeeded")
+	}
+
+	// keys of the wrong shape
+	_, _, client = newSearchServers(t, 2)
+	for _, keys := range [][][]uint64{nil, {{1, 2}}, {{1, 2}, {3}}} {
+		if _, err := client.PrivateANNQuery(context.Background(), keys, nil); err == nil {
+			t.Fatalf("keys %v were accepted", keys)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+)
+
+// NewTLSConfig returns the TLS configuration used to connect to the servers.
+// Server certificates are verified against the CAs in caFile (the system CAs if empty);
+// when certFile and keyFile are set, the client presents that certificate to the
+// servers (required by servers that authenticate their clients)
+func NewTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in " + caFile)
+		}
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// PublicKeyPin returns the pin of a certificate: the SHA-256 digest of its public key
+// (DER-encoded SubjectPublicKeyInfo). The pin of a PEM certificate can be computed with
+// openssl x509 -in cert.pem -pubkey -noout | openssl pkey -pubin -outform der | sha256sum
+func PublicKeyPin(cert *x509.Certificate) []byte {
+	digest := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return digest[:]
+}
+
+// PinServer returns a copy of config that, in addition to the usual certificate checks,
+// only accepts servers whose certificate has the given pin (see PublicKeyPin).
+// Pinning prevents a CA from impersonating one server to obtain both query shares
+func PinServer(config *tls.Config, pin []byte) *tls.Config {
+	pinned := config.Clone()
+	pinned.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("server did not present a certificate")
+		}
+
+		cert, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(PublicKeyPin(cert), pin) {
+			return errors.New("server certificate does not match the pinned public key")
+		}
+
+		return nil
+	}
+
+	return pinned
+}
+
+// tlsConfig returns the TLS configuration used to connect to the server
+// (nil if the client does not use TLS)
+func (client *Client) tlsConfig(serverID int) *tls.Config {
+	if client.TLSConfig == nil {
+		return nil
+	}
+
+	config := client.TLSConfig
+	if client.ServerPins[serverID] != nil {
+		config = PinServer(config, client.ServerPins[serverID])
+	}
+
+	if config.ServerName == "" {
+		config = config.Clone()
+		config.ServerName = client.ServerAddresses[serverID]
+	}
+
+	return config
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/rpc"
+	"reflect"
+
+	"github.com/alexflint/go-arg"
+	"github.com/sachaservan/private-ann/ann"
+	"github.com/sachaservan/private-ann/cmd/api"
+)
+
+// command-line arguments to run the admin tool
+var args struct {
+	Command   string   `arg:"positional,required"`           // shutdown, reload, status or update
+	Sockets   []string `arg:"--socket,separate,required"`    // admin socket of each server (see --adminsocket)
+	TokenFile []string `arg:"--tokenfile,separate,required"` // file containing the admin token (one per socket or shared)
+
+	// (update) file of the vectors to insert (see ann.OpenVectors) and ids to delete; the same
+	// update is applied to every server (see api.UpdateArgs)
+	Insert string
+	Delete []int
+}
+
+func main() {
+	arg.MustParse(&args)
+
+	if len(args.TokenFile) != 1 && len(args.TokenFile) != len(args.Sockets) {
+		log.Fatal("[Admin]: expected one token file per socket (or a single token file)")
+	}
+
+	clients := make([]*rpc.Client, len(args.Sockets))
+	tokens := make([]string, len(args.Sockets))
+	for i, socket := range args.Sockets {
+		tokenFile := args.TokenFile[0]
+		if len(args.TokenFile) > 1 {
+			tokenFile = args.TokenFile[i]
+		}
+
+		token, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			log.Fatalf("[Admin]: failed to read admin token: %v", err)
+		}
+		tokens[i] = string(bytes.TrimSpace(token))
+
+		clients[i], err = rpc.Dial("unix", socket)
+		if err != nil {
+			log.Fatalf("[Admin]: failed to connect to admin socket %v: %v", socket, err)
+		}
+		defer clients[i].Close()
+	}
+
+	switch args.Command {
+	case "shutdown":
+		for i, cli := range clients {
+			req := &api.AdminArgs{Token: tokens[i]}
+			if err := cli.Call("Admin.Shutdown", req, &api.AdminResponse{}); err != nil {
+				log.Fatalf("[Admin]: shutdown of %v failed: %v", args.Sockets[i], err)
+			}
+		}
+	case "reload":
+		reload(clients, tokens)
+	case "status":
+		for i := range clients {
+			status := getStatus(clients[i], tokens[i], args.Sockets[i])
+			log.Printf("[Admin]: %v: version %v, %v ids, digest %x", args.Sockets[i], status.Version, status.DBSize, status.Digest)
+		}
+	case "update":
+		update(clients, tokens)
+	default:
+		log.Fatalf("[Admin]: unknown command %q (expected shutdown, reload, status or update)", args.Command)
+	}
+
+	log.Printf("[Admin]: %v succeeded", args.Command)
+}
+
+func getStatus(cli *rpc.Client, token, socket string) *api.AdminStatusResponse {
+	status := &api.AdminStatusResponse{}
+	if err := cli.Call("Admin.Status", &api.AdminArgs{Token: token}, status); err != nil {
+		log.Fatalf("[Admin]: status of %v failed: %v", socket, err)
+	}
+	return status
+}
+
+// reload rebuilds the tables of every server as the same version, which is newer than
+// the versions of all the servers (so that servers restarted separately agree again)
+func reload(clients []*rpc.Client, tokens []string) {
+	req := &api.ReloadArgs{}
+	for i := range clients {
+		if status := getStatus(clients[i], tokens[i], args.Sockets[i]); status.Version >= req.Version {
+			req.Version = status.Version + 1
+		}
+	}
+
+	for i, cli := range clients {
+		req.Token = tokens[i]
+		if err := cli.Call("Admin.Reload", req, &api.AdminResponse{}); err != nil {
+			log.Fatalf("[Admin]: reload of %v failed: %v", args.Sockets[i], err)
+		}
+	}
+
+	log.Printf("[Admin]: reloaded the tables as version %v", req.Version)
+}
+
+// update applies the next update to every server, making sure that
+// the servers have identical tables before and after the update
+func update(clients []*rpc.Client, tokens []string) {
+	req := &api.UpdateArgs{Delete: args.Delete}
+	if args.Insert != "" {
+		vectors, err := ann.NewDatastream(args.Insert)
+		if err != nil {
+			log.Fatalf("[Admin]: failed to read vectors to insert: %v", err)
+		}
+		req.Insert = vectors
+	}
+
+	status := getStatus(clients[0], tokens[0], args.Sockets[0])
+	for i := 1; i < len(clients); i++ {
+		if !reflect.DeepEqual(getStatus(clients[i], tokens[i], args.Sockets[i]), status) {
+			log.Fatalf("[Admin]: %v and %v have different tables (see the status command)", args.Sockets[0], args.Sockets[i])
+		}
+	}
+	req.Version = status.Version + 1
+
+	var first *api.UpdateResponse
+	for i, cli := range clients {
+		req.Token = tokens[i]
+		reply := &api.UpdateResponse{}
+		if err := cli.Call("Admin.Update", req, reply); err != nil {
+			// servers that applied the update are ahead: the update should be retried on the others
+			log.Fatalf("[Admin]: update to version %v of %v failed: %v", req.Version, args.Sockets[i], err)
+		}
+
+		if first == nil {
+			first = reply
+		} else if !reflect.DeepEqual(reply, first) {
+			log.Fatalf("[Admin]: %v and %v have different tables after the update to version %v", args.Sockets[0], args.Sockets[i], req.Version)
+		}
+	}
+
+	log.Printf("[Admin]: updated the tables to version %v (inserted ids %v)", req.Version, first.IDs)
+}
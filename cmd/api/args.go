@@ -1,11 +1,19 @@
 package api
 
 import (
-	"github.com/sachaservan/private-ann/hash"
+	"github.com/sachaservan/paillier"
+	"github.com/sachaservan/private-ann/ann"
 	"github.com/sachaservan/private-ann/pir"
+	"github.com/sachaservan/private-ann/pir/field"
 	"github.com/sachaservan/vec"
 )
 
+// Wire protocols between the clients and the servers
+const (
+	TransportGRPC = "grpc" // protobuf messages over gRPC (see cmd/api/pb)
+	TransportRPC  = "rpc"  // gob-encoded api structs over net/rpc (for compatibility)
+)
+
 // Error is provided as a response to API queries
 type Error struct {
 	Msg string
@@ -15,18 +23,70 @@ type Error struct {
 type ANNQueryArgs struct {
 	SessionID    int64
 	MultiProbes  int
+	NumResults   int                    // number of non-empty buckets to reveal (k); 0 is treated as 1
 	SecretShared []*pir.BatchQueryShare // MultiProbes queries for each hash table
+	Version      int                    // version of the tables of the session (see SessionParameters)
 }
 
 // ANNQueryResponse responds with a set of (masked) PIR query results
 type ANNQueryResponse struct {
 	Error                Error
 	SessionID            int64
-	ResSecretShared      []*pir.SecretSharedQueryResult
+	ResSecretShared      []*pir.SecretSharedQueryResult // NumResults (masked) versions of the record of BucketSize ids of each bucket
+	StatsQueryTimeInMS   int64
+	StatsMaskingTimeInUS int64
+}
+
+// EncryptedANNQueryArgs arguments for querying the hash tables of a single server
+// with encrypted selection vectors (see pir.EncryptedQuery)
+type EncryptedANNQueryArgs struct {
+	SessionID  int64
+	NumResults int                        // number of non-empty buckets to reveal (k); 0 is treated as 1
+	PublicKey  *paillier.PublicKey        // key used to encrypt the selection vectors
+	Encrypted  []*pir.EncryptedBatchQuery // one selection vector per partition for each hash table
+	Version    int                        // version of the tables of the session (see SessionParameters)
+}
+
+// EncryptedANNQueryResponse responds with a set of (masked) encrypted PIR query results
+type EncryptedANNQueryResponse struct {
+	Error                Error
+	SessionID            int64
+	ResEncrypted         []*pir.EncryptedQueryResult // NumResults (masked) versions of the record of BucketSize ids of each bucket
 	StatsQueryTimeInMS   int64
 	StatsMaskingTimeInUS int64
 }
 
+// ItemQueryArgs arguments for privately retrieving items (vectors and payloads) by id
+type ItemQueryArgs struct {
+	SessionID int64
+	Queries   []*pir.QueryShare // one index query per item
+	Version   int               // version of the tables of the session (see SessionParameters)
+}
+
+// ItemQueryResponse responds with the PIR query results for each item
+type ItemQueryResponse struct {
+	Error              Error
+	SessionID          int64
+	ResSecretShared    []*pir.SecretSharedQueryResult // one record per query
+	StatsQueryTimeInMS int64
+}
+
+// PeerMessage is a message between the two servers about a request that they check together
+// before answering it (see server.Peer). Messages are authenticated with a MAC keyed by the
+// secret shared by the servers
+type PeerMessage struct {
+	QueryID []byte     // identifies the request on both servers
+	Round   int        // step of the check
+	Nonce   []byte     // (first round) fresh randomness of the sender
+	Proofs  [][]byte   // VDPF proof of each query
+	Shares  []field.FP // shares opened in the round (one per query)
+	Abort   bool       // the sender rejected the request
+	MAC     []byte
+}
+
+// PeerResponse acknowledges a PeerMessage
+type PeerResponse struct{}
+
 // InitSessionArgs arguments provided by client to initialize a new a PIR session
 type InitSessionArgs struct {
 }
@@ -35,6 +95,7 @@ type InitSessionArgs struct {
 type InitSessionResponse struct {
 	SessionParameters
 	Error                      Error
+	TableDigests               [][]byte // digest of each table (identical on both servers, see server.TableDigests)
 	StatsPreprocessingTimeInMS int64
 	StatsDatasetSize           int
 	StatsNumFeatures           int
@@ -42,12 +103,54 @@ type InitSessionResponse struct {
 	StatsNumServerProcs        int
 }
 
-// TerminateSessionArgs used by client to kill the server (useful for experiments)
-type TerminateSessionArgs struct{}
+// TerminateSessionArgs used by client to end its session
+type TerminateSessionArgs struct {
+	SessionID int64
+}
 
 // TerminateSessionResponse response to clients terminate session call
 type TerminateSessionResponse struct{}
 
+// AdminArgs authenticates requests to the admin service (see server.Admin)
+type AdminArgs struct {
+	Token string
+}
+
+// AdminResponse response to admin requests
+type AdminResponse struct{}
+
+// ReloadArgs rebuilds the tables of the server as the given version
+// (0: the version following the current one); the version must be newer than
+// the current one. Servers reloaded with the same version serve the same tables
+type ReloadArgs struct {
+	AdminArgs
+	Version int
+}
+
+// AdminStatusResponse describes the state of the tables (see UpdateArgs)
+type AdminStatusResponse struct {
+	Version int    // version of the tables (see server.Snapshot)
+	DBSize  int    // number of ids (deleted ids included)
+	Digest  []byte // digest of the tables (identical on both servers)
+}
+
+// UpdateArgs inserts vectors into and deletes ids from the tables of the servers.
+// Both servers must apply the same updates in the same order to keep their tables
+// identical: each update creates the version following the current one and
+// a server only applies the update if Version is that version
+type UpdateArgs struct {
+	AdminArgs
+	Version int
+	Insert  []*vec.Vec // vectors to insert (assigned the next ids in order)
+	Delete  []int      // ids to delete
+}
+
+// UpdateResponse response to updates
+type UpdateResponse struct {
+	IDs []int // ids assigned to the inserted vectors
+	AdminStatusResponse
+}
+
 // WaitForExperimentArgs is used by the client to wait until the experiment starts
 // before making API calls
 type WaitForExperimentArgs struct{}
@@ -55,14 +158,59 @@ type WaitForExperimentArgs struct{}
 // WaitForExperimentResponse is used to signal to the client that server is ready
 type WaitForExperimentResponse struct{}
 
+// HashBundleArgs requests the hash functions with the digest
+// (hash functions are only fetched once per bundle, see hash.Bundle)
+type HashBundleArgs struct {
+	Digest []byte
+}
+
+// HashBundleResponse contains the encoded bundle of hash functions (see hash.EncodeBundle)
+type HashBundleResponse struct {
+	Bundle []byte
+}
+
 // SessionParameters contains all the metadata information
 // needed for a client to issue PIR queries
 type SessionParameters struct {
 	SessionID           int64
-	NumTables           int               // number of hash tables
-	NumProbes           int               // number of bucket probes per table
-	TestQuery           *vec.Vec          // a test query to use in the evaluation
-	HashFunctions       []hash.Hash       // hash functions the client uses to compute keys
-	HashFunctionRange   int               // range (in bits) of the hash function output
-	TableBucketMetadata []*pir.DBMetadata // PIR db metadata for table buckets
+	NumTables           int                  // number of hash tables
+	NumProbes           int                  // number of multi-probe hashes per table
+	NumPartitions       int                  // number of partitions per table (one probed bucket per partition)
+	ProbeStrategy       ann.ProbeStrategy    // bucket probed in each partition
+	BucketSize          int                  // number of slots in each (PIR) bucket record
+	TestQuery           *vec.Vec             // a test query to use in the evaluation
+	HashBundleDigest    []byte               // digest of the hash functions used to compute keys (see HashBundleArgs)
+	HashFunctionRange   int                  // range (in bits) of the hash function output
+	DistanceMetric      ann.DistanceMetric   // queries are normalized and candidates ranked with this metric
+	TableBucketMetadata []*pir.DBMetadata    // PIR db metadata for table buckets
+	ItemDB              *ItemDBParameters    // item database parameters (nil if items are not served)
+	SlotTables          *SlotTableParameters // single-server table parameters (nil if not served)
+	Version             int                  // version of the tables queried in the session (see server.Snapshot)
+}
+
+// Probing returns how the client probes the tables (see ann.Probing)
+func (params *SessionParameters) Probing() ann.Probing {
+	return ann.Probing{
+		KeyBits:       params.HashFunctionRange,
+		NumPartitions: params.NumPartitions,
+		NumProbes:     params.NumProbes,
+		Strategy:      params.ProbeStrategy,
+	}
+}
+
+// ItemDBParameters contains the metadata needed to query
+// the item database (vectors and payloads indexed by id)
+type ItemDBParameters struct {
+	pir.DBMetadata
+	IndexBits       int // range (in bits) of the index queries
+	Dimension       int // dimension of the item vectors
+	MaxPayloadBytes int // max size of the item payloads
+	RecordBytes     int // size of each encoded item record
+}
+
+// SlotTableParameters contains the metadata needed to query the tables
+// of a single server (each table has the same layout, see ann.ComputeSlotTable)
+type SlotTableParameters struct {
+	pir.DBMetadata
+	SlotsPerPartition int // number of records in each of the ann.SlotChoices ranges of a partition
 }
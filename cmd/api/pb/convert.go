@@ -0,0 +1,362 @@
+package pb
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/paillier"
+	"github.com/sachaservan/private-ann/ann"
+	"github.com/sachaservan/private-ann/cmd/api"
+	"github.com/sachaservan/private-ann/pir"
+	"github.com/sachaservan/private-ann/pir/dpfc"
+	"github.com/sachaservan/private-ann/pir/field"
+	"github.com/sachaservan/vec"
+)
+
+// Conversions between the api types (used by the client and server) and the protobuf messages.
+// Messages received from the network are validated so that decoding never panics.
+
+// MaxMessageSize is the max size of the messages sent and received over gRPC
+// (single-server queries contain one ciphertext per slot of each table)
+const MaxMessageSize = 1 << 30
+
+var errMissingField = errors.New("missing field in message")
+
+func encodeSessionParameters(p *api.SessionParameters) (*SessionParameters, error) {
+	m := &SessionParameters{
+		SessionId:         p.SessionID,
+		NumTables:         int64(p.NumTables),
+		NumProbes:         int64(p.NumProbes),
+		NumPartitions:     int64(p.NumPartitions),
+		BucketSize:        int64(p.BucketSize),
+		HashFunctionRange: int64(p.HashFunctionRange),
+		HashBundleDigest:  p.HashBundleDigest,
+		Version:           int64(p.Version),
+	}
+
+	if p.TestQuery != nil {
+		m.TestQuery = p.TestQuery.Coords
+	}
+
+	switch p.DistanceMetric {
+	case ann.Euclidean:
+		m.DistanceMetric = DistanceMetric_EUCLIDEAN
+	case ann.Angular:
+		m.DistanceMetric = DistanceMetric_ANGULAR
+	default:
+		return nil, fmt.Errorf("unknown distance metric %v", p.DistanceMetric)
+	}
+
+	switch p.ProbeStrategy {
+	case ann.ProbeClosest:
+		m.ProbeStrategy = ProbeStrategy_CLOSEST
+	default:
+		return nil, fmt.Errorf("unknown probing strategy %v", p.ProbeStrategy)
+	}
+
+	for _, md := range p.TableBucketMetadata {
+		m.TableBucketMetadata = append(m.TableBucketMetadata, encodeDBMetadata(md))
+	}
+
+	if p.ItemDB != nil {
+		m.ItemDb = &ItemDBParameters{
+			Db:              encodeDBMetadata(&p.ItemDB.DBMetadata),
+			IndexBits:       int64(p.ItemDB.IndexBits),
+			Dimension:       int64(p.ItemDB.Dimension),
+			MaxPayloadBytes: int64(p.ItemDB.MaxPayloadBytes),
+			RecordBytes:     int64(p.ItemDB.RecordBytes),
+		}
+	}
+
+	if p.SlotTables != nil {
+		m.SlotTables = &SlotTableParameters{
+			Db:                encodeDBMetadata(&p.SlotTables.DBMetadata),
+			SlotsPerPartition: int64(p.SlotTables.SlotsPerPartition),
+		}
+	}
+
+	return m, nil
+}
+
+func decodeSessionParameters(m *SessionParameters) (*api.SessionParameters, error) {
+	if m == nil {
+		return nil, errMissingField
+	}
+
+	// the client divides the key space into NumPartitions partitions
+	if m.NumPartitions <= 0 {
+		return nil, fmt.Errorf("invalid number of partitions %v", m.NumPartitions)
+	}
+
+	p := &api.SessionParameters{
+		SessionID:         m.SessionId,
+		NumTables:         int(m.NumTables),
+		NumProbes:         int(m.NumProbes),
+		NumPartitions:     int(m.NumPartitions),
+		BucketSize:        int(m.BucketSize),
+		HashFunctionRange: int(m.HashFunctionRange),
+		HashBundleDigest:  m.HashBundleDigest,
+		TestQuery:         vec.NewVec(m.TestQuery),
+		Version:           int(m.Version),
+	}
+
+	switch m.DistanceMetric {
+	case DistanceMetric_EUCLIDEAN:
+		p.DistanceMetric = ann.Euclidean
+	case DistanceMetric_ANGULAR:
+		p.DistanceMetric = ann.Angular
+	default:
+		return nil, fmt.Errorf("unknown distance metric %v", m.DistanceMetric)
+	}
+
+	switch m.ProbeStrategy {
+	case ProbeStrategy_CLOSEST:
+		p.ProbeStrategy = ann.ProbeClosest
+	default:
+		return nil, fmt.Errorf("unknown probing strategy %v", m.ProbeStrategy)
+	}
+
+	for _, md := range m.TableBucketMetadata {
+		p.TableBucketMetadata = append(p.TableBucketMetadata, decodeDBMetadata(md))
+	}
+
+	if m.ItemDb != nil {
+		p.ItemDB = &api.ItemDBParameters{
+			DBMetadata:      *decodeDBMetadata(m.ItemDb.Db),
+			IndexBits:       int(m.ItemDb.IndexBits),
+			Dimension:       int(m.ItemDb.Dimension),
+			MaxPayloadBytes: int(m.ItemDb.MaxPayloadBytes),
+			RecordBytes:     int(m.ItemDb.RecordBytes),
+		}
+	}
+
+	if m.SlotTables != nil {
+		p.SlotTables = &api.SlotTableParameters{
+			DBMetadata:        *decodeDBMetadata(m.SlotTables.Db),
+			SlotsPerPartition: int(m.SlotTables.SlotsPerPartition),
+		}
+	}
+
+	return p, nil
+}
+
+func encodeDBMetadata(md *pir.DBMetadata) *DBMetadata {
+	return &DBMetadata{DbSize: int64(md.DBSize), SlotSize: int64(md.SlotSize)}
+}
+
+func decodeDBMetadata(m *DBMetadata) *pir.DBMetadata {
+	if m == nil {
+		return &pir.DBMetadata{}
+	}
+	return &pir.DBMetadata{DBSize: int(m.DbSize), SlotSize: int(m.SlotSize)}
+}
+
+func encodeQueryShare(q *pir.QueryShare) *QueryShare {
+	m := &QueryShare{
+		PrfKey:       append([]byte{}, q.PrfKey[:]...),
+		ShareNumber:  uint32(q.ShareNumber),
+		KeywordBased: q.IsKeywordBased,
+		Verifiable:   q.IsVerifiable,
+	}
+	if q.DPFKey != nil {
+		m.DpfKey = &DPFKey{Key: q.DPFKey.Bytes, RangeSize: uint32(q.DPFKey.RangeSize), Index: q.DPFKey.Index}
+	}
+	if q.IsVerifiable {
+		m.H1Key = append([]byte{}, q.H1Key[:]...)
+		m.H2Key = append([]byte{}, q.H2Key[:]...)
+		m.SketchMask = uint64(q.SketchMask)
+		m.SketchMaskSquare = uint64(q.SketchMaskSquare)
+	}
+	return m
+}
+
+// decodeQueryShare decodes the query (pir.QueryShare.CheckWellFormed checks the key itself)
+func decodeQueryShare(m *QueryShare) (*pir.QueryShare, error) {
+	if m == nil || m.DpfKey == nil {
+		return nil, errMissingField
+	}
+
+	q := &pir.QueryShare{
+		DPFKey:         &dpfc.DPFKey{Bytes: m.DpfKey.Key, RangeSize: uint(m.DpfKey.RangeSize), Index: m.DpfKey.Index},
+		ShareNumber:    uint(m.ShareNumber),
+		IsKeywordBased: m.KeywordBased,
+		IsVerifiable:   m.Verifiable,
+	}
+
+	if len(m.PrfKey) != len(q.PrfKey) {
+		return nil, errors.New("invalid PRF key")
+	}
+	copy(q.PrfKey[:], m.PrfKey)
+
+	if m.Verifiable {
+		if len(m.H1Key) != len(q.H1Key) || len(m.H2Key) != len(q.H2Key) {
+			return nil, errors.New("invalid VDPF hash keys")
+		}
+		copy(q.H1Key[:], m.H1Key)
+		copy(q.H2Key[:], m.H2Key)
+		q.SketchMask = field.FP(m.SketchMask)
+		q.SketchMaskSquare = field.FP(m.SketchMaskSquare)
+	}
+
+	return q, nil
+}
+
+func encodeBatchQueryShares(batches []*pir.BatchQueryShare) []*BatchQueryShare {
+	m := make([]*BatchQueryShare, len(batches))
+	for i, batch := range batches {
+		m[i] = &BatchQueryShare{}
+		for _, q := range batch.Queries {
+			m[i].Queries = append(m[i].Queries, encodeQueryShare(q))
+		}
+	}
+	return m
+}
+
+func decodeBatchQueryShares(m []*BatchQueryShare) ([]*pir.BatchQueryShare, error) {
+	batches := make([]*pir.BatchQueryShare, len(m))
+	for i, batch := range m {
+		if batch == nil {
+			return nil, errMissingField
+		}
+		batches[i] = &pir.BatchQueryShare{Queries: make([]*pir.QueryShare, len(batch.Queries))}
+		for j, q := range batch.Queries {
+			var err error
+			batches[i].Queries[j], err = decodeQueryShare(q)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return batches, nil
+}
+
+func encodeResults(results []*pir.SecretSharedQueryResult) []*SecretSharedQueryResult {
+	m := make([]*SecretSharedQueryResult, len(results))
+	for i, res := range results {
+		m[i] = &SecretSharedQueryResult{Shares: make([]uint64, len(res.Shares))}
+		for e, share := range res.Shares {
+			m[i].Shares[e] = uint64(share)
+		}
+	}
+	return m
+}
+
+func decodeResults(m []*SecretSharedQueryResult) ([]*pir.SecretSharedQueryResult, error) {
+	results := make([]*pir.SecretSharedQueryResult, len(m))
+	for i, res := range m {
+		if res == nil {
+			return nil, errMissingField
+		}
+		results[i] = &pir.SecretSharedQueryResult{Shares: make([]field.FP, len(res.Shares))}
+		for e, share := range res.Shares {
+			results[i].Shares[e] = field.FP(share)
+		}
+	}
+	return results, nil
+}
+
+func encodeBigInt(x *gmp.Int) []byte {
+	if x == nil {
+		return nil
+	}
+	return x.Bytes()
+}
+
+func encodePublicKey(pk *paillier.PublicKey) *PaillierPublicKey {
+	if pk == nil {
+		return nil
+	}
+	return &PaillierPublicKey{N: encodeBigInt(pk.N), G: encodeBigInt(pk.G), H: encodeBigInt(pk.H), K: encodeBigInt(pk.K)}
+}
+
+// decodePublicKey decodes the key (pir.CheckPublicKey checks the key itself)
+func decodePublicKey(m *PaillierPublicKey) (*paillier.PublicKey, error) {
+	if m == nil {
+		return nil, errMissingField
+	}
+	return &paillier.PublicKey{
+		N: new(gmp.Int).SetBytes(m.N),
+		G: new(gmp.Int).SetBytes(m.G),
+		H: new(gmp.Int).SetBytes(m.H),
+		K: new(gmp.Int).SetBytes(m.K),
+	}, nil
+}
+
+func encodeCiphertexts(cts []*paillier.Ciphertext) []*PaillierCiphertext {
+	m := make([]*PaillierCiphertext, len(cts))
+	for i, ct := range cts {
+		m[i] = &PaillierCiphertext{C: encodeBigInt(ct.C), Level: int32(ct.Level), Method: int32(ct.EncMethod)}
+	}
+	return m
+}
+
+func decodeCiphertexts(m []*PaillierCiphertext) ([]*paillier.Ciphertext, error) {
+	cts := make([]*paillier.Ciphertext, len(m))
+	for i, ct := range m {
+		if ct == nil {
+			return nil, errMissingField
+		}
+		cts[i] = &paillier.Ciphertext{
+			C:         new(gmp.Int).SetBytes(ct.C),
+			Level:     paillier.EncryptionLevel(ct.Level),
+			EncMethod: paillier.EncryptionMethod(ct.Method),
+		}
+	}
+	return cts, nil
+}
+
+func encodeEncryptedBatchQueries(batches []*pir.EncryptedBatchQuery) []*EncryptedBatchQuery {
+	m := make([]*EncryptedBatchQuery, len(batches))
+	for i, batch := range batches {
+		m[i] = &EncryptedBatchQuery{}
+		for _, q := range batch.Queries {
+			m[i].Queries = append(m[i].Queries, &EncryptedQuery{Selection: encodeCiphertexts(q.Selection)})
+		}
+	}
+	return m
+}
+
+func decodeEncryptedBatchQueries(m []*EncryptedBatchQuery) ([]*pir.EncryptedBatchQuery, error) {
+	batches := make([]*pir.EncryptedBatchQuery, len(m))
+	for i, batch := range m {
+		if batch == nil {
+			return nil, errMissingField
+		}
+		batches[i] = &pir.EncryptedBatchQuery{Queries: make([]*pir.EncryptedQuery, len(batch.Queries))}
+		for j, q := range batch.Queries {
+			if q == nil {
+				return nil, errMissingField
+			}
+			selection, err := decodeCiphertexts(q.Selection)
+			if err != nil {
+				return nil, err
+			}
+			batches[i].Queries[j] = &pir.EncryptedQuery{Selection: selection}
+		}
+	}
+	return batches, nil
+}
+
+func encodeEncryptedResults(results []*pir.EncryptedQueryResult) []*EncryptedQueryResult {
+	m := make([]*EncryptedQueryResult, len(results))
+	for i, res := range results {
+		m[i] = &EncryptedQueryResult{Ciphertexts: encodeCiphertexts(res.Ciphertexts)}
+	}
+	return m
+}
+
+func decodeEncryptedResults(m []*EncryptedQueryResult) ([]*pir.EncryptedQueryResult, error) {
+	results := make([]*pir.EncryptedQueryResult, len(m))
+	for i, res := range m {
+		if res == nil {
+			return nil, errMissingField
+		}
+		cts, err := decodeCiphertexts(res.Ciphertexts)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = &pir.EncryptedQueryResult{Ciphertexts: cts}
+	}
+	return results, nil
+}
@@ -0,0 +1,2550 @@
+// Wire protocol of the private ANN servers (see cmd/api for the Go types).
+//
+// Field elements (ids, record shares) are integers modulo the 31-bit prime of pir/field.
+// Big integers (Paillier keys and ciphertexts) are big-endian unsigned bytes.
+// Hash function bundles use the binary encoding of the hash package
+// (see hash/encoding.go): little-endian, length-prefixed arrays.
+//
+// Regenerate the Go code (protoc-gen-go v1.27.1, protoc-gen-go-grpc v1.1.0) with
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//          --go-grpc_out=. --go-grpc_opt=paths=source_relative private_ann.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.27.1
+// 	protoc        (unknown)
+// source: private_ann.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type DistanceMetric int32
+
+const (
+	DistanceMetric_EUCLIDEAN DistanceMetric = 0
+	DistanceMetric_ANGULAR   DistanceMetric = 1
+)
+
+// Enum value maps for DistanceMetric.
+var (
+	DistanceMetric_name = map[int32]string{
+		0: "EUCLIDEAN",
+		1: "ANGULAR",
+	}
+	DistanceMetric_value = map[string]int32{
+		"EUCLIDEAN": 0,
+		"ANGULAR":   1,
+	}
+)
+
+func (x DistanceMetric) Enum() *DistanceMetric {
+	p := new(DistanceMetric)
+	*p = x
+	return p
+}
+
+func (x DistanceMetric) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DistanceMetric) Descriptor() protoreflect.EnumDescriptor {
+	return file_private_ann_proto_enumTypes[0].Descriptor()
+}
+
+func (DistanceMetric) Type() protoreflect.EnumType {
+	return &file_private_ann_proto_enumTypes[0]
+}
+
+func (x DistanceMetric) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DistanceMetric.Descriptor instead.
+func (DistanceMetric) EnumDescriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{0}
+}
+
+type ProbeStrategy int32
+
+const (
+	ProbeStrategy_CLOSEST ProbeStrategy = 0
+)
+
+// Enum value maps for ProbeStrategy.
+var (
+	ProbeStrategy_name = map[int32]string{
+		0: "CLOSEST",
+	}
+	ProbeStrategy_value = map[string]int32{
+		"CLOSEST": 0,
+	}
+)
+
+func (x ProbeStrategy) Enum() *ProbeStrategy {
+	p := new(ProbeStrategy)
+	*p = x
+	return p
+}
+
+func (x ProbeStrategy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ProbeStrategy) Descriptor() protoreflect.EnumDescriptor {
+	return file_private_ann_proto_enumTypes[1].Descriptor()
+}
+
+func (ProbeStrategy) Type() protoreflect.EnumType {
+	return &file_private_ann_proto_enumTypes[1]
+}
+
+func (x ProbeStrategy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ProbeStrategy.Descriptor instead.
+func (ProbeStrategy) EnumDescriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{1}
+}
+
+type WaitForExperimentRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *WaitForExperimentRequest) Reset() {
+	*x = WaitForExperimentRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WaitForExperimentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WaitForExperimentRequest) ProtoMessage() {}
+
+func (x *WaitForExperimentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WaitForExperimentRequest.ProtoReflect.Descriptor instead.
+func (*WaitForExperimentRequest) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{0}
+}
+
+type WaitForExperimentResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *WaitForExperimentResponse) Reset() {
+	*x = WaitForExperimentResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WaitForExperimentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WaitForExperimentResponse) ProtoMessage() {}
+
+func (x *WaitForExperimentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WaitForExperimentResponse.ProtoReflect.Descriptor instead.
+func (*WaitForExperimentResponse) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{1}
+}
+
+type InitSessionRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *InitSessionRequest) Reset() {
+	*x = InitSessionRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *InitSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InitSessionRequest) ProtoMessage() {}
+
+func (x *InitSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InitSessionRequest.ProtoReflect.Descriptor instead.
+func (*InitSessionRequest) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{2}
+}
+
+type InitSessionResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Params                   *SessionParameters `protobuf:"bytes,1,opt,name=params,proto3" json:"params,omitempty"`
+	StatsPreprocessingTimeMs int64              `protobuf:"varint,2,opt,name=stats_preprocessing_time_ms,json=statsPreprocessingTimeMs,proto3" json:"stats_preprocessing_time_ms,omitempty"`
+	StatsDatasetSize         int64              `protobuf:"varint,3,opt,name=stats_dataset_size,json=statsDatasetSize,proto3" json:"stats_dataset_size,omitempty"`
+	StatsNumFeatures         int64              `protobuf:"varint,4,opt,name=stats_num_features,json=statsNumFeatures,proto3" json:"stats_num_features,omitempty"`
+	StatsDatasetName         string             `protobuf:"bytes,5,opt,name=stats_dataset_name,json=statsDatasetName,proto3" json:"stats_dataset_name,omitempty"`
+	StatsNumServerProcs      int64              `protobuf:"varint,6,opt,name=stats_num_server_procs,json=statsNumServerProcs,proto3" json:"stats_num_server_procs,omitempty"`
+	TableDigests             [][]byte           `protobuf:"bytes,7,rep,name=table_digests,json=tableDigests,proto3" json:"table_digests,omitempty"` // one per table (identical on both servers)
+}
+
+func (x *InitSessionResponse) Reset() {
+	*x = InitSessionResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *InitSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InitSessionResponse) ProtoMessage() {}
+
+func (x *InitSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InitSessionResponse.ProtoReflect.Descriptor instead.
+func (*InitSessionResponse) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *InitSessionResponse) GetParams() *SessionParameters {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *InitSessionResponse) GetStatsPreprocessingTimeMs() int64 {
+	if x != nil {
+		return x.StatsPreprocessingTimeMs
+	}
+	return 0
+}
+
+func (x *InitSessionResponse) GetStatsDatasetSize() int64 {
+	if x != nil {
+		return x.StatsDatasetSize
+	}
+	return 0
+}
+
+func (x *InitSessionResponse) GetStatsNumFeatures() int64 {
+	if x != nil {
+		return x.StatsNumFeatures
+	}
+	return 0
+}
+
+func (x *InitSessionResponse) GetStatsDatasetName() string {
+	if x != nil {
+		return x.StatsDatasetName
+	}
+	return ""
+}
+
+func (x *InitSessionResponse) GetStatsNumServerProcs() int64 {
+	if x != nil {
+		return x.StatsNumServerProcs
+	}
+	return 0
+}
+
+func (x *InitSessionResponse) GetTableDigests() [][]byte {
+	if x != nil {
+		return x.TableDigests
+	}
+	return nil
+}
+
+type TerminateSessionRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SessionId int64 `protobuf:"varint,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+}
+
+func (x *TerminateSessionRequest) Reset() {
+	*x = TerminateSessionRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TerminateSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TerminateSessionRequest) ProtoMessage() {}
+
+func (x *TerminateSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TerminateSessionRequest.ProtoReflect.Descriptor instead.
+func (*TerminateSessionRequest) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *TerminateSessionRequest) GetSessionId() int64 {
+	if x != nil {
+		return x.SessionId
+	}
+	return 0
+}
+
+type TerminateSessionResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *TerminateSessionResponse) Reset() {
+	*x = TerminateSessionResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TerminateSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TerminateSessionResponse) ProtoMessage() {}
+
+func (x *TerminateSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TerminateSessionResponse.ProtoReflect.Descriptor instead.
+func (*TerminateSessionResponse) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{5}
+}
+
+type HashBundleRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Digest []byte `protobuf:"bytes,1,opt,name=digest,proto3" json:"digest,omitempty"` // SHA-256 digest of the bundle
+}
+
+func (x *HashBundleRequest) Reset() {
+	*x = HashBundleRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HashBundleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HashBundleRequest) ProtoMessage() {}
+
+func (x *HashBundleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HashBundleRequest.ProtoReflect.Descriptor instead.
+func (*HashBundleRequest) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *HashBundleRequest) GetDigest() []byte {
+	if x != nil {
+		return x.Digest
+	}
+	return nil
+}
+
+type HashBundleResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Bundle []byte `protobuf:"bytes,1,opt,name=bundle,proto3" json:"bundle,omitempty"` // descriptions (seed and parameters) of the hash functions, see hash.EncodeBundle
+}
+
+func (x *HashBundleResponse) Reset() {
+	*x = HashBundleResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HashBundleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HashBundleResponse) ProtoMessage() {}
+
+func (x *HashBundleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HashBundleResponse.ProtoReflect.Descriptor instead.
+func (*HashBundleResponse) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *HashBundleResponse) GetBundle() []byte {
+	if x != nil {
+		return x.Bundle
+	}
+	return nil
+}
+
+type DBMetadata struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	DbSize   int64 `protobuf:"varint,1,opt,name=db_size,json=dbSize,proto3" json:"db_size,omitempty"`       // number of records
+	SlotSize int64 `protobuf:"varint,2,opt,name=slot_size,json=slotSize,proto3" json:"slot_size,omitempty"` // number of field elements in each record
+}
+
+func (x *DBMetadata) Reset() {
+	*x = DBMetadata{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DBMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DBMetadata) ProtoMessage() {}
+
+func (x *DBMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DBMetadata.ProtoReflect.Descriptor instead.
+func (*DBMetadata) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DBMetadata) GetDbSize() int64 {
+	if x != nil {
+		return x.DbSize
+	}
+	return 0
+}
+
+func (x *DBMetadata) GetSlotSize() int64 {
+	if x != nil {
+		return x.SlotSize
+	}
+	return 0
+}
+
+type ItemDBParameters struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Db              *DBMetadata `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	IndexBits       int64       `protobuf:"varint,2,opt,name=index_bits,json=indexBits,proto3" json:"index_bits,omitempty"`
+	Dimension       int64       `protobuf:"varint,3,opt,name=dimension,proto3" json:"dimension,omitempty"`
+	MaxPayloadBytes int64       `protobuf:"varint,4,opt,name=max_payload_bytes,json=maxPayloadBytes,proto3" json:"max_payload_bytes,omitempty"`
+	RecordBytes     int64       `protobuf:"varint,5,opt,name=record_bytes,json=recordBytes,proto3" json:"record_bytes,omitempty"`
+}
+
+func (x *ItemDBParameters) Reset() {
+	*x = ItemDBParameters{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ItemDBParameters) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ItemDBParameters) ProtoMessage() {}
+
+func (x *ItemDBParameters) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ItemDBParameters.ProtoReflect.Descriptor instead.
+func (*ItemDBParameters) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ItemDBParameters) GetDb() *DBMetadata {
+	if x != nil {
+		return x.Db
+	}
+	return nil
+}
+
+func (x *ItemDBParameters) GetIndexBits() int64 {
+	if x != nil {
+		return x.IndexBits
+	}
+	return 0
+}
+
+func (x *ItemDBParameters) GetDimension() int64 {
+	if x != nil {
+		return x.Dimension
+	}
+	return 0
+}
+
+func (x *ItemDBParameters) GetMaxPayloadBytes() int64 {
+	if x != nil {
+		return x.MaxPayloadBytes
+	}
+	return 0
+}
+
+func (x *ItemDBParameters) GetRecordBytes() int64 {
+	if x != nil {
+		return x.RecordBytes
+	}
+	return 0
+}
+
+type SlotTableParameters struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Db                *DBMetadata `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	SlotsPerPartition int64       `protobuf:"varint,2,opt,name=slots_per_partition,json=slotsPerPartition,proto3" json:"slots_per_partition,omitempty"`
+}
+
+func (x *SlotTableParameters) Reset() {
+	*x = SlotTableParameters{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SlotTableParameters) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SlotTableParameters) ProtoMessage() {}
+
+func (x *SlotTableParameters) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SlotTableParameters.ProtoReflect.Descriptor instead.
+func (*SlotTableParameters) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SlotTableParameters) GetDb() *DBMetadata {
+	if x != nil {
+		return x.Db
+	}
+	return nil
+}
+
+func (x *SlotTableParameters) GetSlotsPerPartition() int64 {
+	if x != nil {
+		return x.SlotsPerPartition
+	}
+	return 0
+}
+
+type SessionParameters struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SessionId           int64                `protobuf:"varint,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	NumTables           int64                `protobuf:"varint,2,opt,name=num_tables,json=numTables,proto3" json:"num_tables,omitempty"`
+	NumProbes           int64                `protobuf:"varint,3,opt,name=num_probes,json=numProbes,proto3" json:"num_probes,omitempty"`
+	BucketSize          int64                `protobuf:"varint,4,opt,name=bucket_size,json=bucketSize,proto3" json:"bucket_size,omitempty"`
+	TestQuery           []float64            `protobuf:"fixed64,5,rep,packed,name=test_query,json=testQuery,proto3" json:"test_query,omitempty"`
+	HashFunctionRange   int64                `protobuf:"varint,7,opt,name=hash_function_range,json=hashFunctionRange,proto3" json:"hash_function_range,omitempty"` // bits
+	DistanceMetric      DistanceMetric       `protobuf:"varint,8,opt,name=distance_metric,json=distanceMetric,proto3,enum=privateann.DistanceMetric" json:"distance_metric,omitempty"`
+	TableBucketMetadata []*DBMetadata        `protobuf:"bytes,9,rep,name=table_bucket_metadata,json=tableBucketMetadata,proto3" json:"table_bucket_metadata,omitempty"`
+	ItemDb              *ItemDBParameters    `protobuf:"bytes,10,opt,name=item_db,json=itemDb,proto3" json:"item_db,omitempty"`             // unset if items are not served
+	SlotTables          *SlotTableParameters `protobuf:"bytes,11,opt,name=slot_tables,json=slotTables,proto3" json:"slot_tables,omitempty"` // unset if single-server queries are not served
+	NumPartitions       int64                `protobuf:"varint,12,opt,name=num_partitions,json=numPartitions,proto3" json:"num_partitions,omitempty"`
+	ProbeStrategy       ProbeStrategy        `protobuf:"varint,13,opt,name=probe_strategy,json=probeStrategy,proto3,enum=privateann.ProbeStrategy" json:"probe_strategy,omitempty"`
+	HashBundleDigest    []byte               `protobuf:"bytes,14,opt,name=hash_bundle_digest,json=hashBundleDigest,proto3" json:"hash_bundle_digest,omitempty"` // see HashBundle
+	Version             int64                `protobuf:"varint,15,opt,name=version,proto3" json:"version,omitempty"`                                            // version of the tables queried in the session
+}
+
+func (x *SessionParameters) Reset() {
+	*x = SessionParameters{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SessionParameters) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionParameters) ProtoMessage() {}
+
+func (x *SessionParameters) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionParameters.ProtoReflect.Descriptor instead.
+func (*SessionParameters) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SessionParameters) GetSessionId() int64 {
+	if x != nil {
+		return x.SessionId
+	}
+	return 0
+}
+
+func (x *SessionParameters) GetNumTables() int64 {
+	if x != nil {
+		return x.NumTables
+	}
+	return 0
+}
+
+func (x *SessionParameters) GetNumProbes() int64 {
+	if x != nil {
+		return x.NumProbes
+	}
+	return 0
+}
+
+func (x *SessionParameters) GetBucketSize() int64 {
+	if x != nil {
+		return x.BucketSize
+	}
+	return 0
+}
+
+func (x *SessionParameters) GetTestQuery() []float64 {
+	if x != nil {
+		return x.TestQuery
+	}
+	return nil
+}
+
+func (x *SessionParameters) GetHashFunctionRange() int64 {
+	if x != nil {
+		return x.HashFunctionRange
+	}
+	return 0
+}
+
+func (x *SessionParameters) GetDistanceMetric() DistanceMetric {
+	if x != nil {
+		return x.DistanceMetric
+	}
+	return DistanceMetric_EUCLIDEAN
+}
+
+func (x *SessionParameters) GetTableBucketMetadata() []*DBMetadata {
+	if x != nil {
+		return x.TableBucketMetadata
+	}
+	return nil
+}
+
+func (x *SessionParameters) GetItemDb() *ItemDBParameters {
+	if x != nil {
+		return x.ItemDb
+	}
+	return nil
+}
+
+func (x *SessionParameters) GetSlotTables() *SlotTableParameters {
+	if x != nil {
+		return x.SlotTables
+	}
+	return nil
+}
+
+func (x *SessionParameters) GetNumPartitions() int64 {
+	if x != nil {
+		return x.NumPartitions
+	}
+	return 0
+}
+
+func (x *SessionParameters) GetProbeStrategy() ProbeStrategy {
+	if x != nil {
+		return x.ProbeStrategy
+	}
+	return ProbeStrategy_CLOSEST
+}
+
+func (x *SessionParameters) GetHashBundleDigest() []byte {
+	if x != nil {
+		return x.HashBundleDigest
+	}
+	return nil
+}
+
+func (x *SessionParameters) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type DPFKey struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Key       []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	RangeSize uint32 `protobuf:"varint,2,opt,name=range_size,json=rangeSize,proto3" json:"range_size,omitempty"` // bits
+	Index     uint64 `protobuf:"varint,3,opt,name=index,proto3" json:"index,omitempty"`                          // share number of the key (0 or 1)
+}
+
+func (x *DPFKey) Reset() {
+	*x = DPFKey{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DPFKey) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DPFKey) ProtoMessage() {}
+
+func (x *DPFKey) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DPFKey.ProtoReflect.Descriptor instead.
+func (*DPFKey) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DPFKey) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *DPFKey) GetRangeSize() uint32 {
+	if x != nil {
+		return x.RangeSize
+	}
+	return 0
+}
+
+func (x *DPFKey) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+type QueryShare struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	DpfKey           *DPFKey `protobuf:"bytes,1,opt,name=dpf_key,json=dpfKey,proto3" json:"dpf_key,omitempty"`
+	PrfKey           []byte  `protobuf:"bytes,2,opt,name=prf_key,json=prfKey,proto3" json:"prf_key,omitempty"` // 16 bytes
+	ShareNumber      uint32  `protobuf:"varint,3,opt,name=share_number,json=shareNumber,proto3" json:"share_number,omitempty"`
+	KeywordBased     bool    `protobuf:"varint,4,opt,name=keyword_based,json=keywordBased,proto3" json:"keyword_based,omitempty"`
+	Verifiable       bool    `protobuf:"varint,5,opt,name=verifiable,proto3" json:"verifiable,omitempty"`
+	H1Key            []byte  `protobuf:"bytes,6,opt,name=h1_key,json=h1Key,proto3" json:"h1_key,omitempty"`                                     // 16 bytes (verifiable queries)
+	H2Key            []byte  `protobuf:"bytes,7,opt,name=h2_key,json=h2Key,proto3" json:"h2_key,omitempty"`                                     // 16 bytes (verifiable queries)
+	SketchMask       uint64  `protobuf:"varint,8,opt,name=sketch_mask,json=sketchMask,proto3" json:"sketch_mask,omitempty"`                     // share of a random a (verifiable queries, see pir.SketchCheckShare)
+	SketchMaskSquare uint64  `protobuf:"varint,9,opt,name=sketch_mask_square,json=sketchMaskSquare,proto3" json:"sketch_mask_square,omitempty"` // share of a^2 (verifiable queries)
+}
+
+func (x *QueryShare) Reset() {
+	*x = QueryShare{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *QueryShare) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryShare) ProtoMessage() {}
+
+func (x *QueryShare) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryShare.ProtoReflect.Descriptor instead.
+func (*QueryShare) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *QueryShare) GetDpfKey() *DPFKey {
+	if x != nil {
+		return x.DpfKey
+	}
+	return nil
+}
+
+func (x *QueryShare) GetPrfKey() []byte {
+	if x != nil {
+		return x.PrfKey
+	}
+	return nil
+}
+
+func (x *QueryShare) GetShareNumber() uint32 {
+	if x != nil {
+		return x.ShareNumber
+	}
+	return 0
+}
+
+func (x *QueryShare) GetKeywordBased() bool {
+	if x != nil {
+		return x.KeywordBased
+	}
+	return false
+}
+
+func (x *QueryShare) GetVerifiable() bool {
+	if x != nil {
+		return x.Verifiable
+	}
+	return false
+}
+
+func (x *QueryShare) GetH1Key() []byte {
+	if x != nil {
+		return x.H1Key
+	}
+	return nil
+}
+
+func (x *QueryShare) GetH2Key() []byte {
+	if x != nil {
+		return x.H2Key
+	}
+	return nil
+}
+
+func (x *QueryShare) GetSketchMask() uint64 {
+	if x != nil {
+		return x.SketchMask
+	}
+	return 0
+}
+
+func (x *QueryShare) GetSketchMaskSquare() uint64 {
+	if x != nil {
+		return x.SketchMaskSquare
+	}
+	return 0
+}
+
+// one query per partition of a table
+type BatchQueryShare struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Queries []*QueryShare `protobuf:"bytes,1,rep,name=queries,proto3" json:"queries,omitempty"`
+}
+
+func (x *BatchQueryShare) Reset() {
+	*x = BatchQueryShare{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchQueryShare) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchQueryShare) ProtoMessage() {}
+
+func (x *BatchQueryShare) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchQueryShare.ProtoReflect.Descriptor instead.
+func (*BatchQueryShare) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *BatchQueryShare) GetQueries() []*QueryShare {
+	if x != nil {
+		return x.Queries
+	}
+	return nil
+}
+
+type SecretSharedQueryResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Shares []uint64 `protobuf:"varint,1,rep,packed,name=shares,proto3" json:"shares,omitempty"`
+}
+
+func (x *SecretSharedQueryResult) Reset() {
+	*x = SecretSharedQueryResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SecretSharedQueryResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SecretSharedQueryResult) ProtoMessage() {}
+
+func (x *SecretSharedQueryResult) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SecretSharedQueryResult.ProtoReflect.Descriptor instead.
+func (*SecretSharedQueryResult) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *SecretSharedQueryResult) GetShares() []uint64 {
+	if x != nil {
+		return x.Shares
+	}
+	return nil
+}
+
+type ANNQueryRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SessionId    int64              `protobuf:"varint,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	MultiProbes  int64              `protobuf:"varint,2,opt,name=multi_probes,json=multiProbes,proto3" json:"multi_probes,omitempty"`
+	NumResults   int64              `protobuf:"varint,3,opt,name=num_results,json=numResults,proto3" json:"num_results,omitempty"`      // number of non-empty buckets to reveal (k); 0 is treated as 1
+	SecretShared []*BatchQueryShare `protobuf:"bytes,4,rep,name=secret_shared,json=secretShared,proto3" json:"secret_shared,omitempty"` // one per table
+	Version      int64              `protobuf:"varint,5,opt,name=version,proto3" json:"version,omitempty"`                              // version of the tables of the session
+}
+
+func (x *ANNQueryRequest) Reset() {
+	*x = ANNQueryRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ANNQueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ANNQueryRequest) ProtoMessage() {}
+
+func (x *ANNQueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ANNQueryRequest.ProtoReflect.Descriptor instead.
+func (*ANNQueryRequest) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ANNQueryRequest) GetSessionId() int64 {
+	if x != nil {
+		return x.SessionId
+	}
+	return 0
+}
+
+func (x *ANNQueryRequest) GetMultiProbes() int64 {
+	if x != nil {
+		return x.MultiProbes
+	}
+	return 0
+}
+
+func (x *ANNQueryRequest) GetNumResults() int64 {
+	if x != nil {
+		return x.NumResults
+	}
+	return 0
+}
+
+func (x *ANNQueryRequest) GetSecretShared() []*BatchQueryShare {
+	if x != nil {
+		return x.SecretShared
+	}
+	return nil
+}
+
+func (x *ANNQueryRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type ANNQueryResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SessionId          int64                      `protobuf:"varint,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Results            []*SecretSharedQueryResult `protobuf:"bytes,2,rep,name=results,proto3" json:"results,omitempty"` // num_results (masked) versions of the bucket of each partition of each table
+	StatsQueryTimeMs   int64                      `protobuf:"varint,4,opt,name=stats_query_time_ms,json=statsQueryTimeMs,proto3" json:"stats_query_time_ms,omitempty"`
+	StatsMaskingTimeUs int64                      `protobuf:"varint,5,opt,name=stats_masking_time_us,json=statsMaskingTimeUs,proto3" json:"stats_masking_time_us,omitempty"`
+}
+
+func (x *ANNQueryResponse) Reset() {
+	*x = ANNQueryResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ANNQueryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ANNQueryResponse) ProtoMessage() {}
+
+func (x *ANNQueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ANNQueryResponse.ProtoReflect.Descriptor instead.
+func (*ANNQueryResponse) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ANNQueryResponse) GetSessionId() int64 {
+	if x != nil {
+		return x.SessionId
+	}
+	return 0
+}
+
+func (x *ANNQueryResponse) GetResults() []*SecretSharedQueryResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *ANNQueryResponse) GetStatsQueryTimeMs() int64 {
+	if x != nil {
+		return x.StatsQueryTimeMs
+	}
+	return 0
+}
+
+func (x *ANNQueryResponse) GetStatsMaskingTimeUs() int64 {
+	if x != nil {
+		return x.StatsMaskingTimeUs
+	}
+	return 0
+}
+
+type PaillierPublicKey struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	N []byte `protobuf:"bytes,1,opt,name=n,proto3" json:"n,omitempty"`
+	G []byte `protobuf:"bytes,2,opt,name=g,proto3" json:"g,omitempty"`
+	H []byte `protobuf:"bytes,3,opt,name=h,proto3" json:"h,omitempty"`
+	K []byte `protobuf:"bytes,4,opt,name=k,proto3" json:"k,omitempty"`
+}
+
+func (x *PaillierPublicKey) Reset() {
+	*x = PaillierPublicKey{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PaillierPublicKey) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaillierPublicKey) ProtoMessage() {}
+
+func (x *PaillierPublicKey) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaillierPublicKey.ProtoReflect.Descriptor instead.
+func (*PaillierPublicKey) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *PaillierPublicKey) GetN() []byte {
+	if x != nil {
+		return x.N
+	}
+	return nil
+}
+
+func (x *PaillierPublicKey) GetG() []byte {
+	if x != nil {
+		return x.G
+	}
+	return nil
+}
+
+func (x *PaillierPublicKey) GetH() []byte {
+	if x != nil {
+		return x.H
+	}
+	return nil
+}
+
+func (x *PaillierPublicKey) GetK() []byte {
+	if x != nil {
+		return x.K
+	}
+	return nil
+}
+
+type PaillierCiphertext struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	C      []byte `protobuf:"bytes,1,opt,name=c,proto3" json:"c,omitempty"`
+	Level  int32  `protobuf:"varint,2,opt,name=level,proto3" json:"level,omitempty"`
+	Method int32  `protobuf:"varint,3,opt,name=method,proto3" json:"method,omitempty"`
+}
+
+func (x *PaillierCiphertext) Reset() {
+	*x = PaillierCiphertext{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PaillierCiphertext) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaillierCiphertext) ProtoMessage() {}
+
+func (x *PaillierCiphertext) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaillierCiphertext.ProtoReflect.Descriptor instead.
+func (*PaillierCiphertext) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *PaillierCiphertext) GetC() []byte {
+	if x != nil {
+		return x.C
+	}
+	return nil
+}
+
+func (x *PaillierCiphertext) GetLevel() int32 {
+	if x != nil {
+		return x.Level
+	}
+	return 0
+}
+
+func (x *PaillierCiphertext) GetMethod() int32 {
+	if x != nil {
+		return x.Method
+	}
+	return 0
+}
+
+type EncryptedQuery struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Selection []*PaillierCiphertext `protobuf:"bytes,1,rep,name=selection,proto3" json:"selection,omitempty"`
+}
+
+func (x *EncryptedQuery) Reset() {
+	*x = EncryptedQuery{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EncryptedQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncryptedQuery) ProtoMessage() {}
+
+func (x *EncryptedQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncryptedQuery.ProtoReflect.Descriptor instead.
+func (*EncryptedQuery) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *EncryptedQuery) GetSelection() []*PaillierCiphertext {
+	if x != nil {
+		return x.Selection
+	}
+	return nil
+}
+
+type EncryptedBatchQuery struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Queries []*EncryptedQuery `protobuf:"bytes,1,rep,name=queries,proto3" json:"queries,omitempty"`
+}
+
+func (x *EncryptedBatchQuery) Reset() {
+	*x = EncryptedBatchQuery{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EncryptedBatchQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncryptedBatchQuery) ProtoMessage() {}
+
+func (x *EncryptedBatchQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncryptedBatchQuery.ProtoReflect.Descriptor instead.
+func (*EncryptedBatchQuery) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *EncryptedBatchQuery) GetQueries() []*EncryptedQuery {
+	if x != nil {
+		return x.Queries
+	}
+	return nil
+}
+
+type EncryptedQueryResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ciphertexts []*PaillierCiphertext `protobuf:"bytes,1,rep,name=ciphertexts,proto3" json:"ciphertexts,omitempty"`
+}
+
+func (x *EncryptedQueryResult) Reset() {
+	*x = EncryptedQueryResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EncryptedQueryResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncryptedQueryResult) ProtoMessage() {}
+
+func (x *EncryptedQueryResult) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncryptedQueryResult.ProtoReflect.Descriptor instead.
+func (*EncryptedQueryResult) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *EncryptedQueryResult) GetCiphertexts() []*PaillierCiphertext {
+	if x != nil {
+		return x.Ciphertexts
+	}
+	return nil
+}
+
+type EncryptedANNQueryRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SessionId  int64                  `protobuf:"varint,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	NumResults int64                  `protobuf:"varint,2,opt,name=num_results,json=numResults,proto3" json:"num_results,omitempty"`
+	PublicKey  *PaillierPublicKey     `protobuf:"bytes,3,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Encrypted  []*EncryptedBatchQuery `protobuf:"bytes,4,rep,name=encrypted,proto3" json:"encrypted,omitempty"` // one per table
+	Version    int64                  `protobuf:"varint,5,opt,name=version,proto3" json:"version,omitempty"`    // version of the tables of the session
+}
+
+func (x *EncryptedANNQueryRequest) Reset() {
+	*x = EncryptedANNQueryRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[23]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EncryptedANNQueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncryptedANNQueryRequest) ProtoMessage() {}
+
+func (x *EncryptedANNQueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncryptedANNQueryRequest.ProtoReflect.Descriptor instead.
+func (*EncryptedANNQueryRequest) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *EncryptedANNQueryRequest) GetSessionId() int64 {
+	if x != nil {
+		return x.SessionId
+	}
+	return 0
+}
+
+func (x *EncryptedANNQueryRequest) GetNumResults() int64 {
+	if x != nil {
+		return x.NumResults
+	}
+	return 0
+}
+
+func (x *EncryptedANNQueryRequest) GetPublicKey() *PaillierPublicKey {
+	if x != nil {
+		return x.PublicKey
+	}
+	return nil
+}
+
+func (x *EncryptedANNQueryRequest) GetEncrypted() []*EncryptedBatchQuery {
+	if x != nil {
+		return x.Encrypted
+	}
+	return nil
+}
+
+func (x *EncryptedANNQueryRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type EncryptedANNQueryResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SessionId          int64                   `protobuf:"varint,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Results            []*EncryptedQueryResult `protobuf:"bytes,2,rep,name=results,proto3" json:"results,omitempty"`
+	StatsQueryTimeMs   int64                   `protobuf:"varint,3,opt,name=stats_query_time_ms,json=statsQueryTimeMs,proto3" json:"stats_query_time_ms,omitempty"`
+	StatsMaskingTimeUs int64                   `protobuf:"varint,4,opt,name=stats_masking_time_us,json=statsMaskingTimeUs,proto3" json:"stats_masking_time_us,omitempty"`
+}
+
+func (x *EncryptedANNQueryResponse) Reset() {
+	*x = EncryptedANNQueryResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[24]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EncryptedANNQueryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncryptedANNQueryResponse) ProtoMessage() {}
+
+func (x *EncryptedANNQueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[24]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncryptedANNQueryResponse.ProtoReflect.Descriptor instead.
+func (*EncryptedANNQueryResponse) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *EncryptedANNQueryResponse) GetSessionId() int64 {
+	if x != nil {
+		return x.SessionId
+	}
+	return 0
+}
+
+func (x *EncryptedANNQueryResponse) GetResults() []*EncryptedQueryResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *EncryptedANNQueryResponse) GetStatsQueryTimeMs() int64 {
+	if x != nil {
+		return x.StatsQueryTimeMs
+	}
+	return 0
+}
+
+func (x *EncryptedANNQueryResponse) GetStatsMaskingTimeUs() int64 {
+	if x != nil {
+		return x.StatsMaskingTimeUs
+	}
+	return 0
+}
+
+type ItemQueryRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SessionId int64         `protobuf:"varint,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Queries   []*QueryShare `protobuf:"bytes,2,rep,name=queries,proto3" json:"queries,omitempty"`
+	Version   int64         `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"` // version of the tables of the session
+}
+
+func (x *ItemQueryRequest) Reset() {
+	*x = ItemQueryRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[25]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ItemQueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ItemQueryRequest) ProtoMessage() {}
+
+func (x *ItemQueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[25]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ItemQueryRequest.ProtoReflect.Descriptor instead.
+func (*ItemQueryRequest) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ItemQueryRequest) GetSessionId() int64 {
+	if x != nil {
+		return x.SessionId
+	}
+	return 0
+}
+
+func (x *ItemQueryRequest) GetQueries() []*QueryShare {
+	if x != nil {
+		return x.Queries
+	}
+	return nil
+}
+
+func (x *ItemQueryRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type ItemQueryResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SessionId        int64                      `protobuf:"varint,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Results          []*SecretSharedQueryResult `protobuf:"bytes,2,rep,name=results,proto3" json:"results,omitempty"`
+	StatsQueryTimeMs int64                      `protobuf:"varint,3,opt,name=stats_query_time_ms,json=statsQueryTimeMs,proto3" json:"stats_query_time_ms,omitempty"`
+}
+
+func (x *ItemQueryResponse) Reset() {
+	*x = ItemQueryResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_private_ann_proto_msgTypes[26]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ItemQueryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ItemQueryResponse) ProtoMessage() {}
+
+func (x *ItemQueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_private_ann_proto_msgTypes[26]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ItemQueryResponse.ProtoReflect.Descriptor instead.
+func (*ItemQueryResponse) Descriptor() ([]byte, []int) {
+	return file_private_ann_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *ItemQueryResponse) GetSessionId() int64 {
+	if x != nil {
+		return x.SessionId
+	}
+	return 0
+}
+
+func (x *ItemQueryResponse) GetResults() []*SecretSharedQueryResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *ItemQueryResponse) GetStatsQueryTimeMs() int64 {
+	if x != nil {
+		return x.StatsQueryTimeMs
+	}
+	return 0
+}
+
+var File_private_ann_proto protoreflect.FileDescriptor
+
+var file_private_ann_proto_rawDesc = []byte{
+	0x0a, 0x11, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x5f, 0x61, 0x6e, 0x6e, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x12, 0x0a, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x22,
+	0x1a, 0x0a, 0x18, 0x57, 0x61, 0x69, 0x74, 0x46, 0x6f, 0x72, 0x45, 0x78, 0x70, 0x65, 0x72, 0x69,
+	0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x1b, 0x0a, 0x19, 0x57,
+	0x61, 0x69, 0x74, 0x46, 0x6f, 0x72, 0x45, 0x78, 0x70, 0x65, 0x72, 0x69, 0x6d, 0x65, 0x6e, 0x74,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x14, 0x0a, 0x12, 0x49, 0x6e, 0x69, 0x74,
+	0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0xef,
+	0x02, 0x0a, 0x13, 0x49, 0x6e, 0x69, 0x74, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x35, 0x0a, 0x06, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x73,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65,
+	0x61, 0x6e, 0x6e, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x50, 0x61, 0x72, 0x61, 0x6d,
+	0x65, 0x74, 0x65, 0x72, 0x73, 0x52, 0x06, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x12, 0x3d, 0x0a,
+	0x1b, 0x73, 0x74, 0x61, 0x74, 0x73, 0x5f, 0x70, 0x72, 0x65, 0x70, 0x72, 0x6f, 0x63, 0x65, 0x73,
+	0x73, 0x69, 0x6e, 0x67, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x5f, 0x6d, 0x73, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x18, 0x73, 0x74, 0x61, 0x74, 0x73, 0x50, 0x72, 0x65, 0x70, 0x72, 0x6f, 0x63,
+	0x65, 0x73, 0x73, 0x69, 0x6e, 0x67, 0x54, 0x69, 0x6d, 0x65, 0x4d, 0x73, 0x12, 0x2c, 0x0a, 0x12,
+	0x73, 0x74, 0x61, 0x74, 0x73, 0x5f, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x5f, 0x73, 0x69,
+	0x7a, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x10, 0x73, 0x74, 0x61, 0x74, 0x73, 0x44,
+	0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x2c, 0x0a, 0x12, 0x73, 0x74,
+	0x61, 0x74, 0x73, 0x5f, 0x6e, 0x75, 0x6d, 0x5f, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x10, 0x73, 0x74, 0x61, 0x74, 0x73, 0x4e, 0x75, 0x6d,
+	0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x12, 0x2c, 0x0a, 0x12, 0x73, 0x74, 0x61, 0x74,
+	0x73, 0x5f, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x73, 0x74, 0x61, 0x74, 0x73, 0x44, 0x61, 0x74, 0x61, 0x73,
+	0x65, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x33, 0x0a, 0x16, 0x73, 0x74, 0x61, 0x74, 0x73, 0x5f,
+	0x6e, 0x75, 0x6d, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x5f, 0x70, 0x72, 0x6f, 0x63, 0x73,
+	0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x13, 0x73, 0x74, 0x61, 0x74, 0x73, 0x4e, 0x75, 0x6d,
+	0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x50, 0x72, 0x6f, 0x63, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x74,
+	0x61, 0x62, 0x6c, 0x65, 0x5f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x18, 0x07, 0x20, 0x03,
+	0x28, 0x0c, 0x52, 0x0c, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73,
+	0x22, 0x38, 0x0a, 0x17, 0x54, 0x65, 0x72, 0x6d, 0x69, 0x6e, 0x61, 0x74, 0x65, 0x53, 0x65, 0x73,
+	0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x73,
+	0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x09, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x22, 0x1a, 0x0a, 0x18, 0x54, 0x65,
+	0x72, 0x6d, 0x69, 0x6e, 0x61, 0x74, 0x65, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x2b, 0x0a, 0x11, 0x48, 0x61, 0x73, 0x68, 0x42, 0x75,
+	0x6e, 0x64, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x64,
+	0x69, 0x67, 0x65, 0x73, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x64, 0x69, 0x67,
+	0x65, 0x73, 0x74, 0x22, 0x2c, 0x0a, 0x12, 0x48, 0x61, 0x73, 0x68, 0x42, 0x75, 0x6e, 0x64, 0x6c,
+	0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x62, 0x75, 0x6e,
+	0x64, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x62, 0x75, 0x6e, 0x64, 0x6c,
+	0x65, 0x22, 0x42, 0x0a, 0x0a, 0x44, 0x42, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x12,
+	0x17, 0x0a, 0x07, 0x64, 0x62, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x06, 0x64, 0x62, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x6c, 0x6f, 0x74,
+	0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x73, 0x6c, 0x6f,
+	0x74, 0x53, 0x69, 0x7a, 0x65, 0x22, 0xc6, 0x01, 0x0a, 0x10, 0x49, 0x74, 0x65, 0x6d, 0x44, 0x42,
+	0x50, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x73, 0x12, 0x26, 0x0a, 0x02, 0x64, 0x62,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65,
+	0x61, 0x6e, 0x6e, 0x2e, 0x44, 0x42, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x52, 0x02,
+	0x64, 0x62, 0x12, 0x1d, 0x0a, 0x0a, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x62, 0x69, 0x74, 0x73,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x42, 0x69, 0x74,
+	0x73, 0x12, 0x1c, 0x0a, 0x09, 0x64, 0x69, 0x6d, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x64, 0x69, 0x6d, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x12,
+	0x2a, 0x0a, 0x11, 0x6d, 0x61, 0x78, 0x5f, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x5f, 0x62,
+	0x79, 0x74, 0x65, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0f, 0x6d, 0x61, 0x78, 0x50,
+	0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x72,
+	0x65, 0x63, 0x6f, 0x72, 0x64, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x0b, 0x72, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x42, 0x79, 0x74, 0x65, 0x73, 0x22, 0x6d,
+	0x0a, 0x13, 0x53, 0x6c, 0x6f, 0x74, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x50, 0x61, 0x72, 0x61, 0x6d,
+	0x65, 0x74, 0x65, 0x72, 0x73, 0x12, 0x26, 0x0a, 0x02, 0x64, 0x62, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x16, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x44,
+	0x42, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x52, 0x02, 0x64, 0x62, 0x12, 0x2e, 0x0a,
+	0x13, 0x73, 0x6c, 0x6f, 0x74, 0x73, 0x5f, 0x70, 0x65, 0x72, 0x5f, 0x70, 0x61, 0x72, 0x74, 0x69,
+	0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x11, 0x73, 0x6c, 0x6f, 0x74,
+	0x73, 0x50, 0x65, 0x72, 0x50, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0xa1, 0x05,
+	0x0a, 0x11, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74,
+	0x65, 0x72, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e,
+	0x49, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x6e, 0x75, 0x6d, 0x5f, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x73,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x6e, 0x75, 0x6d, 0x54, 0x61, 0x62, 0x6c, 0x65,
+	0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x6e, 0x75, 0x6d, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x6e, 0x75, 0x6d, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x73,
+	0x12, 0x1f, 0x0a, 0x0b, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x53, 0x69, 0x7a,
+	0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x74, 0x65, 0x73, 0x74, 0x5f, 0x71, 0x75, 0x65, 0x72, 0x79, 0x18,
+	0x05, 0x20, 0x03, 0x28, 0x01, 0x52, 0x09, 0x74, 0x65, 0x73, 0x74, 0x51, 0x75, 0x65, 0x72, 0x79,
+	0x12, 0x2e, 0x0a, 0x13, 0x68, 0x61, 0x73, 0x68, 0x5f, 0x66, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x5f, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x11, 0x68,
+	0x61, 0x73, 0x68, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x61, 0x6e, 0x67, 0x65,
+	0x12, 0x43, 0x0a, 0x0f, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x5f, 0x6d, 0x65, 0x74,
+	0x72, 0x69, 0x63, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1a, 0x2e, 0x70, 0x72, 0x69, 0x76,
+	0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x44, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x4d,
+	0x65, 0x74, 0x72, 0x69, 0x63, 0x52, 0x0e, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x4d,
+	0x65, 0x74, 0x72, 0x69, 0x63, 0x12, 0x4a, 0x0a, 0x15, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x5f, 0x62,
+	0x75, 0x63, 0x6b, 0x65, 0x74, 0x5f, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x18, 0x09,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e,
+	0x6e, 0x2e, 0x44, 0x42, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x52, 0x13, 0x74, 0x61,
+	0x62, 0x6c, 0x65, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74,
+	0x61, 0x12, 0x35, 0x0a, 0x07, 0x69, 0x74, 0x65, 0x6d, 0x5f, 0x64, 0x62, 0x18, 0x0a, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e,
+	0x49, 0x74, 0x65, 0x6d, 0x44, 0x42, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x73,
+	0x52, 0x06, 0x69, 0x74, 0x65, 0x6d, 0x44, 0x62, 0x12, 0x40, 0x0a, 0x0b, 0x73, 0x6c, 0x6f, 0x74,
+	0x5f, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x73, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1f, 0x2e,
+	0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x53, 0x6c, 0x6f, 0x74, 0x54,
+	0x61, 0x62, 0x6c, 0x65, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x73, 0x52, 0x0a,
+	0x73, 0x6c, 0x6f, 0x74, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x6e, 0x75,
+	0x6d, 0x5f, 0x70, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x0c, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x0d, 0x6e, 0x75, 0x6d, 0x50, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e,
+	0x73, 0x12, 0x40, 0x0a, 0x0e, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x5f, 0x73, 0x74, 0x72, 0x61, 0x74,
+	0x65, 0x67, 0x79, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x19, 0x2e, 0x70, 0x72, 0x69, 0x76,
+	0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x53, 0x74, 0x72, 0x61,
+	0x74, 0x65, 0x67, 0x79, 0x52, 0x0d, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x53, 0x74, 0x72, 0x61, 0x74,
+	0x65, 0x67, 0x79, 0x12, 0x2c, 0x0a, 0x12, 0x68, 0x61, 0x73, 0x68, 0x5f, 0x62, 0x75, 0x6e, 0x64,
+	0x6c, 0x65, 0x5f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x10, 0x68, 0x61, 0x73, 0x68, 0x42, 0x75, 0x6e, 0x64, 0x6c, 0x65, 0x44, 0x69, 0x67, 0x65, 0x73,
+	0x74, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x0f, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x4a, 0x04, 0x08, 0x06, 0x10,
+	0x07, 0x22, 0x4f, 0x0a, 0x06, 0x44, 0x50, 0x46, 0x4b, 0x65, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b,
+	0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x1d, 0x0a,
+	0x0a, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x09, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x14, 0x0a, 0x05,
+	0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x69, 0x6e, 0x64,
+	0x65, 0x78, 0x22, 0xb7, 0x02, 0x0a, 0x0a, 0x51, 0x75, 0x65, 0x72, 0x79, 0x53, 0x68, 0x61, 0x72,
+	0x65, 0x12, 0x2b, 0x0a, 0x07, 0x64, 0x70, 0x66, 0x5f, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x12, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e,
+	0x44, 0x50, 0x46, 0x4b, 0x65, 0x79, 0x52, 0x06, 0x64, 0x70, 0x66, 0x4b, 0x65, 0x79, 0x12, 0x17,
+	0x0a, 0x07, 0x70, 0x72, 0x66, 0x5f, 0x6b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x06, 0x70, 0x72, 0x66, 0x4b, 0x65, 0x79, 0x12, 0x21, 0x0a, 0x0c, 0x73, 0x68, 0x61, 0x72, 0x65,
+	0x5f, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0b, 0x73,
+	0x68, 0x61, 0x72, 0x65, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x23, 0x0a, 0x0d, 0x6b, 0x65,
+	0x79, 0x77, 0x6f, 0x72, 0x64, 0x5f, 0x62, 0x61, 0x73, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x0c, 0x6b, 0x65, 0x79, 0x77, 0x6f, 0x72, 0x64, 0x42, 0x61, 0x73, 0x65, 0x64, 0x12,
+	0x1e, 0x0a, 0x0a, 0x76, 0x65, 0x72, 0x69, 0x66, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x0a, 0x76, 0x65, 0x72, 0x69, 0x66, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x12,
+	0x15, 0x0a, 0x06, 0x68, 0x31, 0x5f, 0x6b, 0x65, 0x79, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x05, 0x68, 0x31, 0x4b, 0x65, 0x79, 0x12, 0x15, 0x0a, 0x06, 0x68, 0x32, 0x5f, 0x6b, 0x65, 0x79,
+	0x18, 0x07, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x68, 0x32, 0x4b, 0x65, 0x79, 0x12, 0x1f, 0x0a,
+	0x0b, 0x73, 0x6b, 0x65, 0x74, 0x63, 0x68, 0x5f, 0x6d, 0x61, 0x73, 0x6b, 0x18, 0x08, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x0a, 0x73, 0x6b, 0x65, 0x74, 0x63, 0x68, 0x4d, 0x61, 0x73, 0x6b, 0x12, 0x2c,
+	0x0a, 0x12, 0x73, 0x6b, 0x65, 0x74, 0x63, 0x68, 0x5f, 0x6d, 0x61, 0x73, 0x6b, 0x5f, 0x73, 0x71,
+	0x75, 0x61, 0x72, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x04, 0x52, 0x10, 0x73, 0x6b, 0x65, 0x74,
+	0x63, 0x68, 0x4d, 0x61, 0x73, 0x6b, 0x53, 0x71, 0x75, 0x61, 0x72, 0x65, 0x22, 0x43, 0x0a, 0x0f,
+	0x42, 0x61, 0x74, 0x63, 0x68, 0x51, 0x75, 0x65, 0x72, 0x79, 0x53, 0x68, 0x61, 0x72, 0x65, 0x12,
+	0x30, 0x0a, 0x07, 0x71, 0x75, 0x65, 0x72, 0x69, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x16, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x51, 0x75,
+	0x65, 0x72, 0x79, 0x53, 0x68, 0x61, 0x72, 0x65, 0x52, 0x07, 0x71, 0x75, 0x65, 0x72, 0x69, 0x65,
+	0x73, 0x22, 0x37, 0x0a, 0x17, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x53, 0x68, 0x61, 0x72, 0x65,
+	0x64, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x16, 0x0a, 0x06,
+	0x73, 0x68, 0x61, 0x72, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x04, 0x52, 0x06, 0x73, 0x68,
+	0x61, 0x72, 0x65, 0x73, 0x4a, 0x04, 0x08, 0x02, 0x10, 0x03, 0x22, 0xd0, 0x01, 0x0a, 0x0f, 0x41,
+	0x4e, 0x4e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1d,
+	0x0a, 0x0a, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x09, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12, 0x21, 0x0a,
+	0x0c, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x5f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x0b, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x73,
+	0x12, 0x1f, 0x0a, 0x0b, 0x6e, 0x75, 0x6d, 0x5f, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x6e, 0x75, 0x6d, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74,
+	0x73, 0x12, 0x40, 0x0a, 0x0d, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x5f, 0x73, 0x68, 0x61, 0x72,
+	0x65, 0x64, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61,
+	0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x42, 0x61, 0x74, 0x63, 0x68, 0x51, 0x75, 0x65, 0x72, 0x79,
+	0x53, 0x68, 0x61, 0x72, 0x65, 0x52, 0x0c, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x53, 0x68, 0x61,
+	0x72, 0x65, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0xd8, 0x01,
+	0x0a, 0x10, 0x41, 0x4e, 0x4e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49,
+	0x64, 0x12, 0x3d, 0x0a, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x18, 0x02, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x23, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e,
+	0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x53, 0x68, 0x61, 0x72, 0x65, 0x64, 0x51, 0x75, 0x65, 0x72,
+	0x79, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73,
+	0x12, 0x2d, 0x0a, 0x13, 0x73, 0x74, 0x61, 0x74, 0x73, 0x5f, 0x71, 0x75, 0x65, 0x72, 0x79, 0x5f,
+	0x74, 0x69, 0x6d, 0x65, 0x5f, 0x6d, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x10, 0x73,
+	0x74, 0x61, 0x74, 0x73, 0x51, 0x75, 0x65, 0x72, 0x79, 0x54, 0x69, 0x6d, 0x65, 0x4d, 0x73, 0x12,
+	0x31, 0x0a, 0x15, 0x73, 0x74, 0x61, 0x74, 0x73, 0x5f, 0x6d, 0x61, 0x73, 0x6b, 0x69, 0x6e, 0x67,
+	0x5f, 0x74, 0x69, 0x6d, 0x65, 0x5f, 0x75, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x12,
+	0x73, 0x74, 0x61, 0x74, 0x73, 0x4d, 0x61, 0x73, 0x6b, 0x69, 0x6e, 0x67, 0x54, 0x69, 0x6d, 0x65,
+	0x55, 0x73, 0x4a, 0x04, 0x08, 0x03, 0x10, 0x04, 0x22, 0x4b, 0x0a, 0x11, 0x50, 0x61, 0x69, 0x6c,
+	0x6c, 0x69, 0x65, 0x72, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x12, 0x0c, 0x0a,
+	0x01, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x01, 0x6e, 0x12, 0x0c, 0x0a, 0x01, 0x67,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x01, 0x67, 0x12, 0x0c, 0x0a, 0x01, 0x68, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x01, 0x68, 0x12, 0x0c, 0x0a, 0x01, 0x6b, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x01, 0x6b, 0x22, 0x50, 0x0a, 0x12, 0x50, 0x61, 0x69, 0x6c, 0x6c, 0x69, 0x65,
+	0x72, 0x43, 0x69, 0x70, 0x68, 0x65, 0x72, 0x74, 0x65, 0x78, 0x74, 0x12, 0x0c, 0x0a, 0x01, 0x63,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x01, 0x63, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x65, 0x76,
+	0x65, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x12,
+	0x16, 0x0a, 0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x22, 0x4e, 0x0a, 0x0e, 0x45, 0x6e, 0x63, 0x72, 0x79,
+	0x70, 0x74, 0x65, 0x64, 0x51, 0x75, 0x65, 0x72, 0x79, 0x12, 0x3c, 0x0a, 0x09, 0x73, 0x65, 0x6c,
+	0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x70,
+	0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x50, 0x61, 0x69, 0x6c, 0x6c, 0x69,
+	0x65, 0x72, 0x43, 0x69, 0x70, 0x68, 0x65, 0x72, 0x74, 0x65, 0x78, 0x74, 0x52, 0x09, 0x73, 0x65,
+	0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0x4b, 0x0a, 0x13, 0x45, 0x6e, 0x63, 0x72, 0x79,
+	0x70, 0x74, 0x65, 0x64, 0x42, 0x61, 0x74, 0x63, 0x68, 0x51, 0x75, 0x65, 0x72, 0x79, 0x12, 0x34,
+	0x0a, 0x07, 0x71, 0x75, 0x65, 0x72, 0x69, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x1a, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x45, 0x6e, 0x63,
+	0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x07, 0x71, 0x75, 0x65,
+	0x72, 0x69, 0x65, 0x73, 0x22, 0x58, 0x0a, 0x14, 0x45, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65,
+	0x64, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x40, 0x0a, 0x0b,
+	0x63, 0x69, 0x70, 0x68, 0x65, 0x72, 0x74, 0x65, 0x78, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x1e, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x50,
+	0x61, 0x69, 0x6c, 0x6c, 0x69, 0x65, 0x72, 0x43, 0x69, 0x70, 0x68, 0x65, 0x72, 0x74, 0x65, 0x78,
+	0x74, 0x52, 0x0b, 0x63, 0x69, 0x70, 0x68, 0x65, 0x72, 0x74, 0x65, 0x78, 0x74, 0x73, 0x22, 0xf1,
+	0x01, 0x0a, 0x18, 0x45, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x41, 0x4e, 0x4e, 0x51,
+	0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x73,
+	0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x09, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12, 0x1f, 0x0a, 0x0b, 0x6e, 0x75,
+	0x6d, 0x5f, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x0a, 0x6e, 0x75, 0x6d, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x12, 0x3c, 0x0a, 0x0a, 0x70,
+	0x75, 0x62, 0x6c, 0x69, 0x63, 0x5f, 0x6b, 0x65, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x1d, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x50, 0x61, 0x69,
+	0x6c, 0x6c, 0x69, 0x65, 0x72, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x52, 0x09,
+	0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x12, 0x3d, 0x0a, 0x09, 0x65, 0x6e, 0x63,
+	0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x70,
+	0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x45, 0x6e, 0x63, 0x72, 0x79, 0x70,
+	0x74, 0x65, 0x64, 0x42, 0x61, 0x74, 0x63, 0x68, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x09, 0x65,
+	0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x22, 0xd8, 0x01, 0x0a, 0x19, 0x45, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64,
+	0x41, 0x4e, 0x4e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12,
+	0x3a, 0x0a, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x20, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x45, 0x6e,
+	0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x73, 0x75,
+	0x6c, 0x74, 0x52, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x12, 0x2d, 0x0a, 0x13, 0x73,
+	0x74, 0x61, 0x74, 0x73, 0x5f, 0x71, 0x75, 0x65, 0x72, 0x79, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x5f,
+	0x6d, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x10, 0x73, 0x74, 0x61, 0x74, 0x73, 0x51,
+	0x75, 0x65, 0x72, 0x79, 0x54, 0x69, 0x6d, 0x65, 0x4d, 0x73, 0x12, 0x31, 0x0a, 0x15, 0x73, 0x74,
+	0x61, 0x74, 0x73, 0x5f, 0x6d, 0x61, 0x73, 0x6b, 0x69, 0x6e, 0x67, 0x5f, 0x74, 0x69, 0x6d, 0x65,
+	0x5f, 0x75, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x12, 0x73, 0x74, 0x61, 0x74, 0x73,
+	0x4d, 0x61, 0x73, 0x6b, 0x69, 0x6e, 0x67, 0x54, 0x69, 0x6d, 0x65, 0x55, 0x73, 0x22, 0x7d, 0x0a,
+	0x10, 0x49, 0x74, 0x65, 0x6d, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x64,
+	0x12, 0x30, 0x0a, 0x07, 0x71, 0x75, 0x65, 0x72, 0x69, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x16, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x51,
+	0x75, 0x65, 0x72, 0x79, 0x53, 0x68, 0x61, 0x72, 0x65, 0x52, 0x07, 0x71, 0x75, 0x65, 0x72, 0x69,
+	0x65, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0xa0, 0x01, 0x0a,
+	0x11, 0x49, 0x74, 0x65, 0x6d, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49,
+	0x64, 0x12, 0x3d, 0x0a, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x18, 0x02, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x23, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e,
+	0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x53, 0x68, 0x61, 0x72, 0x65, 0x64, 0x51, 0x75, 0x65, 0x72,
+	0x79, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73,
+	0x12, 0x2d, 0x0a, 0x13, 0x73, 0x74, 0x61, 0x74, 0x73, 0x5f, 0x71, 0x75, 0x65, 0x72, 0x79, 0x5f,
+	0x74, 0x69, 0x6d, 0x65, 0x5f, 0x6d, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x10, 0x73,
+	0x74, 0x61, 0x74, 0x73, 0x51, 0x75, 0x65, 0x72, 0x79, 0x54, 0x69, 0x6d, 0x65, 0x4d, 0x73, 0x2a,
+	0x2c, 0x0a, 0x0e, 0x44, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x4d, 0x65, 0x74, 0x72, 0x69,
+	0x63, 0x12, 0x0d, 0x0a, 0x09, 0x45, 0x55, 0x43, 0x4c, 0x49, 0x44, 0x45, 0x41, 0x4e, 0x10, 0x00,
+	0x12, 0x0b, 0x0a, 0x07, 0x41, 0x4e, 0x47, 0x55, 0x4c, 0x41, 0x52, 0x10, 0x01, 0x2a, 0x1c, 0x0a,
+	0x0d, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x53, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x12, 0x0b,
+	0x0a, 0x07, 0x43, 0x4c, 0x4f, 0x53, 0x45, 0x53, 0x54, 0x10, 0x00, 0x32, 0xf2, 0x04, 0x0a, 0x0a,
+	0x50, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x41, 0x4e, 0x4e, 0x12, 0x60, 0x0a, 0x11, 0x57, 0x61,
+	0x69, 0x74, 0x46, 0x6f, 0x72, 0x45, 0x78, 0x70, 0x65, 0x72, 0x69, 0x6d, 0x65, 0x6e, 0x74, 0x12,
+	0x24, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x57, 0x61, 0x69,
+	0x74, 0x46, 0x6f, 0x72, 0x45, 0x78, 0x70, 0x65, 0x72, 0x69, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x25, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61,
+	0x6e, 0x6e, 0x2e, 0x57, 0x61, 0x69, 0x74, 0x46, 0x6f, 0x72, 0x45, 0x78, 0x70, 0x65, 0x72, 0x69,
+	0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4e, 0x0a, 0x0b,
+	0x49, 0x6e, 0x69, 0x74, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1e, 0x2e, 0x70, 0x72,
+	0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x49, 0x6e, 0x69, 0x74, 0x53, 0x65, 0x73,
+	0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x70, 0x72,
+	0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x49, 0x6e, 0x69, 0x74, 0x53, 0x65, 0x73,
+	0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x5d, 0x0a, 0x10,
+	0x54, 0x65, 0x72, 0x6d, 0x69, 0x6e, 0x61, 0x74, 0x65, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e,
+	0x12, 0x23, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x54, 0x65,
+	0x72, 0x6d, 0x69, 0x6e, 0x61, 0x74, 0x65, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x24, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61,
+	0x6e, 0x6e, 0x2e, 0x54, 0x65, 0x72, 0x6d, 0x69, 0x6e, 0x61, 0x74, 0x65, 0x53, 0x65, 0x73, 0x73,
+	0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4b, 0x0a, 0x0a, 0x48,
+	0x61, 0x73, 0x68, 0x42, 0x75, 0x6e, 0x64, 0x6c, 0x65, 0x12, 0x1d, 0x2e, 0x70, 0x72, 0x69, 0x76,
+	0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x48, 0x61, 0x73, 0x68, 0x42, 0x75, 0x6e, 0x64, 0x6c,
+	0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61,
+	0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x48, 0x61, 0x73, 0x68, 0x42, 0x75, 0x6e, 0x64, 0x6c, 0x65,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4c, 0x0a, 0x0f, 0x50, 0x72, 0x69, 0x76,
+	0x61, 0x74, 0x65, 0x41, 0x4e, 0x4e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x12, 0x1b, 0x2e, 0x70, 0x72,
+	0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x41, 0x4e, 0x4e, 0x51, 0x75, 0x65, 0x72,
+	0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61,
+	0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x41, 0x4e, 0x4e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x67, 0x0a, 0x18, 0x50, 0x72, 0x69, 0x76, 0x61, 0x74,
+	0x65, 0x45, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x41, 0x4e, 0x4e, 0x51, 0x75, 0x65,
+	0x72, 0x79, 0x12, 0x24, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e,
+	0x45, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x41, 0x4e, 0x4e, 0x51, 0x75, 0x65, 0x72,
+	0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x25, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61,
+	0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x45, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x41,
+	0x4e, 0x4e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x4f, 0x0a, 0x10, 0x50, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x49, 0x74, 0x65, 0x6d, 0x51, 0x75,
+	0x65, 0x72, 0x79, 0x12, 0x1c, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e,
+	0x2e, 0x49, 0x74, 0x65, 0x6d, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x1d, 0x2e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x61, 0x6e, 0x6e, 0x2e, 0x49,
+	0x74, 0x65, 0x6d, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x42, 0x2f, 0x5a, 0x2d, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x73,
+	0x61, 0x63, 0x68, 0x61, 0x73, 0x65, 0x72, 0x76, 0x61, 0x6e, 0x2f, 0x70, 0x72, 0x69, 0x76, 0x61,
+	0x74, 0x65, 0x2d, 0x61, 0x6e, 0x6e, 0x2f, 0x63, 0x6d, 0x64, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x70,
+	0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_private_ann_proto_rawDescOnce sync.Once
+	file_private_ann_proto_rawDescData = file_private_ann_proto_rawDesc
+)
+
+func file_private_ann_proto_rawDescGZIP() []byte {
+	file_private_ann_proto_rawDescOnce.Do(func() {
+		file_private_ann_proto_rawDescData = protoimpl.X.CompressGZIP(file_private_ann_proto_rawDescData)
+	})
+	return file_private_ann_proto_rawDescData
+}
+
+var file_private_ann_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_private_ann_proto_msgTypes = make([]protoimpl.MessageInfo, 27)
+var file_private_ann_proto_goTypes = []interface{}{
+	(DistanceMetric)(0),               // 0: privateann.DistanceMetric
+	(ProbeStrategy)(0),                // 1: privateann.ProbeStrategy
+	(*WaitForExperimentRequest)(nil),  // 2: privateann.WaitForExperimentRequest
+	(*WaitForExperimentResponse)(nil), // 3: privateann.WaitForExperimentResponse
+	(*InitSessionRequest)(nil),        // 4: privateann.InitSessionRequest
+	(*InitSessionResponse)(nil),       // 5: privateann.InitSessionResponse
+	(*TerminateSessionRequest)(nil),   // 6: privateann.TerminateSessionRequest
+	(*TerminateSessionResponse)(nil),  // 7: privateann.TerminateSessionResponse
+	(*HashBundleRequest)(nil),         // 8: privateann.HashBundleRequest
+	(*HashBundleResponse)(nil),        // 9: privateann.HashBundleResponse
+	(*DBMetadata)(nil),                // 10: privateann.DBMetadata
+	(*ItemDBParameters)(nil),          // 11: privateann.ItemDBParameters
+	(*SlotTableParameters)(nil),       // 12: privateann.SlotTableParameters
+	(*SessionParameters)(nil),         // 13: privateann.SessionParameters
+	(*DPFKey)(nil),                    // 14: privateann.DPFKey
+	(*QueryShare)(nil),                // 15: privateann.QueryShare
+	(*BatchQueryShare)(nil),           // 16: privateann.BatchQueryShare
+	(*SecretSharedQueryResult)(nil),   // 17: privateann.SecretSharedQueryResult
+	(*ANNQueryRequest)(nil),           // 18: privateann.ANNQueryRequest
+	(*ANNQueryResponse)(nil),          // 19: privateann.ANNQueryResponse
+	(*PaillierPublicKey)(nil),         // 20: privateann.PaillierPublicKey
+	(*PaillierCiphertext)(nil),        // 21: privateann.PaillierCiphertext
+	(*EncryptedQuery)(nil),            // 22: privateann.EncryptedQuery
+	(*EncryptedBatchQuery)(nil),       // 23: privateann.EncryptedBatchQuery
+	(*EncryptedQueryResult)(nil),      // 24: privateann.EncryptedQueryResult
+	(*EncryptedANNQueryRequest)(nil),  // 25: privateann.EncryptedANNQueryRequest
+	(*EncryptedANNQueryResponse)(nil), // 26: privateann.EncryptedANNQueryResponse
+	(*ItemQueryRequest)(nil),          // 27: privateann.ItemQueryRequest
+	(*ItemQueryResponse)(nil),         // 28: privateann.ItemQueryResponse
+}
+var file_private_ann_proto_depIdxs = []int32{
+	13, // 0: privateann.InitSessionResponse.params:type_name -> privateann.SessionParameters
+	10, // 1: privateann.ItemDBParameters.db:type_name -> privateann.DBMetadata
+	10, // 2: privateann.SlotTableParameters.db:type_name -> privateann.DBMetadata
+	0,  // 3: privateann.SessionParameters.distance_metric:type_name -> privateann.DistanceMetric
+	10, // 4: privateann.SessionParameters.table_bucket_metadata:type_name -> privateann.DBMetadata
+	11, // 5: privateann.SessionParameters.item_db:type_name -> privateann.ItemDBParameters
+	12, // 6: privateann.SessionParameters.slot_tables:type_name -> privateann.SlotTableParameters
+	1,  // 7: privateann.SessionParameters.probe_strategy:type_name -> privateann.ProbeStrategy
+	14, // 8: privateann.QueryShare.dpf_key:type_name -> privateann.DPFKey
+	15, // 9: privateann.BatchQueryShare.queries:type_name -> privateann.QueryShare
+	16, // 10: privateann.ANNQueryRequest.secret_shared:type_name -> privateann.BatchQueryShare
+	17, // 11: privateann.ANNQueryResponse.results:type_name -> privateann.SecretSharedQueryResult
+	21, // 12: privateann.EncryptedQuery.selection:type_name -> privateann.PaillierCiphertext
+	22, // 13: privateann.EncryptedBatchQuery.queries:type_name -> privateann.EncryptedQuery
+	21, // 14: privateann.EncryptedQueryResult.ciphertexts:type_name -> privateann.PaillierCiphertext
+	20, // 15: privateann.EncryptedANNQueryRequest.public_key:type_name -> privateann.PaillierPublicKey
+	23, // 16: privateann.EncryptedANNQueryRequest.encrypted:type_name -> privateann.EncryptedBatchQuery
+	24, // 17: privateann.EncryptedANNQueryResponse.results:type_name -> privateann.EncryptedQueryResult
+	15, // 18: privateann.ItemQueryRequest.queries:type_name -> privateann.QueryShare
+	17, // 19: privateann.ItemQueryResponse.results:type_name -> privateann.SecretSharedQueryResult
+	2,  // 20: privateann.PrivateANN.WaitForExperiment:input_type -> privateann.WaitForExperimentRequest
+	4,  // 21: privateann.PrivateANN.InitSession:input_type -> privateann.InitSessionRequest
+	6,  // 22: privateann.PrivateANN.TerminateSession:input_type -> privateann.TerminateSessionRequest
+	8,  // 23: privateann.PrivateANN.HashBundle:input_type -> privateann.HashBundleRequest
+	18, // 24: privateann.PrivateANN.PrivateANNQuery:input_type -> privateann.ANNQueryRequest
+	25, // 25: privateann.PrivateANN.PrivateEncryptedANNQuery:input_type -> privateann.EncryptedANNQueryRequest
+	27, // 26: privateann.PrivateANN.PrivateItemQuery:input_type -> privateann.ItemQueryRequest
+	3,  // 27: privateann.PrivateANN.WaitForExperiment:output_type -> privateann.WaitForExperimentResponse
+	5,  // 28: privateann.PrivateANN.InitSession:output_type -> privateann.InitSessionResponse
+	7,  // 29: privateann.PrivateANN.TerminateSession:output_type -> privateann.TerminateSessionResponse
+	9,  // 30: privateann.PrivateANN.HashBundle:output_type -> privateann.HashBundleResponse
+	19, // 31: privateann.PrivateANN.PrivateANNQuery:output_type -> privateann.ANNQueryResponse
+	26, // 32: privateann.PrivateANN.PrivateEncryptedANNQuery:output_type -> privateann.EncryptedANNQueryResponse
+	28, // 33: privateann.PrivateANN.PrivateItemQuery:output_type -> privateann.ItemQueryResponse
+	27, // [27:34] is the sub-list for method output_type
+	20, // [20:27] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
+}
+
+func init() { file_private_ann_proto_init() }
+func file_private_ann_proto_init() {
+	if File_private_ann_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_private_ann_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WaitForExperimentRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WaitForExperimentResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*InitSessionRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*InitSessionResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TerminateSessionRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TerminateSessionResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HashBundleRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HashBundleResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DBMetadata); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ItemDBParameters); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SlotTableParameters); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SessionParameters); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DPFKey); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*QueryShare); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BatchQueryShare); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SecretSharedQueryResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ANNQueryRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ANNQueryResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PaillierPublicKey); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PaillierCiphertext); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EncryptedQuery); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EncryptedBatchQuery); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EncryptedQueryResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EncryptedANNQueryRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[24].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EncryptedANNQueryResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[25].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ItemQueryRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_private_ann_proto_msgTypes[26].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ItemQueryResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_private_ann_proto_rawDesc,
+			NumEnums:      2,
+			NumMessages:   27,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_private_ann_proto_goTypes,
+		DependencyIndexes: file_private_ann_proto_depIdxs,
+		EnumInfos:         file_private_ann_proto_enumTypes,
+		MessageInfos:      file_private_ann_proto_msgTypes,
+	}.Build()
+	File_private_ann_proto = out.File
+	file_private_ann_proto_rawDesc = nil
+	file_private_ann_proto_goTypes = nil
+	file_private_ann_proto_depIdxs = nil
+}
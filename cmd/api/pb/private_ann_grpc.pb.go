@@ -0,0 +1,329 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// PrivateANNClient is the client API for PrivateANN service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PrivateANNClient interface {
+	// waits until the server is ready to answer queries
+	WaitForExperiment(ctx context.Context, in *WaitForExperimentRequest, opts ...grpc.CallOption) (*WaitForExperimentResponse, error)
+	// opens a session and returns the parameters needed to query the server
+	InitSession(ctx context.Context, in *InitSessionRequest, opts ...grpc.CallOption) (*InitSessionResponse, error)
+	TerminateSession(ctx context.Context, in *TerminateSessionRequest, opts ...grpc.CallOption) (*TerminateSessionResponse, error)
+	// returns the hash functions with the digest sent in the session parameters
+	HashBundle(ctx context.Context, in *HashBundleRequest, opts ...grpc.CallOption) (*HashBundleResponse, error)
+	// two-server (DPF) queries for buckets of the hash tables
+	PrivateANNQuery(ctx context.Context, in *ANNQueryRequest, opts ...grpc.CallOption) (*ANNQueryResponse, error)
+	// single-server (Paillier) queries for buckets of the hash tables
+	PrivateEncryptedANNQuery(ctx context.Context, in *EncryptedANNQueryRequest, opts ...grpc.CallOption) (*EncryptedANNQueryResponse, error)
+	// two-server (DPF) queries for the vectors and payloads of items
+	PrivateItemQuery(ctx context.Context, in *ItemQueryRequest, opts ...grpc.CallOption) (*ItemQueryResponse, error)
+}
+
+type privateANNClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPrivateANNClient(cc grpc.ClientConnInterface) PrivateANNClient {
+	return &privateANNClient{cc}
+}
+
+func (c *privateANNClient) WaitForExperiment(ctx context.Context, in *WaitForExperimentRequest, opts ...grpc.CallOption) (*WaitForExperimentResponse, error) {
+	out := new(WaitForExperimentResponse)
+	err := c.cc.Invoke(ctx, "/privateann.PrivateANN/WaitForExperiment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *privateANNClient) InitSession(ctx context.Context, in *InitSessionRequest, opts ...grpc.CallOption) (*InitSessionResponse, error) {
+	out := new(InitSessionResponse)
+	err := c.cc.Invoke(ctx, "/privateann.PrivateANN/InitSession", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *privateANNClient) TerminateSession(ctx context.Context, in *TerminateSessionRequest, opts ...grpc.CallOption) (*TerminateSessionResponse, error) {
+	out := new(TerminateSessionResponse)
+	err := c.cc.Invoke(ctx, "/privateann.PrivateANN/TerminateSession", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *privateANNClient) HashBundle(ctx context.Context, in *HashBundleRequest, opts ...grpc.CallOption) (*HashBundleResponse, error) {
+	out := new(HashBundleResponse)
+	err := c.cc.Invoke(ctx, "/privateann.PrivateANN/HashBundle", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *privateANNClient) PrivateANNQuery(ctx context.Context, in *ANNQueryRequest, opts ...grpc.CallOption) (*ANNQueryResponse, error) {
+	out := new(ANNQueryResponse)
+	err := c.cc.Invoke(ctx, "/privateann.PrivateANN/PrivateANNQuery", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *privateANNClient) PrivateEncryptedANNQuery(ctx context.Context, in *EncryptedANNQueryRequest, opts ...grpc.CallOption) (*EncryptedANNQueryResponse, error) {
+	out := new(EncryptedANNQueryResponse)
+	err := c.cc.Invoke(ctx, "/privateann.PrivateANN/PrivateEncryptedANNQuery", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *privateANNClient) PrivateItemQuery(ctx context.Context, in *ItemQueryRequest, opts ...grpc.CallOption) (*ItemQueryResponse, error) {
+	out := new(ItemQueryResponse)
+	err := c.cc.Invoke(ctx, "/privateann.PrivateANN/PrivateItemQuery", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PrivateANNServer is the server API for PrivateANN service.
+// All implementations must embed UnimplementedPrivateANNServer
+// for forward compatibility
+type PrivateANNServer interface {
+	// waits until the server is ready to answer queries
+	WaitForExperiment(context.Context, *WaitForExperimentRequest) (*WaitForExperimentResponse, error)
+	// opens a session and returns the parameters needed to query the server
+	InitSession(context.Context, *InitSessionRequest) (*InitSessionResponse, error)
+	TerminateSession(context.Context, *TerminateSessionRequest) (*TerminateSessionResponse, error)
+	// returns the hash functions with the digest sent in the session parameters
+	HashBundle(context.Context, *HashBundleRequest) (*HashBundleResponse, error)
+	// two-server (DPF) queries for buckets of the hash tables
+	PrivateANNQuery(context.Context, *ANNQueryRequest) (*ANNQueryResponse, error)
+	// single-server (Paillier) queries for buckets of the hash tables
+	PrivateEncryptedANNQuery(context.Context, *EncryptedANNQueryRequest) (*EncryptedANNQueryResponse, error)
+	// two-server (DPF) queries for the vectors and payloads of items
+	PrivateItemQuery(context.Context, *ItemQueryRequest) (*ItemQueryResponse, error)
+	mustEmbedUnimplementedPrivateANNServer()
+}
+
+// UnimplementedPrivateANNServer must be embedded to have forward compatible implementations.
+type UnimplementedPrivateANNServer struct {
+}
+
+func (UnimplementedPrivateANNServer) WaitForExperiment(context.Context, *WaitForExperimentRequest) (*WaitForExperimentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WaitForExperiment not implemented")
+}
+func (UnimplementedPrivateANNServer) InitSession(context.Context, *InitSessionRequest) (*InitSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InitSession not implemented")
+}
+func (UnimplementedPrivateANNServer) TerminateSession(context.Context, *TerminateSessionRequest) (*TerminateSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TerminateSession not implemented")
+}
+func (UnimplementedPrivateANNServer) HashBundle(context.Context, *HashBundleRequest) (*HashBundleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HashBundle not implemented")
+}
+func (UnimplementedPrivateANNServer) PrivateANNQuery(context.Context, *ANNQueryRequest) (*ANNQueryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PrivateANNQuery not implemented")
+}
+func (UnimplementedPrivateANNServer) PrivateEncryptedANNQuery(context.Context, *EncryptedANNQueryRequest) (*EncryptedANNQueryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PrivateEncryptedANNQuery not implemented")
+}
+func (UnimplementedPrivateANNServer) PrivateItemQuery(context.Context, *ItemQueryRequest) (*ItemQueryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PrivateItemQuery not implemented")
+}
+func (UnimplementedPrivateANNServer) mustEmbedUnimplementedPrivateANNServer() {}
+
+// UnsafePrivateANNServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PrivateANNServer will
+// result in compilation errors.
+type UnsafePrivateANNServer interface {
+	mustEmbedUnimplementedPrivateANNServer()
+}
+
+func RegisterPrivateANNServer(s grpc.ServiceRegistrar, srv PrivateANNServer) {
+	s.RegisterService(&PrivateANN_ServiceDesc, srv)
+}
+
+func _PrivateANN_WaitForExperiment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WaitForExperimentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PrivateANNServer).WaitForExperiment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/privateann.PrivateANN/WaitForExperiment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PrivateANNServer).WaitForExperiment(ctx, req.(*WaitForExperimentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PrivateANN_InitSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InitSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PrivateANNServer).InitSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/privateann.PrivateANN/InitSession",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PrivateANNServer).InitSession(ctx, req.(*InitSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PrivateANN_TerminateSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TerminateSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PrivateANNServer).TerminateSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/privateann.PrivateANN/TerminateSession",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PrivateANNServer).TerminateSession(ctx, req.(*TerminateSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PrivateANN_HashBundle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HashBundleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PrivateANNServer).HashBundle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/privateann.PrivateANN/HashBundle",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PrivateANNServer).HashBundle(ctx, req.(*HashBundleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PrivateANN_PrivateANNQuery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ANNQueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PrivateANNServer).PrivateANNQuery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/privateann.PrivateANN/PrivateANNQuery",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PrivateANNServer).PrivateANNQuery(ctx, req.(*ANNQueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PrivateANN_PrivateEncryptedANNQuery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EncryptedANNQueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PrivateANNServer).PrivateEncryptedANNQuery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/privateann.PrivateANN/PrivateEncryptedANNQuery",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PrivateANNServer).PrivateEncryptedANNQuery(ctx, req.(*EncryptedANNQueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PrivateANN_PrivateItemQuery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ItemQueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PrivateANNServer).PrivateItemQuery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/privateann.PrivateANN/PrivateItemQuery",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PrivateANNServer).PrivateItemQuery(ctx, req.(*ItemQueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PrivateANN_ServiceDesc is the grpc.ServiceDesc for PrivateANN service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PrivateANN_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "privateann.PrivateANN",
+	HandlerType: (*PrivateANNServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "WaitForExperiment",
+			Handler:    _PrivateANN_WaitForExperiment_Handler,
+		},
+		{
+			MethodName: "InitSession",
+			Handler:    _PrivateANN_InitSession_Handler,
+		},
+		{
+			MethodName: "TerminateSession",
+			Handler:    _PrivateANN_TerminateSession_Handler,
+		},
+		{
+			MethodName: "HashBundle",
+			Handler:    _PrivateANN_HashBundle_Handler,
+		},
+		{
+			MethodName: "PrivateANNQuery",
+			Handler:    _PrivateANN_PrivateANNQuery_Handler,
+		},
+		{
+			MethodName: "PrivateEncryptedANNQuery",
+			Handler:    _PrivateANN_PrivateEncryptedANNQuery_Handler,
+		},
+		{
+			MethodName: "PrivateItemQuery",
+			Handler:    _PrivateANN_PrivateItemQuery_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "private_ann.proto",
+}
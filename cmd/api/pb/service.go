@@ -0,0 +1,290 @@
+package pb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/sachaservan/private-ann/cmd/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// private_ann.pb.go and private_ann_grpc.pb.go are generated (see private_ann.proto)
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative private_ann.proto
+
+// Handler answers the API calls (see server.Server.GRPCHandler); the calls
+// take the context of the gRPC request, which is done when the client cancels it
+type Handler interface {
+	WaitForExperiment(ctx context.Context, args *api.WaitForExperimentArgs, reply *api.WaitForExperimentResponse) error
+	InitSession(ctx context.Context, args api.InitSessionArgs, reply *api.InitSessionResponse) error
+	TerminateSession(ctx context.Context, args *api.TerminateSessionArgs, reply *api.TerminateSessionResponse) error
+	HashBundle(ctx context.Context, args *api.HashBundleArgs, reply *api.HashBundleResponse) error
+	PrivateANNQuery(ctx context.Context, args *api.ANNQueryArgs, reply *api.ANNQueryResponse) error
+	PrivateEncryptedANNQuery(ctx context.Context, args *api.EncryptedANNQueryArgs, reply *api.EncryptedANNQueryResponse) error
+	PrivateItemQuery(ctx context.Context, args *api.ItemQueryArgs, reply *api.ItemQueryResponse) error
+}
+
+// NewServer returns the gRPC service that answers requests with the handler
+func NewServer(handler Handler) PrivateANNServer {
+	return &handlerServer{handler: handler}
+}
+
+type handlerServer struct {
+	UnimplementedPrivateANNServer
+	handler Handler
+}
+
+func invalidArgument(err error) error {
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+func (s *handlerServer) WaitForExperiment(ctx context.Context, req *WaitForExperimentRequest) (*WaitForExperimentResponse, error) {
+	err := s.handler.WaitForExperiment(ctx, &api.WaitForExperimentArgs{}, &api.WaitForExperimentResponse{})
+	if err != nil {
+		return nil, err
+	}
+	return &WaitForExperimentResponse{}, nil
+}
+
+func (s *handlerServer) InitSession(ctx context.Context, req *InitSessionRequest) (*InitSessionResponse, error) {
+	reply := &api.InitSessionResponse{}
+	if err := s.handler.InitSession(ctx, api.InitSessionArgs{}, reply); err != nil {
+		return nil, err
+	}
+
+	params, err := encodeSessionParameters(&reply.SessionParameters)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &InitSessionResponse{
+		Params:                   params,
+		StatsPreprocessingTimeMs: reply.StatsPreprocessingTimeInMS,
+		StatsDatasetSize:         int64(reply.StatsDatasetSize),
+		StatsNumFeatures:         int64(reply.StatsNumFeatures),
+		StatsDatasetName:         reply.StatsDatasetName,
+		StatsNumServerProcs:      int64(reply.StatsNumServerProcs),
+		TableDigests:             reply.TableDigests,
+	}, nil
+}
+
+func (s *handlerServer) TerminateSession(ctx context.Context, req *TerminateSessionRequest) (*TerminateSessionResponse, error) {
+	err := s.handler.TerminateSession(ctx, &api.TerminateSessionArgs{SessionID: req.SessionId}, &api.TerminateSessionResponse{})
+	if err != nil {
+		return nil, err
+	}
+	return &TerminateSessionResponse{}, nil
+}
+
+func (s *handlerServer) HashBundle(ctx context.Context, req *HashBundleRequest) (*HashBundleResponse, error) {
+	reply := &api.HashBundleResponse{}
+	if err := s.handler.HashBundle(ctx, &api.HashBundleArgs{Digest: req.Digest}, reply); err != nil {
+		return nil, err
+	}
+	return &HashBundleResponse{Bundle: reply.Bundle}, nil
+}
+
+func (s *handlerServer) PrivateANNQuery(ctx context.Context, req *ANNQueryRequest) (*ANNQueryResponse, error) {
+	queries, err := decodeBatchQueryShares(req.SecretShared)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+
+	args := &api.ANNQueryArgs{
+		SessionID:    req.SessionId,
+		MultiProbes:  int(req.MultiProbes),
+		NumResults:   int(req.NumResults),
+		SecretShared: queries,
+		Version:      int(req.Version),
+	}
+	reply := &api.ANNQueryResponse{}
+	if err := s.handler.PrivateANNQuery(ctx, args, reply); err != nil {
+		return nil, err
+	}
+
+	return &ANNQueryResponse{
+		SessionId:          reply.SessionID,
+		Results:            encodeResults(reply.ResSecretShared),
+		StatsQueryTimeMs:   reply.StatsQueryTimeInMS,
+		StatsMaskingTimeUs: reply.StatsMaskingTimeInUS,
+	}, nil
+}
+
+func (s *handlerServer) PrivateEncryptedANNQuery(ctx context.Context, req *EncryptedANNQueryRequest) (*EncryptedANNQueryResponse, error) {
+	pk, err := decodePublicKey(req.PublicKey)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	queries, err := decodeEncryptedBatchQueries(req.Encrypted)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+
+	args := &api.EncryptedANNQueryArgs{
+		SessionID:  req.SessionId,
+		NumResults: int(req.NumResults),
+		PublicKey:  pk,
+		Encrypted:  queries,
+		Version:    int(req.Version),
+	}
+	reply := &api.EncryptedANNQueryResponse{}
+	if err := s.handler.PrivateEncryptedANNQuery(ctx, args, reply); err != nil {
+		return nil, err
+	}
+
+	return &EncryptedANNQueryResponse{
+		SessionId:          reply.SessionID,
+		Results:            encodeEncryptedResults(reply.ResEncrypted),
+		StatsQueryTimeMs:   reply.StatsQueryTimeInMS,
+		StatsMaskingTimeUs: reply.StatsMaskingTimeInUS,
+	}, nil
+}
+
+func (s *handlerServer) PrivateItemQuery(ctx context.Context, req *ItemQueryRequest) (*ItemQueryResponse, error) {
+	args := &api.ItemQueryArgs{SessionID: req.SessionId, Version: int(req.Version)}
+	for _, q := range req.Queries {
+		query, err := decodeQueryShare(q)
+		if err != nil {
+			return nil, invalidArgument(err)
+		}
+		args.Queries = append(args.Queries, query)
+	}
+
+	reply := &api.ItemQueryResponse{}
+	if err := s.handler.PrivateItemQuery(ctx, args, reply); err != nil {
+		return nil, err
+	}
+
+	return &ItemQueryResponse{
+		SessionId:        reply.SessionID,
+		Results:          encodeResults(reply.ResSecretShared),
+		StatsQueryTimeMs: reply.StatsQueryTimeInMS,
+	}, nil
+}
+
+// Invoke calls the API method (net/rpc name, e.g., "Server.InitSession") with the api
+// arguments over gRPC and decodes the response into the api reply
+func Invoke(ctx context.Context, client PrivateANNClient, method string, args, reply interface{}) error {
+	opts := []grpc.CallOption{grpc.MaxCallSendMsgSize(MaxMessageSize), grpc.MaxCallRecvMsgSize(MaxMessageSize)}
+
+	switch method {
+	case "Server.WaitForExperiment":
+		_, err := client.WaitForExperiment(ctx, &WaitForExperimentRequest{}, opts...)
+		return err
+
+	case "Server.InitSession":
+		res, err := client.InitSession(ctx, &InitSessionRequest{}, opts...)
+		if err != nil {
+			return err
+		}
+		params, err := decodeSessionParameters(res.Params)
+		if err != nil {
+			return err
+		}
+		r := indirect(reply).(*api.InitSessionResponse)
+		r.SessionParameters = *params
+		r.StatsPreprocessingTimeInMS = res.StatsPreprocessingTimeMs
+		r.StatsDatasetSize = int(res.StatsDatasetSize)
+		r.StatsNumFeatures = int(res.StatsNumFeatures)
+		r.StatsDatasetName = res.StatsDatasetName
+		r.StatsNumServerProcs = int(res.StatsNumServerProcs)
+		r.TableDigests = res.TableDigests
+		return nil
+
+	case "Server.TerminateSession":
+		a := indirect(args).(*api.TerminateSessionArgs)
+		_, err := client.TerminateSession(ctx, &TerminateSessionRequest{SessionId: a.SessionID}, opts...)
+		return err
+
+	case "Server.HashBundle":
+		a := indirect(args).(*api.HashBundleArgs)
+		res, err := client.HashBundle(ctx, &HashBundleRequest{Digest: a.Digest}, opts...)
+		if err != nil {
+			return err
+		}
+		indirect(reply).(*api.HashBundleResponse).Bundle = res.Bundle
+		return nil
+
+	case "Server.PrivateANNQuery":
+		a := indirect(args).(*api.ANNQueryArgs)
+		req := &ANNQueryRequest{
+			SessionId:    a.SessionID,
+			MultiProbes:  int64(a.MultiProbes),
+			NumResults:   int64(a.NumResults),
+			SecretShared: encodeBatchQueryShares(a.SecretShared),
+			Version:      int64(a.Version),
+		}
+		res, err := client.PrivateANNQuery(ctx, req, opts...)
+		if err != nil {
+			return err
+		}
+		results, err := decodeResults(res.Results)
+		if err != nil {
+			return err
+		}
+		r := indirect(reply).(*api.ANNQueryResponse)
+		r.SessionID = res.SessionId
+		r.ResSecretShared = results
+		r.StatsQueryTimeInMS = res.StatsQueryTimeMs
+		r.StatsMaskingTimeInUS = res.StatsMaskingTimeUs
+		return nil
+
+	case "Server.PrivateEncryptedANNQuery":
+		a := indirect(args).(*api.EncryptedANNQueryArgs)
+		req := &EncryptedANNQueryRequest{
+			SessionId:  a.SessionID,
+			NumResults: int64(a.NumResults),
+			PublicKey:  encodePublicKey(a.PublicKey),
+			Encrypted:  encodeEncryptedBatchQueries(a.Encrypted),
+			Version:    int64(a.Version),
+		}
+		res, err := client.PrivateEncryptedANNQuery(ctx, req, opts...)
+		if err != nil {
+			return err
+		}
+		results, err := decodeEncryptedResults(res.Results)
+		if err != nil {
+			return err
+		}
+		r := indirect(reply).(*api.EncryptedANNQueryResponse)
+		r.SessionID = res.SessionId
+		r.ResEncrypted = results
+		r.StatsQueryTimeInMS = res.StatsQueryTimeMs
+		r.StatsMaskingTimeInUS = res.StatsMaskingTimeUs
+		return nil
+
+	case "Server.PrivateItemQuery":
+		a := indirect(args).(*api.ItemQueryArgs)
+		req := &ItemQueryRequest{SessionId: a.SessionID, Version: int64(a.Version)}
+		for _, q := range a.Queries {
+			req.Queries = append(req.Queries, encodeQueryShare(q))
+		}
+		res, err := client.PrivateItemQuery(ctx, req, opts...)
+		if err != nil {
+			return err
+		}
+		results, err := decodeResults(res.Results)
+		if err != nil {
+			return err
+		}
+		r := indirect(reply).(*api.ItemQueryResponse)
+		r.SessionID = res.SessionId
+		r.ResSecretShared = results
+		r.StatsQueryTimeInMS = res.StatsQueryTimeMs
+		return nil
+
+	default:
+		return fmt.Errorf("unknown method %v", method)
+	}
+}
+
+// indirect strips the extra pointers of arguments passed as for net/rpc (e.g., **api.InitSessionArgs)
+func indirect(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && rv.Elem().Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	return rv.Interface()
+}
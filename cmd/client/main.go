@@ -2,7 +2,9 @@ package main
 
 import (
 	"bufio"
-	"encoding/gob"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"io/ioutil"
 	"log"
@@ -10,8 +12,8 @@ import (
 	"time"
 
 	"github.com/alexflint/go-arg"
-	"github.com/sachaservan/private-ann/ann"
 	"github.com/sachaservan/private-ann/client"
+	"github.com/sachaservan/private-ann/cmd/api"
 	"github.com/sachaservan/private-ann/hash"
 )
 
@@ -26,66 +28,132 @@ var args struct {
 	EvaluateProfileHash bool   `default:"false"` // run client server protocol to compute hash of client's profile
 	EvaluatePrivateANN  bool   `default:"false"` // run ANN search protocol
 	AutoCloseClient     bool   `default:"true"`  // close client when done
+	RetrieveItems       bool   `default:"false"` // privately retrieve the vectors and payloads of the ANN candidates
+	NumNeighbors        int    `default:"1"`     // number of nearest neighbors (k) to retrieve; k > 1 requires the servers to serve items
+	Transport           string `default:"grpc"`  // wire protocol of the servers: grpc or rpc (net/rpc)
+	HashBundleDir       string // directory caching the hash functions fetched from the servers (optional)
+
+	// bound on each call to the servers (0: no bound) and number of retries
+	// (with exponential backoff) of calls that fail to reach a server
+	Timeout time.Duration `default:"0"`
+	Retries int           `default:"5"`
+
+	// connect to the servers over TLS (implied by the other TLS options);
+	// server certificates are verified against TLSCAFile (system CAs if empty)
+	// and, when set, the hex-encoded pins of each server (see client.PublicKeyPin)
+	TLS         bool `default:"false"`
+	TLSCAFile   string
+	TLSCertFile string // client certificate (and key) for servers that authenticate clients
+	TLSKeyFile  string
+	ServerPins  []string
 }
 
 func main() {
 
-	gob.Register(&hash.MultiLatticeHash{})
-
 	arg.MustParse(&args)
 
+	if args.SingleServer && (args.RetrieveItems || args.NumNeighbors > 1) {
+		log.Fatal("[Client]: retrieving items (and k-NN queries) require two servers")
+	}
+
+	if args.Transport != api.TransportGRPC && args.Transport != api.TransportRPC {
+		log.Fatalf("[Client]: unknown transport %q (expected %v or %v)", args.Transport, api.TransportGRPC, api.TransportRPC)
+	}
+
 	cli := &client.Client{}
 	cli.ServerAddresses = args.ServerAddrs
 	cli.ServerPorts = args.ServerPorts
-	cli.Experiment = &client.RuntimeExperiment{}
+	cli.SingleServer = args.SingleServer
+	cli.SecurityBits = args.SecurityBits
+	cli.Transport = args.Transport
+	cli.Timeout = args.Timeout
+	cli.Retries = args.Retries
+	cli.HashBundleDir = args.HashBundleDir
+
+	if args.TLS || args.TLSCAFile != "" || args.TLSCertFile != "" || args.TLSKeyFile != "" || len(args.ServerPins) > 0 {
+		config, err := client.NewTLSConfig(args.TLSCAFile, args.TLSCertFile, args.TLSKeyFile)
+		if err != nil {
+			log.Fatalf("[Client]: failed to load TLS configuration: %v", err)
+		}
+		cli.TLSConfig = config
+
+		if len(args.ServerPins) > len(cli.ServerPins) {
+			log.Fatal("[Client]: expected at most one pin per server")
+		}
+		for i, pin := range args.ServerPins {
+			cli.ServerPins[i], err = hex.DecodeString(pin)
+			if err != nil || len(cli.ServerPins[i]) != sha256.Size {
+				log.Fatalf("[Client]: invalid pin %q for server %v (expected a hex-encoded SHA-256 digest)", pin, i)
+			}
+		}
+	}
+	cli.NumNeighbors = args.NumNeighbors
+	cli.RetrieveItems = args.RetrieveItems
 
 	// init experiment
-	cli.Experiment.QueryClientMS = make([]int64, 0)
-	cli.Experiment.QueryServerMS = make([]int64, 0)
-	cli.Experiment.QueryMaskingServerUS = make([]int64, 0)
-	cli.Experiment.QueryUpBandwidthBytes = make([]int64, 0)
-	cli.Experiment.QueryDownBandwidthBytes = make([]int64, 0)
+	experiment := &client.RuntimeExperiment{}
+	experiment.QueryClientMS = make([]int64, 0)
+	experiment.QueryServerMS = make([]int64, 0)
+	experiment.QueryMaskingServerUS = make([]int64, 0)
+	experiment.QueryUpBandwidthBytes = make([]int64, 0)
+	experiment.QueryDownBandwidthBytes = make([]int64, 0)
+	cli.Stats = experiment.RecordQuery
+
+	ctx := context.Background()
 
 	hash.Precompute()
-	for i := 0; i < args.ExperimentNumTrials; i++ {
 
-		log.Printf("[Client]: waiting for servers to initialize \n")
+	log.Printf("[Client]: waiting for servers to initialize \n")
 
-		// wait for the servers to finish initializing
-		cli.WaitForExperimentStart()
+	// wait for the servers to finish initializing
+	if err := cli.WaitForExperimentStart(ctx); err != nil {
+		log.Fatalf("[Client]: failed to reach the servers: %v", err)
+	}
 
-		log.Printf("[Client]: starting experiment \n")
+	log.Printf("[Client]: starting experiment \n")
 
-		start := time.Now()
+	// Step 1: Initialize the session (returns hash functions and test queries)
+	start := time.Now()
+	log.Printf("[Client]: initializing session \n")
+	if err := cli.InitSession(ctx); err != nil {
+		log.Fatalf("[Client]: failed to initialize session: %v", err)
+	}
+	log.Printf("[Client]: session initialized (SID = %v) in %v seconds\n", cli.SessionParams.SessionID, time.Since(start).Seconds())
 
-		// Step 1: Initialize the session (returns hash functions and test queries)
-		log.Printf("[Client]: initializing session \n")
-		cli.InitSession()
-		log.Printf("[Client]: session initialized (SID = %v) in %v seconds\n", cli.SessionParams.SessionID, time.Since(start).Seconds())
+	experiment.NumProbes = cli.SessionParams.NumProbes
+	experiment.HashFunctionRange = cli.SessionParams.HashFunctionRange
+	experiment.NumTables = cli.SessionParams.NumTables
+	experiment.DatasetName = cli.ServerInfo.DatasetName
+	experiment.DatasetSize = cli.ServerInfo.DatasetSize
+	experiment.NumFeatures = cli.ServerInfo.NumFeatures
+	experiment.NumServerProcs = cli.ServerInfo.NumServerProcs
+	experiment.ServerPreprocessingMS = cli.ServerInfo.PreprocessingMS
 
-		// Step 2: Compute hash for the test query
-		start = time.Now()
-		q := cli.SessionParams.TestQuery
-
-		keys := make([][]uint64, cli.SessionParams.NumTables)
-		for i := range keys {
-			// Returns numProbes values inserted into numPartition buckets
-			// 0 is the value in slots without hashes
-			// Here numPartitions = numProbes
-			keys[i] = ann.ComputeProbes(cli.SessionParams.HashFunctions[i], q, cli.SessionParams.NumProbes, cli.SessionParams.NumProbes)
-		}
+	for i := 0; i < args.ExperimentNumTrials; i++ {
 
-		// Step 3: query the buckets using PIR
+		// Step 2: hash the test query and query the buckets using PIR
+		// (Step 3, optional: retrieve the candidate vectors and re-rank them locally)
+		start = time.Now()
 		log.Printf("[Client]: querying %v buckets in %v tables\n",
-			cli.SessionParams.NumTables*cli.SessionParams.NumProbes,
+			cli.SessionParams.NumTables*cli.SessionParams.NumPartitions,
 			cli.SessionParams.NumTables)
 
-		candidate := cli.PrivateANNQuery(keys)
+		res, err := cli.Search(ctx, cli.SessionParams.TestQuery)
+		if err != nil {
+			log.Fatalf("[Client]: query failed: %v", err)
+		}
 
-		log.Printf("[Client]: ANN result is %v\n", candidate)
+		if args.NumNeighbors > 1 {
+			log.Printf("[Client]: %v-NN result is %v at distances %v\n", args.NumNeighbors, res.IDs, res.Distances)
+		} else {
+			log.Printf("[Client]: ANN result is %v\n", res.IDs)
+			if len(res.Distances) > 0 {
+				log.Printf("[Client]: nearest candidate is %v at distance %v\n", res.IDs[0], res.Distances[0])
+			}
+		}
 
 		queryTime := time.Since(start).Milliseconds()
-		cli.Experiment.QueryClientMS = append(cli.Experiment.QueryClientMS, queryTime)
+		experiment.QueryClientMS = append(experiment.QueryClientMS, queryTime)
 		log.Printf("[Client]: ANN query took %v seconds\n", time.Since(start).Seconds())
 
 		// Experiment completed
@@ -93,7 +161,7 @@ func main() {
 	}
 
 	// write the result of the evalaution to the specified file
-	experimentJSON, _ := json.MarshalIndent(cli.Experiment, "", " ")
+	experimentJSON, _ := json.MarshalIndent(experiment, "", " ")
 	ioutil.WriteFile(args.ExperimentSaveFile, experimentJSON, 0644)
 
 	// prevent client from closing until user input
@@ -104,5 +172,8 @@ func main() {
 	}
 
 	// terminate the client's session on the server
-	cli.TerminateSessions()
+	if err := cli.TerminateSessions(ctx); err != nil {
+		log.Printf("[Client]: failed to terminate session: %v", err)
+	}
+	cli.Close()
 }
@@ -1,15 +1,19 @@
 package main
 
 import (
-	"encoding/gob"
-	"encoding/json"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"log"
-	"math/rand"
 	"net"
 	"net/http"
 	"net/rpc"
+	"os"
 	"path/filepath"
 	"runtime"
 	"strconv"
@@ -17,38 +21,87 @@ import (
 
 	"github.com/alexflint/go-arg"
 	"github.com/sachaservan/private-ann/ann"
+	"github.com/sachaservan/private-ann/cmd/api"
+	"github.com/sachaservan/private-ann/cmd/api/pb"
 	"github.com/sachaservan/private-ann/hash"
 	"github.com/sachaservan/private-ann/pir"
 	"github.com/sachaservan/private-ann/pir/field"
 	"github.com/sachaservan/private-ann/server"
 	"github.com/sachaservan/vec"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
-type CachedHashTable struct {
-	Dimension int        `json:"dimension"`
-	N         int        `json:"n"`
-	TestQuery []float64  `json:"testQuery"`
-	Keys      []uint64   `json:"keys"`
-	Values    []field.FP `json:"values"`
-}
-
 type ServerArgs struct {
 	ServerID              int     `default:"0"`
 	Dataset               string  `default:"../datasets/mnist"`
 	CacheDir              string  `default:"../cache"`
 	NumTables             int     `default:"10"`
 	NumProbes             int     `default:"100"`
+	NumPartitions         int     `default:"0"`       // partitions (batches) of each table (0: NumProbes)
+	ProbeStrategy         string  `default:"closest"` // bucket probed by the clients in each partition
 	HashFunctionRange     int     `default:"64"`
 	ProjectionWidthMean   float64 `default:"887.7"`
 	ProjectionWidthStddev float64 `default:"244.9"`
 	MaxCoordinateValue    int     `default:"1000"`
 	NumProcs              int     `default:"40"`
 	BucketSize            int     `default:"1"`
-	DistanceMetric        string  `default:"euclidean"`
+	DistanceMetric        string  `default:"euclidean"` // euclidean or angular (cosine)
+	NumHyperplanes        int     `default:"16"`        // hyperplanes per hash function (angular metric only)
+	Quantize              bool    `default:"false"`     // store the training vectors with uint8 coordinates (see ann.Matrix.Quantize)
 
 	// only for synthetic dataset
 	DatasetSize int `default:"10000"`
 	NumFeatures int `default:"50"`
+
+	// serve the vectors (and optional payloads) of the dataset items
+	// PayloadFile contains one base64-encoded payload per line (line i is the payload of item i)
+	ServeItems      bool `default:"false"`
+	PayloadFile     string
+	MaxPayloadBytes int `default:"0"`
+
+	// also serve single-server queries (encrypted selection vectors, see server.SlotTables)
+	SingleServer bool `default:"false"`
+
+	// concurrent PIR evaluations (per table) shared by all requests (0: NumProcs)
+	// and max number of requests running or queued; further requests are rejected as busy
+	NumWorkers int `default:"0"`
+	QueueDepth int `default:"64"`
+
+	// evaluate the PrivateANNQuery requests received within BatchWindow of each other
+	// (up to MaxBatchSize requests) together (0: no batching)
+	BatchWindow  time.Duration `default:"0"`
+	MaxBatchSize int           `default:"16"`
+
+	// wire protocol served to the clients: grpc or rpc (net/rpc, for compatibility)
+	Transport string `default:"grpc"`
+
+	// serve the clients over TLS with this certificate (and key); when TLSClientCAFile is set,
+	// clients must present a certificate signed by one of its CAs (mutual authentication)
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSClientCAFile string
+
+	// client sessions expire after this long without requests (0: never)
+	// and sessions opened on more than MaxVersions old tables are ended (0: never)
+	SessionTimeout time.Duration `default:"30m"`
+	MaxVersions    int           `default:"2"`
+
+	// local control socket (unix socket) serving the admin RPC (shutdown, reload, status and update; see server.Admin)
+	// requests must carry the token stored in AdminTokenFile
+	AdminSocket    string
+	AdminTokenFile string
+
+	// secret seed (hex) that both servers agree on;
+	// used to generate the hash functions and tables
+	HashSeed string
+
+	// the servers check the queries together before answering them (see server.Peer):
+	// port of the peer service of this server (0: 9000 + ServerID) and address (host:port)
+	// of the peer service of the other server (default: localhost on its default port)
+	PeerPort    int
+	PeerAddr    string
+	PeerTimeout time.Duration `default:"1m"`
 }
 
 func main() {
@@ -62,17 +115,43 @@ func main() {
 	////////////////////////////////////////////////////////////////////////////
 	// IMPORTANT: All servers need to have the same randomness to generate
 	// consistent hash tables.
-	// As a hack, we use math.Rand for randomness (THIS IS NOT SECURE!) which
-	// allows us to set the seed deterministically on both servers.
-	// While this works well for experiment purposes, it should never be
-	// used in the wild.
-	rand.Seed(0)
+	// All randomness used to build the hash functions and tables is derived
+	// from the secret seed shared by the servers (AES-CTR keystream).
+	if args.HashSeed == "" {
+		log.Fatal("[Server]: a hash seed shared by both servers must be provided (see --hashseed)")
+	}
+	seed, err := hex.DecodeString(args.HashSeed)
+	if err != nil {
+		log.Fatalf("[Server]: invalid hash seed: %v", err)
+	}
 	////////////////////////////////////////////////////////////////////////////
 
 	if args.BucketSize <= 0 {
 		panic("bucket size must be at least 1")
-	} else if args.BucketSize != 1 {
-		panic("bucket size not implemented")
+	}
+
+	if args.Transport != api.TransportGRPC && args.Transport != api.TransportRPC {
+		log.Fatalf("[Server]: unknown transport %q (expected %v or %v)", args.Transport, api.TransportGRPC, api.TransportRPC)
+	}
+
+	metric, err := ann.ParseDistanceMetric(args.DistanceMetric)
+	if err != nil {
+		log.Fatalf("[Server]: %v", err)
+	}
+
+	probeStrategy, err := ann.ParseProbeStrategy(args.ProbeStrategy)
+	if err != nil {
+		log.Fatalf("[Server]: %v", err)
+	}
+
+	var tlsConfig *tls.Config
+	if args.TLSCertFile != "" || args.TLSKeyFile != "" || args.TLSClientCAFile != "" {
+		tlsConfig, err = server.NewTLSConfig(args.TLSCertFile, args.TLSKeyFile, args.TLSClientCAFile)
+		if err != nil {
+			log.Fatalf("[Server]: failed to load TLS configuration: %v", err)
+		}
+	} else {
+		log.Println("[Server]: serving without TLS (see --tlscertfile); query shares are sent in the clear")
 	}
 
 	log.Printf("[Server]: starting server with args:\n%+v\n", args)
@@ -80,6 +159,11 @@ func main() {
 	// limit the number of concurrent processors that we use
 	// runtime.GOMAXPROCS(args.NumProcs)
 
+	numWorkers := args.NumWorkers
+	if numWorkers <= 0 {
+		numWorkers = args.NumProcs
+	}
+
 	// init the server
 	serv := &server.Server{
 		NumProcs:          args.NumProcs,
@@ -87,8 +171,20 @@ func main() {
 		DatasetName:       filepath.Base(args.Dataset),
 		NumTables:         args.NumTables,
 		NumProbes:         args.NumProbes,
+		NumPartitions:     args.NumPartitions,
+		ProbeStrategy:     probeStrategy,
+		BucketSize:        args.BucketSize,
 		CacheDir:          args.CacheDir,
 		HashFunctionRange: args.HashFunctionRange,
+		DistanceMetric:    metric,
+		MaskingSeed:       append([]byte("masking"), seed...),
+		Scheduler:         server.NewScheduler(numWorkers, args.QueueDepth),
+		Sessions:          server.NewSessionManager(args.SessionTimeout),
+		MaxVersions:       args.MaxVersions,
+	}
+
+	if args.BatchWindow > 0 {
+		serv.StartQueryBatching(args.MaxBatchSize, args.BatchWindow)
 	}
 
 	serverPort := "8000"
@@ -96,41 +192,15 @@ func main() {
 		serverPort = "8001"
 	}
 
+	startPeer(serv, &args)
+
 	go func(serv *server.Server) {
 		// hack to ensure server starts before this completes
 		time.Sleep(100 * time.Millisecond)
 
-		start := time.Now()
-
-		tables, hashes := readOrConstructCache(serv, &args)
-
-		serv.HashFunctions = hashes
-		serv.TestQuery = vec.NewVec(tables[0].TestQuery)
-
-		log.Printf("[Server]: number of tables = %v\n", serv.NumTables)
-		log.Printf("[Server]: number of probes = %v\n", serv.NumProbes)
-
-		// build PIR databases for each LSH table
-		serv.TableDBs = make([]*pir.Database, serv.NumTables)
-
-		for i := range serv.TableDBs {
-			table := pir.NewDatabase()
-			starts, stops := ann.ComputeBucketDivisions(serv.NumProbes, tables[i].Keys, tables[i].Values, serv.HashFunctionRange)
-
-			var err error
-			err = table.BuildForKeysAndValues(tables[i].Keys, tables[i].Values)
-			if err != nil {
-				panic(err)
-			}
-			err = table.SetBatchingParameters(serv.NumProbes, starts, stops)
-			if err != nil {
-				panic(err)
-			}
-			serv.TableDBs[i] = table
-		}
-
-		serv.StatsDatasetNumFeatures = serv.TestQuery.Size()
-		serv.StatsTotalPreprocessingTime = time.Since(start).Milliseconds()
+		snapshot := loadTables(serv, &args, seed)
+		snapshot.Version = 1
+		serv.SetSnapshot(snapshot)
 
 		log.Printf("[Server]: server is ready and waiting for client on port %v\n", serverPort)
 
@@ -139,120 +209,419 @@ func main() {
 		serv.Ready = true
 	}(serv)
 
+	// rebuild the tables (e.g., after the dataset or cache changed) when the admin asks
+	serv.Reload = func() (snapshot *server.Snapshot, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%v", r)
+			}
+		}()
+		return loadTables(serv, &args, seed), nil
+	}
+
+	if args.AdminSocket != "" {
+		startAdmin(serv, args.AdminSocket, args.AdminTokenFile)
+	}
+
 	// start the server in the background
 	// will set ready=true when ready to take API calls
 	go killLoop(serv)
-	startServer(serv, serverPort)
+	startServer(serv, serverPort, args.Transport, tlsConfig)
 }
 
-// avoid recomputing hash tables if a cached hash table already exists
-func readOrConstructCache(serv *server.Server, args *ServerArgs) ([]*CachedHashTable, []hash.Hash) {
-	var trainingData, testQueries []*vec.Vec
-	var inputDim int
-	cachedTables := make([]*CachedHashTable, serv.NumTables)
+// loadTables reads (or builds) the hash tables and the PIR databases of the server
+// and returns them as a snapshot (without version, see server.Snapshot)
+func loadTables(serv *server.Server, args *ServerArgs, seed []byte) *server.Snapshot {
+	start := time.Now()
 
-	// test if we have a cache
-	cachedFilename := getCachedHashTableFilename(serv.DatasetName, serv.NumTables, serv.CacheDir, 0)
-	_, err := ioutil.ReadFile(cachedFilename)
+	tables, hashes, trainingData := readOrConstructCache(serv, args, seed)
 
-	// read the cache
-	if err == nil {
-		for i := range cachedTables {
-			cachedFilename = getCachedHashTableFilename(serv.DatasetName, serv.NumTables, serv.CacheDir, i)
-			var cached []byte
-			cached, err = ioutil.ReadFile(cachedFilename)
-			if err != nil {
-				panic(fmt.Sprintf("error occured when loading cached hash table %v", err))
-			}
-			cachedTables[i] = &CachedHashTable{}
-			err = json.Unmarshal(cached, cachedTables[i])
-			if err != nil {
-				panic(fmt.Sprintf("error occured when loading cached hash table %v", err))
-			}
+	log.Printf("[Server]: number of tables = %v\n", serv.NumTables)
+	log.Printf("[Server]: number of probes = %v\n", serv.NumProbes)
 
-			// mask the hash table keys based on the specified number of output bits
-			mask := (uint64(1) << uint64(args.HashFunctionRange)) - uint64(1)
-			for j := 0; j < len(cachedTables[i].Keys); j++ {
-				cachedTables[i].Keys[j] = cachedTables[i].Keys[j] & mask
-			}
+	// the clients probe one bucket in each partition of the tables
+	partitions := serv.Probing().Partitions()
+
+	// build PIR databases for each LSH table
+	tableDBs := make([]*pir.Database, serv.NumTables)
+
+	for i := range tableDBs {
+		starts, stops := ann.ComputeBucketDivisions(partitions, tables[i].Keys, tables[i].Values)
+
+		// each record holds the (encoded) ids of a bucket
+		table := pir.NewDatabase()
+		err := table.BuildForKeysAndRecords(tables[i].Keys, tables[i].Values)
+		if err != nil {
+			panic(err)
+		}
+		err = table.SetBatchingParameters(partitions.NumBuckets, starts, stops)
+		if err != nil {
+			panic(err)
+		}
+		tableDBs[i] = table
+	}
 
-			log.Printf("[Server]: loaded cached table %v \n", cachedFilename)
+	var slotTables *server.SlotTables
+	if args.SingleServer {
+		keys := make([][]uint64, serv.NumTables)
+		values := make([][][]field.FP, serv.NumTables)
+		for i := range tables {
+			keys[i] = tables[i].Keys
+			values[i] = tables[i].Values
 		}
 
-		serv.DBSize = cachedTables[0].N
-		inputDim = cachedTables[0].Dimension
+		var err error
+		slotTables, err = server.NewSlotTables(partitions, keys, values, serv.BucketSize)
+		if err != nil {
+			panic(err)
+		}
+		log.Printf("[Server]: built single-server tables with %v slots per partition (%v keys rejected)\n", ann.SlotChoices*slotTables.SlotsPerPartition, slotTables.RejectedKeys)
 	}
 
-	// otherwise load data
-	if err != nil {
+	var itemDB *server.ItemDatabase
+	if args.ServeItems {
+		itemDB = buildItemDatabase(serv, args, trainingData)
+	}
+
+	testQuery := vec.NewVec(tables[0].TestQuery)
+	return &server.Snapshot{
+		DBSize:                      tables[0].N,
+		TableDBs:                    tableDBs,
+		TestQuery:                   testQuery,
+		HashFunctions:               hashes,
+		ItemDB:                      itemDB,
+		SlotTables:                  slotTables,
+		StatsTotalPreprocessingTime: time.Since(start).Milliseconds(),
+		StatsDatasetNumFeatures:     testQuery.Size(),
+	}
+}
+
+// avoid recomputing hash tables if a valid cached hash table already exists
+// returns the tables, hash functions and training data (nil if the tables were read from the cache)
+func readOrConstructCache(serv *server.Server, args *ServerArgs, seed []byte) ([]*server.CachedHashTable, *hash.Bundle, *ann.Matrix) {
+	var trainingData *ann.Matrix
+	var testQueries []*vec.Vec
+	var inputDim int
+
+	loadDataset := func() {
 		log.Printf("[Server]: loading %v dataset\n", args.Dataset)
-		var err2 error
-		trainingData, testQueries, _, err2 = ann.ReadDataset(args.Dataset)
-		if err2 != nil {
-			panic(err2)
+		var err error
+		trainingData, testQueries, _, err = ann.ReadDatasetMatrix(args.Dataset, serv.DistanceMetric, args.Quantize)
+		if err != nil {
+			panic(err)
 		}
-		serv.DBSize = len(trainingData)
-		inputDim = trainingData[0].Size()
+		inputDim = trainingData.Dimension
+	}
+
+	rnd := hash.NewSeededRand(seed)
+	header := expectedCacheHeader(serv, args, seed)
+
+	cachedTables, err := readCache(serv, header)
+	if err == nil {
+		inputDim = cachedTables[0].Dimension
+	} else {
+		// otherwise load data
+		log.Printf("[Server]: not using cached tables: %v\n", err)
+		loadDataset()
 	}
 
 	// construct hash functions
 	radii := ann.GetNormalSequence2(args.ProjectionWidthMean, args.ProjectionWidthStddev, serv.NumTables)
+	// each hash function is described by a seed derived from the (secret) seed
+	// so that the clients can reconstruct it (see hash.Bundle)
+	bundle := &hash.Bundle{Descriptions: make([]*hash.Description, serv.NumTables)}
 	hashes := make([]hash.Hash, serv.NumTables)
+	hashParameters := make([][]byte, serv.NumTables)
 	for i := 0; i < len(hashes); i++ {
-		hashes[i] = hash.NewMultiLatticeHash(inputDim, 2, radii[i], float64(args.MaxCoordinateValue))
+		bundle.Descriptions[i], hashes[i], hashParameters[i] = newHashFunction(hash.DeriveSeed(seed, i), serv.DistanceMetric, args, inputDim, radii[i])
+	}
+
+	// the cached tables are only valid if they were built with the same hash functions
+	if err == nil {
+		err = checkCachedHashes(cachedTables, hashParameters)
+		if err != nil {
+			log.Printf("[Server]: refusing cached tables: %v\n", err)
+			loadDataset()
+		}
 	}
 
 	// construct the hash tables if we did not read from the cache
 	if err != nil {
-		log.Printf("[Server]: building ANN data structure for %v items\n", serv.DBSize)
+		log.Printf("[Server]: building ANN data structure for %v items\n", trainingData.Rows)
+		cachedTables = make([]*server.CachedHashTable, serv.NumTables)
 		for i := range cachedTables {
-			keys := make([][]uint64, serv.NumTables)
-			values := make([][]field.FP, serv.NumTables)
-			cachedFilename = getCachedHashTableFilename(serv.DatasetName, serv.NumTables, serv.CacheDir, i)
-			// cached table does not exist
-			keys[i], values[i] = ann.ComputeHashes(i, hashes[i], trainingData, uint64(serv.HashFunctionRange))
-			cachedTables[i] = &CachedHashTable{
-				Dimension: trainingData[0].Size(),
-				N:         len(trainingData),
-				TestQuery: testQueries[0].Coords,
-				Keys:      keys[i],
-				Values:    values[i],
+			keys, values := ann.ComputeHashes(rnd, i, hashes[i], trainingData, uint64(serv.HashFunctionRange), serv.BucketSize)
+			cachedTables[i] = &server.CachedHashTable{
+				Dimension:      trainingData.Dimension,
+				N:              trainingData.Rows,
+				TestQuery:      testQueries[0].Coords,
+				Keys:           keys,
+				Values:         values,
+				HashParameters: hashParameters[i],
 			}
+
 			// write the hash table key/values to the cache
-			cachedJSON, _ := json.MarshalIndent(cachedTables[i], "", " ")
-			ioutil.WriteFile(cachedFilename, cachedJSON, 0644)
+			header.Table = uint32(i)
+			cachedFilename := getCachedHashTableFilename(serv.DatasetName, serv.NumTables, serv.BucketSize, serv.CacheDir, i)
+			if err := server.WriteCachedTable(cachedFilename, header, cachedTables[i]); err != nil {
+				log.Printf("[Server]: failed to cache table %v: %v\n", i, err)
+				continue
+			}
 			log.Printf("[Server]: cached table %v to %v\n", i, cachedFilename)
 		}
 	}
-	return cachedTables, hashes
+	return cachedTables, bundle, trainingData
+}
+
+// newHashFunction describes an LSH function for the metric (sampled from the seed) and
+// returns the description (with the digest of its parameters), the hash function and its encoded parameters
+func newHashFunction(seed []byte, metric ann.DistanceMetric, args *ServerArgs, inputDim int, radius float64) (*hash.Description, hash.Hash, []byte) {
+	d := &hash.Description{Seed: seed, Dimension: inputDim}
+	switch metric {
+	case ann.Angular:
+		d.Kind = hash.Hyperplane
+		d.NumPlanes = args.NumHyperplanes
+	default:
+		d.Kind = hash.MultiLattice
+		d.Copies = 2
+		d.Width = radius
+		d.Max = float64(args.MaxCoordinateValue)
+	}
+
+	h, parameters, err := d.Sample()
+	if err != nil {
+		panic(err)
+	}
+	d.Digest = hash.ParametersDigest(parameters)
+
+	return d, h, parameters
+}
+
+// checkCachedHashes makes sure that every cached table was built with the given hash functions.
+// Tables that do not record their hash functions are refused (and rebuilt)
+// since there is no way to tell how they were built.
+func checkCachedHashes(cachedTables []*server.CachedHashTable, hashParameters [][]byte) error {
+	for i, table := range cachedTables {
+		if table.HashParameters == nil {
+			return fmt.Errorf("table %v does not record the parameters of its hash function", i)
+		}
+		if !bytes.Equal(table.HashParameters, hashParameters[i]) {
+			return fmt.Errorf("table %v was built with different hash function parameters", i)
+		}
+	}
+
+	return nil
+}
+
+// expectedCacheHeader returns the header that a valid cache must match
+func expectedCacheHeader(serv *server.Server, args *ServerArgs, seed []byte) *server.CacheHeader {
+	checksum, err := ann.DatasetChecksum(args.Dataset)
+	if err != nil {
+		panic(err)
+	}
+
+	numHyperplanes := 0
+	if serv.DistanceMetric == ann.Angular {
+		numHyperplanes = args.NumHyperplanes
+	}
+
+	quantized := uint32(0)
+	if args.Quantize {
+		quantized = 1
+	}
+
+	return &server.CacheHeader{
+		Version:               server.CacheVersion,
+		NumTables:             uint32(serv.NumTables),
+		BucketSize:            uint32(serv.BucketSize),
+		HashFunctionRange:     uint32(serv.HashFunctionRange),
+		MaxCoordinateValue:    uint32(args.MaxCoordinateValue),
+		DistanceMetric:        uint32(serv.DistanceMetric),
+		NumHyperplanes:        uint32(numHyperplanes),
+		Quantized:             quantized,
+		ProjectionWidthMean:   args.ProjectionWidthMean,
+		ProjectionWidthStddev: args.ProjectionWidthStddev,
+		DatasetChecksum:       checksum,
+		HashSeedDigest:        sha256.Sum256(seed),
+	}
+}
+
+// readCache reads all the cached tables and checks that they match the expected header
+// (tables cached by older versions in JSON are ignored, with a warning, and rebuilt)
+func readCache(serv *server.Server, expected *server.CacheHeader) ([]*server.CachedHashTable, error) {
+	cachedTables := make([]*server.CachedHashTable, serv.NumTables)
+	for i := range cachedTables {
+		header := *expected
+		header.Table = uint32(i)
+
+		cachedFilename := getCachedHashTableFilename(serv.DatasetName, serv.NumTables, serv.BucketSize, serv.CacheDir, i)
+		cached, table, err := server.ReadCachedTable(cachedFilename)
+		if os.IsNotExist(err) {
+			// JSON caches were built with hash functions that can no longer be reconstructed
+			// (they were sampled from a different source of randomness), so they cannot be converted
+			jsonFilename := getJSONCachedHashTableFilename(serv.DatasetName, serv.NumTables, serv.BucketSize, serv.CacheDir, i)
+			if _, statErr := os.Stat(jsonFilename); statErr == nil {
+				log.Printf("[Server]: ignoring legacy JSON cache %v (the table is rebuilt and cached to %v; the JSON file can be removed)\n", jsonFilename, cachedFilename)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := cached.Matches(&header); err != nil {
+			return nil, fmt.Errorf("%v: %v", cachedFilename, err)
+		}
+
+		cachedTables[i] = table
+		log.Printf("[Server]: loaded cached table %v \n", cachedFilename)
+	}
+
+	return cachedTables, nil
+}
+
+// build the PIR database of item vectors (normalized for the metric) and payloads
+func buildItemDatabase(serv *server.Server, args *ServerArgs, trainingData *ann.Matrix) *server.ItemDatabase {
+	if trainingData == nil {
+		var err error
+		trainingData, _, _, err = ann.ReadDatasetMatrix(args.Dataset, serv.DistanceMetric, args.Quantize)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	var payloads [][]byte
+	if args.PayloadFile != "" {
+		file, err := os.Open(args.PayloadFile)
+		if err != nil {
+			panic(err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 1024), 4*args.MaxPayloadBytes+1024)
+		for scanner.Scan() {
+			payload, err := base64.StdEncoding.DecodeString(scanner.Text())
+			if err != nil {
+				panic(fmt.Sprintf("invalid payload on line %v: %v", len(payloads)+1, err))
+			}
+			payloads = append(payloads, payload)
+		}
+		if err := scanner.Err(); err != nil {
+			panic(err)
+		}
+	}
+
+	itemDB, err := server.NewItemDatabase(trainingData, payloads, args.MaxPayloadBytes)
+	if err != nil {
+		panic(err)
+	}
+
+	log.Printf("[Server]: built item database with %v items of %v bytes\n", itemDB.NumItems, itemDB.RecordBytes)
+
+	return itemDB
+}
+
+func getCachedHashTableFilename(dataset string, numTables int, bucketSize int, basedir string, table int) string {
+	return basedir + "/" + dataset + "_cached_table_" + strconv.Itoa(numTables) + "x" + strconv.Itoa(bucketSize) + "-" + strconv.Itoa(table) + ".bin"
+}
+
+// filename of tables cached in the legacy JSON format
+func getJSONCachedHashTableFilename(dataset string, numTables int, bucketSize int, basedir string, table int) string {
+	return basedir + "/" + dataset + "_cached_table_" + strconv.Itoa(numTables) + "x" + strconv.Itoa(bucketSize) + "-" + strconv.Itoa(table) + ".json"
+}
+
+// serve the admin RPC on a unix socket that only the server's user can access
+func startAdmin(serv *server.Server, socket string, tokenFile string) {
+	if tokenFile == "" {
+		log.Fatal("[Server]: the admin socket requires an admin token (see --admintokenfile)")
+	}
+	token, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		log.Fatalf("[Server]: failed to read admin token: %v", err)
+	}
+	token = bytes.TrimSpace(token)
+	if len(token) == 0 {
+		log.Fatal("[Server]: empty admin token")
+	}
+
+	// remove a stale socket left by a previous run
+	os.Remove(socket)
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		log.Fatalf("[Server]: failed to listen on admin socket: %v", err)
+	}
+	if err := os.Chmod(socket, 0600); err != nil {
+		log.Fatalf("[Server]: failed to restrict admin socket: %v", err)
+	}
+
+	admin := rpc.NewServer()
+	admin.RegisterName("Admin", server.NewAdmin(serv, token))
+	go admin.Accept(listener)
+
+	log.Println("[Server]: admin socket listening on " + socket)
 }
 
-func getCachedHashTableFilename(dataset string, numTables int, basedir string, table int) string {
-	return basedir + "/" + dataset + "_cached_table_" + strconv.Itoa(numTables) + "-" + strconv.Itoa(table) + ".json"
+// serve the peer service and connect to the one of the other server
+func startPeer(serv *server.Server, args *ServerArgs) {
+	port := args.PeerPort
+	if port == 0 {
+		port = 9000 + args.ServerID
+	}
+
+	address := args.PeerAddr
+	if address == "" {
+		address = "localhost:" + strconv.Itoa(9000+1-args.ServerID)
+	}
+
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		log.Fatalf("[Server]: failed to listen for the peer: %v", err)
+	}
+	go serv.ServePeer(listener)
+
+	serv.Peer = server.NewRPCPeer(address, args.PeerTimeout)
+	serv.PeerTimeout = args.PeerTimeout
+
+	log.Printf("[Server]: waiting for the peer on port %v (peer at %v)\n", port, address)
 }
 
-// kill server when Killed flag set
+// kill server once it is shut down (see server.Shutdown)
 func killLoop(server *server.Server) {
-	for !server.Killed {
+	for !server.Killed() {
 		time.Sleep(100 * time.Millisecond)
 	}
 
 	server.Listener.Close()
 }
 
-func startServer(server *server.Server, port string) {
+func startServer(server *server.Server, port string, transport string, tlsConfig *tls.Config) {
 
-	gob.Register(&hash.MultiLatticeHash{})
-
-	rpc.HandleHTTP()
-	rpc.RegisterName("Server", server)
 	listener, err := net.Listen("tcp", ":"+port)
 	if err != nil {
 		log.Fatal("listen error:", err)
 	}
 
-	log.Println("[Server]: waiting for clients on port " + port)
+	log.Println("[Server]: waiting for " + transport + " clients on port " + port)
 
 	server.Listener = listener
+
+	if transport == api.TransportGRPC {
+		opts := []grpc.ServerOption{grpc.MaxRecvMsgSize(pb.MaxMessageSize), grpc.MaxSendMsgSize(pb.MaxMessageSize)}
+		if tlsConfig != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		}
+
+		grpcServer := grpc.NewServer(opts...)
+		pb.RegisterPrivateANNServer(grpcServer, pb.NewServer(server.GRPCHandler()))
+		grpcServer.Serve(listener)
+		return
+	}
+
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	rpc.HandleHTTP()
+	rpc.RegisterName("Server", server)
 	http.Serve(listener, nil)
 }
@@ -15,7 +15,9 @@ require (
 	github.com/gonum/stat v0.0.0-20181125101827-41a0da705a5b
 	github.com/montanaflynn/stats v0.6.6
 	github.com/ncw/gmp v1.0.4
-	github.com/sachaservan/paillier v0.0.0-20201119232153-30237183ba29 // indirect
+	github.com/sachaservan/paillier v0.0.0-20201119232153-30237183ba29
 	github.com/sachaservan/vec v0.0.0-20210525154010-4d83667d9588
 	gonum.org/v1/plot v0.9.0
+	google.golang.org/grpc v1.40.0
+	google.golang.org/protobuf v1.27.1
 )
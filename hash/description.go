@@ -0,0 +1,139 @@
+package hash
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// Kind is the family of a hash function
+type Kind int
+
+const (
+	MultiLattice Kind = iota + 1 // MultiLatticeHash (euclidean metric)
+	Hyperplane                   // HyperplaneHash (angular metric)
+)
+
+func (k Kind) String() string {
+	switch k {
+	case MultiLattice:
+		return "multi-lattice"
+	case Hyperplane:
+		return "hyperplane"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// Description describes a hash function compactly: the hash function is sampled
+// deterministically (see NewSeededRand) from the seed with the parameters, so the
+// description takes a few bytes while the hash function holds dense projection matrices
+type Description struct {
+	Kind      Kind
+	Seed      []byte
+	Dimension int     // input dimension
+	NumPlanes int     // number of hyperplanes (Hyperplane)
+	Copies    int     // number of lattices (MultiLattice)
+	Width     float64 // LSH radius (MultiLattice)
+	Max       float64 // max coordinate value (MultiLattice)
+
+	// digest of the parameters of the sampled hash function (see ParametersDigest);
+	// reconstructed hash functions are checked against it (optional)
+	Digest []byte
+}
+
+// MaxNumPlanes bounds the hyperplanes of a hash function (Hyperplane): each
+// hyperplane is a vector of the input dimension that is sampled with the hash function
+const MaxNumPlanes = 256
+
+// ParametersDigest returns the SHA-256 digest of encoded hash function parameters (see EncodeParameters)
+func ParametersDigest(parameters []byte) []byte {
+	digest := sha256.Sum256(parameters)
+	return digest[:]
+}
+
+// Sample samples the described hash function and returns it with its encoded parameters
+func (d *Description) Sample() (Hash, []byte, error) {
+	if d.Dimension < 1 {
+		return nil, nil, errors.New("hash function dimension should be positive")
+	}
+
+	rnd := NewSeededRand(d.Seed)
+	switch d.Kind {
+	case MultiLattice:
+		if d.Copies < 1 || d.Copies > d.Dimension || !(d.Width > 0) {
+			return nil, nil, errors.New("invalid multi-lattice hash function parameters")
+		}
+		h := NewMultiLatticeHash(rnd, d.Dimension, d.Copies, d.Width, d.Max)
+		parameters, err := h.EncodeParameters()
+		return h, parameters, err
+	case Hyperplane:
+		if d.NumPlanes < 1 || d.NumPlanes > MaxNumPlanes {
+			return nil, nil, errors.New("invalid hyperplane hash function parameters")
+		}
+		h := NewHyperplaneHash(rnd, d.Dimension, d.NumPlanes)
+		parameters, err := h.EncodeParameters()
+		return h, parameters, err
+	default:
+		return nil, nil, fmt.Errorf("unsupported hash function kind %v", d.Kind)
+	}
+}
+
+// New samples the described hash function and makes sure that it matches the digest
+// (the hash function may differ if the platform computes floating points differently)
+func (d *Description) New() (Hash, error) {
+	h, parameters, err := d.Sample()
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Digest != nil && !bytes.Equal(ParametersDigest(parameters), d.Digest) {
+		return nil, errors.New("sampled hash function does not match the digest of its parameters")
+	}
+
+	return h, nil
+}
+
+// Bundle describes the hash functions of the tables (one per table).
+// Bundles are content-addressed (see Digest) so that clients only fetch
+// and reconstruct the hash functions of a bundle once
+type Bundle struct {
+	Descriptions []*Description
+}
+
+// Digest returns the SHA-256 digest of the encoded bundle (see EncodeBundle)
+func (b *Bundle) Digest() ([]byte, error) {
+	data, err := EncodeBundle(b)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(data)
+	return digest[:], nil
+}
+
+// CheckDimension returns an error unless each hash function of the bundle hashes vectors
+// of the dimension. Sampling a hash function takes time and memory proportional to its
+// dimension: clients check the bundles of the servers (against the dimension of the
+// dataset) before they reconstruct the hash functions
+func (b *Bundle) CheckDimension(dimension int) error {
+	for i, d := range b.Descriptions {
+		if d.Dimension != dimension {
+			return fmt.Errorf("hash function %v has dimension %v (expected %v)", i, d.Dimension, dimension)
+		}
+	}
+	return nil
+}
+
+// HashFunctions reconstructs the hash functions of the bundle
+func (b *Bundle) HashFunctions() ([]Hash, error) {
+	hashes := make([]Hash, len(b.Descriptions))
+	for i, d := range b.Descriptions {
+		h, err := d.New()
+		if err != nil {
+			return nil, fmt.Errorf("hash function %v: %v", i, err)
+		}
+		hashes[i] = h
+	}
+	return hashes, nil
+}
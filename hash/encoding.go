@@ -0,0 +1,290 @@
+package hash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/vec"
+)
+
+// Binary encoding of the hash function parameters and descriptions (little endian).
+// The encoding is deterministic so two hash functions have the same
+// parameters iff their encodings are equal (used to validate cached tables).
+
+// EncodeParameters encodes the permutation, spans, lattice hashes (radius, rotation,
+// offsets and universal hash) and universal hash of the hash function
+func (m *MultiLatticeHash) EncodeParameters() ([]byte, error) {
+	e := &encoder{}
+	e.ints(m.Permutation)
+	e.uint64(uint64(len(m.Spans)))
+	for _, span := range m.Spans {
+		e.ints(span[:])
+	}
+	e.uint64(uint64(len(m.Hashes)))
+	for _, h := range m.Hashes {
+		e.latticeHash(h)
+	}
+	e.universalHash(m.UHash)
+	return e.buf.Bytes(), e.err
+}
+
+// DecodeMultiLatticeHash decodes parameters encoded with EncodeParameters
+func DecodeMultiLatticeHash(data []byte) (*MultiLatticeHash, error) {
+	m := &MultiLatticeHash{}
+	d := &decoder{r: bytes.NewReader(data)}
+	m.Permutation = d.ints()
+	m.Spans = make([][2]int, d.length())
+	for i := range m.Spans {
+		span := d.ints()
+		if len(span) != 2 {
+			d.fail()
+			break
+		}
+		m.Spans[i] = [2]int{span[0], span[1]}
+	}
+	m.Hashes = make([]*LatticeHash, d.length())
+	for i := range m.Hashes {
+		m.Hashes[i] = d.latticeHash()
+	}
+	m.UHash = d.universalHash()
+
+	if d.err == nil && d.r.Len() != 0 {
+		d.fail()
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return m, nil
+}
+
+// EncodeParameters encodes the hyperplanes and universal hash of the hash function
+func (h *HyperplaneHash) EncodeParameters() ([]byte, error) {
+	e := &encoder{}
+	e.uint64(uint64(len(h.Planes)))
+	for _, plane := range h.Planes {
+		e.floats(plane.Coords)
+	}
+	e.universalHash(h.UHash)
+	return e.buf.Bytes(), e.err
+}
+
+// DecodeHyperplaneHash decodes parameters encoded with EncodeParameters
+func DecodeHyperplaneHash(data []byte) (*HyperplaneHash, error) {
+	h := &HyperplaneHash{}
+	d := &decoder{r: bytes.NewReader(data)}
+	h.Planes = make([]*vec.Vec, d.length())
+	for i := range h.Planes {
+		h.Planes[i] = vec.NewVec(d.floats())
+	}
+	h.UHash = d.universalHash()
+
+	if d.err == nil && d.r.Len() != 0 {
+		d.fail()
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return h, nil
+}
+
+// EncodeBundle encodes the descriptions of the hash functions of the bundle
+// (a nil bundle has no hash functions)
+func EncodeBundle(b *Bundle) ([]byte, error) {
+	var descriptions []*Description
+	if b != nil {
+		descriptions = b.Descriptions
+	}
+
+	e := &encoder{}
+	e.uint64(uint64(len(descriptions)))
+	for _, d := range descriptions {
+		e.uint64(uint64(d.Kind))
+		e.bytes(d.Seed)
+		e.ints([]int{d.Dimension, d.NumPlanes, d.Copies})
+		e.write(d.Width)
+		e.write(d.Max)
+		e.bytes(d.Digest)
+	}
+	return e.buf.Bytes(), e.err
+}
+
+// DecodeBundle decodes a bundle encoded with EncodeBundle
+func DecodeBundle(data []byte) (*Bundle, error) {
+	b := &Bundle{}
+	d := &decoder{r: bytes.NewReader(data)}
+	b.Descriptions = make([]*Description, d.length())
+	for i := range b.Descriptions {
+		desc := &Description{Kind: Kind(d.uint64()), Seed: d.bytes()}
+		params := d.ints()
+		if len(params) != 3 {
+			d.fail()
+			break
+		}
+		desc.Dimension, desc.NumPlanes, desc.Copies = params[0], params[1], params[2]
+		d.read(&desc.Width)
+		d.read(&desc.Max)
+		desc.Digest = d.bytes()
+		b.Descriptions[i] = desc
+	}
+
+	if d.err == nil && d.r.Len() != 0 {
+		d.fail()
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return b, nil
+}
+
+type encoder struct {
+	buf bytes.Buffer
+	err error
+}
+
+func (e *encoder) write(data interface{}) {
+	if e.err == nil {
+		e.err = binary.Write(&e.buf, binary.LittleEndian, data)
+	}
+}
+
+func (e *encoder) uint64(v uint64) {
+	e.write(v)
+}
+
+func (e *encoder) ints(v []int) {
+	e.uint64(uint64(len(v)))
+	for _, x := range v {
+		e.write(int64(x))
+	}
+}
+
+// bytes encodes a (possibly nil) byte slice; nil and empty slices have the same encoding
+func (e *encoder) bytes(v []byte) {
+	e.uint64(uint64(len(v)))
+	e.write(v)
+}
+
+func (e *encoder) floats(v []float64) {
+	e.uint64(uint64(len(v)))
+	e.write(v)
+}
+
+func (e *encoder) latticeHash(l *LatticeHash) {
+	e.write(l.Width)
+	e.write(l.Scale)
+	e.hashCommon(l.H)
+}
+
+func (e *encoder) hashCommon(h *HashCommon) {
+	e.write(h.Orthogonal)
+	e.uint64(uint64(len(h.ProjectionLines)))
+	for _, line := range h.ProjectionLines {
+		e.floats(line.Coords)
+	}
+	e.floats(h.Offsets.Coords)
+	e.universalHash(h.UHash)
+}
+
+func (e *encoder) universalHash(u *UniversalHash) {
+	// coefficients and modulus are elements of F_p (p < 2^64)
+	e.uint64(u.Modulus.Uint64())
+	e.uint64(uint64(len(u.Coefficients)))
+	for _, c := range u.Coefficients {
+		e.uint64(c.Uint64())
+	}
+}
+
+type decoder struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = errors.New("invalid hash function encoding")
+	}
+}
+
+func (d *decoder) read(data interface{}) {
+	if d.err == nil && binary.Read(d.r, binary.LittleEndian, data) != nil {
+		d.fail()
+	}
+}
+
+func (d *decoder) uint64() uint64 {
+	var v uint64
+	d.read(&v)
+	return v
+}
+
+// length reads a length and makes sure the remaining data can hold that many (8 byte) elements
+func (d *decoder) length() int {
+	n := d.uint64()
+	if n > uint64(d.r.Len())/8 {
+		d.fail()
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) ints() []int {
+	v := make([]int, d.length())
+	for i := range v {
+		var x int64
+		d.read(&x)
+		v[i] = int(x)
+	}
+	return v
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.uint64()
+	if n > uint64(d.r.Len()) {
+		d.fail()
+		return nil
+	}
+	if n == 0 {
+		return nil
+	}
+	v := make([]byte, n)
+	d.read(v)
+	return v
+}
+
+func (d *decoder) floats() []float64 {
+	v := make([]float64, d.length())
+	d.read(v)
+	return v
+}
+
+func (d *decoder) latticeHash() *LatticeHash {
+	l := &LatticeHash{}
+	d.read(&l.Width)
+	d.read(&l.Scale)
+	l.H = d.hashCommon()
+	return l
+}
+
+func (d *decoder) hashCommon() *HashCommon {
+	h := &HashCommon{}
+	d.read(&h.Orthogonal)
+	h.ProjectionLines = make([]*vec.Vec, d.length())
+	for i := range h.ProjectionLines {
+		h.ProjectionLines[i] = vec.NewVec(d.floats())
+	}
+	h.Offsets = vec.NewVec(d.floats())
+	h.UHash = d.universalHash()
+	return h
+}
+
+func (d *decoder) universalHash() *UniversalHash {
+	u := &UniversalHash{}
+	u.Modulus = new(gmp.Int).SetUint64(d.uint64())
+	u.Coefficients = make([]*gmp.Int, d.length())
+	for i := range u.Coefficients {
+		u.Coefficients[i] = new(gmp.Int).SetUint64(d.uint64())
+	}
+	return u
+}
@@ -0,0 +1,185 @@
+package hash
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeParametersRoundTrip(t *testing.T) {
+	seed, err := NewRandomSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dim := 50
+	h := NewMultiLatticeHash(NewSeededRand(seed), dim, 2, 100, 1000)
+
+	encoded, err := h.EncodeParameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := DecodeMultiLatticeHash(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reencoded, err := decoded.EncodeParameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, reencoded) {
+		t.Fatalf("re-encoded parameters differ")
+	}
+
+	rnd := NewSeededRand(seed)
+	for i := 0; i < 100; i++ {
+		v := Normals(rnd, dim).Scale(100)
+		if h.Hash(v) != decoded.Hash(v) {
+			t.Fatalf("decoded hash function differs on %v", v)
+		}
+	}
+
+	// the encoding identifies the parameters
+	other := NewMultiLatticeHash(NewSeededRand(seed), dim, 2, 101, 1000)
+	otherEncoded, err := other.EncodeParameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(encoded, otherEncoded) {
+		t.Fatalf("hash functions with different radii have the same encoding")
+	}
+}
+
+func TestEncodeHyperplaneParametersRoundTrip(t *testing.T) {
+	seed, err := NewRandomSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dim := 50
+	h := NewHyperplaneHash(NewSeededRand(seed), dim, 16)
+
+	encoded, err := h.EncodeParameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := DecodeHyperplaneHash(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rnd := NewSeededRand(seed)
+	for i := 0; i < 100; i++ {
+		v := Normals(rnd, dim)
+		if h.Hash(v) != decoded.Hash(v) {
+			t.Fatalf("decoded hash function differs on %v", v)
+		}
+	}
+
+	if _, err := DecodeHyperplaneHash(encoded[:len(encoded)-1]); err == nil {
+		t.Fatalf("truncated encoding was accepted")
+	}
+}
+
+func TestDecodeInvalidParameters(t *testing.T) {
+	seed, err := NewRandomSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewMultiLatticeHash(NewSeededRand(seed), 50, 2, 100, 1000)
+	encoded, err := h.EncodeParameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, size := range []int{0, 7, len(encoded) / 2, len(encoded) - 1} {
+		if _, err := DecodeMultiLatticeHash(encoded[:size]); err == nil {
+			t.Fatalf("truncated encoding of %v bytes was accepted", size)
+		}
+	}
+
+	if _, err := DecodeMultiLatticeHash(append(encoded, 0)); err == nil {
+		t.Fatalf("encoding with trailing data was accepted")
+	}
+}
+
+func TestBundle(t *testing.T) {
+	seed, err := NewRandomSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dim := 50
+	bundle := &Bundle{Descriptions: []*Description{
+		{Kind: MultiLattice, Seed: DeriveSeed(seed, 0), Dimension: dim, Copies: 2, Width: 100, Max: 1000},
+		{Kind: Hyperplane, Seed: DeriveSeed(seed, 1), Dimension: dim, NumPlanes: 16},
+	}}
+
+	expected := make([]Hash, len(bundle.Descriptions))
+	for i, d := range bundle.Descriptions {
+		var parameters []byte
+		expected[i], parameters, err = d.Sample()
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.Digest = ParametersDigest(parameters)
+	}
+
+	encoded, err := EncodeBundle(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := DecodeBundle(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest, _ := bundle.Digest()
+	decodedDigest, _ := decoded.Digest()
+	if !bytes.Equal(digest, decodedDigest) {
+		t.Fatalf("decoded bundle has a different digest")
+	}
+
+	hashes, err := decoded.HashFunctions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rnd := NewSeededRand(seed)
+	for i := 0; i < 100; i++ {
+		v := Normals(rnd, dim).Scale(100)
+		for j := range hashes {
+			if hashes[j].Hash(v) != expected[j].Hash(v) {
+				t.Fatalf("reconstructed hash function %v differs on %v", j, v)
+			}
+		}
+	}
+
+	if err := decoded.CheckDimension(dim); err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.CheckDimension(dim + 1); err == nil {
+		t.Fatalf("hash functions of another dimension were accepted")
+	}
+
+	// the number of hyperplanes is bounded
+	planes := *decoded.Descriptions[1]
+	planes.NumPlanes = MaxNumPlanes + 1
+	if _, _, err := planes.Sample(); err == nil {
+		t.Fatalf("hash function with too many hyperplanes was accepted")
+	}
+
+	// the reconstructed hash functions must match their digests
+	decoded.Descriptions[0].Width++
+	if _, err := decoded.HashFunctions(); err == nil {
+		t.Fatalf("hash function with a different digest was accepted")
+	}
+
+	if _, err := DecodeBundle(encoded[:len(encoded)-1]); err == nil {
+		t.Fatalf("truncated bundle was accepted")
+	}
+}
@@ -9,6 +9,9 @@ import (
 // Hash is an abstract hash function
 type Hash interface {
 	Hash(*vec.Vec) uint64
+	// hash a (float32) row of a matrix without converting it to a vector;
+	// the hash is the same as the hash of the vector with the row's coordinates
+	HashRow([]float32) uint64
 	// return k hashes (for multiprobing)
 	MultiHash(*vec.Vec, int) []uint64
 }
@@ -1,6 +1,10 @@
 package hash
 
-import "github.com/sachaservan/vec"
+import (
+	"math/rand"
+
+	"github.com/sachaservan/vec"
+)
 
 type HashCommon struct {
 	ProjectionLines []*vec.Vec
@@ -13,25 +17,25 @@ type HashCommon struct {
 Rotation and translation and dimension scaling that are common to many LSH hash functions
 Dimensionality reduction is a random rotation followed by a projection (e.g taking the first k coordinates).
 */
-func NewHashCommon(dim, amplification int, max float64, Orthogonal bool) *HashCommon {
+func NewHashCommon(rnd *rand.Rand, dim, amplification int, max float64, Orthogonal bool) *HashCommon {
 	h := &HashCommon{Orthogonal: Orthogonal}
 	h.ProjectionLines = make([]*vec.Vec, amplification)
 
 	if Orthogonal {
 		// if amplification is > dim, then there is no possible Orthogonal configuration and this panics
 		// Perhaps the rotation matrix is max(dim, amplification)
-		copy(h.ProjectionLines[:], RandomRotationMatrix(dim)[:amplification])
+		copy(h.ProjectionLines[:], RandomRotationMatrix(rnd, dim)[:amplification])
 	} else {
 		for i := range h.ProjectionLines {
-			h.ProjectionLines[i] = RandomVector(dim)
+			h.ProjectionLines[i] = RandomVector(rnd, dim)
 		}
 	}
 	// Normalize vectors
 	for i := range h.ProjectionLines {
 		h.ProjectionLines[i] = h.ProjectionLines[i].Normalize()
 	}
-	h.Offsets = RandomTranslationVector(amplification, max)
-	h.UHash = NewUniversalHash(amplification)
+	h.Offsets = RandomTranslationVector(rnd, amplification, max)
+	h.UHash = NewUniversalHash(rnd, amplification)
 	return h
 }
 
@@ -45,6 +49,35 @@ func (h *HashCommon) Project(v *vec.Vec) *vec.Vec {
 	return projection
 }
 
+// Apply the rotation and translation to a row (see Hash.HashRow)
+// The coordinates of the row are the ones in index (if not nil)
+func (h *HashCommon) ProjectRow(row []float32, index []int) []float64 {
+	projection := make([]float64, len(h.ProjectionLines))
+	for i := range projection {
+		projection[i] = DotRow(row, index, h.ProjectionLines[i]) + h.Offsets.Coords[i]
+	}
+	return projection
+}
+
+// Dot product of a row (or of its coordinates in index if not nil) and a vector
+// The products are summed in the same order as vec.Vec.Dot so that the result is identical
+func DotRow(row []float32, index []int, v *vec.Vec) float64 {
+	if index == nil && len(row) != v.Size() || index != nil && len(index) != v.Size() {
+		panic("cannot take dot product of different sized vectors")
+	}
+	res := 0.0
+	for j, c := range v.Coords {
+		var x float32
+		if index != nil {
+			x = row[index[j]]
+		} else {
+			x = row[j]
+		}
+		res += float64(x) * c
+	}
+	return res
+}
+
 // helper to sort points because golang
 // A heap or priority queue would be more efficient
 type Candidates struct {
@@ -0,0 +1,37 @@
+package hash
+
+import (
+	"testing"
+
+	"github.com/sachaservan/vec"
+)
+
+func TestHashRow(t *testing.T) {
+	seed, err := NewRandomSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rnd := NewSeededRand(seed)
+
+	dim := 50
+	hashes := map[string]Hash{
+		"hyperplane":   NewHyperplaneHash(rnd, dim, 16),
+		"lattice":      NewLatticeHash(rnd, dim, 2, 1000),
+		"multilattice": NewMultiLatticeHash(rnd, dim, 2, 2, 1000),
+	}
+
+	for name, h := range hashes {
+		for i := 0; i < 100; i++ {
+			row := make([]float32, dim)
+			coords := make([]float64, dim)
+			for j := range row {
+				row[j] = float32(5 * rnd.NormFloat64())
+				coords[j] = float64(row[j])
+			}
+
+			if h.HashRow(row) != h.Hash(vec.NewVec(coords)) {
+				t.Fatalf("%v: hash of row %v differs from the hash of the vector", name, row)
+			}
+		}
+	}
+}
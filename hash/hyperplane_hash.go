@@ -0,0 +1,149 @@
+package hash
+
+import (
+	"container/heap"
+	"math/rand"
+	"sort"
+
+	"github.com/sachaservan/vec"
+)
+
+/*
+This implements an angular (cosine) LSH with random hyperplanes (Charikar's SimHash)
+Each bit of the hash is the side of a random hyperplane through the origin that the point lies on
+Two points at angle theta agree on each bit with probability 1 - theta/pi
+The hash only depends on the direction of the point and ignores its norm
+
+Multiprobing flips the bits of the hyperplanes closest to the point first
+(see "Multi-Probe LSH: Efficient Indexing for High-Dimensional Similarity Search", Lv et al.)
+*/
+
+type HyperplaneHash struct {
+	Planes []*vec.Vec // normal vectors of the hyperplanes
+	UHash  *UniversalHash
+}
+
+func NewHyperplaneHash(rnd *rand.Rand, dim, numPlanes int) *HyperplaneHash {
+	h := &HyperplaneHash{Planes: make([]*vec.Vec, numPlanes)}
+	for i := range h.Planes {
+		// gaussian normals are uniformly distributed over the directions
+		h.Planes[i] = Normals(rnd, dim).Normalize()
+	}
+	h.UHash = NewUniversalHash(rnd, numPlanes)
+	return h
+}
+
+// This computes the bits of the hash and the (signed) distance of the point to each hyperplane
+func (h *HyperplaneHash) HashWithMargins(v *vec.Vec) (*vec.Vec, []float64) {
+	bits := make([]float64, len(h.Planes))
+	margins := make([]float64, len(h.Planes))
+	for i := range h.Planes {
+		margins[i], _ = v.Dot(h.Planes[i])
+		if margins[i] >= 0 {
+			bits[i] = 1
+		}
+	}
+	return vec.NewVec(bits), margins
+}
+
+// This returns the (at most) probes most likely hashes and the squared distance to the
+// flipped hyperplanes (the hash of the point is first with distance 0)
+func (h *HyperplaneHash) MultiProbeHashWithDist(v *vec.Vec, probes int) ([]*vec.Vec, []float64) {
+	bits, margins := h.HashWithMargins(v)
+
+	// hyperplanes ordered by squared distance to the point
+	order := make([]int, len(margins))
+	sorted := &Candidates{Indexes: make([]uint64, len(margins)), Distances: make([]float64, len(margins))}
+	for i := range margins {
+		sorted.Indexes[i] = uint64(i)
+		sorted.Distances[i] = margins[i] * margins[i]
+	}
+	sort.Sort(sorted)
+	for i := range order {
+		order[i] = int(sorted.Indexes[i])
+	}
+
+	output := []*vec.Vec{bits}
+	distances := []float64{0}
+
+	// each set of flipped bits is generated exactly once by shifting or expanding
+	// the last (in sorted order) flipped bit of a previously generated set
+	q := &flipQueue{}
+	if len(order) > 0 {
+		heap.Push(q, &flipSet{flipped: []int{0}, distance: sorted.Distances[0]})
+	}
+	for len(output) < probes && q.Len() > 0 {
+		s := heap.Pop(q).(*flipSet)
+
+		probe := bits.Copy()
+		for _, j := range s.flipped {
+			probe.Coords[order[j]] = 1 - probe.Coords[order[j]]
+		}
+		output = append(output, probe)
+		distances = append(distances, s.distance)
+
+		last := s.flipped[len(s.flipped)-1]
+		if last+1 < len(order) {
+			shift := append(append([]int{}, s.flipped[:len(s.flipped)-1]...), last+1)
+			heap.Push(q, &flipSet{flipped: shift, distance: s.distance - sorted.Distances[last] + sorted.Distances[last+1]})
+
+			expand := append(append([]int{}, s.flipped...), last+1)
+			heap.Push(q, &flipSet{flipped: expand, distance: s.distance + sorted.Distances[last+1]})
+		}
+	}
+
+	return output, distances
+}
+
+func (h *HyperplaneHash) Hash(v *vec.Vec) uint64 {
+	bits, _ := h.HashWithMargins(v)
+	return h.UHash.Hash(bits)
+}
+
+// HashRow is Hash for a row of a matrix (see Hash)
+func (h *HyperplaneHash) HashRow(row []float32) uint64 {
+	bits := make([]float64, len(h.Planes))
+	for i := range h.Planes {
+		if DotRow(row, nil, h.Planes[i]) >= 0 {
+			bits[i] = 1
+		}
+	}
+	return h.UHash.HashCoords(bits)
+}
+
+// MultiHash always returns probes hashes; when there are fewer than probes
+// distinct hashes (2^len(Planes)) the last hash is repeated
+func (h *HyperplaneHash) MultiHash(v *vec.Vec, probes int) []uint64 {
+	vs, _ := h.MultiProbeHashWithDist(v, probes)
+
+	hashes := make([]uint64, probes)
+	for i := range hashes {
+		if i < len(vs) {
+			hashes[i] = h.UHash.Hash(vs[i])
+		} else {
+			hashes[i] = hashes[i-1]
+		}
+	}
+
+	return hashes
+}
+
+// flipSet is a set of flipped bits (positions in the sorted order of the hyperplanes)
+type flipSet struct {
+	flipped  []int
+	distance float64
+}
+
+// flipQueue is a min-heap of flipSets ordered by distance
+type flipQueue []*flipSet
+
+func (q flipQueue) Len() int            { return len(q) }
+func (q flipQueue) Less(i, j int) bool  { return q[i].distance < q[j].distance }
+func (q flipQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *flipQueue) Push(x interface{}) { *q = append(*q, x.(*flipSet)) }
+func (q *flipQueue) Pop() interface{} {
+	old := *q
+	s := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return s
+}
@@ -0,0 +1,81 @@
+package hash
+
+import (
+	"testing"
+)
+
+func TestHyperplaneHashScaleInvariant(t *testing.T) {
+	seed, err := NewRandomSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rnd := NewSeededRand(seed)
+
+	dim := 50
+	h := NewHyperplaneHash(rnd, dim, 16)
+	for i := 0; i < 100; i++ {
+		v := Normals(rnd, dim)
+		hash := h.Hash(v)
+		if hash != h.Hash(v.Copy().Scale(1000)) || hash != h.Hash(v.Copy().Normalize()) {
+			t.Fatalf("hash of %v depends on the norm", v)
+		}
+	}
+}
+
+func TestHyperplaneMultiProbe(t *testing.T) {
+	seed, err := NewRandomSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rnd := NewSeededRand(seed)
+
+	dim := 50
+	probes := 100
+	h := NewHyperplaneHash(rnd, dim, 16)
+	for i := 0; i < 20; i++ {
+		v := Normals(rnd, dim)
+		vs, distances := h.MultiProbeHashWithDist(v, probes)
+		if len(vs) != probes {
+			t.Fatalf("expected %v probes but got %v", probes, len(vs))
+		}
+
+		hashes := h.MultiHash(v, probes)
+		if hashes[0] != h.Hash(v) {
+			t.Fatalf("first probe is not the hash of the query")
+		}
+
+		seen := make(map[string]bool)
+		for j := range vs {
+			if j > 0 && distances[j-1] > distances[j] {
+				t.Fatalf("probes are not sorted by distance: %v", distances)
+			}
+			key := string(bitsToBytes(vs[j].Coords))
+			if seen[key] {
+				t.Fatalf("probe %v was generated twice", j)
+			}
+			seen[key] = true
+		}
+	}
+
+	// fewer hyperplanes than needed for the probes
+	small := NewHyperplaneHash(rnd, dim, 3)
+	hashes := small.MultiHash(Normals(rnd, dim), probes)
+	if len(hashes) != probes {
+		t.Fatalf("expected %v hashes but got %v", probes, len(hashes))
+	}
+	distinct := make(map[uint64]bool)
+	for _, hash := range hashes {
+		distinct[hash] = true
+	}
+	if len(distinct) != 8 {
+		t.Fatalf("expected all 8 hashes of 3 hyperplanes but got %v", len(distinct))
+	}
+}
+
+func bitsToBytes(bits []float64) []byte {
+	b := make([]byte, len(bits))
+	for i := range bits {
+		b[i] = byte(bits[i])
+	}
+	return b
+}
@@ -2,6 +2,7 @@ package hash
 
 import (
 	"math"
+	"math/rand"
 	"sort"
 	"sync"
 
@@ -20,6 +21,7 @@ var once = sync.Once{}
 
 type LatticeHash struct {
 	H     *HashCommon
+	Width float64 // LSH radius
 	Scale float64
 }
 
@@ -28,7 +30,7 @@ This constructs a new leech lattice hash where the lattice points are the center
 A random rotation and translation are applied and the space is scaled for the desired "R" - LSH hash width
 The JL-transform step is performed in the same matrix as rotation
 */
-func NewLatticeHash(dim int, width, max float64) *LatticeHash {
+func NewLatticeHash(rnd *rand.Rand, dim int, width, max float64) *LatticeHash {
 	// alternatively this could be read from a file
 	once.Do(Precompute)
 
@@ -40,7 +42,7 @@ func NewLatticeHash(dim int, width, max float64) *LatticeHash {
 	jlScale := math.Sqrt(float64(dim) / 24.0)
 
 	// width scales the space down to fit within the lattice
-	H := &LatticeHash{H: NewHashCommon(dim, 24, max, true), Scale: baseScale * jlScale / width}
+	H := &LatticeHash{H: NewHashCommon(rnd, dim, 24, max, true), Width: width, Scale: baseScale * jlScale / width}
 	return H
 }
 
@@ -49,18 +51,38 @@ func (l *LatticeHash) HashWithDist(v *vec.Vec) (*vec.Vec, float64) {
 	// apply rotation and translation
 	v = l.H.Project(v)
 
+	p, dist := l.closestPoint(v.Coords)
+	return vec.NewVec(p), dist
+}
+
+// This finds the (offset) lattice point closest to the projection of a point and its squared distance
+func (l *LatticeHash) closestPoint(projection []float64) ([]float64, float64) {
 	// apply scaling
-	v = v.Scale(l.Scale)
+	for i := range projection {
+		projection[i] *= l.Scale
+	}
 
 	// this always returns an integer coordinate of the leech lattice
-	v, dist := LeechLatticeClosestVector(v)
+	p, dist := LeechLatticeClosestPoint(projection)
+	for i := range p {
+		// ensure no floating point shenanigans
+		p[i] = math.Round(p[i])
+	}
 
 	// lattice points are always the same set of keys
 	// To distinguish between hashes add a random value to the vector
 	// We could also unrotate to return the true closest point
-	v, _ = v.Add(l.H.Offsets)
+	for i := range p {
+		p[i] += l.H.Offsets.Coords[i]
+	}
 
-	return v, dist
+	return p, dist
+}
+
+// This computes the hash of a row (or of its coordinates in index if not nil)
+func (l *LatticeHash) hashRow(row []float32, index []int) []float64 {
+	p, _ := l.closestPoint(l.H.ProjectRow(row, index))
+	return p
 }
 
 // This returns the k closest hashes and squared distances
@@ -84,6 +106,11 @@ func (l *LatticeHash) Hash(v *vec.Vec) uint64 {
 	return l.H.UHash.Hash(H)
 }
 
+// HashRow is Hash for a row of a matrix (see Hash)
+func (l *LatticeHash) HashRow(row []float32) uint64 {
+	return l.H.UHash.HashCoords(l.hashRow(row, nil))
+}
+
 func (l *LatticeHash) MultiHash(v *vec.Vec, probes int) []uint64 {
 	H, _ := l.MultiProbeHashWithDist(v, probes)
 	hashes := make([]uint64, probes)
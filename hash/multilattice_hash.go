@@ -29,15 +29,15 @@ type MultiLatticeHash struct {
 	UHash       *UniversalHash
 }
 
-func NewMultiLatticeHash(dim, copies int, width, max float64) *MultiLatticeHash {
+func NewMultiLatticeHash(rnd *rand.Rand, dim, copies int, width, max float64) *MultiLatticeHash {
 	m := &MultiLatticeHash{}
-	m.Permutation = rand.Perm(dim)
+	m.Permutation = rnd.Perm(dim)
 	m.Hashes = make([]*LatticeHash, copies)
 	m.Spans = Spans(dim, copies)
 	for i := 0; i < copies; i++ {
-		m.Hashes[i] = NewLatticeHash(m.Spans[i][1]-m.Spans[i][0], width, max)
+		m.Hashes[i] = NewLatticeHash(rnd, m.Spans[i][1]-m.Spans[i][0], width, max)
 	}
-	m.UHash = NewUniversalHash(copies * 24)
+	m.UHash = NewUniversalHash(rnd, copies*24)
 	return m
 }
 
@@ -79,6 +79,19 @@ func (m *MultiLatticeHash) HashWithDist(v *vec.Vec) (*vec.Vec, float64) {
 	return vec.NewVec(totalHash), totalDist
 }
 
+// HashRow is Hash for a row of a matrix (see Hash)
+// The sublattices hash the permuted coordinates of the row in place
+func (m *MultiLatticeHash) HashRow(row []float32) uint64 {
+	if len(row) != len(m.Permutation) {
+		panic("row size mismatch")
+	}
+	totalHash := make([]float64, 0, len(m.Hashes)*24)
+	for i := range m.Hashes {
+		totalHash = append(totalHash, m.Hashes[i].hashRow(row, m.Permutation[m.Spans[i][0]:m.Spans[i][1]])...)
+	}
+	return m.UHash.HashCoords(totalHash)
+}
+
 // We have to iterate through each of the closest points of the sublattices to find the closest point
 func (m *MultiLatticeHash) MultiProbeHashWithDist(v *vec.Vec, probes int) ([]*vec.Vec, []float64) {
 	permuted := make([]float64, v.Size())
@@ -1,27 +1,93 @@
 package hash
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
 	"math"
-	"math/rand"
+	mrand "math/rand"
 
 	"github.com/sachaservan/vec"
 )
 
+// SeedSize is the size (in bytes) of seeds generated by NewRandomSeed
+const SeedSize = 16
+
+// streamSource is a rand.Source64 backed by an AES-CTR keystream
+// so that the randomness is both reproducible (given the seed)
+// and unpredictable (without the seed)
+type streamSource struct {
+	stream cipher.Stream
+	buf    [8]byte
+}
+
+// NewRandomSeed samples a fresh seed using crypto/rand
+func NewRandomSeed() ([]byte, error) {
+	seed := make([]byte, SeedSize)
+	_, err := rand.Read(seed)
+	if err != nil {
+		return nil, err
+	}
+	return seed, nil
+}
+
+// NewSeededRand returns a source of randomness derived from the (secret) seed.
+// Servers that agree on the seed generate identical hash functions.
+func NewSeededRand(seed []byte) *mrand.Rand {
+	key := sha256.Sum256(seed)
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		panic(err)
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	return mrand.New(&streamSource{stream: cipher.NewCTR(block, iv)})
+}
+
+// DeriveSeed derives the seed of the i-th hash function from the (secret) seed.
+// Derived seeds are sent to the clients (see Description) and do not reveal the seed
+func DeriveSeed(seed []byte, i int) []byte {
+	mac := hmac.New(sha256.New, seed)
+	mac.Write([]byte("hash function"))
+	binary.Write(mac, binary.LittleEndian, uint64(i))
+	return mac.Sum(nil)[:SeedSize]
+}
+
+func (s *streamSource) Uint64() uint64 {
+	for i := range s.buf {
+		s.buf[i] = 0
+	}
+	s.stream.XORKeyStream(s.buf[:], s.buf[:])
+	return binary.LittleEndian.Uint64(s.buf[:])
+}
+
+func (s *streamSource) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+// Seed is not supported; the source is keyed when created
+func (s *streamSource) Seed(seed int64) {
+	panic("stream source cannot be reseeded")
+}
+
 // Return a random rotation matrix chosen uniformly
-func RandomRotationMatrix(dim int) []*vec.Vec {
-	return GetRandomRotation(dim)
+func RandomRotationMatrix(rnd *mrand.Rand, dim int) []*vec.Vec {
+	return GetRandomRotation(rnd, dim)
 }
 
 // Return a random directional vector
-func RandomVector(dim int) *vec.Vec {
-	return Normals(dim)
+func RandomVector(rnd *mrand.Rand, dim int) *vec.Vec {
+	return Normals(rnd, dim)
 }
 
 // Return a random vector chosen uniformly from the cube
-func RandomTranslationVector(dim int, max float64) *vec.Vec {
+func RandomTranslationVector(rnd *mrand.Rand, dim int, max float64) *vec.Vec {
 	v := vec.NewVec(make([]float64, dim))
 	for i := range v.Coords {
-		v.SetValueToCoord((rand.Float64()-0.5)*2*max, i)
+		v.SetValueToCoord((rnd.Float64()-0.5)*2*max, i)
 	}
 	return v
 }
@@ -38,10 +104,10 @@ func Identity(dim int) []*vec.Vec {
 }
 
 // Gaussian matrix
-func Normals(size int) *vec.Vec {
+func Normals(rnd *mrand.Rand, size int) *vec.Vec {
 	d := make([]float64, size)
 	for i := range d {
-		d[i] = rand.NormFloat64()
+		d[i] = rnd.NormFloat64()
 	}
 	return vec.NewVec(d)
 }
@@ -136,12 +202,12 @@ func op(v []int, m []*vec.Vec) []*vec.Vec {
 	return m
 }
 
-func GetRandomRotation(dim int) []*vec.Vec {
+func GetRandomRotation(rnd *mrand.Rand, dim int) []*vec.Vec {
 	// scipy/stats/_multivariate.py:3418-3432
 	H := Identity(dim)
 	D := make([]int, dim)
 	for n := 0; n < dim-1; n++ {
-		x := Normals(dim - n)
+		x := Normals(rnd, dim-n)
 		norm2, _ := x.Dot(x)
 		x0 := x.Coord(0)
 		D[n] = Sign(x0)
@@ -0,0 +1,48 @@
+package hash
+
+import (
+	"testing"
+)
+
+func TestSeededRandDeterministic(t *testing.T) {
+	seed, err := NewRandomSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dim := 50
+	h1 := NewMultiLatticeHash(NewSeededRand(seed), dim, 2, 100, 1000)
+	h2 := NewMultiLatticeHash(NewSeededRand(seed), dim, 2, 100, 1000)
+
+	otherSeed, err := NewRandomSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h3 := NewMultiLatticeHash(NewSeededRand(otherSeed), dim, 2, 100, 1000)
+
+	v := Normals(NewSeededRand(otherSeed), dim).Scale(100)
+	if h1.Hash(v) != h2.Hash(v) {
+		t.Fatalf("hash functions generated from the same seed differ")
+	}
+
+	if h1.Hash(v) == h3.Hash(v) {
+		t.Fatalf("hash functions generated from different seeds match")
+	}
+}
+
+func TestSeededRandStream(t *testing.T) {
+	a := NewSeededRand([]byte("seed"))
+	b := NewSeededRand([]byte("seed"))
+	for i := 0; i < 100; i++ {
+		if a.Uint64() != b.Uint64() {
+			t.Fatalf("streams from the same seed differ at %v", i)
+		}
+	}
+
+	v := RandomTranslationVector(NewSeededRand([]byte("seed")), 10, 5)
+	for _, c := range v.Coords {
+		if c < -5 || c > 5 {
+			t.Fatalf("translation %v outside of [-5, 5]", c)
+		}
+	}
+}
@@ -19,18 +19,18 @@ type UniversalHash struct {
 // largest 64 bit prime
 const Prime = 18446744073709551557
 
-func NewUniversalHash(dim int) *UniversalHash {
+func NewUniversalHash(rnd *rand.Rand, dim int) *UniversalHash {
 	u := &UniversalHash{Coefficients: make([]*gmp.Int, dim+1)}
 	u.Modulus = new(gmp.Int).SetUint64(Prime)
 	for i := range u.Coefficients {
 		rBytes := make([]byte, 8)
-		rand.Read(rBytes)
+		rnd.Read(rBytes)
 		u.Coefficients[i] = new(gmp.Int).SetBytes(rBytes)
 
 		// keep sampling until we get a suitable element from the field
 		// to avoid biased universal hashing
 		for u.Coefficients[i].Cmp(u.Modulus) >= 0 {
-			rand.Read(rBytes)
+			rnd.Read(rBytes)
 			u.Coefficients[i] = new(gmp.Int).SetBytes(rBytes)
 		}
 	}
@@ -38,12 +38,16 @@ func NewUniversalHash(dim int) *UniversalHash {
 }
 
 func (u *UniversalHash) Hash(v *vec.Vec) uint64 {
-	if v.Size()+1 != len(u.Coefficients) {
+	return u.HashCoords(v.Coords)
+}
+
+func (u *UniversalHash) HashCoords(coords []float64) uint64 {
+	if len(coords)+1 != len(u.Coefficients) {
 		panic("Universal hash size mismatch")
 	}
 	t := new(gmp.Int)
 	s := new(gmp.Int).Set(u.Coefficients[0])
-	for i, f := range v.Coords {
+	for i, f := range coords {
 		t.SetUint64(math.Float64bits(f))
 		s = s.AddMul(u.Coefficients[i+1], t)
 	}
@@ -2,6 +2,7 @@ package pir
 
 import (
 	"errors"
+	"sort"
 
 	"github.com/sachaservan/private-ann/pir/dpfc"
 	"github.com/sachaservan/private-ann/pir/field"
@@ -10,24 +11,31 @@ import (
 // DBMetadata contains information on the layout
 // and size information for a slot database type
 type DBMetadata struct {
-	DBSize int
+	DBSize   int // number of records
+	SlotSize int // number of field elements in each record
 }
 
-// Database is a set of slots arranged in a grid of size width x height
-// where each slot has size slotBytes
+// Database is a set of DBSize records where each record
+// is a vector of SlotSize field elements
 type Database struct {
 	DBMetadata
-	Data     []field.FP
-	Keywords []uint64 // set of keywords (optional)
+	Data     []field.FP // records stored contiguously: record i is Data[i*SlotSize:(i+1)*SlotSize]
+	Keywords []uint64   // set of keywords (optional)
 
 	BatchSize   int   // (for batch queries) number of batches (aka regions)
 	BatchStarts []int // (for batch queries) start index of each key region
 	BatchStops  []int // (for batch queries) end index of each key region
 }
 
-// SecretSharedQueryResult contains shares of the resulting slots
+// SecretSharedQueryResult contains shares of the resulting record
 type SecretSharedQueryResult struct {
-	Share field.FP
+	Shares []field.FP // one share per field element of the record
+	Proof  []byte     // (for verifiable queries) VDPF proof of well-formedness
+	Sketch Sketch     // (for queries evaluated with a sketch key) sketch of the DPF outputs
+
+	// share of 1 if the selected record is non-empty (its first field element is non-zero)
+	// and of 0 otherwise; used by the servers to mask the results (see server.obliviousMasking)
+	Found field.FP
 }
 
 // NewDatabase returns an empty database
@@ -60,15 +68,79 @@ func (db *Database) SetBatchingParameters(batchSize int, batchStarts []int, batc
 	return nil
 }
 
-// PrivateSecretSharedQuery uses the provided PIR query to retreive a slot row
-func (db *Database) PrivateSecretSharedQuery(query *QueryShare) (*SecretSharedQueryResult, error) {
+// PrivateSecretSharedQuery uses the provided PIR query to retreive a slot row.
+// The result includes the sketch of the DPF outputs when key is not nil (see SketchKey)
+func (db *Database) PrivateSecretSharedQuery(query *QueryShare, key SketchKey) (*SecretSharedQueryResult, error) {
+
+	if err := query.CheckWellFormed(); err != nil {
+		return nil, err
+	}
+
+	var bits []field.FP
+	var proof []byte
+	if query.IsVerifiable {
+		bits, proof = db.ExpandVerifiableSharedQuery(query, 0, db.DBSize)
+	} else {
+		bits = db.ExpandSharedQuery(query, 0, db.DBSize)
+	}
+
+	res, err := db.PrivateSecretSharedQueryWithExpandedBits(query, bits, 0, db.DBSize)
+	if err != nil {
+		return nil, err
+	}
+	res.Proof = proof
+	if key != nil {
+		res.Sketch = db.sketch(query, key, bits, 0, db.DBSize)
+	}
+
+	return res, nil
+}
+
+// PrivateSecretSharedBatchQuery uses the provided PIR query to retreive a slot row.
+// The results include the sketches of the DPF outputs when key is not nil (see SketchKey)
+func (db *Database) PrivateSecretSharedBatchQuery(batchQuery *BatchQueryShare, key SketchKey) ([]*SecretSharedQueryResult, error) {
+
+	bits, proofs, err := db.ExpandSharedBatchQuery(batchQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := db.PrivateSecretSharedBatchQueryWithExpandedBits(batchQuery, bits, proofs)
+	if err != nil {
+		return nil, err
+	}
+
+	if key != nil {
+		for b, res := range results {
+			res.Sketch = db.sketch(batchQuery.Queries[b], key, bits[b], db.BatchStarts[b], db.BatchStops[b])
+		}
+	}
+
+	return results, nil
+}
+
+// ExpandSharedBatchQuery checks that the batch query is well-formed and expands
+// the DPF of each query over the keywords of its batch.
+// Returns the expanded bits and (for verifiable queries) the proof of each batch.
+// The expanded bits can be applied to any database with the same keywords
+// and batching parameters (see PrivateSecretSharedBatchQueryWithExpandedBits)
+func (db *Database) ExpandSharedBatchQuery(batchQuery *BatchQueryShare) ([][]field.FP, [][]byte, error) {
+
+	if err := db.checkBatchQuery(batchQuery); err != nil {
+		return nil, nil, err
+	}
+
+	bits := make([][]field.FP, db.BatchSize)
+	proofs := make([][]byte, db.BatchSize)
+	for b := 0; b < len(batchQuery.Queries); b++ {
+		bits[b], proofs[b] = db.expandBatch(batchQuery.Queries[b], b)
+	}
 
-	bits := db.ExpandSharedQuery(query, 0, db.DBSize)
-	return db.PrivateSecretSharedQueryWithExpandedBits(query, bits, 0, db.DBSize)
+	return bits, proofs, nil
 }
 
-// PrivateSecretSharedBatchQuery uses the provided PIR query to retreive a slot row
-func (db *Database) PrivateSecretSharedBatchQuery(batchQuery *BatchQueryShare) ([]*SecretSharedQueryResult, error) {
+// checkBatchQuery checks that the batch query has one well-formed query per batch
+func (db *Database) checkBatchQuery(batchQuery *BatchQueryShare) error {
 
 	if db == nil {
 		panic("database is null")
@@ -82,36 +154,151 @@ func (db *Database) PrivateSecretSharedBatchQuery(batchQuery *BatchQueryShare) (
 		panic("invalid batching parameters")
 	}
 
+	if batchQuery == nil || len(batchQuery.Queries) != db.BatchSize {
+		return errors.New("number of queries does not match number of batches")
+	}
+
+	// reject malformed keys before doing any work
+	for _, query := range batchQuery.Queries {
+		if err := query.CheckWellFormed(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// expandBatch expands the DPF of the query over the keywords of batch b
+// and returns the bits and (for verifiable queries) the proof
+func (db *Database) expandBatch(query *QueryShare, b int) ([]field.FP, []byte) {
+	start := db.BatchStarts[b]
+	stop := db.BatchStops[b]
+
+	if query.IsVerifiable {
+		return db.ExpandVerifiableSharedQuery(query, start, stop)
+	} else if start < stop {
+		return db.ExpandSharedQuery(query, start, stop), nil
+	}
+
+	// empty batch
+	return make([]field.FP, 0), nil
+}
+
+// PrivateSecretSharedBatchQueryWithExpandedBits returns the batch result
+// without expanding the query DPFs (see ExpandSharedBatchQuery)
+func (db *Database) PrivateSecretSharedBatchQueryWithExpandedBits(batchQuery *BatchQueryShare, bits [][]field.FP, proofs [][]byte) ([]*SecretSharedQueryResult, error) {
+
+	if len(bits) != db.BatchSize || len(proofs) != db.BatchSize {
+		return nil, errors.New("number of expanded queries does not match number of batches")
+	}
+
 	var err error
 	results := make([]*SecretSharedQueryResult, db.BatchSize)
-	for b := 0; b < len(batchQuery.Queries); b++ {
+	for b := 0; b < db.BatchSize; b++ {
 		start := db.BatchStarts[b]
 		stop := db.BatchStops[b]
 
-		bits := db.ExpandSharedQuery(batchQuery.Queries[b], start, stop)
-		results[b], err = db.PrivateSecretSharedQueryWithExpandedBits(batchQuery.Queries[b], bits, start, stop)
+		results[b], err = db.PrivateSecretSharedQueryWithExpandedBits(batchQuery.Queries[b], bits[b], start, stop)
 		if err != nil {
 			return nil, err
 		}
+		results[b].Proof = proofs[b]
 	}
 
 	return results, nil
 }
 
+// PrivateSecretSharedMultiBatchQuery answers several batch queries (e.g., from different clients)
+// together: the records of each batch are scanned once for all the queries.
+// The DPF of each query is expanded one batch at a time (and the bits discarded once the batch
+// is scanned) so that the memory used is bounded by the number of queries times the size of
+// the largest batch rather than times the size of the database.
+// The results of batch query q include the sketches of its DPF outputs when keys[q] is not nil (keys may be nil).
+// Returns the results of each batch query and its error (a malformed batch query only fails itself)
+func (db *Database) PrivateSecretSharedMultiBatchQuery(batchQueries []*BatchQueryShare, keys []SketchKey) ([][]*SecretSharedQueryResult, []error) {
+
+	errs := make([]error, len(batchQueries))
+	results := make([][]*SecretSharedQueryResult, len(batchQueries))
+	for q := range results {
+		errs[q] = db.checkBatchQuery(batchQueries[q])
+		if errs[q] == nil {
+			results[q] = make([]*SecretSharedQueryResult, db.BatchSize)
+		}
+	}
+
+	// expanded bits of each query over the current batch (nil for failed queries)
+	bits := make([][]field.FP, len(batchQueries))
+	proofs := make([][]byte, len(batchQueries))
+	for b := 0; b < db.BatchSize; b++ {
+		start := db.BatchStarts[b]
+		stop := db.BatchStops[b]
+
+		// accumulators of the queries (nil for failed queries)
+		acc := make([][]field.FP, len(batchQueries))
+		found := make([]field.FP, len(batchQueries))
+		for q := range acc {
+			if errs[q] == nil {
+				acc[q] = make([]field.FP, db.SlotSize)
+				bits[q], proofs[q] = db.expandBatch(batchQueries[q].Queries[b], b)
+			}
+		}
+
+		i := 0
+		for row := start; row < stop; row++ {
+			record := db.Record(row)
+			for q := range acc {
+				if acc[q] == nil {
+					continue
+				}
+				bit := bits[q][i]
+				for e := range record {
+					acc[q][e] = field.Add(acc[q][e], field.Multiply(record[e], bit))
+				}
+				if record[0] != 0 {
+					found[q] = field.Add(found[q], bit)
+				}
+			}
+			i++
+		}
+
+		for q := range acc {
+			if acc[q] != nil {
+				results[q][b] = &SecretSharedQueryResult{Shares: acc[q], Proof: proofs[q], Found: found[q]}
+				if keys != nil && keys[q] != nil {
+					results[q][b].Sketch = db.sketch(batchQueries[q].Queries[b], keys[q], bits[q], start, stop)
+				}
+			}
+		}
+	}
+
+	return results, errs
+}
+
 // PrivateSecretSharedQueryWithExpandedBits returns the result without expanding the query DPF
 // start: index of start key
 // stop: index of end key
 func (db *Database) PrivateSecretSharedQueryWithExpandedBits(query *QueryShare, bits []field.FP, start, stop int) (*SecretSharedQueryResult, error) {
 
-	result := field.FP(0)
+	if len(bits) != stop-start {
+		return nil, errors.New("number of expanded bits does not match the keyword range")
+	}
+
+	result := make([]field.FP, db.SlotSize)
+	found := field.FP(0)
 
 	i := 0
 	for row := start; row < stop; row++ {
-		result = field.Add(result, field.Multiply(db.Data[row], bits[i]))
+		record := db.Record(row)
+		for e := range result {
+			result[e] = field.Add(result[e], field.Multiply(record[e], bits[i]))
+		}
+		if record[0] != 0 {
+			found = field.Add(found, bits[i])
+		}
 		i++
 	}
 
-	return &SecretSharedQueryResult{result}, nil
+	return &SecretSharedQueryResult{Shares: result, Found: found}, nil
 }
 
 // ExpandSharedQuery returns the expands the DPF and returns an array of bits
@@ -129,19 +316,7 @@ func (db *Database) ExpandSharedQuery(query *QueryShare, start, stop int) []fiel
 	bits := make([]field.FP, stop-start)
 
 	// expand the DPF into the bits array
-	// key (index or uint) depending on whether
-	// the query is keyword based or index based
-	// when keyword based use FSS
-	var indices []uint64
-	if query.IsKeywordBased {
-		indices = db.Keywords[start:stop]
-	} else {
-		indices = make([]uint64, stop-start)
-		for i := 0; i < stop-start; i++ {
-			indices[i] = uint64(i)
-		}
-	}
-
+	indices := db.queryIndices(query, start, stop)
 	bitsRaw := pf.BatchEval(query.DPFKey, indices)
 	for i := 0; i < stop-start; i++ {
 		bits[i] = field.FP(bitsRaw[i])
@@ -152,16 +327,136 @@ func (db *Database) ExpandSharedQuery(query *QueryShare, start, stop int) []fiel
 	return bits
 }
 
+// ExpandVerifiableSharedQuery expands the VDPF and returns an array of bits
+// along with the proof that the key is well-formed on the evaluated range
+// start: index of start key
+// stop: index of end key
+func (db *Database) ExpandVerifiableSharedQuery(query *QueryShare, start, stop int) ([]field.FP, []byte) {
+
+	if start > stop {
+		panic("can't evaluate on invalid keyword range")
+	}
+
+	// init server VDPF
+	pf := dpfc.ServerVDPFInitialize(query.PrfKey, query.H1Key, query.H2Key)
+
+	bits := make([]field.FP, stop-start)
+
+	indices := db.queryIndices(query, start, stop)
+	bitsRaw, proof := pf.BatchVerEval(query.DPFKey, indices)
+	for i := 0; i < stop-start; i++ {
+		bits[i] = field.FP(bitsRaw[i])
+	}
+
+	pf.Free()
+
+	return bits, proof
+}
+
+// queryIndices returns the DPF evaluation points (index or uint) depending
+// on whether the query is keyword based or index based
+func (db *Database) queryIndices(query *QueryShare, start, stop int) []uint64 {
+	if query.IsKeywordBased {
+		return db.Keywords[start:stop]
+	}
+
+	indices := make([]uint64, stop-start)
+	for i := 0; i < stop-start; i++ {
+		indices[i] = uint64(i)
+	}
+	return indices
+}
+
 func (db *Database) BuildForKeysAndValues(keys []uint64, data []field.FP) error {
 	db.BuildForData(data)
 	err := db.SetKeywords(keys)
 	return err
 }
 
-// BuildForData constrcuts a PIR database
+// BuildForKeysAndRecords constructs a keyword PIR database where each keyword
+// is associated with a record of field elements
+func (db *Database) BuildForKeysAndRecords(keys []uint64, records [][]field.FP) error {
+	err := db.BuildForRecords(records)
+	if err != nil {
+		return err
+	}
+	return db.SetKeywords(keys)
+}
+
+// BuildForData constrcuts a PIR database where each record is a single field element
 func (db *Database) BuildForData(data []field.FP) {
 	db.Data = data
 	db.DBSize = len(data)
+	db.SlotSize = 1
+}
+
+// BuildForRecords constructs a PIR database where each record
+// is a vector of field elements (all records must have the same size)
+func (db *Database) BuildForRecords(records [][]field.FP) error {
+	if len(records) == 0 {
+		return errors.New("no records provided")
+	}
+
+	slotSize := len(records[0])
+	if slotSize == 0 {
+		return errors.New("records should contain at least one field element")
+	}
+
+	data := make([]field.FP, 0, len(records)*slotSize)
+	for _, record := range records {
+		if len(record) != slotSize {
+			return errors.New("all records should have the same size")
+		}
+		data = append(data, record...)
+	}
+
+	db.Data = data
+	db.DBSize = len(records)
+	db.SlotSize = slotSize
+
+	return nil
+}
+
+// BuildForBytes constructs a PIR database where each record holds up to recordBytes raw bytes
+// (packed into field elements, see BytesToElements); shorter records are zero padded
+func (db *Database) BuildForBytes(records [][]byte, recordBytes int) error {
+	if recordBytes <= 0 {
+		return errors.New("record size should be positive")
+	}
+
+	elements := make([][]field.FP, len(records))
+	for i, record := range records {
+		if len(record) > recordBytes {
+			return errors.New("record exceeds the record size")
+		}
+
+		padded := make([]byte, recordBytes)
+		copy(padded, record)
+		elements[i] = BytesToElements(padded)
+	}
+
+	return db.BuildForRecords(elements)
+}
+
+// Record returns the ith record of the database
+func (db *Database) Record(i int) []field.FP {
+	return db.Data[i*db.SlotSize : (i+1)*db.SlotSize]
+}
+
+// Copy returns a deep copy of the database
+// (the copy can be modified while the database is queried)
+func (db *Database) Copy() *Database {
+	cpy := &Database{
+		DBMetadata:  db.DBMetadata,
+		Data:        append([]field.FP{}, db.Data...),
+		BatchSize:   db.BatchSize,
+		BatchStarts: append([]int{}, db.BatchStarts...),
+		BatchStops:  append([]int{}, db.BatchStops...),
+	}
+	if db.Keywords != nil {
+		cpy.Keywords = append([]uint64{}, db.Keywords...)
+	}
+	return cpy
 }
 
 // SetKeywords set the keywords (uint64) associated with each row of the database
@@ -174,3 +469,85 @@ func (db *Database) SetKeywords(keywords []uint64) error {
 
 	return nil
 }
+
+// KeywordIndex returns the index of the record of the keyword
+// and false if the keyword is not in the database
+func (db *Database) KeywordIndex(keyword uint64) (int, bool) {
+	i := sort.Search(len(db.Keywords), func(j int) bool { return db.Keywords[j] >= keyword })
+	return i, i < len(db.Keywords) && db.Keywords[i] == keyword
+}
+
+// InsertKeyword inserts the record of a new keyword into the batch (ignored without batching).
+// The keywords stay sorted and the batches consistent; since the batches are consecutive
+// ranges of the (sorted) keywords, the keyword must be ordered with the keywords of its batch
+func (db *Database) InsertKeyword(keyword uint64, record []field.FP, batch int) error {
+	if len(record) != db.SlotSize {
+		return errors.New("record size does not match the slot size")
+	}
+
+	i, found := db.KeywordIndex(keyword)
+	if found {
+		return errors.New("keyword already in the database")
+	}
+
+	if db.BatchSize > 0 && (batch < 0 || batch >= db.BatchSize || i < db.BatchStarts[batch] || i > db.BatchStops[batch]) {
+		return errors.New("keyword does not fall in the batch")
+	}
+
+	db.Keywords = append(db.Keywords, 0)
+	copy(db.Keywords[i+1:], db.Keywords[i:])
+	db.Keywords[i] = keyword
+
+	db.Data = append(db.Data, record...)
+	copy(db.Data[(i+1)*db.SlotSize:], db.Data[i*db.SlotSize:])
+	copy(db.Data[i*db.SlotSize:], record)
+	db.DBSize++
+
+	if db.BatchSize > 0 {
+		db.BatchStops[batch]++
+		for b := batch + 1; b < db.BatchSize; b++ {
+			db.BatchStarts[b]++
+			db.BatchStops[b]++
+		}
+	}
+
+	return nil
+}
+
+// DeleteKeyword removes the keyword and its record from the database (and its batch)
+func (db *Database) DeleteKeyword(keyword uint64) error {
+	i, found := db.KeywordIndex(keyword)
+	if !found {
+		return errors.New("keyword not in the database")
+	}
+
+	db.Keywords = append(db.Keywords[:i], db.Keywords[i+1:]...)
+	db.Data = append(db.Data[:i*db.SlotSize], db.Data[(i+1)*db.SlotSize:]...)
+	db.DBSize--
+
+	for b := 0; b < db.BatchSize; b++ {
+		if db.BatchStarts[b] > i {
+			db.BatchStarts[b]--
+		}
+		if db.BatchStops[b] > i {
+			db.BatchStops[b]--
+		}
+	}
+
+	return nil
+}
+
+// AppendRecord adds a record at the end of an index (not keyword based) database
+func (db *Database) AppendRecord(record []field.FP) error {
+	if db.Keywords != nil {
+		return errors.New("records of keyword databases should be inserted with their keyword")
+	}
+	if len(record) != db.SlotSize {
+		return errors.New("record size does not match the slot size")
+	}
+
+	db.Data = append(db.Data, record...)
+	db.DBSize++
+
+	return nil
+}
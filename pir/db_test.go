@@ -1,6 +1,7 @@
 package pir
 
 import (
+	"bytes"
 	"math/rand"
 	"os"
 	"runtime/pprof"
@@ -36,12 +37,12 @@ func TestSharedQuery(t *testing.T) {
 		qIndex := uint64(rand.Intn(db.DBSize))
 		shares := db.NewIndexQueryShares(qIndex, 2, RangeSize)
 
-		resA, err := db.PrivateSecretSharedQuery(shares[0])
+		resA, err := db.PrivateSecretSharedQuery(shares[0], nil)
 		if err != nil {
 			t.Fatalf("%v", err)
 		}
 
-		resB, err := db.PrivateSecretSharedQuery(shares[1])
+		resB, err := db.PrivateSecretSharedQuery(shares[1], nil)
 		if err != nil {
 			t.Fatalf("%v", err)
 		}
@@ -49,10 +50,10 @@ func TestSharedQuery(t *testing.T) {
 		resultShares := [...]*SecretSharedQueryResult{resA, resB}
 		res := Recover(resultShares[:])
 
-		if db.Data[qIndex] != res {
+		if !equalRecords(db.Record(int(qIndex)), res) {
 			t.Fatalf(
 				"Query result is incorrect. %v != %v\n",
-				db.Data[qIndex],
+				db.Record(int(qIndex)),
 				res,
 			)
 		}
@@ -62,6 +63,430 @@ func TestSharedQuery(t *testing.T) {
 
 }
 
+func TestVerifiableSharedQuery(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for i := 0; i < NumQueries; i++ {
+		qIndex := uint64(rand.Intn(db.DBSize))
+		shares := db.NewVerifiableIndexQueryShares(qIndex, 2, RangeSize)
+
+		resA, err := db.PrivateSecretSharedQuery(shares[0], nil)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		resB, err := db.PrivateSecretSharedQuery(shares[1], nil)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		resultShares := [...]*SecretSharedQueryResult{resA, resB}
+		if !VerifyProofs(resultShares[:]) {
+			t.Fatalf("proofs do not match for well-formed query")
+		}
+
+		res := Recover(resultShares[:])
+		if !equalRecords(db.Record(int(qIndex)), res) {
+			t.Fatalf(
+				"Query result is incorrect. %v != %v\n",
+				db.Record(int(qIndex)),
+				res,
+			)
+		}
+	}
+}
+
+func TestSharedQueryMultiElementRecords(t *testing.T) {
+	setup()
+
+	for numBytes := SlotBytes; numBytes < 10*SlotBytesStep; numBytes += SlotBytesStep {
+		db := GenerateRandomDB(TestDBSize, numBytes)
+		if db.SlotSize != NumElementsForBytes(numBytes) {
+			t.Fatalf("expected slot size %v, got %v", NumElementsForBytes(numBytes), db.SlotSize)
+		}
+
+		qIndex := uint64(rand.Intn(db.DBSize))
+		shares := db.NewVerifiableIndexQueryShares(qIndex, 2, RangeSize)
+
+		resA, err := db.PrivateSecretSharedQuery(shares[0], nil)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		resB, err := db.PrivateSecretSharedQuery(shares[1], nil)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		res := Recover([]*SecretSharedQueryResult{resA, resB})
+		if !equalRecords(db.Record(int(qIndex)), res) {
+			t.Fatalf("Query result is incorrect. %v != %v\n", db.Record(int(qIndex)), res)
+		}
+	}
+}
+
+func TestBatchQueryBytesRecords(t *testing.T) {
+	setup()
+
+	numRecords := 100
+	recordBytes := 20
+	numBatches := 4
+
+	keys := make([]uint64, numRecords)
+	records := make([][]byte, numRecords)
+	for i := range records {
+		keys[i] = uint64(i * 7)
+		records[i] = make([]byte, rand.Intn(recordBytes+1))
+		rand.Read(records[i])
+	}
+
+	db := NewDatabase()
+	if err := db.BuildForBytes(records, recordBytes); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetKeywords(keys); err != nil {
+		t.Fatal(err)
+	}
+
+	starts := make([]int, numBatches)
+	stops := make([]int, numBatches)
+	for b := 0; b < numBatches; b++ {
+		starts[b] = b * numRecords / numBatches
+		stops[b] = (b + 1) * numRecords / numBatches
+	}
+	if err := db.SetBatchingParameters(numBatches, starts, stops); err != nil {
+		t.Fatal(err)
+	}
+
+	// query one keyword from each batch
+	targets := make([]int, numBatches)
+	batchA := &BatchQueryShare{}
+	batchB := &BatchQueryShare{}
+	for b := 0; b < numBatches; b++ {
+		targets[b] = starts[b] + rand.Intn(stops[b]-starts[b])
+		shares := db.NewKeywordQueryShares(keys[targets[b]], 2, RangeSize)
+		batchA.Queries = append(batchA.Queries, shares[0])
+		batchB.Queries = append(batchB.Queries, shares[1])
+	}
+
+	resA, err := db.PrivateSecretSharedBatchQuery(batchA, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resB, err := db.PrivateSecretSharedBatchQuery(batchB, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for b := 0; b < numBatches; b++ {
+		res := RecoverBytes([]*SecretSharedQueryResult{resA[b], resB[b]}, recordBytes)
+		expected := make([]byte, recordBytes)
+		copy(expected, records[targets[b]])
+		if string(res) != string(expected) {
+			t.Fatalf("wrong record for batch %v: %v != %v", b, res, expected)
+		}
+	}
+}
+
+func TestInsertDeleteKeywords(t *testing.T) {
+	setup()
+
+	numRecords := 100
+	slotSize := 3
+	numBatches := 4
+
+	// batch b holds the keywords in [175b, 175(b+1))
+	keys := make([]uint64, numRecords)
+	records := make([][]field.FP, numRecords)
+	for i := range records {
+		keys[i] = uint64(i * 7)
+		records[i] = []field.FP{field.FP(i), field.FP(rand.Intn(100)), 1}
+	}
+
+	db := NewDatabase()
+	if err := db.BuildForKeysAndRecords(keys, records); err != nil {
+		t.Fatal(err)
+	}
+
+	starts := make([]int, numBatches)
+	stops := make([]int, numBatches)
+	for b := 0; b < numBatches; b++ {
+		starts[b] = b * numRecords / numBatches
+		stops[b] = (b + 1) * numRecords / numBatches
+	}
+	if err := db.SetBatchingParameters(numBatches, starts, stops); err != nil {
+		t.Fatal(err)
+	}
+
+	// the updates must not affect copies of the database
+	original := db.Copy()
+	root := db.MerkleRoot()
+
+	inserted := map[uint64]int{3: 0, 176: 1, 351: 2, 700: 3, 701: 3}
+	for key, batch := range inserted {
+		if err := db.InsertKeyword(key, []field.FP{field.FP(key), 0, 2}, batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, key := range []uint64{0, 210, 693} {
+		if err := db.DeleteKeyword(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := db.InsertKeyword(4, make([]field.FP, slotSize), 2); err == nil {
+		t.Fatalf("keyword outside of its batch was inserted")
+	}
+	if err := db.InsertKeyword(7, make([]field.FP, slotSize), 0); err == nil {
+		t.Fatalf("existing keyword was inserted")
+	}
+	if err := db.DeleteKeyword(1); err == nil {
+		t.Fatalf("missing keyword was deleted")
+	}
+
+	if db.DBSize != numRecords+2 || len(db.Keywords) != db.DBSize || len(db.Data) != db.DBSize*slotSize {
+		t.Fatalf("database size is inconsistent after the updates")
+	}
+	for i := 1; i < len(db.Keywords); i++ {
+		if db.Keywords[i-1] >= db.Keywords[i] {
+			t.Fatalf("keywords are not sorted after the updates")
+		}
+	}
+	if original.DBSize != numRecords || !bytes.Equal(original.MerkleRoot(), root) {
+		t.Fatalf("copy of the database was modified by the updates")
+	}
+	for b := 0; b < numBatches; b++ {
+		for i := db.BatchStarts[b]; i < db.BatchStops[b]; i++ {
+			if db.Keywords[i]/175 != uint64(b) && db.Keywords[i] < 700 {
+				t.Fatalf("keyword %v is not in batch %v", db.Keywords[i], b)
+			}
+		}
+	}
+
+	// query the inserted keyword of each batch
+	targets := []uint64{3, 176, 351, 701}
+	batchA := &BatchQueryShare{}
+	batchB := &BatchQueryShare{}
+	for _, key := range targets {
+		shares := db.NewKeywordQueryShares(key, 2, RangeSize)
+		batchA.Queries = append(batchA.Queries, shares[0])
+		batchB.Queries = append(batchB.Queries, shares[1])
+	}
+
+	resA, err := db.PrivateSecretSharedBatchQuery(batchA, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resB, err := db.PrivateSecretSharedBatchQuery(batchB, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for b, key := range targets {
+		res := Recover([]*SecretSharedQueryResult{resA[b], resB[b]})
+		if !equalRecords(res, []field.FP{field.FP(key), 0, 2}) {
+			t.Fatalf("wrong record for keyword %v: %v", key, res)
+		}
+	}
+}
+
+func TestMerkleRoot(t *testing.T) {
+	setup()
+
+	keys := make([]uint64, 37)
+	records := make([][]field.FP, len(keys))
+	for i := range records {
+		keys[i] = uint64(i * 3)
+		records[i] = []field.FP{field.FP(rand.Intn(1000)), field.FP(rand.Intn(1000))}
+	}
+
+	db := NewDatabase()
+	if err := db.BuildForKeysAndRecords(keys, records); err != nil {
+		t.Fatal(err)
+	}
+	root := db.MerkleRoot()
+
+	same := NewDatabase()
+	if err := same.BuildForKeysAndRecords(append([]uint64{}, keys...), records); err != nil {
+		t.Fatal(err)
+	}
+	if string(same.MerkleRoot()) != string(root) {
+		t.Fatalf("identical databases have different roots")
+	}
+
+	same.Data[len(same.Data)-1]++
+	if string(same.MerkleRoot()) == string(root) {
+		t.Fatalf("databases with different records have the same root")
+	}
+	same.Data[len(same.Data)-1]--
+
+	same.Keywords[10]++
+	if string(same.MerkleRoot()) == string(root) {
+		t.Fatalf("databases with different keywords have the same root")
+	}
+
+	// the root only depends on the contents of the database
+	if err := db.InsertKeyword(1, []field.FP{1, 2}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteKeyword(1); err != nil {
+		t.Fatal(err)
+	}
+	if string(db.MerkleRoot()) != string(root) {
+		t.Fatalf("root changed after inserting and deleting a keyword")
+	}
+}
+
+func TestMultiBatchQuery(t *testing.T) {
+	setup()
+
+	numRecords := 100
+	slotSize := 3
+	numBatches := 4
+	numQueries := 5
+
+	keys := make([]uint64, numRecords)
+	records := make([][]field.FP, numRecords)
+	for i := range records {
+		keys[i] = uint64(i * 7)
+		records[i] = make([]field.FP, slotSize)
+		for e := range records[i] {
+			records[i][e] = field.FP(rand.Intn(1 << 30))
+		}
+
+		// every other record is empty
+		if i%2 == 0 {
+			records[i] = make([]field.FP, slotSize)
+		}
+	}
+
+	db := NewDatabase()
+	if err := db.BuildForKeysAndRecords(keys, records); err != nil {
+		t.Fatal(err)
+	}
+
+	// the last batch is empty
+	starts := []int{0, 30, 60, numRecords}
+	stops := []int{30, 60, numRecords, numRecords}
+	if err := db.SetBatchingParameters(numBatches, starts, stops); err != nil {
+		t.Fatal(err)
+	}
+
+	// one batch query per client (verifiable and not); query 1 is malformed
+	targets := make([][]int, numQueries)
+	batchesA := make([]*BatchQueryShare, numQueries)
+	batchesB := make([]*BatchQueryShare, numQueries)
+	for q := 0; q < numQueries; q++ {
+		batchesA[q] = &BatchQueryShare{}
+		batchesB[q] = &BatchQueryShare{}
+		for b := 0; b < numBatches; b++ {
+			target := -1
+			key := uint64(1) // absent keyword
+			if starts[b] < stops[b] {
+				target = starts[b] + rand.Intn(stops[b]-starts[b])
+				key = keys[target]
+			}
+			targets[q] = append(targets[q], target)
+
+			var shares []*QueryShare
+			if q%2 == 0 {
+				shares = db.NewVerifiableKeywordQueryShares(key, 2, RangeSize)
+			} else {
+				shares = db.NewKeywordQueryShares(key, 2, RangeSize)
+			}
+			batchesA[q].Queries = append(batchesA[q].Queries, shares[0])
+			batchesB[q].Queries = append(batchesB[q].Queries, shares[1])
+		}
+	}
+	batchesA[1].Queries[0].ShareNumber = 1
+
+	resA, errsA := db.PrivateSecretSharedMultiBatchQuery(batchesA, nil)
+	resB, errsB := db.PrivateSecretSharedMultiBatchQuery(batchesB, nil)
+
+	if errsA[1] == nil {
+		t.Fatalf("malformed query was not rejected")
+	}
+
+	for q := 0; q < numQueries; q++ {
+		if q == 1 {
+			continue
+		}
+		if errsA[q] != nil || errsB[q] != nil {
+			t.Fatalf("query %v failed: %v %v", q, errsA[q], errsB[q])
+		}
+
+		// results match the results of the query on its own
+		single, err := db.PrivateSecretSharedBatchQuery(batchesA[q], nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for b := 0; b < numBatches; b++ {
+			if !equalRecords(single[b].Shares, resA[q][b].Shares) || single[b].Found != resA[q][b].Found {
+				t.Fatalf("batched result of query %v differs in batch %v", q, b)
+			}
+
+			pair := []*SecretSharedQueryResult{resA[q][b], resB[q][b]}
+			if q%2 == 0 && !VerifyProofs(pair) {
+				t.Fatalf("proofs do not match for query %v in batch %v", q, b)
+			}
+
+			res := Recover(pair)
+			expected := make([]field.FP, slotSize)
+			if targets[q][b] >= 0 {
+				expected = records[targets[q][b]]
+			}
+			if !equalRecords(res, expected) {
+				t.Fatalf("wrong record for query %v in batch %v: %v != %v", q, b, res, expected)
+			}
+
+			// the indicator is a sharing of 1 iff the record is non-empty
+			found := field.Add(resA[q][b].Found, resB[q][b].Found)
+			if (found == 1) != (expected[0] != 0) || found > 1 {
+				t.Fatalf("wrong indicator for query %v in batch %v: %v", q, b, found)
+			}
+		}
+	}
+}
+
+func TestMalformedQueryRejected(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	shares := db.NewVerifiableIndexQueryShares(0, 2, RangeSize)
+
+	// truncated key
+	shares[0].DPFKey.Bytes = shares[0].DPFKey.Bytes[1:]
+	if _, err := db.PrivateSecretSharedQuery(shares[0], nil); err == nil {
+		t.Fatalf("malformed key was not rejected")
+	}
+
+	// key share with the wrong share number
+	shares[1].ShareNumber = 0
+	if _, err := db.PrivateSecretSharedQuery(shares[1], nil); err == nil {
+		t.Fatalf("mismatched share number was not rejected")
+	}
+}
+
+func TestBytesEncoding(t *testing.T) {
+	for numBytes := 0; numBytes < 20; numBytes++ {
+		b := make([]byte, numBytes)
+		rand.Read(b)
+
+		elements := BytesToElements(b)
+		if len(elements) != NumElementsForBytes(numBytes) {
+			t.Fatalf("expected %v elements, got %v", NumElementsForBytes(numBytes), len(elements))
+		}
+
+		decoded := ElementsToBytes(elements, numBytes)
+		if string(decoded) != string(b) {
+			t.Fatalf("decoded bytes %v != %v", decoded, b)
+		}
+	}
+}
+
 func BenchmarkBuildDB(b *testing.B) {
 	setup()
 
@@ -82,7 +507,7 @@ func BenchmarkQuerySecretShares(b *testing.B) {
 
 	// benchmark index build time
 	for i := 0; i < b.N; i++ {
-		_, err := db.PrivateSecretSharedQuery(queryA)
+		_, err := db.PrivateSecretSharedQuery(queryA, nil)
 		if err != nil {
 			panic(err)
 		}
@@ -103,14 +528,16 @@ func BenchmarkQueryGen(b *testing.B) {
 	}
 }
 
-// GenerateRandomDB generates a database of slots (where each slot is of size NumBytes)
-// the width and height parameter specify the number of rows and columns in the database
+// GenerateRandomDB generates a database of size random records (where each record holds numBytes bytes)
 func GenerateRandomDB(size, numBytes int) *Database {
 
+	slotSize := NumElementsForBytes(numBytes)
+
 	db := Database{}
-	db.Data = make([]field.FP, size)
+	db.Data = make([]field.FP, size*slotSize)
 	db.DBSize = size
-	for i := 0; i < size; i++ {
+	db.SlotSize = slotSize
+	for i := 0; i < size*slotSize; i++ {
 		db.Data[i] = field.RandomFieldElement()
 	}
 
@@ -120,9 +547,26 @@ func GenerateRandomDB(size, numBytes int) *Database {
 // GenerateEmptyDB  generates an empty database
 func GenerateEmptyDB(size, numBytes int) *Database {
 
+	slotSize := NumElementsForBytes(numBytes)
+
 	db := Database{}
-	db.Data = make([]field.FP, size)
+	db.Data = make([]field.FP, size*slotSize)
 	db.DBSize = size
+	db.SlotSize = slotSize
 
 	return &db
 }
+
+func equalRecords(a, b []field.FP) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -1,6 +1,7 @@
 package dpfc
 
 import (
+	"bytes"
 	"fmt"
 	"math/rand"
 	"testing"
@@ -59,6 +60,152 @@ func TestCorrectPointFunctionTwoServer(t *testing.T) {
 	}
 }
 
+func TestVerifiablePointFunctionTwoServer(t *testing.T) {
+
+	for trial := 0; trial < numTrials; trial++ {
+		num := 20
+
+		specialIndex := uint64(rand.Intn(num))
+
+		// generate vdpf keys on client
+		client := ClientVDPFInitialize()
+		keyA, keyB := client.GenVDPFKeys(specialIndex, 64)
+
+		// simulate the server
+		server := ServerVDPFInitialize(client.PrfKey, client.H1Key, client.H2Key)
+
+		indices := make([]uint64, num)
+		for i := 0; i < num; i++ {
+			indices[i] = uint64(rand.Intn(num))
+		}
+		ans0, pi0 := server.BatchVerEval(keyA, indices)
+		ans1, pi1 := server.BatchVerEval(keyB, indices)
+
+		if !bytes.Equal(pi0, pi1) {
+			t.Fatalf("proofs do not match for well-formed key")
+		}
+
+		for i := 0; i < num; i++ {
+			sum := field.Add(field.FP(ans0[i]), field.FP(ans1[i]))
+
+			if uint64(indices[i]) == specialIndex && uint(sum) != 1 {
+				t.Fatalf("Expected: %v Got: %v", 1, sum)
+			}
+
+			if uint64(indices[i]) != specialIndex && sum != 0 {
+				t.Fatalf("Expected: 0 Got: %v", sum)
+			}
+		}
+
+		server.Free()
+		client.Free()
+	}
+}
+
+func TestVerifiablePointFunctionMalformedKey(t *testing.T) {
+
+	num := 1000
+	client := ClientVDPFInitialize()
+	keyA, keyB := client.GenVDPFKeys(uint64(rand.Intn(num)), 64)
+	server := ServerVDPFInitialize(client.PrfKey, client.H1Key, client.H2Key)
+
+	indices := make([]uint64, num)
+	for i := 0; i < num; i++ {
+		indices[i] = uint64(i)
+	}
+
+	// corrupt a correction word shared by both keys
+	// (skip the least significant bit which is ignored by the PRG)
+	keyA.Bytes[18*60+1] ^= 1
+	keyB.Bytes[18*60+1] ^= 1
+
+	_, pi0 := server.BatchVerEval(keyA, indices)
+	_, pi1 := server.BatchVerEval(keyB, indices)
+
+	if bytes.Equal(pi0, pi1) {
+		t.Fatalf("proofs match for malformed key")
+	}
+
+	server.Free()
+	client.Free()
+}
+
+func TestVerifiablePointFunctionDifferentLastCW(t *testing.T) {
+
+	num := 100
+	client := ClientVDPFInitialize()
+	keyA, keyB := client.GenVDPFKeys(uint64(rand.Intn(num)), 64)
+	server := ServerVDPFInitialize(client.PrfKey, client.H1Key, client.H2Key)
+
+	indices := make([]uint64, num)
+	for i := 0; i < num; i++ {
+		indices[i] = uint64(i)
+	}
+
+	// the keys correct the outputs differently: every leaf with control bit 1 outputs a non-zero value
+	keyB.Bytes[18*64+18] ^= 1
+
+	_, pi0 := server.BatchVerEval(keyA, indices)
+	_, pi1 := server.BatchVerEval(keyB, indices)
+
+	if bytes.Equal(pi0, pi1) {
+		t.Fatalf("proofs match for keys with different last correction words")
+	}
+
+	server.Free()
+	client.Free()
+}
+
+func TestVerifiablePointFunctionDifferentControlBits(t *testing.T) {
+
+	num := 100
+	rangeSize := 64
+	client := ClientVDPFInitialize()
+	keyA, keyB := client.GenVDPFKeys(uint64(rand.Intn(num)), uint(rangeSize))
+	server := ServerVDPFInitialize(client.PrfKey, client.H1Key, client.H2Key)
+
+	// same seeds on both sides of every level but different control bits:
+	// the keys output +/- the last correction word everywhere
+	copy(keyB.Bytes[1:17], keyA.Bytes[1:17])
+	for _, key := range []*DPFKey{keyA, keyB} {
+		for i := 1; i <= rangeSize; i++ {
+			for j := 0; j < 16; j++ {
+				key.Bytes[18*i+j] = 0
+			}
+			key.Bytes[18*i+16] = 1
+			key.Bytes[18*i+17] = 1
+		}
+		for j := 18*rangeSize + 18 + 16; j < len(key.Bytes); j++ {
+			key.Bytes[j] = 0
+		}
+	}
+
+	indices := make([]uint64, num)
+	for i := 0; i < num; i++ {
+		indices[i] = uint64(i)
+	}
+
+	ans0, pi0 := server.BatchVerEval(keyA, indices)
+	ans1, pi1 := server.BatchVerEval(keyB, indices)
+
+	nonZero := 0
+	for i := range indices {
+		if field.Add(field.FP(ans0[i]), field.FP(ans1[i])) != 0 {
+			nonZero++
+		}
+	}
+	if nonZero < 2 {
+		t.Fatalf("expected the malformed key to output several non-zero values")
+	}
+
+	if bytes.Equal(pi0, pi1) {
+		t.Fatalf("proofs match for keys with different control bits")
+	}
+
+	server.Free()
+	client.Free()
+}
+
 func Benchmark2PartyServerInit(b *testing.B) {
 
 	client := ClientDPFInitialize()
@@ -92,6 +239,25 @@ func Benchmark2Party64BitKeywordEval(b *testing.B) {
 	client.Free()
 }
 
+func Benchmark2Party64BitKeywordVerEval(b *testing.B) {
+
+	client := ClientVDPFInitialize()
+	keyA, _ := client.GenVDPFKeys(1, 64)
+	server := ServerVDPFInitialize(client.PrfKey, client.H1Key, client.H2Key)
+
+	indices := make([]uint64, 1)
+	indices[0] = 1
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		server.BatchVerEval(keyA, indices)
+	}
+
+	server.Free()
+	client.Free()
+}
+
 func BenchmarkDPFGen(b *testing.B) {
 
 	client := ClientDPFInitialize()
@@ -16,6 +16,7 @@
 
 #define INDEX_LASTCW 18*size + 18
 #define CWSIZE 18
+#define INDEX_CS INDEX_LASTCW + 16 // VDPF correction string follows the last CW
 
 #define FIELDSIZE 2147483647
 #define FIELDBITS 31
@@ -24,10 +25,15 @@
 #define LEFT 0
 #define RIGHT 1
 
-typedef struct Hash hash;
 typedef __int128 int128_t;
 typedef unsigned __int128 uint128_t;
 
+// fixed-key AES (Matyas-Meyer-Oseas) hash used by the VDPF
+typedef struct Hash {
+	EVP_CIPHER_CTX *ctx;
+	uint64_t outblocks;
+} hash;
+
 // PRG cipher context
 extern EVP_CIPHER_CTX* getDPFContext(uint8_t*);
 extern void destroyContext(EVP_CIPHER_CTX*);
@@ -37,4 +43,13 @@ extern void genDPF(EVP_CIPHER_CTX *ctx, int size, uint64_t index, unsigned char*
 extern void batchEvalDPF(EVP_CIPHER_CTX *ctx, int size, bool b, unsigned char* k, uint64_t *in, uint64_t inl, uint8_t* out);
 extern void fullDomainDPF(EVP_CIPHER_CTX *ctx, int size, bool b, unsigned char* k, uint128_t *outSeeds, int *outBits);
 
+// MMO hash functions
+extern struct Hash* initMMOHash(uint8_t* seed, uint64_t outblocks);
+extern void destroyMMOHash(struct Hash *hash);
+extern void mmoHash(struct Hash *hash, uint8_t *in, uint64_t inblocks, uint8_t *out);
+
+// VDPF functions
+extern void genVDPF(EVP_CIPHER_CTX *ctx, struct Hash *mmo_hash1, int size, uint64_t index, unsigned char* k0, unsigned char *k1);
+extern void batchEvalVDPF(EVP_CIPHER_CTX *ctx, struct Hash *mmo_hash1, struct Hash *mmo_hash2, int size, bool b, unsigned char* k, uint64_t *in, uint64_t inl, uint8_t* out, uint8_t* pi);
+
 #endif
@@ -78,6 +78,71 @@ void testDPF() {
 }
 
 
+void testVDPF() {
+    int size = EVALDOMAIN;
+    uint64_t secretIndex = randIndex();
+    uint8_t* key = malloc(16);
+    RAND_bytes(key, 16);
+    EVP_CIPHER_CTX* ctx = getDPFContext(key);
+
+    uint8_t hashKey1[16];
+    uint8_t hashKey2[16];
+    RAND_bytes(hashKey1, 16);
+    RAND_bytes(hashKey2, 16);
+    struct Hash* mmo_hash1 = initMMOHash(hashKey1, 4);
+    struct Hash* mmo_hash2 = initMMOHash(hashKey2, 2);
+
+    unsigned char *k0 = malloc(INDEX_CS + 64);
+    unsigned char *k1 = malloc(INDEX_CS + 64);
+    genVDPF(ctx, mmo_hash1, size, secretIndex, k0, k1);
+
+    size_t L = 1 << 10;
+    uint64_t *X = malloc(sizeof(uint64_t) * L);
+    for (size_t i = 0; i < L; i++) {
+        X[i] = randIndex();
+    }
+    X[0] = secretIndex;
+
+    uint128_t *shares0 = malloc(sizeof(uint128_t) * L);
+    uint128_t *shares1 = malloc(sizeof(uint128_t) * L);
+    uint8_t pi0[32];
+    uint8_t pi1[32];
+
+    batchEvalVDPF(ctx, mmo_hash1, mmo_hash2, size, false, k0, X, L, (uint8_t*)shares0, pi0);
+    batchEvalVDPF(ctx, mmo_hash1, mmo_hash2, size, true, k1, X, L, (uint8_t*)shares1, pi1);
+
+    if (((shares0[0] + shares1[0]) % FIELDSIZE) != 1) {
+        printf("FAIL (zero)\n");
+        exit(0);
+    }
+
+    if (memcmp(pi0, pi1, 32) != 0) {
+        printf("FAIL (proof mismatch)\n");
+        exit(0);
+    }
+
+    // tamper with a correction word (in both keys); proofs should no longer match
+    k0[CWSIZE * 50 + 1] ^= 1;
+    k1[CWSIZE * 50 + 1] ^= 1;
+    batchEvalVDPF(ctx, mmo_hash1, mmo_hash2, size, false, k0, X, L, (uint8_t*)shares0, pi0);
+    batchEvalVDPF(ctx, mmo_hash1, mmo_hash2, size, true, k1, X, L, (uint8_t*)shares1, pi1);
+    if (memcmp(pi0, pi1, 32) == 0) {
+        printf("FAIL (tampered key verified)\n");
+        exit(0);
+    }
+
+    destroyMMOHash(mmo_hash1);
+    destroyMMOHash(mmo_hash2);
+    destroyContext(ctx);
+    free(shares0);
+    free(shares1);
+    free(k0);
+    free(k1);
+    free(X);
+    free(key);
+    printf("DONE\n\n");
+}
+
 int main(int argc, char** argv) {
 
     int testTrials = 10;
@@ -86,6 +151,9 @@ int main(int argc, char** argv) {
     printf("Testing DPF\n");
     for (int i = 0; i < testTrials; i++) testDPF();
     printf("******************************************\n");
+    printf("Testing VDPF\n");
+    for (int i = 0; i < testTrials; i++) testVDPF();
+    printf("******************************************\n");
     printf("PASS\n");
     printf("******************************************\n\n");
 }
\ No newline at end of file
@@ -0,0 +1,244 @@
+// Verifiable DPF based on "Lightweight, Maliciously Secure Verifiable Function Secret Sharing"
+// by Leo de Castro and Antigoni Polychroniadou (EUROCRYPT 2022).
+//
+// The VDPF key is a regular DPF key (see dpf.c) followed by a correction string (CS)
+// of HASH1BLOCKOUT blocks. When evaluating the key on a set of inputs, each server
+// computes a proof that it outputs along with the evaluation. If the key is a well-formed
+// point function (on the evaluated inputs) the two proofs are identical.
+// The proof covers the control bit of each leaf (leaves whose seeds agree but whose control
+// bits differ would otherwise go unnoticed) and the last correction word (so that both keys
+// correct the output the same way). It does not constrain the value of the point function:
+// the servers check it separately (see pir.Sketch).
+
+#include "../include/dpf.h"
+#include <openssl/rand.h>
+
+#define HASH1BLOCKOUT 4
+#define HASH2BLOCKOUT 2
+
+extern void dpfPRG(EVP_CIPHER_CTX *ctx, uint128_t input, uint128_t* output1, uint128_t* output2, int* bit1, int* bit2);
+
+static inline uint128_t convert(uint128_t raw) {
+	uint128_t r = raw & FIELDMASK;
+	return r < FIELDSIZE ? r : r - FIELDSIZE;
+}
+
+static inline int getbit(uint128_t x, int size, int b) {
+	return ((x) >> (size - b)) & 1;
+}
+
+static inline uint128_t negate(uint128_t x) {
+	return x != 0 ? ((uint128_t)FIELDSIZE) - x : 0;
+}
+
+static inline uint128_t modAfterAdd(uint128_t r) {
+	return r < FIELDSIZE ? r : r - ((uint128_t)FIELDSIZE);
+}
+
+struct Hash* initMMOHash(uint8_t* seed, uint64_t outblocks) {
+	struct Hash *hash = malloc(sizeof(struct Hash));
+	hash->outblocks = outblocks;
+	if(!(hash->ctx = EVP_CIPHER_CTX_new()))
+		printf("errors occured in creating context\n");
+	if(1 != EVP_EncryptInit_ex(hash->ctx, EVP_aes_128_ecb(), NULL, seed, NULL))
+		printf("errors occured in hash init\n");
+	EVP_CIPHER_CTX_set_padding(hash->ctx, 0);
+	return hash;
+}
+
+void destroyMMOHash(struct Hash *hash) {
+	EVP_CIPHER_CTX_free(hash->ctx);
+	free(hash);
+}
+
+// Matyas-Meyer-Oseas compression of inblocks input blocks
+// followed by expansion into hash->outblocks output blocks
+void mmoHash(struct Hash *hash, uint8_t *in, uint64_t inblocks, uint8_t *out) {
+	int len = 0;
+	uint128_t state = 0;
+	uint128_t x, y;
+
+	for (uint64_t i = 0; i < inblocks; i++) {
+		memcpy(&x, &in[16 * i], 16);
+		x ^= state;
+		if (1 != EVP_EncryptUpdate(hash->ctx, (uint8_t*)&y, &len, (uint8_t*)&x, 16))
+			printf("errors occured in encrypt\n");
+		state = x ^ y;
+	}
+
+	for (uint64_t i = 0; i < hash->outblocks; i++) {
+		x = state ^ (uint128_t)(i + 1);
+		if (1 != EVP_EncryptUpdate(hash->ctx, (uint8_t*)&y, &len, (uint8_t*)&x, 16))
+			printf("errors occured in encrypt\n");
+		y ^= x;
+		memcpy(&out[16 * i], &y, 16);
+	}
+}
+
+// walks the DPF tree down to the leaf for input x and returns the leaf seed and control bit
+static void evalLeaf(EVP_CIPHER_CTX *ctx, int size, bool b, unsigned char* k, uint64_t x, uint128_t *seed, int *bit) {
+	uint128_t s, sCW, sL, sR;
+	int t, tL, tR;
+
+	memcpy(&s, &k[1], 16);
+	t = b;
+
+	for (int i = 1; i <= size; i++) {
+		dpfPRG(ctx, s, &sL, &sR, &tL, &tR);
+
+		if (t == 1) {
+			memcpy(&sCW, &k[CWSIZE * i], 16);
+			sL = sL ^ sCW;
+			sR = sR ^ sCW;
+			tL = tL ^ k[CWSIZE * i + CWSIZE-2];
+			tR = tR ^ k[CWSIZE * i + CWSIZE-1];
+		}
+
+		if (getbit(x, size, i) == 0) {
+			s = sL;
+			t = tL;
+		} else {
+			s = sR;
+			t = tR;
+		}
+	}
+
+	*seed = s;
+	*bit = t;
+}
+
+// hashes the input x together with the leaf seed and control bit
+// (the control bit is stored above the 64 bits of the input)
+static void leafHash(struct Hash *mmo_hash1, uint64_t x, uint128_t seed, int bit, uint8_t *out) {
+	uint128_t in[2];
+	in[0] = (uint128_t)x | ((uint128_t)bit << 64);
+	in[1] = seed;
+	mmoHash(mmo_hash1, (uint8_t*)in, 2, out);
+}
+
+void genVDPF(EVP_CIPHER_CTX *ctx, struct Hash *mmo_hash1, int size, uint64_t index, unsigned char* k0, unsigned char *k1) {
+
+	genDPF(ctx, size, index, k0, k1);
+
+	uint128_t seed0, seed1;
+	int bit0, bit1;
+	evalLeaf(ctx, size, false, k0, index, &seed0, &bit0);
+	evalLeaf(ctx, size, true, k1, index, &seed1, &bit1);
+
+	uint128_t pi0[HASH1BLOCKOUT];
+	uint128_t pi1[HASH1BLOCKOUT];
+	leafHash(mmo_hash1, index, seed0, bit0, (uint8_t*)pi0);
+	leafHash(mmo_hash1, index, seed1, bit1, (uint8_t*)pi1);
+
+	// correction string that makes the two leaf hashes agree on the special index
+	uint128_t cs[HASH1BLOCKOUT];
+	for (int i = 0; i < HASH1BLOCKOUT; i++) {
+		cs[i] = pi0[i] ^ pi1[i];
+	}
+
+	memcpy(&k0[INDEX_CS], cs, 16 * HASH1BLOCKOUT);
+	memcpy(&k1[INDEX_CS], cs, 16 * HASH1BLOCKOUT);
+}
+
+void batchEvalVDPF(
+	EVP_CIPHER_CTX *ctx,
+	struct Hash *mmo_hash1,
+	struct Hash *mmo_hash2,
+	int size,
+	bool b,
+	unsigned char* k,
+	uint64_t *in,
+	uint64_t inl,
+	uint8_t* out,
+	uint8_t* pi) {
+
+	// parse the key
+	uint128_t seeds[size+1];
+	int bits[size+1];
+	uint128_t sCW[size+1];
+	int tCW0[size];
+	int tCW1[size];
+	uint128_t cs[HASH1BLOCKOUT];
+	uint128_t lastCW;
+
+	memcpy(&seeds[0], &k[1], 16);
+	bits[0] = b;
+
+	for(int i = 1; i <= size; i++){
+		memcpy(&sCW[i-1], &k[18 * i], 16);
+		tCW0[i-1] = k[18 * i + 16];
+		tCW1[i-1] = k[18 * i + 17];
+	}
+
+	memcpy(&lastCW, &k[INDEX_LASTCW], 16);
+	memcpy(cs, &k[INDEX_CS], 16 * HASH1BLOCKOUT);
+
+	// the proof is chained over all evaluated inputs, starting from the last correction word
+	uint128_t proof[HASH1BLOCKOUT];
+	uint128_t leafProof[HASH1BLOCKOUT];
+	memset(proof, 0, sizeof(proof));
+	proof[0] = lastCW;
+
+	// [optimization]: cache the first layers of the tree (see batchEvalDPF)
+	int numCacheLayers = size < 12 ? size : 12;
+	int numCached = (1 << numCacheLayers);
+	uint128_t *cachedSeeds = malloc(numCached * sizeof(uint128_t));
+	int *cachedBits = malloc(numCached * sizeof(int));
+	fullDomainDPF(ctx, numCacheLayers, b, k, cachedSeeds, cachedBits);
+
+	for (int l = 0; l < inl; l++) {
+
+		uint64_t idx = (in[l] >> (size - numCacheLayers)) & (numCached - 1);
+		seeds[numCacheLayers] = cachedSeeds[idx];
+		bits[numCacheLayers] = cachedBits[idx];
+
+		uint128_t sL, sR;
+		int tL, tR;
+		for (int i = numCacheLayers+1; i <= size; i++){
+			dpfPRG(ctx, seeds[i - 1], &sL, &sR, &tL, &tR);
+
+			if (bits[i-1] == 1){
+				sL = sL ^ sCW[i-1];
+				sR = sR ^ sCW[i-1];
+				tL = tL ^ tCW0[i-1];
+				tR = tR ^ tCW1[i-1];
+			}
+
+			uint128_t xbit = getbit(in[l], size, i);
+			seeds[i] = (1-xbit) * sL + xbit * sR;
+			bits[i] = (1-xbit) * tL + xbit * tR;
+		}
+
+		// leaf proof: H1(x, seed, t) xor (t * CS)
+		leafHash(mmo_hash1, in[l], seeds[size], bits[size], (uint8_t*)leafProof);
+		if (bits[size] == 1) {
+			for (int i = 0; i < HASH1BLOCKOUT; i++) {
+				leafProof[i] ^= cs[i];
+			}
+		}
+
+		// proof = H2(proof xor leaf proof)
+		for (int i = 0; i < HASH1BLOCKOUT; i++) {
+			leafProof[i] ^= proof[i];
+		}
+		memset(proof, 0, sizeof(proof));
+		mmoHash(mmo_hash2, (uint8_t*)leafProof, HASH1BLOCKOUT, (uint8_t*)proof);
+
+		uint128_t res = convert(seeds[size]);
+
+		if (bits[size] == 1) {
+			res = modAfterAdd(res + lastCW);
+		}
+
+		if (b == true) {
+			res = negate(res);
+		}
+
+		memcpy(&out[l*sizeof(uint128_t)], &res, sizeof(uint128_t));
+	}
+
+	memcpy(pi, proof, 16 * HASH2BLOCKOUT);
+
+	free(cachedSeeds);
+	free(cachedBits);
+}
@@ -0,0 +1,53 @@
+package dpfc
+
+import "crypto/rand"
+
+type HashKey [16]byte
+
+type Vdpf struct {
+	PrfKey PrfKey
+	H1Key  HashKey
+	H2Key  HashKey
+	ctx    PrfCtx
+	H1     Hash
+	H2     Hash
+}
+
+func ClientVDPFInitialize() *Vdpf {
+	randKey := PrfKey{}
+	_, err := rand.Read(randKey[:])
+	if err != nil {
+		panic("Error generating prf randomness")
+	}
+
+	hashKey1 := HashKey{}
+	_, err = rand.Read(hashKey1[:])
+	if err != nil {
+		panic("Error generating hash key randomness")
+	}
+
+	hashKey2 := HashKey{}
+	_, err = rand.Read(hashKey2[:])
+	if err != nil {
+		panic("Error generating hash key randomness")
+	}
+
+	return ServerVDPFInitialize(randKey, hashKey1, hashKey2)
+}
+
+func ServerVDPFInitialize(key PrfKey, hashKey1, hashKey2 HashKey) *Vdpf {
+	return &Vdpf{
+		PrfKey: key,
+		H1Key:  hashKey1,
+		H2Key:  hashKey2,
+		ctx:    InitDPFContext(key[:]),
+		H1:     InitMMOHash(hashKey1[:], HASH1BLOCKOUT),
+		H2:     InitMMOHash(hashKey2[:], HASH2BLOCKOUT),
+	}
+}
+
+func (vdpf *Vdpf) Free() {
+	DestroyDPFContext(vdpf.ctx)
+	DestroyMMOHash(vdpf.H1)
+	DestroyMMOHash(vdpf.H2)
+}
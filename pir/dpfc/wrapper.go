@@ -14,6 +14,9 @@ import (
 var HASH1BLOCKOUT uint = 4
 var HASH2BLOCKOUT uint = 2
 
+// ProofSize is the size (in bytes) of the proof output by BatchVerEval
+var ProofSize = 16 * HASH2BLOCKOUT
+
 type PrfCtx *C.struct_evp_cipher_ctx_st
 type Hash *C.struct_Hash
 
@@ -29,6 +32,16 @@ func getRequiredKeySize(rangeSize uint) uint {
 	return 18*rangeSize + 18 + 16 + 16*4
 }
 
+// IsWellFormed returns true if the key has the size and
+// share index expected for its range size
+func (key *DPFKey) IsWellFormed() bool {
+	if key == nil || key.RangeSize == 0 || key.RangeSize > 64 || key.Index > 1 {
+		return false
+	}
+
+	return len(key.Bytes) == int(getRequiredKeySize(key.RangeSize))
+}
+
 func InitDPFContext(prfKey []byte) PrfCtx {
 	if len(prfKey) != 16 {
 		panic("bad prf key size")
@@ -42,6 +55,19 @@ func DestroyDPFContext(ctx PrfCtx) {
 	C.destroyContext(ctx)
 }
 
+func InitMMOHash(hashKey []byte, outblocks uint) Hash {
+	if len(hashKey) != 16 {
+		panic("bad hash key size")
+	}
+
+	p := C.initMMOHash((*C.uchar)(unsafe.Pointer(&hashKey[0])), C.uint64_t(outblocks))
+	return p
+}
+
+func DestroyMMOHash(h Hash) {
+	C.destroyMMOHash(h)
+}
+
 func (dpf *Dpf) GenDPFKeys(specialIndex uint64, rangeSize uint) (*DPFKey, *DPFKey) {
 
 	keySize := getRequiredKeySize(rangeSize)
@@ -88,3 +114,63 @@ func (dpf *Dpf) BatchEval(key *DPFKey, indices []uint64) []uint64 {
 
 	return resTrunc
 }
+
+func (vdpf *Vdpf) GenVDPFKeys(specialIndex uint64, rangeSize uint) (*DPFKey, *DPFKey) {
+
+	keySize := getRequiredKeySize(rangeSize)
+	k0 := make([]byte, keySize)
+	k1 := make([]byte, keySize)
+
+	C.genVDPF(
+		vdpf.ctx,
+		vdpf.H1,
+		C.int(rangeSize),
+		C.uint64_t(specialIndex),
+		(*C.uchar)(unsafe.Pointer(&k0[0])),
+		(*C.uchar)(unsafe.Pointer(&k1[0])),
+	)
+
+	return NewDPFKey(k0, rangeSize, 0), NewDPFKey(k1, rangeSize, 1)
+}
+
+// BatchVerEval evaluates the VDPF key on each of the indices and returns
+// the (truncated) outputs along with a proof of well-formedness.
+// Both servers output the same proof iff the key is well-formed
+// on the provided indices.
+func (vdpf *Vdpf) BatchVerEval(key *DPFKey, indices []uint64) ([]uint64, []byte) {
+
+	keySize := getRequiredKeySize(key.RangeSize)
+	if len(key.Bytes) != int(keySize) {
+		panic("invalid key size")
+	}
+
+	res := make([]uint64, len(indices)*2) // returned output is uint128_t
+	resTrunc := make([]uint64, len(indices))
+	pi := make([]byte, ProofSize)
+
+	if len(indices) == 0 {
+		return resTrunc, pi
+	}
+
+	C.batchEvalVDPF(
+		vdpf.ctx,
+		vdpf.H1,
+		vdpf.H2,
+		C.int(key.RangeSize),
+		C.bool(key.Index == 1),
+		(*C.uchar)(unsafe.Pointer(&key.Bytes[0])),
+		(*C.uint64_t)(unsafe.Pointer(&indices[0])),
+		C.uint64_t(len(indices)),
+		(*C.uint8_t)(unsafe.Pointer(&res[0])),
+		(*C.uint8_t)(unsafe.Pointer(&pi[0])),
+	)
+
+	// skip two uint64 blocks at a time
+	b := 0
+	for i := 0; i < len(res); i += 2 {
+		resTrunc[b] = res[i]
+		b++
+	}
+
+	return resTrunc, pi
+}
@@ -0,0 +1,34 @@
+package pir
+
+import (
+	"github.com/sachaservan/private-ann/pir/field"
+)
+
+// BytesPerElement is the number of bytes packed into each field element
+// (the field is 31 bits so 3 bytes always fit)
+const BytesPerElement = 3
+
+// NumElementsForBytes returns the number of field elements needed to store numBytes bytes
+func NumElementsForBytes(numBytes int) int {
+	return (numBytes + BytesPerElement - 1) / BytesPerElement
+}
+
+// BytesToElements packs the bytes into field elements, BytesPerElement bytes per element
+func BytesToElements(b []byte) []field.FP {
+	elements := make([]field.FP, NumElementsForBytes(len(b)))
+	for i := range b {
+		shift := 8 * uint(i%BytesPerElement)
+		elements[i/BytesPerElement] |= field.FP(b[i]) << shift
+	}
+	return elements
+}
+
+// ElementsToBytes unpacks numBytes bytes from the field elements (see BytesToElements)
+func ElementsToBytes(elements []field.FP, numBytes int) []byte {
+	b := make([]byte, numBytes)
+	for i := range b {
+		shift := 8 * uint(i%BytesPerElement)
+		b[i] = byte(elements[i/BytesPerElement] >> shift)
+	}
+	return b
+}
@@ -1,6 +1,7 @@
 package field
 
 import (
+	crand "crypto/rand"
 	"math/big"
 	"math/rand"
 )
@@ -10,6 +11,10 @@ type FP uint64
 // need prime > n so that every id can be represented
 // need prime > nL so no overflow during oblivious masking
 const fieldPrime = 2147483647 // 2^31-1, 31 bits
+
+// Half is the inverse of 2 (two parties can each add c*Half to share a public constant c)
+const Half FP = (fieldPrime + 1) / 2
+
 var fieldPrimeBigInt *big.Int
 
 func init() {
@@ -46,7 +51,27 @@ func fieldMod(a FP) FP {
 	return Add(FP(a>>31), FP(a&fieldPrime))
 }
 
+// Reduce maps an integer to the field
+func Reduce(a uint64) FP {
+	return FP(a % fieldPrime)
+}
+
 func RandomFieldElement() FP {
 	r := rand.Intn(fieldPrime)
 	return FP(r)
 }
+
+// RandomFieldElementFrom samples a field element using the provided randomness
+func RandomFieldElementFrom(rnd *rand.Rand) FP {
+	r := rnd.Intn(fieldPrime)
+	return FP(r)
+}
+
+// SecureRandomFieldElement samples a field element with a cryptographically secure source
+func SecureRandomFieldElement() FP {
+	r, err := crand.Int(crand.Reader, fieldPrimeBigInt)
+	if err != nil {
+		panic(err)
+	}
+	return FP(r.Uint64())
+}
@@ -0,0 +1,63 @@
+package pir
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// domain separation of the leaves and inner nodes of Merkle trees
+const (
+	merkleLeaf  byte = 0
+	merkleInner byte = 1
+)
+
+// MerkleRoot returns the root of the Merkle tree whose leaves are the records of the
+// database (each with its keyword for keyword databases). Databases with the same root
+// hold the same (keywords and) records, so the root can be used to compare databases
+func (db *Database) MerkleRoot() []byte {
+	level := make([][]byte, db.DBSize)
+	buf := make([]byte, 1+8*(1+db.SlotSize))
+	for i := range level {
+		buf[0] = merkleLeaf
+		offset := 1
+		if db.Keywords != nil {
+			binary.LittleEndian.PutUint64(buf[offset:], db.Keywords[i])
+			offset += 8
+		}
+		for _, v := range db.Record(i) {
+			binary.LittleEndian.PutUint64(buf[offset:], uint64(v))
+			offset += 8
+		}
+		leaf := sha256.Sum256(buf[:offset])
+		level[i] = leaf[:]
+	}
+
+	return merkleRoot(level)
+}
+
+// merkleRoot returns the root of the tree with the given leaves;
+// the last node of a level with an odd number of nodes is moved up a level
+func merkleRoot(level [][]byte) []byte {
+	if len(level) == 0 {
+		empty := sha256.Sum256(nil)
+		return empty[:]
+	}
+
+	node := make([]byte, 1+2*sha256.Size)
+	node[0] = merkleInner
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i+1 < len(level); i += 2 {
+			copy(node[1:], level[i])
+			copy(node[1+sha256.Size:], level[i+1])
+			parent := sha256.Sum256(node)
+			next = append(next, parent[:])
+		}
+		if len(level)%2 == 1 {
+			next = append(next, level[len(level)-1])
+		}
+		level = next
+	}
+
+	return level[0]
+}
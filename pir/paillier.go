@@ -0,0 +1,201 @@
+package pir
+
+import (
+	"errors"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/paillier"
+	"github.com/sachaservan/private-ann/pir/field"
+)
+
+// Single-server (computational) PIR based on the Paillier cryptosystem.
+// The client sends an encrypted selection vector (an encryption of 1 for the queried
+// record and of 0 for every other record) and the server homomorphically computes the
+// inner product of the selection vector with the records. Each record is packed into
+// as few Paillier plaintexts as possible (see ElementsPerPlaintext).
+// Note: unlike VDPF keys, the server cannot check that a selection vector is well-formed.
+
+// bits used for each field element packed into a Paillier plaintext
+const packedElementBits = 32
+
+// EncryptedQuery is an encrypted selection vector over a range of records
+type EncryptedQuery struct {
+	Selection []*paillier.Ciphertext // one ciphertext per record in the range
+}
+
+// EncryptedBatchQuery contains one encrypted query per batch
+type EncryptedBatchQuery struct {
+	Queries []*EncryptedQuery
+}
+
+// EncryptedQueryResult contains the encryption of the resulting record
+type EncryptedQueryResult struct {
+	Ciphertexts []*paillier.Ciphertext // packed field elements of the record (see ElementsPerPlaintext)
+
+	// encryption of 1 if the selected record is non-empty (its first field element is non-zero)
+	// and of 0 otherwise; used by the server to mask the results (see server.encryptedMasking)
+	Found *paillier.Ciphertext
+}
+
+// ElementsPerPlaintext returns the number of field elements packed into each plaintext
+func ElementsPerPlaintext(pk *paillier.PublicKey) int {
+	return (pk.N.BitLen() - 1) / packedElementBits
+}
+
+// NewEncryptedIndexQuery generates an encrypted selection vector for the
+// record at index of a range of size records
+func (dbmd *DBMetadata) NewEncryptedIndexQuery(pk *paillier.PublicKey, index, size int) *EncryptedQuery {
+	if index < 0 || index >= size {
+		panic("index out of range")
+	}
+	return newEncryptedQuery(pk, index, size)
+}
+
+// NewEncryptedEmptyQuery generates an encrypted selection vector that selects no record
+// (the result is an encryption of the all-zero record)
+func (dbmd *DBMetadata) NewEncryptedEmptyQuery(pk *paillier.PublicKey, size int) *EncryptedQuery {
+	return newEncryptedQuery(pk, -1, size)
+}
+
+func newEncryptedQuery(pk *paillier.PublicKey, index, size int) *EncryptedQuery {
+	query := &EncryptedQuery{Selection: make([]*paillier.Ciphertext, size)}
+	for i := range query.Selection {
+		if i == index {
+			query.Selection[i] = pk.EncryptOne()
+		} else {
+			query.Selection[i] = pk.EncryptZero()
+		}
+	}
+	return query
+}
+
+// PrivateEncryptedQuery uses the encrypted selection vector to retrieve a record
+func (db *Database) PrivateEncryptedQuery(pk *paillier.PublicKey, query *EncryptedQuery) (*EncryptedQueryResult, error) {
+	return db.PrivateEncryptedQueryInRange(pk, query, 0, db.DBSize)
+}
+
+// PrivateEncryptedBatchQuery uses the encrypted selection vector of each batch
+// to retrieve a record from each batch (see SetBatchingParameters)
+func (db *Database) PrivateEncryptedBatchQuery(pk *paillier.PublicKey, batchQuery *EncryptedBatchQuery) ([]*EncryptedQueryResult, error) {
+
+	if db.BatchSize == 0 {
+		panic("no batching parameters specified")
+	}
+
+	if batchQuery == nil || len(batchQuery.Queries) != db.BatchSize {
+		return nil, errors.New("number of queries does not match number of batches")
+	}
+
+	var err error
+	results := make([]*EncryptedQueryResult, db.BatchSize)
+	for b := 0; b < db.BatchSize; b++ {
+		results[b], err = db.PrivateEncryptedQueryInRange(pk, batchQuery.Queries[b], db.BatchStarts[b], db.BatchStops[b])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// PrivateEncryptedQueryInRange computes the encryption of the record selected in the range
+// start: index of the first record
+// stop: index after the last record
+func (db *Database) PrivateEncryptedQueryInRange(pk *paillier.PublicKey, query *EncryptedQuery, start, stop int) (*EncryptedQueryResult, error) {
+
+	if err := CheckPublicKey(pk); err != nil {
+		return nil, err
+	}
+
+	if query == nil || len(query.Selection) != stop-start {
+		return nil, errors.New("size of the selection vector does not match the range")
+	}
+
+	n2 := pk.GetN2()
+	for _, c := range query.Selection {
+		if c == nil || c.C == nil || c.Level != paillier.EncLevelOne || c.C.Sign() <= 0 || c.C.Cmp(n2) >= 0 {
+			return nil, errors.New("malformed ciphertext")
+		}
+	}
+
+	perPlaintext := ElementsPerPlaintext(pk)
+	numPlaintexts := (db.SlotSize + perPlaintext - 1) / perPlaintext
+
+	// product of the selection ciphertexts raised to the (packed) records
+	// starts from a fresh encryption of zero so that the result is rerandomized
+	result := &EncryptedQueryResult{Ciphertexts: make([]*paillier.Ciphertext, numPlaintexts)}
+	for c := range result.Ciphertexts {
+		result.Ciphertexts[c] = pk.EncryptZero()
+	}
+	result.Found = pk.EncryptZero()
+
+	for row := start; row < stop; row++ {
+		record := db.Record(row)
+		if record[0] != 0 {
+			result.Found = pk.Add(result.Found, query.Selection[row-start])
+		}
+
+		plaintexts := packElements(record, perPlaintext)
+		for c, m := range plaintexts {
+			if m.Sign() == 0 {
+				continue
+			}
+			selected := pk.ConstMult(query.Selection[row-start], m)
+			result.Ciphertexts[c] = pk.Add(result.Ciphertexts[c], selected)
+		}
+	}
+
+	return result, nil
+}
+
+// RecoverEncrypted decrypts a record of slotSize field elements
+func RecoverEncrypted(sk *paillier.SecretKey, res *EncryptedQueryResult, slotSize int) []field.FP {
+	plaintexts := make([]*gmp.Int, len(res.Ciphertexts))
+	for c := range res.Ciphertexts {
+		plaintexts[c] = sk.Decrypt(res.Ciphertexts[c])
+	}
+
+	return unpackElements(plaintexts, ElementsPerPlaintext(&sk.PublicKey), slotSize)
+}
+
+// packElements packs the elements into plaintexts of perPlaintext elements
+// (element e of a plaintext is stored in bits [32e, 32e+32))
+func packElements(elements []field.FP, perPlaintext int) []*gmp.Int {
+	plaintexts := make([]*gmp.Int, (len(elements)+perPlaintext-1)/perPlaintext)
+	for c := range plaintexts {
+		m := new(gmp.Int)
+		end := (c + 1) * perPlaintext
+		if end > len(elements) {
+			end = len(elements)
+		}
+		for e := end - 1; e >= c*perPlaintext; e-- {
+			m.Lsh(m, packedElementBits)
+			m.Add(m, new(gmp.Int).SetUint64(uint64(elements[e])))
+		}
+		plaintexts[c] = m
+	}
+	return plaintexts
+}
+
+// unpackElements reverses packElements
+// (masked records decode to arbitrary values)
+func unpackElements(plaintexts []*gmp.Int, perPlaintext int, numElements int) []field.FP {
+	elements := make([]field.FP, numElements)
+	mask := new(gmp.Int).Sub(new(gmp.Int).Lsh(gmp.NewInt(1), packedElementBits), gmp.NewInt(1))
+	for c, p := range plaintexts {
+		m := new(gmp.Int).Set(p)
+		for e := c * perPlaintext; e < numElements && e < (c+1)*perPlaintext; e++ {
+			elements[e] = field.FP(new(gmp.Int).And(m, mask).Uint64())
+			m.Rsh(m, packedElementBits)
+		}
+	}
+	return elements
+}
+
+// CheckPublicKey makes sure the public key can be used for homomorphic operations
+func CheckPublicKey(pk *paillier.PublicKey) error {
+	if pk == nil || pk.N == nil || pk.G == nil || pk.N.BitLen() <= 2*packedElementBits {
+		return errors.New("invalid public key")
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package pir
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/sachaservan/paillier"
+	"github.com/sachaservan/private-ann/pir/field"
+)
+
+// small keys keep the tests fast (not secure)
+const TestPaillierBits = 512
+const TestEncryptedDBSize = 1 << 6
+
+func TestEncryptedQuery(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(TestPaillierBits)
+
+	// records that span several plaintexts
+	perPlaintext := ElementsPerPlaintext(pk)
+	for _, numBytes := range []int{SlotBytes, 4 * perPlaintext * 3} {
+		db := GenerateRandomDB(TestEncryptedDBSize, numBytes)
+
+		for i := 0; i < 5; i++ {
+			qIndex := rand.Intn(db.DBSize)
+			query := db.NewEncryptedIndexQuery(pk, qIndex, db.DBSize)
+
+			res, err := db.PrivateEncryptedQuery(pk, query)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			record := RecoverEncrypted(sk, res, db.SlotSize)
+			if !equalRecords(db.Record(qIndex), record) {
+				t.Fatalf("Query result is incorrect. %v != %v\n", db.Record(qIndex), record)
+			}
+		}
+
+		// the empty query selects the all-zero record
+		res, err := db.PrivateEncryptedQuery(pk, db.NewEncryptedEmptyQuery(pk, db.DBSize))
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if !equalRecords(make([]field.FP, db.SlotSize), RecoverEncrypted(sk, res, db.SlotSize)) {
+			t.Fatalf("empty query returned a non-zero record")
+		}
+	}
+}
+
+func TestEncryptedBatchQuery(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(TestPaillierBits)
+
+	db := GenerateRandomDB(TestEncryptedDBSize, SlotBytes)
+	numBatches := 4
+	starts := make([]int, numBatches)
+	stops := make([]int, numBatches)
+	for b := 0; b < numBatches; b++ {
+		starts[b] = b * db.DBSize / numBatches
+		stops[b] = (b + 1) * db.DBSize / numBatches
+	}
+	if err := db.SetBatchingParameters(numBatches, starts, stops); err != nil {
+		t.Fatal(err)
+	}
+
+	indices := make([]int, numBatches)
+	batchQuery := &EncryptedBatchQuery{Queries: make([]*EncryptedQuery, numBatches)}
+	for b := range batchQuery.Queries {
+		indices[b] = rand.Intn(stops[b] - starts[b])
+		batchQuery.Queries[b] = db.NewEncryptedIndexQuery(pk, indices[b], stops[b]-starts[b])
+	}
+
+	res, err := db.PrivateEncryptedBatchQuery(pk, batchQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for b := range res {
+		record := RecoverEncrypted(sk, res[b], db.SlotSize)
+		if !equalRecords(db.Record(starts[b]+indices[b]), record) {
+			t.Fatalf("batch %v result is incorrect", b)
+		}
+		if found := sk.Decrypt(res[b].Found).Int64(); (found == 1) != (record[0] != 0) || found > 1 {
+			t.Fatalf("batch %v indicator is %v", b, found)
+		}
+	}
+
+	// selection vectors must match the batch sizes
+	batchQuery.Queries[0] = db.NewEncryptedIndexQuery(pk, 0, 1)
+	if _, err := db.PrivateEncryptedBatchQuery(pk, batchQuery); err == nil {
+		t.Fatalf("selection vector of the wrong size was accepted")
+	}
+}
+
+func TestPackElements(t *testing.T) {
+	elements := make([]field.FP, 10)
+	for i := range elements {
+		elements[i] = field.RandomFieldElement()
+	}
+
+	for _, perPlaintext := range []int{1, 3, 10, 15} {
+		unpacked := unpackElements(packElements(elements, perPlaintext), perPlaintext, len(elements))
+		if !equalRecords(elements, unpacked) {
+			t.Fatalf("unpacked elements do not match: %v != %v", elements, unpacked)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package pir
 
 import (
+	"bytes"
+	"errors"
+
 	"github.com/sachaservan/private-ann/pir/dpfc"
 	"github.com/sachaservan/private-ann/pir/field"
 )
@@ -12,6 +15,16 @@ type QueryShare struct {
 	PrfKey         dpfc.PrfKey
 	ShareNumber    uint
 	IsKeywordBased bool
+
+	// (for verifiable queries) keys of the hash functions used in the VDPF proof
+	IsVerifiable bool
+	H1Key        dpfc.HashKey
+	H2Key        dpfc.HashKey
+
+	// (for verifiable queries) shares of a random a and of a^2, with which the servers
+	// check that the DPF outputs 1 at the selected point (see SketchCheckShare)
+	SketchMask       field.FP
+	SketchMaskSquare field.FP
 }
 
 // BatchQueryShare is a secret share of a batch query over the database
@@ -30,6 +43,18 @@ func (dbmd *DBMetadata) NewKeywordQueryShares(keyword uint64, numShares uint, ra
 	return dbmd.newQueryShares(keyword, numShares, false, rangeBits)
 }
 
+// NewVerifiableIndexQueryShares generates PIR query shares for the index
+// using a verifiable DPF
+func (dbmd *DBMetadata) NewVerifiableIndexQueryShares(index uint64, numShares uint, rangeBits uint) []*QueryShare {
+	return dbmd.newVerifiableQueryShares(index, numShares, true, rangeBits)
+}
+
+// NewVerifiableKeywordQueryShares generates keyword-based PIR query shares
+// for keyword using a verifiable DPF
+func (dbmd *DBMetadata) NewVerifiableKeywordQueryShares(keyword uint64, numShares uint, rangeBits uint) []*QueryShare {
+	return dbmd.newVerifiableQueryShares(keyword, numShares, false, rangeBits)
+}
+
 // NewQueryShares generates random PIR query shares for the index
 func (dbmd *DBMetadata) newQueryShares(key uint64, numShares uint, isIndexQuery bool, rangeBits uint) []*QueryShare {
 
@@ -60,13 +85,106 @@ func (dbmd *DBMetadata) newQueryShares(key uint64, numShares uint, isIndexQuery
 	return shares
 }
 
-// Recover combines shares of slots to recover the data
-func Recover(resShares []*SecretSharedQueryResult) field.FP {
+// newVerifiableQueryShares generates random VDPF-based PIR query shares for the index
+func (dbmd *DBMetadata) newVerifiableQueryShares(key uint64, numShares uint, isIndexQuery bool, rangeBits uint) []*QueryShare {
 
-	res := field.FP(0)
+	if numShares != 2 {
+		panic("only two-server DPF supported")
+	}
+
+	client := dpfc.ClientVDPFInitialize()
+
+	keyA, keyB := client.GenVDPFKeys(key, rangeBits)
+
+	a := field.SecureRandomFieldElement()
+	maskShares := shareOf(a, numShares)
+	maskSquareShares := shareOf(field.Multiply(a, a), numShares)
+
+	shares := make([]*QueryShare, numShares)
+	for i := 0; i < int(numShares); i++ {
+		shares[i] = &QueryShare{}
+		shares[i].ShareNumber = uint(i)
+		shares[i].PrfKey = client.PrfKey
+		shares[i].IsKeywordBased = !isIndexQuery
+		shares[i].IsVerifiable = true
+		shares[i].H1Key = client.H1Key
+		shares[i].H2Key = client.H2Key
+		shares[i].SketchMask = maskShares[i]
+		shares[i].SketchMaskSquare = maskSquareShares[i]
+
+		if i == 0 {
+			shares[i].DPFKey = keyA
+		} else {
+			shares[i].DPFKey = keyB
+		}
+	}
+
+	client.Free()
+
+	return shares
+}
+
+// shareOf splits the value into random additive shares
+func shareOf(value field.FP, numShares uint) []field.FP {
+	shares := make([]field.FP, numShares)
+	shares[0] = value
+	for i := 1; i < int(numShares); i++ {
+		shares[i] = field.SecureRandomFieldElement()
+		shares[0] = field.Add(shares[0], field.Negate(shares[i]))
+	}
+	return shares
+}
+
+// CheckWellFormed returns an error if the query share is malformed
+func (query *QueryShare) CheckWellFormed() error {
+	if query == nil || !query.DPFKey.IsWellFormed() {
+		return errors.New("malformed DPF key")
+	}
+
+	if query.DPFKey.Index != uint64(query.ShareNumber) {
+		return errors.New("DPF key index does not match share number")
+	}
+
+	if field.Reduce(uint64(query.SketchMask)) != query.SketchMask || field.Reduce(uint64(query.SketchMaskSquare)) != query.SketchMaskSquare {
+		return errors.New("sketch masks should be field elements")
+	}
+
+	return nil
+}
+
+// VerifyProofs returns true if all the (verifiable) result shares carry
+// the same VDPF proof, i.e., the query selects at most one of the evaluated points.
+// The proofs say nothing about the answers: the servers compare them with each other
+// before answering the query (together with its sketch, see SketchCheckShare)
+func VerifyProofs(resShares []*SecretSharedQueryResult) bool {
+	for _, s := range resShares {
+		if s.Proof == nil || !bytes.Equal(s.Proof, resShares[0].Proof) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Recover combines shares of a record to recover the data
+func Recover(resShares []*SecretSharedQueryResult) []field.FP {
+
+	res := make([]field.FP, len(resShares[0].Shares))
 	for _, s := range resShares {
-		res = field.Add(res, s.Share)
+		if len(s.Shares) != len(res) {
+			panic("result shares have different record sizes")
+		}
+
+		for e := range res {
+			res[e] = field.Add(res[e], s.Shares[e])
+		}
 	}
 
 	return res
 }
+
+// RecoverBytes combines shares of a record built with BuildForBytes
+// to recover the first numBytes bytes of the record
+func RecoverBytes(resShares []*SecretSharedQueryResult, numBytes int) []byte {
+	return ElementsToBytes(Recover(resShares), numBytes)
+}
@@ -0,0 +1,75 @@
+package pir
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/sachaservan/private-ann/pir/field"
+)
+
+// The VDPF proofs (see VerifyProofs) show that a verifiable query selects at most one of the
+// evaluated points, but not that the DPF outputs 1 there: the client could scale the selected
+// record (and its Found indicator) by any value. The servers check the output with a random
+// linear sketch (Boyle, Gilboa and Ishai, "Function Secret Sharing: Improvements and Extensions").
+// For coefficients r_x that the client cannot predict, the DPF outputs y_x give
+//   z = sum_x r_x*y_x   and   z' = sum_x r_x^2*y_x
+// and z^2 = z' iff the (single) non-zero output is 1, except with probability 2/p.
+// The servers compute shares of z^2 with the shares of a random a and a^2 sent by the client:
+// they open d = z - a (see SketchMaskedShare) and then w = a^2 + 2*d*a + d^2 - z'
+// (see SketchCheckShare), which is 0 for well-formed queries.
+
+// SketchKey seeds the coefficients of the sketch; the servers agree on it after
+// receiving the query so that the client cannot predict the coefficients
+type SketchKey []byte
+
+// Sketch contains the shares of the sketch of the DPF outputs of a query
+type Sketch struct {
+	Linear    field.FP // share of sum_x r_x*y_x
+	Quadratic field.FP // share of sum_x r_x^2*y_x
+}
+
+// sketch computes the sketch of the DPF outputs (bits) of the query over the
+// keyword (or index) range [start, stop)
+func (db *Database) sketch(query *QueryShare, key SketchKey, bits []field.FP, start, stop int) Sketch {
+	digest := sha256.Sum256(key)
+	block, err := aes.NewCipher(digest[:16])
+	if err != nil {
+		panic(err)
+	}
+
+	var sketch Sketch
+	for i, x := range db.queryIndices(query, start, stop) {
+		r := sketchCoefficient(block, x)
+		ry := field.Multiply(r, bits[i])
+		sketch.Linear = field.Add(sketch.Linear, ry)
+		sketch.Quadratic = field.Add(sketch.Quadratic, field.Multiply(r, ry))
+	}
+
+	return sketch
+}
+
+// sketchCoefficient returns the coefficient r_x of the evaluation point x
+func sketchCoefficient(block cipher.Block, x uint64) field.FP {
+	var in, out [aes.BlockSize]byte
+	binary.LittleEndian.PutUint64(in[:], x)
+	block.Encrypt(out[:], in[:])
+	return field.Reduce(binary.LittleEndian.Uint64(out[:]))
+}
+
+// SketchMaskedShare returns the share of d = z - a, which the servers open
+// (d reveals nothing about z since a is random)
+func (query *QueryShare) SketchMaskedShare(res *SecretSharedQueryResult) field.FP {
+	return field.Add(res.Sketch.Linear, field.Negate(query.SketchMask))
+}
+
+// SketchCheckShare returns the share of w = z^2 - z' computed with the opened d;
+// the shares of both servers add up to 0 iff the query passes the check
+func (query *QueryShare) SketchCheckShare(res *SecretSharedQueryResult, d field.FP) field.FP {
+	w := field.Add(query.SketchMaskSquare, field.Multiply(field.Add(d, d), query.SketchMask))
+	if query.ShareNumber == 0 {
+		w = field.Add(w, field.Multiply(d, d))
+	}
+	return field.Add(w, field.Negate(res.Sketch.Quadratic))
+}
@@ -0,0 +1,143 @@
+package pir
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/sachaservan/private-ann/pir/field"
+)
+
+// checkSketch runs the sketch check of both servers on their results
+func checkSketch(queries []*QueryShare, results []*SecretSharedQueryResult) bool {
+	d := field.FP(0)
+	for i, query := range queries {
+		d = field.Add(d, query.SketchMaskedShare(results[i]))
+	}
+
+	w := field.FP(0)
+	for i, query := range queries {
+		w = field.Add(w, query.SketchCheckShare(results[i], d))
+	}
+
+	return w == 0
+}
+
+func TestSketchWellFormedQuery(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	key := SketchKey("sketch key")
+
+	for i := 0; i < NumQueries; i++ {
+		shares := db.NewVerifiableIndexQueryShares(uint64(rand.Intn(db.DBSize)), 2, RangeSize)
+
+		resA, err := db.PrivateSecretSharedQuery(shares[0], key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resB, err := db.PrivateSecretSharedQuery(shares[1], key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !checkSketch(shares, []*SecretSharedQueryResult{resA, resB}) {
+			t.Fatalf("well-formed query failed the sketch check")
+		}
+	}
+}
+
+func TestSketchBatchQuery(t *testing.T) {
+	setup()
+
+	numBatches := 4
+	keys := make([]uint64, 200)
+	records := make([][]field.FP, len(keys))
+	for i := range keys {
+		keys[i] = uint64(2 + rand.Intn(1<<RangeSize-2))
+		records[i] = []field.FP{field.FP(i + 1)}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	db := NewDatabase()
+	if err := db.BuildForKeysAndRecords(keys, records); err != nil {
+		t.Fatal(err)
+	}
+	starts := make([]int, numBatches)
+	stops := make([]int, numBatches)
+	for b := range starts {
+		starts[b] = b * len(keys) / numBatches
+		stops[b] = (b + 1) * len(keys) / numBatches
+	}
+	if err := db.SetBatchingParameters(numBatches, starts, stops); err != nil {
+		t.Fatal(err)
+	}
+
+	batchA := &BatchQueryShare{}
+	batchB := &BatchQueryShare{}
+	for b := 0; b < numBatches; b++ {
+		keyword := db.Keywords[starts[b]]
+		if b == 0 {
+			keyword = 1 // absent keyword
+		}
+		shares := db.NewVerifiableKeywordQueryShares(keyword, 2, RangeSize)
+		batchA.Queries = append(batchA.Queries, shares[0])
+		batchB.Queries = append(batchB.Queries, shares[1])
+	}
+
+	key := SketchKey("sketch key")
+	resA, errs := db.PrivateSecretSharedMultiBatchQuery([]*BatchQueryShare{batchA}, []SketchKey{key})
+	if errs[0] != nil {
+		t.Fatal(errs[0])
+	}
+	resB, err := db.PrivateSecretSharedBatchQuery(batchB, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for b := 0; b < numBatches; b++ {
+		queries := []*QueryShare{batchA.Queries[b], batchB.Queries[b]}
+		if !checkSketch(queries, []*SecretSharedQueryResult{resA[0][b], resB[b]}) {
+			t.Fatalf("well-formed query failed the sketch check in batch %v", b)
+		}
+	}
+}
+
+func TestSketchScaledOutput(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	key := SketchKey("sketch key")
+	index := uint64(rand.Intn(db.DBSize))
+	shares := db.NewVerifiableIndexQueryShares(index, 2, RangeSize)
+
+	// shift the last correction word of both keys: the proofs still match
+	// but the DPF outputs 1 +/- 5 at the selected index
+	for _, share := range shares {
+		lastCW := share.DPFKey.Bytes[18*RangeSize+18:]
+		cw := field.Add(field.FP(binary.LittleEndian.Uint64(lastCW)), 5)
+		binary.LittleEndian.PutUint64(lastCW, uint64(cw))
+	}
+
+	resA, err := db.PrivateSecretSharedQuery(shares[0], key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resB, err := db.PrivateSecretSharedQuery(shares[1], key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := []*SecretSharedQueryResult{resA, resB}
+	if !VerifyProofs(results) {
+		t.Fatalf("expected the proofs to match")
+	}
+	if equalRecords(Recover(results), db.Record(int(index))) {
+		t.Fatalf("expected the record to be scaled")
+	}
+
+	if checkSketch(shares, results) {
+		t.Fatalf("query with a scaled output passed the sketch check")
+	}
+}
@@ -1,6 +1,6 @@
 #!/bin/bash
 
-# Runs the client, kills the servers, waits 10s and repeats the process, 100 times in total.  
+# Runs the client, which then shuts down the servers through their admin sockets (the servers then move to the next configuration), waits 5s and repeats the process, 100 times in total.
 #
 # example usage: 
 #     bash clicycle.sh
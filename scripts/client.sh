@@ -33,3 +33,10 @@ fi
     --experimentnumtrials ${ExperimentNumTrials} \
     ${boolargs[@]} \
 
+# shut down the servers (started by server.sh) so that they move to the next configuration
+go build -o ../bin/admin ../cmd/admin/main.go
+../bin/admin shutdown \
+    --socket ../bin/server0.sock \
+    --socket ../bin/server1.sock \
+    --tokenfile ../bin/admintoken
+
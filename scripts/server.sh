@@ -12,9 +12,10 @@ usage() {
     [--maxval <max coordinate value>] 
     [--pwm <projection width mean>] 
     [--pws <projection width std]
-    [--procs <degree of parallelism>]"
+    [--procs <degree of parallelism>]
+    [--seed <hex-encoded secret seed shared by both servers>]"
     echo "Example: 
-    bash server.sh --sid 0 --dataset mnist --cachedir cache --numtables 10 --numprobes 100 --bucketcap 1 --maxval 1000 --pwm 887.7 --pws 244.9 --procs 1"
+    bash server.sh --sid 0 --dataset mnist --cachedir cache --numtables 10 --numprobes 100 --bucketcap 1 --maxval 1000 --pwm 887.7 --pws 244.9 --procs 1 --seed 8a3f09c1d27e4b56a0f1c2d3e4f50617"
     1>&2; exit 1; 
 }
 
@@ -80,6 +81,11 @@ case $key in
     shift # past argument
     shift # past value
     ;;
+    --seed)
+    SEED="$2"
+    shift # past argument
+    shift # past value
+    ;;
     *)    # unknown option
     POSITIONAL+=("$1") # save it in an array for later
     shift # past argument
@@ -100,7 +106,8 @@ if  [ -z "${SERVID}" ] ||
     [ -z "${MAXVAL}" ] || 
     [ -z "${PWMEAN}" ] || 
     [ -z "${PWSTD}" ] || 
-    [ -z "${PROCS}" ]; then
+    [ -z "${PROCS}" ] ||
+    [ -z "${SEED}" ]; then
     usage
     exit
 fi
@@ -120,6 +127,10 @@ echo 'Num procs:  ' ${PROCS}
 # make sure cache directory exists 
 mkdir -p ${CACHEDIR}
 
+# admin token shared with client.sh, which shuts down the servers once the client is done
+mkdir -p ../bin
+[ -s ../bin/admintoken ] || (umask 077; head -c 16 /dev/urandom | od -An -tx1 | tr -d ' \n' > ../bin/admintoken)
+
 # build the server 
 go build -o ../bin/server ../cmd/server/main.go 
 ../bin/server \
@@ -134,5 +145,8 @@ go build -o ../bin/server ../cmd/server/main.go
     --maxcoordinatevalue ${MAXVAL} \
     --numprocs ${PROCS} \
     --bucketsize ${BUCKETCAP} \
+    --hashseed ${SEED} \
+    --adminsocket ../bin/server${SERVID}.sock \
+    --admintokenfile ../bin/admintoken \
 
 
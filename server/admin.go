@@ -0,0 +1,180 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"github.com/sachaservan/private-ann/cmd/api"
+)
+
+// Admin is the RPC service used by the operator to shut down, reload or update the server.
+// It is registered separately from the client API (clients cannot reach it) and
+// every request must carry the admin token.
+type Admin struct {
+	server *Server
+	token  []byte
+}
+
+// NewAdmin returns the admin service of the server; an empty token rejects all requests
+func NewAdmin(server *Server, token []byte) *Admin {
+	return &Admin{server: server, token: token}
+}
+
+// Shutdown stops accepting queries, waits for the in-flight queries to complete and kills the server
+func (admin *Admin) Shutdown(args *api.AdminArgs, reply *api.AdminResponse) error {
+	if err := admin.authenticate(args.Token); err != nil {
+		log.Printf("[Server]: rejected Shutdown request: %v", err)
+		return err
+	}
+
+	log.Printf("[Server]: received request to Shutdown")
+
+	admin.server.Shutdown()
+
+	return nil
+}
+
+// Reload rebuilds the tables (see Server.Reload) as a new version (see api.ReloadArgs)
+// that is served to the sessions opened after the reload. Queries are answered
+// during the reload; the open sessions keep querying the previous version.
+func (admin *Admin) Reload(args *api.ReloadArgs, reply *api.AdminResponse) error {
+	if err := admin.authenticate(args.Token); err != nil {
+		log.Printf("[Server]: rejected Reload request: %v", err)
+		return err
+	}
+
+	log.Printf("[Server]: received request to Reload")
+
+	version, err := admin.server.reload(args.Version)
+	if err != nil {
+		log.Printf("[Server]: failed to reload: %v", err)
+		return err
+	}
+
+	log.Printf("[Server]: reloaded tables (version %v)", version)
+
+	return nil
+}
+
+// Status returns the version of the tables and their digest
+// (the servers have identical tables iff they return the same status)
+func (admin *Admin) Status(args *api.AdminArgs, reply *api.AdminStatusResponse) error {
+	if err := admin.authenticate(args.Token); err != nil {
+		log.Printf("[Server]: rejected Status request: %v", err)
+		return err
+	}
+
+	if err := admin.server.beginRequest(); err != nil {
+		return err
+	}
+	defer admin.server.endRequest()
+
+	snapshot, err := admin.server.currentSnapshot()
+	if err != nil {
+		return err
+	}
+	*reply = *snapshot.status()
+
+	return nil
+}
+
+// Update inserts vectors into the tables (hashed with the hash functions of the tables)
+// and deletes ids from them; the item database (if served) is updated accordingly.
+// The same updates must be sent to both servers in the same order (see api.UpdateArgs).
+// Inserted vectors are dropped from the tables in which their bucket is full.
+// The updated tables are a new version (see api.UpdateArgs) served to the sessions
+// opened after the update; the open sessions keep querying the previous version.
+// Updates are not written to the cache and are lost when the tables are reloaded
+func (admin *Admin) Update(args *api.UpdateArgs, reply *api.UpdateResponse) error {
+	if err := admin.authenticate(args.Token); err != nil {
+		log.Printf("[Server]: rejected Update request: %v", err)
+		return err
+	}
+
+	log.Printf("[Server]: received request to Update")
+
+	res, err := admin.server.update(args)
+	if err != nil {
+		log.Printf("[Server]: failed to update: %v", err)
+		return err
+	}
+	*reply = *res
+
+	return nil
+}
+
+func (admin *Admin) authenticate(token string) error {
+	if len(admin.token) == 0 || subtle.ConstantTimeCompare(admin.token, []byte(token)) != 1 {
+		return errors.New("invalid admin token")
+	}
+	return nil
+}
+
+// beginRequest must be called (and followed by endRequest) by every API call
+// that reads the tables so that shutdowns wait for it to complete
+func (server *Server) beginRequest() error {
+	server.requests.RLock()
+	if server.draining {
+		server.requests.RUnlock()
+		return errors.New("server is shutting down")
+	}
+	return nil
+}
+
+func (server *Server) endRequest() {
+	server.requests.RUnlock()
+}
+
+// Shutdown rejects new requests, waits for the in-flight requests to complete and kills the server
+func (server *Server) Shutdown() {
+	server.requests.Lock()
+	server.draining = true
+	server.requests.Unlock()
+
+	atomic.StoreInt32(&server.killed, 1)
+}
+
+// Killed returns true once the server has been shut down (see Shutdown)
+func (server *Server) Killed() bool {
+	return atomic.LoadInt32(&server.killed) == 1
+}
+
+// reload rebuilds the tables as the version (0: the version following the current one)
+// and returns the version
+func (server *Server) reload(version int) (int, error) {
+	if server.Reload == nil {
+		return 0, errors.New("server does not support reloading")
+	}
+
+	// shutdowns wait for the reload
+	if err := server.beginRequest(); err != nil {
+		return 0, err
+	}
+	defer server.endRequest()
+
+	server.updates.Lock()
+	defer server.updates.Unlock()
+
+	next := 1
+	if current := server.Snapshot(); current != nil {
+		next = current.Version + 1
+	}
+	if version == 0 {
+		version = next
+	} else if version < next {
+		return 0, fmt.Errorf("version %v is not newer than the current version %v", version, next-1)
+	}
+
+	// the rebuilt tables do not contain the updates
+	snapshot, err := server.Reload()
+	if err != nil {
+		return 0, err
+	}
+	snapshot.Version = version
+	server.SetSnapshot(snapshot)
+
+	return version, nil
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sachaservan/private-ann/cmd/api"
+)
+
+func TestAdminAuthentication(t *testing.T) {
+	servers, _, _ := generateTestServers(1, 10, 2, 1, 8)
+	server := servers[0]
+
+	for _, admin := range []*Admin{NewAdmin(server, []byte("secret")), NewAdmin(server, nil)} {
+		for _, token := range []string{"", "secre", "secret2", "wrong!"} {
+			if err := admin.Shutdown(&api.AdminArgs{Token: token}, &api.AdminResponse{}); err == nil {
+				t.Fatalf("shutdown with token %q was accepted", token)
+			}
+		}
+	}
+
+	if server.Killed() {
+		t.Fatalf("server was killed without a valid token")
+	}
+
+	// clients cannot shut down the server by terminating their session
+	id := openTestSession(t, server)
+	if err := server.TerminateSession(&api.TerminateSessionArgs{SessionID: id}, &api.TerminateSessionResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if server.Killed() {
+		t.Fatalf("server was killed by a client")
+	}
+}
+
+func TestAdminShutdownDrains(t *testing.T) {
+	servers, _, _ := generateTestServers(1, 10, 2, 1, 8)
+	server := servers[0]
+	admin := NewAdmin(server, []byte("secret"))
+	id := openTestSession(t, server)
+
+	// in-flight request
+	if err := server.beginRequest(); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- admin.Shutdown(&api.AdminArgs{Token: "secret"}, &api.AdminResponse{})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if server.Killed() {
+		t.Fatalf("server was killed before the in-flight request completed")
+	}
+
+	server.endRequest()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !server.Killed() {
+		t.Fatalf("server was not killed")
+	}
+
+	// new requests are rejected
+	args := &api.ANNQueryArgs{SessionID: id}
+	if err := server.PrivateANNQuery(args, &api.ANNQueryResponse{}); err == nil {
+		t.Fatalf("query was accepted after shutdown")
+	}
+	if err := server.InitSession(api.InitSessionArgs{}, &api.InitSessionResponse{}); err == nil {
+		t.Fatalf("session was opened after shutdown")
+	}
+}
+
+func TestAdminReload(t *testing.T) {
+	servers, _, _ := generateTestServers(1, 10, 2, 1, 8)
+	server := servers[0]
+	admin := NewAdmin(server, []byte("secret"))
+	args := &api.ReloadArgs{AdminArgs: api.AdminArgs{Token: "secret"}}
+
+	if err := admin.Reload(args, &api.AdminResponse{}); err == nil {
+		t.Fatalf("reload was accepted without a reload function")
+	}
+
+	previous := server.Snapshot()
+	reloads := 0
+	server.Reload = func() (*Snapshot, error) {
+		reloads++
+		return &Snapshot{TableDBs: previous.TableDBs}, nil
+	}
+
+	id := openTestSession(t, server)
+	if err := admin.Reload(args, &api.AdminResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	if reloads != 1 || server.Snapshot().Version != previous.Version+1 {
+		t.Fatalf("expected 1 reload to version %v but got %v reloads to version %v", previous.Version+1, reloads, server.Snapshot().Version)
+	}
+
+	// sessions opened before the reload keep querying their version
+	if snapshot, err := server.sessionSnapshot(id, previous.Version); err != nil || snapshot != previous {
+		t.Fatalf("session did not keep its version of the tables")
+	}
+	if _, err := server.sessionSnapshot(openTestSession(t, server), previous.Version); err == nil {
+		t.Fatalf("query for another version than the version of the session was accepted")
+	}
+
+	// versions only increase
+	args.Version = server.Snapshot().Version
+	if err := admin.Reload(args, &api.AdminResponse{}); err == nil {
+		t.Fatalf("reload to the current version was accepted")
+	}
+	args.Version = 5
+	if err := admin.Reload(args, &api.AdminResponse{}); err != nil || server.Snapshot().Version != 5 {
+		t.Fatalf("reload to version 5 failed: %v", err)
+	}
+
+	// failed reloads are reported (and keep the tables)
+	server.Reload = func() (*Snapshot, error) { return nil, errors.New("failed") }
+	args.Version = 0
+	if err := admin.Reload(args, &api.AdminResponse{}); err == nil {
+		t.Fatalf("failed reload was not reported")
+	}
+	if server.Snapshot().Version != 5 {
+		t.Fatalf("failed reload replaced the tables")
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"log"
+	"time"
+
+	"github.com/sachaservan/private-ann/pir"
+)
+
+// QueryBatcher accumulates the PrivateANNQuery requests received within Window of each
+// other (up to MaxBatchSize requests) and evaluates them together: each table is evaluated
+// once per batch (see pir.Database.PrivateSecretSharedMultiBatchQuery) so that the records
+// of each partition are scanned once for all the queries of the batch.
+type QueryBatcher struct {
+	MaxBatchSize int
+	Window       time.Duration
+
+	pending chan *batchedQuery
+}
+
+// batchedQuery is a request waiting for its batch to be evaluated
+type batchedQuery struct {
+	snapshot *Snapshot                        // tables of the session of the request
+	queries  []*pir.BatchQueryShare           // one batch query per table
+	key      pir.SketchKey                    // key of the sketches of the request
+	results  [][]*pir.SecretSharedQueryResult // results of each table
+	errs     []error                          // error of each table
+	done     chan struct{}
+}
+
+// StartQueryBatching batches the PrivateANNQuery requests of concurrent clients
+// (see QueryBatcher); a request waits at most window for other requests to join its batch
+func (server *Server) StartQueryBatching(maxBatchSize int, window time.Duration) {
+	if maxBatchSize <= 0 {
+		panic("batch size must be at least 1")
+	}
+
+	server.Batcher = &QueryBatcher{
+		MaxBatchSize: maxBatchSize,
+		Window:       window,
+		pending:      make(chan *batchedQuery),
+	}
+	go server.batchLoop(server.Batcher)
+}
+
+// submit adds the request to the next batch and waits for its results
+func (batcher *QueryBatcher) submit(server *Server, snapshot *Snapshot, queries []*pir.BatchQueryShare, key pir.SketchKey) ([][]*pir.SecretSharedQueryResult, error) {
+	if server.Scheduler != nil {
+		if err := server.Scheduler.admit(); err != nil {
+			return nil, err
+		}
+		defer server.Scheduler.release()
+	}
+
+	q := &batchedQuery{
+		snapshot: snapshot,
+		queries:  queries,
+		key:      key,
+		results:  make([][]*pir.SecretSharedQueryResult, len(queries)),
+		errs:     make([]error, len(queries)),
+		done:     make(chan struct{}),
+	}
+	batcher.pending <- q
+	<-q.done
+
+	for _, err := range q.errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return q.results, nil
+}
+
+func (server *Server) batchLoop(batcher *QueryBatcher) {
+	for first := range batcher.pending {
+		batch := []*batchedQuery{first}
+
+		timer := time.NewTimer(batcher.Window)
+	collect:
+		for len(batch) < batcher.MaxBatchSize {
+			select {
+			case q := <-batcher.pending:
+				batch = append(batch, q)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		// the next batch is collected while this one is evaluated
+		go server.evaluateBatch(batch)
+	}
+}
+
+func (server *Server) evaluateBatch(batch []*batchedQuery) {
+	log.Printf("[Server]: evaluating a batch of %v PrivateANNQuery requests", len(batch))
+
+	// requests of sessions opened on different versions of the tables are evaluated separately
+	var snapshots []*Snapshot
+	groups := make(map[*Snapshot][]*batchedQuery)
+	for _, q := range batch {
+		if groups[q.snapshot] == nil {
+			snapshots = append(snapshots, q.snapshot)
+		}
+		groups[q.snapshot] = append(groups[q.snapshot], q)
+	}
+
+	for _, snapshot := range snapshots {
+		group := groups[snapshot]
+		server.executeTasks(server.NumTables, func(t int) {
+			queries := make([]*pir.BatchQueryShare, len(group))
+			keys := make([]pir.SketchKey, len(group))
+			for i, q := range group {
+				queries[i] = q.queries[t]
+				keys[i] = q.key
+			}
+
+			results, errs := snapshot.TableDBs[t].PrivateSecretSharedMultiBatchQuery(queries, keys)
+			for i, q := range group {
+				q.results[t] = results[i]
+				q.errs[t] = errs[i]
+			}
+		})
+	}
+
+	for _, q := range batch {
+		close(q.done)
+	}
+}
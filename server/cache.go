@@ -0,0 +1,334 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"unsafe"
+
+	"github.com/sachaservan/private-ann/pir/field"
+)
+
+// CacheVersion is the version of the binary hash table cache format (bump it whenever
+// the layout or the way the tables are built changes; older caches are then rebuilt)
+const CacheVersion = 6
+
+// cacheMagic identifies binary hash table cache files
+var cacheMagic = [8]byte{'P', 'A', 'N', 'N', 'H', 'T', 'B', 'L'}
+
+// CachedHashTable contains the keys and (encoded) buckets of a hash table
+type CachedHashTable struct {
+	Dimension int
+	N         int
+	TestQuery []float64
+	Keys      []uint64
+	Values    [][]field.FP // bucket of encoded ids for each key
+
+	// encoded parameters of the hash function used to build the table (see
+	// hash.MultiLatticeHash.EncodeParameters and hash.HyperplaneHash.EncodeParameters)
+	HashParameters []byte
+}
+
+// CacheHeader describes how a cached hash table was built.
+// A cache is only valid if it was built with the same parameters
+// (see Matches); Dimension and N are informational.
+//
+// Binary cache layout (little endian, every section 8-byte aligned):
+//
+//	magic [8]byte | header | test query length (uint64) | test query ([]float64)
+//	| hash parameters length (uint64) | hash parameters (zero padded to 8 bytes)
+//	| number of keys (uint64) | sorted keys ([]uint64) | buckets ([]uint64, BucketSize per key)
+type CacheHeader struct {
+	Version               uint32
+	Table                 uint32 // index of the table
+	NumTables             uint32
+	BucketSize            uint32
+	HashFunctionRange     uint32
+	MaxCoordinateValue    uint32
+	DistanceMetric        uint32 // see ann.DistanceMetric
+	NumHyperplanes        uint32 // hyperplanes of the angular hash functions (0 for the euclidean metric)
+	Quantized             uint32 // 1 if the tables were built from quantized vectors (see ann.Matrix.Quantize)
+	ProjectionWidthMean   float64
+	ProjectionWidthStddev float64
+	DatasetChecksum       [32]byte // sampled digest of the dataset files (see ann.DatasetChecksum)
+	HashSeedDigest        [32]byte // SHA-256 of the hash seed (the seed itself is never written)
+	Dimension             uint64
+	N                     uint64
+}
+
+// Matches returns an error describing the first parameter
+// that differs between the header and the expected header
+func (h *CacheHeader) Matches(expected *CacheHeader) error {
+	switch {
+	case h.Version != expected.Version:
+		return fmt.Errorf("cache version %v (expected %v)", h.Version, expected.Version)
+	case h.Table != expected.Table:
+		return fmt.Errorf("cache is for table %v (expected %v)", h.Table, expected.Table)
+	case h.NumTables != expected.NumTables:
+		return fmt.Errorf("cache built for %v tables (expected %v)", h.NumTables, expected.NumTables)
+	case h.BucketSize != expected.BucketSize:
+		return fmt.Errorf("cache built with bucket size %v (expected %v)", h.BucketSize, expected.BucketSize)
+	case h.HashFunctionRange != expected.HashFunctionRange:
+		return fmt.Errorf("cache built with hash range %v (expected %v)", h.HashFunctionRange, expected.HashFunctionRange)
+	case h.MaxCoordinateValue != expected.MaxCoordinateValue:
+		return fmt.Errorf("cache built with max coordinate value %v (expected %v)", h.MaxCoordinateValue, expected.MaxCoordinateValue)
+	case h.DistanceMetric != expected.DistanceMetric:
+		return fmt.Errorf("cache built for distance metric %v (expected %v)", h.DistanceMetric, expected.DistanceMetric)
+	case h.NumHyperplanes != expected.NumHyperplanes:
+		return fmt.Errorf("cache built with %v hyperplanes (expected %v)", h.NumHyperplanes, expected.NumHyperplanes)
+	case h.Quantized != expected.Quantized:
+		return fmt.Errorf("cache built with quantized vectors %v (expected %v)", h.Quantized == 1, expected.Quantized == 1)
+	case h.ProjectionWidthMean != expected.ProjectionWidthMean || h.ProjectionWidthStddev != expected.ProjectionWidthStddev:
+		return fmt.Errorf("cache built with projection width %v±%v (expected %v±%v)",
+			h.ProjectionWidthMean, h.ProjectionWidthStddev, expected.ProjectionWidthMean, expected.ProjectionWidthStddev)
+	case h.DatasetChecksum != expected.DatasetChecksum:
+		return errors.New("cache built for a different dataset")
+	case h.HashSeedDigest != expected.HashSeedDigest:
+		return errors.New("cache built with a different hash seed")
+	}
+
+	return nil
+}
+
+// WriteCachedTable writes the hash table in the binary cache format.
+// The file is written to a temporary file first and then renamed so that
+// a crash never leaves a partially written cache behind.
+func WriteCachedTable(path string, header *CacheHeader, table *CachedHashTable) error {
+	if err := checkTable(table, int(header.BucketSize)); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := *header
+	h.Dimension = uint64(table.Dimension)
+	h.N = uint64(table.N)
+
+	w := bufio.NewWriter(tmp)
+	le := binary.LittleEndian
+	write := func(data interface{}) {
+		if err == nil {
+			err = binary.Write(w, le, data)
+		}
+	}
+
+	write(cacheMagic)
+	write(&h)
+	write(make([]byte, headerPadding()))
+	write(uint64(len(table.TestQuery)))
+	write(table.TestQuery)
+	write(uint64(len(table.HashParameters)))
+	write(table.HashParameters)
+	write(make([]byte, padding(len(table.HashParameters))))
+	write(uint64(len(table.Keys)))
+	write(table.Keys)
+	for _, bucket := range table.Values {
+		write(bucket)
+	}
+
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// ReadCachedTable reads a hash table written by WriteCachedTable.
+// The file is read in one piece and the keys and buckets of the table
+// point into it (the PIR databases copy them, see pir.Database.BuildForKeysAndRecords).
+func ReadCachedTable(path string) (*CacheHeader, *CachedHashTable, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header, table, err := decodeCachedTable(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid cache %v: %v", path, err)
+	}
+
+	return header, table, nil
+}
+
+func decodeCachedTable(data []byte) (*CacheHeader, *CachedHashTable, error) {
+	r := bytes.NewReader(data)
+	le := binary.LittleEndian
+
+	var magic [8]byte
+	if err := binary.Read(r, le, &magic); err != nil || magic != cacheMagic {
+		return nil, nil, errors.New("not a hash table cache")
+	}
+
+	header := &CacheHeader{}
+	if err := binary.Read(r, le, header); err != nil {
+		return nil, nil, errors.New("truncated header")
+	}
+
+	// check the version before interpreting the rest of the file
+	if header.Version != CacheVersion {
+		return nil, nil, fmt.Errorf("unsupported cache version %v", header.Version)
+	}
+
+	if header.BucketSize == 0 {
+		return nil, nil, errors.New("invalid bucket size")
+	}
+
+	offset := len(data) - r.Len() + headerPadding()
+
+	readUint64s := func(n uint64) ([]uint64, error) {
+		if offset > len(data) || n > uint64(len(data)-offset)/8 {
+			return nil, errors.New("truncated cache")
+		}
+		res := bytesToUint64s(data[offset : offset+8*int(n)])
+		offset += 8 * int(n)
+		return res, nil
+	}
+
+	length, err := readUint64s(1)
+	if err != nil {
+		return nil, nil, err
+	}
+	testQuery, err := readUint64s(length[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	length, err = readUint64s(1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if offset > len(data) || length[0] > uint64(len(data)-offset) {
+		return nil, nil, errors.New("truncated cache")
+	}
+	hashParameters := data[offset : offset+int(length[0])]
+	offset += int(length[0]) + padding(int(length[0]))
+
+	numKeys, err := readUint64s(1)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys, err := readUint64s(numKeys[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	if numKeys[0] > math.MaxUint64/uint64(header.BucketSize) {
+		return nil, nil, errors.New("truncated cache")
+	}
+	values, err := readUint64s(numKeys[0] * uint64(header.BucketSize))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if offset != len(data) {
+		return nil, nil, errors.New("unexpected trailing data")
+	}
+
+	table := &CachedHashTable{
+		Dimension:      int(header.Dimension),
+		N:              int(header.N),
+		TestQuery:      uint64sToFloat64s(testQuery),
+		Keys:           keys,
+		Values:         make([][]field.FP, len(keys)),
+		HashParameters: hashParameters,
+	}
+
+	buckets := uint64sToFPs(values)
+	bucketSize := int(header.BucketSize)
+	for i := range table.Values {
+		table.Values[i] = buckets[i*bucketSize : (i+1)*bucketSize : (i+1)*bucketSize]
+	}
+
+	if err := checkTable(table, bucketSize); err != nil {
+		return nil, nil, err
+	}
+
+	return header, table, nil
+}
+
+// checkTable makes sure the keys are sorted and every bucket has bucketSize elements
+func checkTable(table *CachedHashTable, bucketSize int) error {
+	if len(table.Keys) != len(table.Values) {
+		return errors.New("number of keys and buckets do not match")
+	}
+
+	for i := range table.Keys {
+		if i > 0 && table.Keys[i-1] >= table.Keys[i] {
+			return errors.New("keys are not sorted")
+		}
+		if len(table.Values[i]) != bucketSize {
+			return errors.New("bucket does not match the bucket size")
+		}
+	}
+
+	return nil
+}
+
+// headerPadding returns the number of bytes needed after
+// the header to align the following sections to 8 bytes
+func headerPadding() int {
+	return padding(len(cacheMagic) + binary.Size(CacheHeader{}))
+}
+
+// padding returns the number of bytes needed to align size bytes to 8 bytes
+func padding(size int) int {
+	return (8 - size%8) % 8
+}
+
+// bytesToUint64s interprets little-endian bytes as uint64s
+// without copying when the host layout allows it
+func bytesToUint64s(b []byte) []uint64 {
+	if len(b) == 0 {
+		return []uint64{}
+	}
+
+	if isLittleEndian() && uintptr(unsafe.Pointer(&b[0]))%8 == 0 {
+		var res []uint64
+		hdr := (*reflect.SliceHeader)(unsafe.Pointer(&res))
+		hdr.Data = uintptr(unsafe.Pointer(&b[0]))
+		hdr.Len = len(b) / 8
+		hdr.Cap = len(b) / 8
+		return res
+	}
+
+	res := make([]uint64, len(b)/8)
+	for i := range res {
+		res[i] = binary.LittleEndian.Uint64(b[8*i:])
+	}
+	return res
+}
+
+func uint64sToFPs(v []uint64) []field.FP {
+	return *(*[]field.FP)(unsafe.Pointer(&v))
+}
+
+func uint64sToFloat64s(v []uint64) []float64 {
+	return *(*[]float64)(unsafe.Pointer(&v))
+}
+
+func isLittleEndian() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}
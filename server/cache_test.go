@@ -0,0 +1,183 @@
+package server
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sachaservan/private-ann/ann"
+	"github.com/sachaservan/private-ann/pir/field"
+)
+
+func generateTestCache(numKeys, bucketSize int) (*CacheHeader, *CachedHashTable) {
+	header := &CacheHeader{
+		Version:               CacheVersion,
+		Table:                 3,
+		NumTables:             10,
+		BucketSize:            uint32(bucketSize),
+		HashFunctionRange:     30,
+		MaxCoordinateValue:    1000,
+		ProjectionWidthMean:   887.7,
+		ProjectionWidthStddev: 244.9,
+	}
+	rand.Read(header.DatasetChecksum[:])
+	rand.Read(header.HashSeedDigest[:])
+
+	table := &CachedHashTable{
+		Dimension: 5,
+		N:         1000,
+		TestQuery: []float64{1.5, -2, 3.25, 0, 1e10},
+	}
+	table.HashParameters = make([]byte, 1+rand.Intn(100))
+	rand.Read(table.HashParameters)
+	for i := 0; i < numKeys; i++ {
+		table.Keys = append(table.Keys, uint64(i)*1000+uint64(rand.Intn(1000)))
+		bucket := make([]field.FP, bucketSize)
+		for l := 0; l < 1+rand.Intn(bucketSize); l++ {
+			bucket[l] = ann.EncodeID(uint32(rand.Intn(table.N)))
+		}
+		table.Values = append(table.Values, bucket)
+	}
+
+	return header, table
+}
+
+func checkTablesEqual(t *testing.T, a, b *CachedHashTable) {
+	if a.Dimension != b.Dimension || a.N != b.N {
+		t.Fatalf("table sizes do not match")
+	}
+
+	if len(a.TestQuery) != len(b.TestQuery) || len(a.Keys) != len(b.Keys) {
+		t.Fatalf("table lengths do not match")
+	}
+
+	if !bytes.Equal(a.HashParameters, b.HashParameters) {
+		t.Fatalf("hash parameters do not match")
+	}
+
+	for i := range a.TestQuery {
+		if a.TestQuery[i] != b.TestQuery[i] {
+			t.Fatalf("test query does not match at %v: %v != %v", i, a.TestQuery[i], b.TestQuery[i])
+		}
+	}
+
+	for i := range a.Keys {
+		if a.Keys[i] != b.Keys[i] {
+			t.Fatalf("key %v does not match: %v != %v", i, a.Keys[i], b.Keys[i])
+		}
+		for l := range a.Values[i] {
+			if a.Values[i][l] != b.Values[i][l] {
+				t.Fatalf("bucket %v does not match: %v != %v", i, a.Values[i], b.Values[i])
+			}
+		}
+	}
+}
+
+func TestCachedTableRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, bucketSize := range []int{1, 3} {
+		header, table := generateTestCache(1000, bucketSize)
+		path := filepath.Join(dir, "table.bin")
+		if err := WriteCachedTable(path, header, table); err != nil {
+			t.Fatal(err)
+		}
+
+		readHeader, readTable, err := ReadCachedTable(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := readHeader.Matches(header); err != nil {
+			t.Fatalf("header does not match: %v", err)
+		}
+
+		if readHeader.N != uint64(table.N) || readHeader.Dimension != uint64(table.Dimension) {
+			t.Fatalf("header does not record the table size")
+		}
+
+		checkTablesEqual(t, table, readTable)
+	}
+}
+
+func TestCachedTableMismatch(t *testing.T) {
+	header, _ := generateTestCache(0, 1)
+
+	other := *header
+	if err := header.Matches(&other); err != nil {
+		t.Fatalf("identical headers do not match: %v", err)
+	}
+
+	other.ProjectionWidthMean++
+	if header.Matches(&other) == nil {
+		t.Fatalf("mismatched projection width was accepted")
+	}
+
+	other = *header
+	other.DistanceMetric = uint32(ann.Angular)
+	other.NumHyperplanes = 16
+	if header.Matches(&other) == nil {
+		t.Fatalf("mismatched distance metric was accepted")
+	}
+
+	other = *header
+	other.DatasetChecksum[0] ^= 1
+	if header.Matches(&other) == nil {
+		t.Fatalf("mismatched dataset checksum was accepted")
+	}
+
+	other = *header
+	other.HashSeedDigest[0] ^= 1
+	if header.Matches(&other) == nil {
+		t.Fatalf("mismatched hash seed was accepted")
+	}
+}
+
+func TestCachedTableInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	header, table := generateTestCache(100, 2)
+	path := filepath.Join(dir, "table.bin")
+	if err := WriteCachedTable(path, header, table); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// truncated files are rejected
+	for _, size := range []int{0, 4, 20, len(data) / 2, len(data) - 8} {
+		if _, _, err := decodeCachedTable(data[:size]); err == nil {
+			t.Fatalf("truncated cache of %v bytes was accepted", size)
+		}
+	}
+
+	// caches with a different version are rejected
+	other := *header
+	other.Version = CacheVersion + 1
+	if err := WriteCachedTable(path, &other, table); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ReadCachedTable(path); err == nil {
+		t.Fatalf("cache with a different version was accepted")
+	}
+
+	// unsorted keys are rejected
+	table.Keys[0], table.Keys[1] = table.Keys[1], table.Keys[0]
+	if err := WriteCachedTable(path, header, table); err == nil {
+		t.Fatalf("unsorted table was written")
+	}
+}
@@ -0,0 +1,302 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+
+	"github.com/sachaservan/private-ann/cmd/api"
+	"github.com/sachaservan/private-ann/hash"
+	"github.com/sachaservan/private-ann/pir"
+	"github.com/sachaservan/private-ann/pir/field"
+)
+
+// The servers only answer a two-server request once they checked its queries together:
+// a malicious client could otherwise send keys that select several records (or scale them)
+// and learn more records than it asked for. The servers exchange one message per round:
+//  0. a fresh nonce each, from which they derive the randomness of the request
+//     (see queryCheck.seed), which the client can neither predict nor replay;
+//  1. the VDPF proof and the masked sketch share of each query (see pir.SketchMaskedShare);
+//  2. the sketch check share of each query (see pir.SketchCheckShare).
+//
+// A server only reveals its shares if the proofs of both servers match (the query selects
+// at most one record) and every sketch check passes (the DPF outputs 1).
+// The messages are authenticated with the seed shared by the servers (see Server.MaskingSeed).
+const (
+	roundNonce = iota
+	roundProofs
+	roundSketch
+	numCheckRounds
+)
+
+// DefaultPeerTimeout is how long a server waits for a message of its peer
+// (the peer may still be evaluating the request) unless Server.PeerTimeout is set
+const DefaultPeerTimeout = time.Minute
+
+const queryIDSize = sha256.Size
+const nonceSize = 16
+
+// ErrCheckFailed is returned when the servers do not agree that the queries are well-formed
+var ErrCheckFailed = errors.New("queries failed the check of the servers")
+
+// queryCheck is the check of the queries of a request (see beginCheck)
+type queryCheck struct {
+	server *Server
+	ctx    context.Context // the check is abandoned (and the peer told so) once ctx is done
+	id     []byte          // identifies the request on both servers
+	seed   []byte          // randomness of the request that both servers agree on
+	round  int             // next round
+}
+
+// peerTimeout returns how long the server waits for a message of its peer
+func (server *Server) peerTimeout() time.Duration {
+	if server.PeerTimeout == 0 {
+		return DefaultPeerTimeout
+	}
+	return server.PeerTimeout
+}
+
+// beginCheck agrees with the peer on the randomness of the request (round 0).
+// The request is identified by kind, the parameters common to both servers
+// and the parts of the queries common to both query shares
+func (server *Server) beginCheck(ctx context.Context, kind string, params []int, queries []*pir.QueryShare) (*queryCheck, error) {
+	if server.Peer == nil {
+		return nil, errors.New("server has no peer to check two-server queries with")
+	}
+
+	// queries that cannot be identified are rejected right away (the peer times out)
+	for _, query := range queries {
+		if query == nil || !query.DPFKey.IsWellFormed() {
+			return nil, errors.New("malformed DPF key")
+		}
+	}
+
+	check := &queryCheck{server: server, ctx: ctx, id: queryID(kind, params, queries)}
+	if !server.peerInbox.begin(check.id) {
+		return nil, errors.New("the same request is already being answered")
+	}
+
+	for _, query := range queries {
+		err := query.CheckWellFormed()
+		if err == nil && !query.IsVerifiable {
+			err = errors.New("two-server queries should be verifiable")
+		}
+		if err != nil {
+			check.abort()
+			check.close()
+			return nil, err
+		}
+	}
+
+	nonce := make([]byte, nonceSize)
+	if _, err := crand.Read(nonce); err != nil {
+		check.abort()
+		check.close()
+		return nil, err
+	}
+
+	msg, err := check.exchange(&api.PeerMessage{Nonce: nonce})
+	if err != nil {
+		check.close()
+		return nil, err
+	}
+
+	if len(msg.Nonce) != nonceSize {
+		check.close()
+		return nil, errors.New("malformed peer message")
+	}
+
+	// the nonces are combined symmetrically so that both servers derive the same seed
+	combined := make([]byte, nonceSize)
+	for i := range combined {
+		combined[i] = nonce[i] ^ msg.Nonce[i]
+	}
+	check.seed = derive(server.MaskingSeed, "request", check.id, combined)
+
+	return check, nil
+}
+
+// sketchKey returns the key of the sketches of the queries (see pir.SketchKey)
+func (check *queryCheck) sketchKey() pir.SketchKey {
+	return derive(check.seed, "sketch")
+}
+
+// maskingRand returns the randomness used to mask the results of the queries (see obliviousMasking).
+// Both servers use the same randomness, derived from the randomness of the request:
+// the client can neither predict it nor make the servers reuse it by replaying a query
+func (check *queryCheck) maskingRand() *rand.Rand {
+	return hash.NewSeededRand(derive(check.seed, "masking"))
+}
+
+// finish checks the results of the queries with the peer (rounds 1 and 2)
+func (check *queryCheck) finish(queries []*pir.QueryShare, results []*pir.SecretSharedQueryResult) error {
+	proofs := make([][]byte, len(queries))
+	masked := make([]field.FP, len(queries))
+	for i, query := range queries {
+		proofs[i] = results[i].Proof
+		masked[i] = query.SketchMaskedShare(results[i])
+	}
+
+	msg, err := check.exchange(&api.PeerMessage{Proofs: proofs, Shares: masked})
+	if err != nil {
+		return err
+	}
+	if len(msg.Proofs) != len(queries) || len(msg.Shares) != len(queries) {
+		check.abort()
+		return errors.New("malformed peer message")
+	}
+
+	shares := make([]field.FP, len(queries))
+	for i, query := range queries {
+		pair := []*pir.SecretSharedQueryResult{results[i], {Proof: msg.Proofs[i]}}
+		if !pir.VerifyProofs(pair) {
+			check.abort()
+			return ErrCheckFailed
+		}
+
+		d := field.Add(masked[i], msg.Shares[i])
+		shares[i] = query.SketchCheckShare(results[i], d)
+	}
+
+	msg, err = check.exchange(&api.PeerMessage{Shares: shares})
+	if err != nil {
+		return err
+	}
+	if len(msg.Shares) != len(queries) {
+		return errors.New("malformed peer message")
+	}
+
+	for i := range queries {
+		if field.Add(shares[i], msg.Shares[i]) != 0 {
+			return ErrCheckFailed
+		}
+	}
+
+	return nil
+}
+
+// exchange sends the message of the next round to the peer and returns the message of the peer
+func (check *queryCheck) exchange(msg *api.PeerMessage) (*api.PeerMessage, error) {
+	round := check.round
+	check.round++
+
+	msg.QueryID = check.id
+	msg.Round = round
+	msg.MAC = check.mac(msg)
+	if err := check.server.Peer.Send(msg); err != nil {
+		return nil, fmt.Errorf("could not reach the peer: %v", err)
+	}
+
+	peerMsg, err := check.server.peerInbox.receive(check.ctx, check.id, round, check.server.peerTimeout())
+	if err != nil {
+		if err == check.ctx.Err() {
+			// the request was abandoned: the peer does not wait for this server
+			check.abort()
+		}
+		return nil, err
+	}
+
+	if !hmac.Equal(peerMsg.MAC, check.mac(peerMsg)) {
+		return nil, errors.New("peer message failed authentication")
+	}
+
+	if peerMsg.Abort {
+		return nil, ErrCheckFailed
+	}
+
+	return peerMsg, nil
+}
+
+// abort tells the peer (waiting for the next round) that the server rejected the request
+func (check *queryCheck) abort() {
+	if check.round >= numCheckRounds {
+		return
+	}
+
+	msg := &api.PeerMessage{QueryID: check.id, Round: check.round, Abort: true}
+	msg.MAC = check.mac(msg)
+	check.round = numCheckRounds
+	check.server.Peer.Send(msg)
+}
+
+// close ends the check (the same request can then be checked again)
+func (check *queryCheck) close() {
+	check.server.peerInbox.end(check.id)
+}
+
+// mac authenticates the message: the nonces are authenticated with the seed shared by
+// the servers and the later rounds with the randomness of the request (so that messages
+// of earlier requests cannot be replayed)
+func (check *queryCheck) mac(msg *api.PeerMessage) []byte {
+	key := check.server.MaskingSeed
+	if msg.Round > roundNonce {
+		key = check.seed
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(msg.QueryID)
+	writeInts(mac, msg.Round, len(msg.Nonce))
+	mac.Write(msg.Nonce)
+	writeInts(mac, len(msg.Proofs))
+	for _, proof := range msg.Proofs {
+		writeInts(mac, len(proof))
+		mac.Write(proof)
+	}
+	writeInts(mac, len(msg.Shares))
+	for _, share := range msg.Shares {
+		writeInts(mac, int(share))
+	}
+	if msg.Abort {
+		writeInts(mac, 1)
+	} else {
+		writeInts(mac, 0)
+	}
+
+	return mac.Sum(nil)
+}
+
+// queryID returns the digest of the request: the kind, the parameters and the parts of
+// the queries that both query shares have in common (everything but the share number and seed of the keys)
+func queryID(kind string, params []int, queries []*pir.QueryShare) []byte {
+	h := sha256.New()
+	writeInts(h, len(kind))
+	h.Write([]byte(kind))
+	writeInts(h, len(params))
+	writeInts(h, params...)
+	writeInts(h, len(queries))
+	for _, query := range queries {
+		h.Write(query.PrfKey[:])
+		h.Write(query.H1Key[:])
+		h.Write(query.H2Key[:])
+		writeInts(h, int(query.DPFKey.RangeSize))
+		// byte 0 is the share number, bytes 1 to 16 the seed and byte 17 the control bit of the key
+		h.Write(query.DPFKey.Bytes[18:])
+	}
+	return h.Sum(nil)
+}
+
+// derive returns a digest of the seed and the labels
+func derive(seed []byte, label string, data ...[]byte) []byte {
+	mac := hmac.New(sha256.New, seed)
+	mac.Write([]byte(label))
+	for _, d := range data {
+		writeInts(mac, len(d))
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+func writeInts(w io.Writer, values ...int) {
+	var buf [8]byte
+	for _, v := range values {
+		binary.LittleEndian.PutUint64(buf[:], uint64(v))
+		w.Write(buf[:])
+	}
+}
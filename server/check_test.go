@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sachaservan/private-ann/ann"
+	"github.com/sachaservan/private-ann/cmd/api"
+	"github.com/sachaservan/private-ann/pir/field"
+	"github.com/sachaservan/vec"
+)
+
+// generateTestItemServers returns two linked servers serving the same random items
+func generateTestItemServers(t *testing.T, numItems, dim int) ([]*Server, *api.ItemDBParameters) {
+	data := make([]*vec.Vec, numItems)
+	for i := range data {
+		coords := make([]float64, dim)
+		for j := range coords {
+			coords[j] = float64(rand.Intn(256))
+		}
+		data[i] = vec.NewVec(coords)
+	}
+
+	matrix, err := ann.MatrixFromVecs(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemDB, err := NewItemDatabase(matrix, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	servers := []*Server{{}, {}}
+	for _, server := range servers {
+		server.Sessions = NewSessionManager(time.Minute)
+		server.MaskingSeed = []byte("test")
+		server.PeerTimeout = 5 * time.Second
+		server.SetSnapshot(&Snapshot{ItemDB: itemDB})
+	}
+	LinkPeers(servers[0], servers[1])
+
+	return servers, itemDB.Metadata()
+}
+
+// numInflight returns the number of requests being checked
+func (in *inbox) numInflight() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	return len(in.inflight)
+}
+
+// newTestItemQuery returns the query shares (for both servers) of random items
+func newTestItemQuery(t *testing.T, servers []*Server, params *api.ItemDBParameters, numQueries int) []*api.ItemQueryArgs {
+	args := []*api.ItemQueryArgs{{SessionID: openTestSession(t, servers[0])}, {SessionID: openTestSession(t, servers[1])}}
+	for i := 0; i < numQueries; i++ {
+		shares := params.NewVerifiableIndexQueryShares(uint64(rand.Intn(params.DBSize)), 2, uint(params.IndexBits))
+		args[0].Queries = append(args[0].Queries, shares[0])
+		args[1].Queries = append(args[1].Queries, shares[1])
+	}
+	return args
+}
+
+// queryItems sends the item queries to both servers and returns the error of each server
+func queryItems(servers []*Server, args []*api.ItemQueryArgs) ([]*api.ItemQueryResponse, []error) {
+	replies := []*api.ItemQueryResponse{{}, {}}
+	errs := onBothServers(func(s int) error {
+		return servers[s].PrivateItemQuery(args[s], replies[s])
+	})
+	return replies, errs
+}
+
+func TestCheckRejectsScaledOutput(t *testing.T) {
+	servers, params := generateTestItemServers(t, 50, 4)
+	args := newTestItemQuery(t, servers, params, 3)
+
+	// the client shifts the last correction word of both keys of one query:
+	// the proofs still match but the selected item is scaled
+	for s := range args {
+		lastCW := args[s].Queries[1].DPFKey.Bytes[18*params.IndexBits+18:]
+		cw := field.Add(field.FP(binary.LittleEndian.Uint64(lastCW)), 5)
+		binary.LittleEndian.PutUint64(lastCW, uint64(cw))
+	}
+
+	replies, errs := queryItems(servers, args)
+	for s, err := range errs {
+		if err != ErrCheckFailed {
+			t.Fatalf("expected ErrCheckFailed from server %v but got %v", s, err)
+		}
+		if replies[s].ResSecretShared != nil {
+			t.Fatalf("server %v revealed its shares", s)
+		}
+	}
+}
+
+func TestCheckRejectsMismatchedProofs(t *testing.T) {
+	servers, params := generateTestItemServers(t, 50, 4)
+	args := newTestItemQuery(t, servers, params, 3)
+
+	// the key of server B no longer matches the key of server A
+	// (its seed does not take part in the request id)
+	args[1].Queries[2].DPFKey.Bytes[5] ^= 1
+
+	_, errs := queryItems(servers, args)
+	for s, err := range errs {
+		if err != ErrCheckFailed {
+			t.Fatalf("expected ErrCheckFailed from server %v but got %v", s, err)
+		}
+	}
+}
+
+func TestCheckRejectsNonVerifiableQueries(t *testing.T) {
+	servers, params := generateTestItemServers(t, 50, 4)
+	args := newTestItemQuery(t, servers, params, 2)
+
+	shares := params.NewIndexQueryShares(0, 2, uint(params.IndexBits))
+	args[0].Queries[0], args[1].Queries[0] = shares[0], shares[1]
+
+	_, errs := queryItems(servers, args)
+	for s, err := range errs {
+		if err == nil {
+			t.Fatalf("server %v answered a non-verifiable query", s)
+		}
+	}
+
+	// the servers keep answering once the request failed
+	if _, errs := queryItems(servers, newTestItemQuery(t, servers, params, 2)); errs[0] != nil || errs[1] != nil {
+		t.Fatalf("well-formed query failed: %v", errs)
+	}
+}
+
+func TestCheckRequiresPeer(t *testing.T) {
+	servers, params := generateTestItemServers(t, 50, 4)
+	servers[0].Peer = nil
+
+	args := newTestItemQuery(t, servers, params, 1)
+	if err := servers[0].PrivateItemQuery(args[0], &api.ItemQueryResponse{}); err == nil {
+		t.Fatalf("server without a peer answered a two-server query")
+	}
+}
+
+func TestRPCPeer(t *testing.T) {
+	servers, params := generateTestItemServers(t, 50, 4)
+
+	listeners := make([]net.Listener, 2)
+	for s := range servers {
+		listener, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		go servers[s].ServePeer(listener)
+		listeners[s] = listener
+	}
+	servers[0].Peer = NewRPCPeer(listeners[1].Addr().String(), time.Second)
+	servers[1].Peer = NewRPCPeer(listeners[0].Addr().String(), time.Second)
+
+	for i := 0; i < 2; i++ {
+		if _, errs := queryItems(servers, newTestItemQuery(t, servers, params, 2)); errs[0] != nil || errs[1] != nil {
+			t.Fatalf("query failed: %v", errs)
+		}
+	}
+
+	// shares that do not belong to the same request are never answered
+	args := newTestItemQuery(t, servers, params, 1)
+	args[1].Queries[0].PrfKey[0] ^= 1
+	servers[0].PeerTimeout = 100 * time.Millisecond
+	servers[1].PeerTimeout = 100 * time.Millisecond
+	if _, errs := queryItems(servers, args); errs[0] == nil || errs[1] == nil {
+		t.Fatalf("servers answered different requests")
+	}
+}
+
+func TestCheckMaskingRand(t *testing.T) {
+	servers, params := generateTestItemServers(t, 50, 4)
+	args := newTestItemQuery(t, servers, params, 2)
+
+	// the same request (e.g., replayed by the client) is masked with fresh randomness
+	// on which both servers agree
+	var previous int64
+	for i := 0; i < 2; i++ {
+		masks := make([]int64, 2)
+		errs := onBothServers(func(s int) error {
+			check, err := servers[s].beginCheck(context.Background(), "items", nil, args[s].Queries)
+			if err != nil {
+				return err
+			}
+			defer check.close()
+			masks[s] = check.maskingRand().Int63()
+			return nil
+		})
+		if errs[0] != nil || errs[1] != nil {
+			t.Fatalf("check failed: %v", errs)
+		}
+
+		if masks[0] != masks[1] {
+			t.Fatalf("servers use different masking randomness")
+		}
+		if masks[0] == previous {
+			t.Fatalf("replayed request reused the masking randomness")
+		}
+		previous = masks[0]
+	}
+}
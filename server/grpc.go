@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+
+	"github.com/sachaservan/private-ann/cmd/api"
+	"github.com/sachaservan/private-ann/cmd/api/pb"
+)
+
+// GRPCHandler returns the handler of the gRPC service (see pb.NewServer): unlike the
+// net/rpc methods of the server, its queries are abandoned when the client cancels them
+func (server *Server) GRPCHandler() pb.Handler {
+	return grpcHandler{server: server}
+}
+
+type grpcHandler struct {
+	server *Server
+}
+
+func (h grpcHandler) WaitForExperiment(ctx context.Context, args *api.WaitForExperimentArgs, reply *api.WaitForExperimentResponse) error {
+	return h.server.waitForExperiment(ctx)
+}
+
+func (h grpcHandler) InitSession(ctx context.Context, args api.InitSessionArgs, reply *api.InitSessionResponse) error {
+	return h.server.InitSession(args, reply)
+}
+
+func (h grpcHandler) TerminateSession(ctx context.Context, args *api.TerminateSessionArgs, reply *api.TerminateSessionResponse) error {
+	return h.server.TerminateSession(args, reply)
+}
+
+func (h grpcHandler) HashBundle(ctx context.Context, args *api.HashBundleArgs, reply *api.HashBundleResponse) error {
+	return h.server.HashBundle(args, reply)
+}
+
+func (h grpcHandler) PrivateANNQuery(ctx context.Context, args *api.ANNQueryArgs, reply *api.ANNQueryResponse) error {
+	return h.server.privateANNQuery(ctx, args, reply)
+}
+
+func (h grpcHandler) PrivateEncryptedANNQuery(ctx context.Context, args *api.EncryptedANNQueryArgs, reply *api.EncryptedANNQueryResponse) error {
+	return h.server.privateEncryptedANNQuery(ctx, args, reply)
+}
+
+func (h grpcHandler) PrivateItemQuery(ctx context.Context, args *api.ItemQueryArgs, reply *api.ItemQueryResponse) error {
+	return h.server.privateItemQuery(ctx, args, reply)
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/bits"
+	"time"
+
+	"github.com/sachaservan/private-ann/ann"
+	"github.com/sachaservan/private-ann/cmd/api"
+	"github.com/sachaservan/private-ann/pir"
+	"github.com/sachaservan/vec"
+)
+
+// ItemDatabase stores the encoded item (vector and payload) of each dataset id
+// Each record of DB holds the encoded item (indexed by id) packed into field elements
+type ItemDatabase struct {
+	DB              *pir.Database
+	NumItems        int
+	IndexBits       int // DPF range (in bits) for index queries
+	Dimension       int
+	MaxPayloadBytes int
+	RecordBytes     int
+}
+
+// NewItemDatabase encodes the rows of the matrix and the payloads (may be nil) into an item database
+func NewItemDatabase(data *ann.Matrix, payloads [][]byte, maxPayloadBytes int) (*ItemDatabase, error) {
+	if data.Rows == 0 {
+		return nil, errors.New("no items provided")
+	}
+
+	if payloads != nil && len(payloads) != data.Rows {
+		return nil, errors.New("number of payloads should match number of items")
+	}
+
+	idb := &ItemDatabase{
+		NumItems:        data.Rows,
+		IndexBits:       bits.Len(uint(data.Rows - 1)),
+		Dimension:       data.Dimension,
+		MaxPayloadBytes: maxPayloadBytes,
+		RecordBytes:     ann.ItemRecordBytes(data.Dimension, maxPayloadBytes),
+	}
+
+	// DPF needs at least one bit of range
+	if idb.IndexBits == 0 {
+		idb.IndexBits = 1
+	}
+
+	records := make([][]byte, data.Rows)
+	buf := make([]float32, data.Dimension)
+	for i := range records {
+		var payload []byte
+		if payloads != nil {
+			payload = payloads[i]
+		}
+
+		record, err := ann.EncodeItemRow(data.Row(i, buf), payload, maxPayloadBytes)
+		if err != nil {
+			return nil, err
+		}
+		records[i] = record
+	}
+
+	idb.DB = pir.NewDatabase()
+	if err := idb.DB.BuildForBytes(records, idb.RecordBytes); err != nil {
+		return nil, err
+	}
+
+	return idb, nil
+}
+
+// Metadata returns the information a client needs to query the item database
+func (idb *ItemDatabase) Metadata() *api.ItemDBParameters {
+	return &api.ItemDBParameters{
+		DBMetadata:      idb.DB.DBMetadata,
+		IndexBits:       idb.IndexBits,
+		Dimension:       idb.Dimension,
+		MaxPayloadBytes: idb.MaxPayloadBytes,
+		RecordBytes:     idb.RecordBytes,
+	}
+}
+
+// copy returns a copy of the item database (see pir.Database.Copy)
+func (idb *ItemDatabase) copy() *ItemDatabase {
+	cpy := *idb
+	cpy.DB = idb.DB.Copy()
+	return &cpy
+}
+
+// insert appends the item (without payload) to the item database;
+// the index range grows when the items no longer fit in it
+func (idb *ItemDatabase) insert(v *vec.Vec) error {
+	record, err := ann.EncodeItem(v, nil, idb.MaxPayloadBytes)
+	if err != nil {
+		return err
+	}
+
+	if err := idb.DB.AppendRecord(pir.BytesToElements(record)); err != nil {
+		return err
+	}
+	idb.NumItems++
+
+	if indexBits := bits.Len(uint(idb.NumItems - 1)); indexBits > idb.IndexBits {
+		idb.IndexBits = indexBits
+	}
+
+	return nil
+}
+
+// delete erases the item (its record is zeroed)
+func (idb *ItemDatabase) delete(id int) {
+	record := idb.DB.Record(id)
+	for i := range record {
+		record[i] = 0
+	}
+}
+
+// query evaluates the index query on the item database (with the sketch of the key, see pir.SketchKey)
+func (idb *ItemDatabase) query(query *pir.QueryShare, key pir.SketchKey) (*pir.SecretSharedQueryResult, error) {
+	if err := query.CheckWellFormed(); err != nil {
+		return nil, err
+	}
+
+	if query.IsKeywordBased || query.DPFKey.RangeSize != uint(idb.IndexBits) {
+		return nil, errors.New("item queries should be index queries over the item range")
+	}
+
+	return idb.DB.PrivateSecretSharedQuery(query, key)
+}
+
+// PrivateItemQuery performs PIR queries to retrieve the items (vectors and payloads)
+// associated with the ids that the client obtained from PrivateANNQuery
+func (server *Server) PrivateItemQuery(args *api.ItemQueryArgs, reply *api.ItemQueryResponse) error {
+	return server.privateItemQuery(context.Background(), args, reply)
+}
+
+// privateItemQuery is PrivateItemQuery for a request that is abandoned once ctx is done
+func (server *Server) privateItemQuery(ctx context.Context, args *api.ItemQueryArgs, reply *api.ItemQueryResponse) error {
+
+	log.Printf("[Server]: received request to PrivateItemQuery")
+
+	if err := server.beginRequest(); err != nil {
+		return err
+	}
+	defer server.endRequest()
+
+	snapshot, err := server.sessionSnapshot(args.SessionID, args.Version)
+	if err != nil {
+		log.Printf("[Server]: rejected PrivateItemQuery request: %v", err)
+		return err
+	}
+
+	if snapshot.ItemDB == nil {
+		return errors.New("server does not serve items")
+	}
+
+	start := time.Now()
+
+	check, err := server.beginCheck(ctx, "items", []int{args.Version}, args.Queries)
+	if err != nil {
+		log.Printf("[Server]: rejected PrivateItemQuery request: %v", err)
+		return err
+	}
+	defer check.close()
+
+	results := make([]*pir.SecretSharedQueryResult, len(args.Queries))
+	errs := make([]error, len(args.Queries))
+	err = server.runTasks(ctx, len(args.Queries), func(i int) {
+		results[i], errs[i] = snapshot.ItemDB.query(args.Queries[i], check.sketchKey())
+	})
+	if err == nil {
+		for _, e := range errs {
+			if e != nil {
+				err = e
+				break
+			}
+		}
+	}
+	if err != nil {
+		check.abort()
+		log.Printf("[Server]: rejected PrivateItemQuery request: %v", err)
+		return err
+	}
+
+	// no share is revealed before both servers checked the queries
+	if err := check.finish(args.Queries, results); err != nil {
+		log.Printf("[Server]: rejected PrivateItemQuery request: %v", err)
+		return err
+	}
+
+	reply.ResSecretShared = results
+	reply.SessionID = args.SessionID
+	reply.StatsQueryTimeInMS = time.Since(start).Milliseconds()
+
+	log.Printf("[Server]: processed PrivateItemQuery request in %v ms", reply.StatsQueryTimeInMS)
+
+	return nil
+}
@@ -0,0 +1,238 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"github.com/sachaservan/private-ann/cmd/api"
+)
+
+// Peer sends messages to the other server, with which the server checks
+// the two-server queries before answering them (see queryCheck)
+type Peer interface {
+	Send(msg *api.PeerMessage) error
+}
+
+// PeerService receives the messages of the peer (see ServePeer)
+type PeerService struct {
+	server *Server
+}
+
+// Deliver stores the message until the request it belongs to reads it
+func (service *PeerService) Deliver(msg *api.PeerMessage, reply *api.PeerResponse) error {
+	return service.server.deliver(msg)
+}
+
+// ServePeer answers the messages of the peer (see NewRPCPeer) on the listener;
+// it returns once the listener is closed
+func (server *Server) ServePeer(listener net.Listener) {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("Peer", &PeerService{server: server}); err != nil {
+		panic(err)
+	}
+	srv.Accept(listener)
+}
+
+// deliver stores a message of the peer (the request it belongs to checks its MAC)
+func (server *Server) deliver(msg *api.PeerMessage) error {
+	if msg == nil || len(msg.QueryID) != queryIDSize || msg.Round < 0 || msg.Round >= numCheckRounds {
+		return errors.New("malformed peer message")
+	}
+
+	server.peerInbox.deliver(msg, server.peerTimeout())
+	return nil
+}
+
+// LinkPeers makes the two servers (running in the same process) each other's peer
+func LinkPeers(a, b *Server) {
+	a.Peer = &localPeer{server: b}
+	b.Peer = &localPeer{server: a}
+}
+
+// localPeer delivers the messages to a server of the same process
+type localPeer struct {
+	server *Server
+}
+
+func (peer *localPeer) Send(msg *api.PeerMessage) error {
+	return peer.server.deliver(msg)
+}
+
+// rpcPeer sends the messages to the peer service of the other server (see ServePeer).
+// The connection does not need TLS: the messages are authenticated (see queryCheck.mac)
+// and reveal nothing about the queries
+type rpcPeer struct {
+	address string
+	timeout time.Duration
+
+	mu     sync.Mutex
+	client *rpc.Client
+}
+
+// NewRPCPeer returns the peer serving its peer service at address;
+// connecting to it times out after timeout
+func NewRPCPeer(address string, timeout time.Duration) Peer {
+	return &rpcPeer{address: address, timeout: timeout}
+}
+
+func (peer *rpcPeer) Send(msg *api.PeerMessage) error {
+	client, err := peer.connect()
+	if err != nil {
+		return err
+	}
+
+	err = client.Call("Peer.Deliver", msg, &api.PeerResponse{})
+	if _, ok := err.(rpc.ServerError); err != nil && !ok {
+		// the connection is broken: the next message reconnects
+		log.Printf("[Server]: lost the connection to the peer: %v", err)
+		peer.reset(client)
+	}
+
+	return err
+}
+
+// connect returns the connection to the peer, connecting if needed
+func (peer *rpcPeer) connect() (*rpc.Client, error) {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	if peer.client != nil {
+		return peer.client, nil
+	}
+
+	conn, err := net.DialTimeout("tcp", peer.address, peer.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	peer.client = rpc.NewClient(conn)
+	return peer.client, nil
+}
+
+// reset closes the connection unless it was already replaced
+func (peer *rpcPeer) reset(client *rpc.Client) {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	if peer.client == client {
+		peer.client.Close()
+		peer.client = nil
+	}
+}
+
+// inbox holds the messages of the peer until the requests they belong to read them
+type inbox struct {
+	mu       sync.Mutex
+	slots    map[inboxKey]chan *api.PeerMessage
+	inflight map[string]bool // ids of the requests being checked
+}
+
+type inboxKey struct {
+	id    string
+	round int
+}
+
+// slot returns the slot of the message (created if needed)
+func (in *inbox) slot(key inboxKey) chan *api.PeerMessage {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if in.slots == nil {
+		in.slots = make(map[inboxKey]chan *api.PeerMessage)
+	}
+	if in.slots[key] == nil {
+		in.slots[key] = make(chan *api.PeerMessage, 1)
+	}
+	return in.slots[key]
+}
+
+// remove deletes the slot unless it was replaced
+func (in *inbox) remove(key inboxKey, slot chan *api.PeerMessage) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if in.slots[key] == slot {
+		delete(in.slots, key)
+	}
+}
+
+// deliver stores the message; only the first message of a round is kept and
+// messages that no request reads are dropped after expiry. The abort of a request that
+// the peer abandoned after it sent its first messages (and that this server is not checking)
+// discards these messages instead: they would be mistaken for those of a new attempt
+func (in *inbox) deliver(msg *api.PeerMessage, expiry time.Duration) {
+	if msg.Abort && msg.Round > 0 && in.discard(msg.QueryID) {
+		return
+	}
+
+	key := inboxKey{id: string(msg.QueryID), round: msg.Round}
+	slot := in.slot(key)
+
+	select {
+	case slot <- msg:
+		time.AfterFunc(expiry, func() { in.remove(key, slot) })
+	default:
+	}
+}
+
+// discard drops the messages of the request unless it is being checked;
+// returns false if it is
+func (in *inbox) discard(id []byte) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if in.inflight[string(id)] {
+		return false
+	}
+	for round := 0; round < numCheckRounds; round++ {
+		delete(in.slots, inboxKey{id: string(id), round: round})
+	}
+	return true
+}
+
+// receive waits (at most timeout, and until ctx is done) for the message of the round
+func (in *inbox) receive(ctx context.Context, id []byte, round int, timeout time.Duration) (*api.PeerMessage, error) {
+	key := inboxKey{id: string(id), round: round}
+	slot := in.slot(key)
+	defer in.remove(key, slot)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case msg := <-slot:
+		return msg, nil
+	case <-timer.C:
+		return nil, errors.New("timed out waiting for the peer")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// begin marks the request as being checked; returns false if it already is
+func (in *inbox) begin(id []byte) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if in.inflight == nil {
+		in.inflight = make(map[string]bool)
+	}
+	if in.inflight[string(id)] {
+		return false
+	}
+	in.inflight[string(id)] = true
+	return true
+}
+
+// end marks the request as checked
+func (in *inbox) end(id []byte) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	delete(in.inflight, string(id))
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrBusy is returned to clients when the server cannot queue more requests
+var ErrBusy = errors.New("server is busy (request queue is full)")
+
+// Scheduler runs the (per-table) PIR evaluations of all requests on a fixed pool of
+// workers so that concurrent requests share NumWorkers evaluations at a time.
+// At most QueueDepth requests are admitted at once (running or waiting for a worker);
+// further requests are rejected with ErrBusy instead of piling up.
+type Scheduler struct {
+	NumWorkers int
+	QueueDepth int
+
+	admitted chan struct{} // one token per admitted request
+	tasks    chan func()
+}
+
+// NewScheduler starts numWorkers workers that serve up to queueDepth requests at once
+func NewScheduler(numWorkers, queueDepth int) *Scheduler {
+	if numWorkers <= 0 || queueDepth <= 0 {
+		panic("scheduler requires at least one worker and a positive queue depth")
+	}
+
+	s := &Scheduler{
+		NumWorkers: numWorkers,
+		QueueDepth: queueDepth,
+		admitted:   make(chan struct{}, queueDepth),
+		tasks:      make(chan func()),
+	}
+	for w := 0; w < numWorkers; w++ {
+		go func() {
+			for task := range s.tasks {
+				task()
+			}
+		}()
+	}
+	return s
+}
+
+// Run calls task(i) for each i in [0, n) on the workers and waits for all calls to complete.
+// Returns ErrBusy (without calling task) if the queue is full.
+func (s *Scheduler) Run(n int, task func(i int)) error {
+	if err := s.admit(); err != nil {
+		return err
+	}
+	defer s.release()
+
+	s.execute(n, task)
+
+	return nil
+}
+
+// admit reserves a place in the queue for a request (see release)
+func (s *Scheduler) admit() error {
+	select {
+	case s.admitted <- struct{}{}:
+		return nil
+	default:
+		return ErrBusy
+	}
+}
+
+func (s *Scheduler) release() {
+	<-s.admitted
+}
+
+// execute calls task(i) for each i in [0, n) on the workers (without admission)
+func (s *Scheduler) execute(n int, task func(i int)) {
+	wg := sync.WaitGroup{}
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		i := i
+		s.tasks <- func() {
+			defer wg.Done()
+			task(i)
+		}
+	}
+	wg.Wait()
+}
+
+// runTasks calls task(i) for each i in [0, n) concurrently, on the workers of the
+// server's scheduler if it has one (and one goroutine per task otherwise);
+// the tasks that did not start when ctx is done are skipped and ctx.Err() is returned
+func (server *Server) runTasks(ctx context.Context, n int, task func(i int)) error {
+	if server.Scheduler != nil {
+		if err := server.Scheduler.admit(); err != nil {
+			return err
+		}
+		defer server.Scheduler.release()
+	}
+
+	server.executeTasks(n, func(i int) {
+		if ctx.Err() == nil {
+			task(i)
+		}
+	})
+
+	return ctx.Err()
+}
+
+// executeTasks is runTasks for requests that were already admitted
+func (server *Server) executeTasks(n int, task func(i int)) {
+	if server.Scheduler != nil {
+		server.Scheduler.execute(n, task)
+		return
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			task(i)
+		}(i)
+	}
+	wg.Wait()
+}
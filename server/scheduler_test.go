@@ -0,0 +1,115 @@
+package server
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sachaservan/private-ann/cmd/api"
+)
+
+func TestSchedulerBoundsWorkers(t *testing.T) {
+	numWorkers := 3
+	s := NewScheduler(numWorkers, 10)
+
+	var running, maxRunning int32
+	var calls [20]int32
+
+	wg := sync.WaitGroup{}
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := s.Run(len(calls), func(i int) {
+				n := atomic.AddInt32(&running, 1)
+				for {
+					max := atomic.LoadInt32(&maxRunning)
+					if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				atomic.AddInt32(&calls[i], 1)
+				atomic.AddInt32(&running, -1)
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxRunning > int32(numWorkers) {
+		t.Fatalf("%v tasks ran concurrently with %v workers", maxRunning, numWorkers)
+	}
+	for i := range calls {
+		if calls[i] != 4 {
+			t.Fatalf("task %v was called %v times (expected 4)", i, calls[i])
+		}
+	}
+}
+
+func TestSchedulerBusy(t *testing.T) {
+	queueDepth := 2
+	s := NewScheduler(1, queueDepth)
+
+	// fill the queue with blocked requests
+	release := make(chan struct{})
+	started := make(chan struct{}, queueDepth)
+	wg := sync.WaitGroup{}
+	for r := 0; r < queueDepth; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Run(1, func(i int) {
+				started <- struct{}{}
+				<-release
+			})
+		}()
+	}
+	<-started
+
+	// wait until both requests are admitted
+	for len(s.admitted) < queueDepth {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := s.Run(1, func(i int) { t.Fatalf("task of a rejected request was called") }); err != ErrBusy {
+		t.Fatalf("expected ErrBusy but got %v", err)
+	}
+
+	close(release)
+	wg.Wait()
+
+	// the queue drained
+	if err := s.Run(1, func(i int) {}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrivateANNQueryBusy(t *testing.T) {
+	servers, tableKeys, _ := generateTestServers(2, 10, 2, 1, 8)
+	server := servers[0]
+	server.Scheduler = NewScheduler(1, 1)
+
+	// occupy the only queue slot
+	release := make(chan struct{})
+	go server.Scheduler.Run(1, func(i int) { <-release })
+	for len(server.Scheduler.admitted) < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	defer close(release)
+
+	// the busy server aborts the check: its peer does not answer either
+	args, _ := newTestANNQuery(t, servers, tableKeys, 2, 8)
+	errs := onBothServers(func(s int) error {
+		return servers[s].PrivateANNQuery(args[s], &api.ANNQueryResponse{})
+	})
+	if errs[0] != ErrBusy {
+		t.Fatalf("expected ErrBusy but got %v", errs[0])
+	}
+	if errs[1] != ErrCheckFailed {
+		t.Fatalf("expected ErrCheckFailed from the peer but got %v", errs[1])
+	}
+}
@@ -1,47 +1,108 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"log"
+	"math/rand"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/sachaservan/private-ann/ann"
 	"github.com/sachaservan/private-ann/cmd/api"
-	"github.com/sachaservan/private-ann/hash"
 	"github.com/sachaservan/private-ann/pir"
 	"github.com/sachaservan/private-ann/pir/field"
-	"github.com/sachaservan/vec"
 )
 
 // Server maintains all the necessary server state
 type Server struct {
 	DatasetName string
-	DBSize      int
 
-	// PIR databases containing the LSH tables
-	TableDBs          []*pir.Database
-	NumTables         int         // number of tables in total
-	NumProbes         int         // number of probes performed per table
-	TestQuery         *vec.Vec    // query that the client can use to test
-	HashFunctions     []hash.Hash // LSH hash functions used to make the tables
-	HashFunctionRange int         // range size of the universal hash function (in bits)
+	BucketSize        int               // max number of elements in each bucket
+	NumTables         int               // number of tables in total
+	NumProbes         int               // number of multi-probe hashes per query and table
+	NumPartitions     int               // number of partitions (batches) of each table (0: NumProbes)
+	ProbeStrategy     ann.ProbeStrategy // bucket that the clients probe in each partition
+	HashFunctionRange int               // range size of the universal hash function (in bits)
+
+	// distance between items; for the angular metric the items are normalized
+	DistanceMetric ann.DistanceMetric
+
+	// tables served to new sessions (a *Snapshot, see SetSnapshot)
+	snapshot atomic.Value
+
+	// bounds the concurrent PIR evaluations of all requests (optional)
+	Scheduler *Scheduler
+
+	// evaluates concurrent PrivateANNQuery requests together (optional, see StartQueryBatching)
+	Batcher *QueryBatcher
+
+	// open client sessions
+	Sessions *SessionManager
+
+	// number of versions of the tables kept for the open sessions (0: no limit); sessions
+	// opened on older versions are ended when a new version is swapped in (see SetSnapshot)
+	MaxVersions int
 
 	NumProcs int // num processors to use
 	Listener net.Listener
-	Ready    bool // true when server has initialized
-	Killed   bool // true if server killed
+	Ready    bool  // true when server has initialized
+	killed   int32 // set (atomically) once the server is killed (see Shutdown)
+
+	// rebuilds the tables when the admin requests a reload (optional);
+	// the server sets the version of the snapshot
+	Reload func() (*Snapshot, error)
+
+	// reloads and updates build the next snapshot one at a time
+	updates sync.Mutex
+
+	// API calls hold a read lock; shutdowns wait for them to complete
+	requests sync.RWMutex
+	draining bool // true once the server stopped accepting requests
 
 	CacheDir string // cache directory for storing pre-built hash tables
 
-	StatsTotalPreprocessingTime int64 // time taken to build the hash tables
-	StatsDatasetNumFeatures     int
+	// secret seed shared by the servers, used to derive the (common) masking randomness
+	// and to authenticate the messages of the peer
+	MaskingSeed []byte
+
+	// other server, with which the two-server queries are checked before they are answered (see queryCheck)
+	Peer        Peer
+	PeerTimeout time.Duration // how long to wait for a message of the peer (0: DefaultPeerTimeout)
+	peerInbox   inbox
+}
+
+// Probing returns how the tables are partitioned and probed (see ann.Probing)
+func (server *Server) Probing() ann.Probing {
+	numPartitions := server.NumPartitions
+	if numPartitions == 0 {
+		numPartitions = server.NumProbes
+	}
+
+	return ann.Probing{
+		KeyBits:       server.HashFunctionRange,
+		NumPartitions: numPartitions,
+		NumProbes:     server.NumProbes,
+		Strategy:      server.ProbeStrategy,
+	}
 }
 
 // WaitForExperiment is used to signal to a waiting client that the server has finishied initializing
 func (server *Server) WaitForExperiment(args *api.WaitForExperimentArgs, reply *api.WaitForExperimentResponse) error {
+	return server.waitForExperiment(context.Background())
+}
+
+// waitForExperiment waits until the server is ready or ctx is done
+func (server *Server) waitForExperiment(ctx context.Context) error {
 
 	for !server.Ready {
-		time.Sleep(1 * time.Second)
+		select {
+		case <-time.After(1 * time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 
 	return nil
@@ -49,38 +110,81 @@ func (server *Server) WaitForExperiment(args *api.WaitForExperimentArgs, reply *
 
 // PrivateANNQuery performs PIR queries for buckets in the hash tables of the ANN data structure
 func (server *Server) PrivateANNQuery(args *api.ANNQueryArgs, reply *api.ANNQueryResponse) error {
+	return server.privateANNQuery(context.Background(), args, reply)
+}
+
+// privateANNQuery is PrivateANNQuery for a request that is abandoned once ctx is done
+func (server *Server) privateANNQuery(ctx context.Context, args *api.ANNQueryArgs, reply *api.ANNQueryResponse) error {
 
 	log.Printf("[Server]: received request to PrivateANNQuery")
 
+	if err := server.beginRequest(); err != nil {
+		return err
+	}
+	defer server.endRequest()
+
+	snapshot, err := server.sessionSnapshot(args.SessionID, args.Version)
+	if err != nil {
+		log.Printf("[Server]: rejected PrivateANNQuery request: %v", err)
+		return err
+	}
+
 	start := time.Now()
 
-	// numPartitions * numTables candidates
-	candidates := make([]*pir.SecretSharedQueryResult, server.TableDBs[0].BatchSize*server.NumTables)
+	if len(args.SecretShared) != server.NumTables || len(snapshot.TableDBs) != server.NumTables {
+		return errors.New("query should contain one batch query per table")
+	}
 
-	wg := sync.WaitGroup{}
-	wg.Add(server.NumTables)
-	for t := 0; t < server.NumTables; t++ {
-		go func(t int) {
-			db := server.TableDBs[t]
+	// numPartitions * numTables candidate buckets
+	numBatches := snapshot.TableDBs[0].BatchSize
 
-			// results is a batch of results, one for each batch
-			res, err := db.PrivateSecretSharedBatchQuery(args.SecretShared[t])
-			if err != nil {
-				panic(err)
-			}
+	numResults, err := checkNumResults(args.NumResults, numBatches*server.NumTables)
+	if err != nil {
+		return err
+	}
+
+	// the queries of all tables in the order of the candidates
+	var queries []*pir.QueryShare
+	for _, batchQuery := range args.SecretShared {
+		if batchQuery == nil || len(batchQuery.Queries) != numBatches {
+			return errors.New("query should contain one query per partition")
+		}
+		queries = append(queries, batchQuery.Queries...)
+	}
+
+	check, err := server.beginCheck(ctx, "ann", []int{numResults, args.Version}, queries)
+	if err != nil {
+		log.Printf("[Server]: rejected PrivateANNQuery request: %v", err)
+		return err
+	}
+	defer check.close()
+
+	results, err := server.evaluateTables(ctx, snapshot, args.SecretShared, check.sketchKey())
+	if err != nil {
+		check.abort()
+		log.Printf("[Server]: rejected PrivateANNQuery request: %v", err)
+		return err
+	}
+
+	candidates := make([]*pir.SecretSharedQueryResult, numBatches*server.NumTables)
+	for t, res := range results {
+		// optional: rand.Shuffle(res)
 
-			// optional: rand.Shuffle(res)
+		for b := range res {
+			candidates[t*numBatches+b] = res[b]
+		}
+	}
 
-			copy(candidates[t*db.BatchSize:(t+1)*db.BatchSize], res)
-			wg.Done()
-		}(t)
+	// no share is revealed before both servers checked the queries
+	if err := check.finish(queries, candidates); err != nil {
+		log.Printf("[Server]: rejected PrivateANNQuery request: %v", err)
+		return err
 	}
-	wg.Wait()
 
 	reply.StatsQueryTimeInMS = time.Since(start).Milliseconds()
 
 	start = time.Now()
-	masked := obliviousMasking(candidates)
+	masked := obliviousMasking(check.maskingRand(), candidates, numResults)
 	reply.StatsMaskingTimeInUS = time.Since(start).Microseconds()
 
 	reply.SessionID = args.SessionID
@@ -92,21 +196,75 @@ func (server *Server) PrivateANNQuery(args *api.ANNQueryArgs, reply *api.ANNQuer
 	return nil
 }
 
-// TODO(sss): figure where this should live, not great to have it as a function in server
-func obliviousMasking(slots []*pir.SecretSharedQueryResult) []*pir.SecretSharedQueryResult {
+// evaluateTables answers the batch query of each table of the snapshot (together with
+// the queries of other requests when the server batches queries, see QueryBatcher)
+func (server *Server) evaluateTables(ctx context.Context, snapshot *Snapshot, queries []*pir.BatchQueryShare, key pir.SketchKey) ([][]*pir.SecretSharedQueryResult, error) {
+	if server.Batcher != nil {
+		return server.Batcher.submit(server, snapshot, queries, key)
+	}
+
+	results := make([][]*pir.SecretSharedQueryResult, server.NumTables)
+	errs := make([]error, server.NumTables)
 
-	// init the results
-	res := make([]*pir.SecretSharedQueryResult, len(slots))
-	for i := 0; i < len(slots); i++ {
-		res[i] = &pir.SecretSharedQueryResult{}
+	// each table is evaluated by a worker of the scheduler
+	err := server.runTasks(ctx, server.NumTables, func(t int) {
+		// results is a batch of results, one for each batch
+		results[t], errs[t] = snapshot.TableDBs[t].PrivateSecretSharedBatchQuery(queries[t], key)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	sum := field.FP(0)
-	for i := 0; i < len(slots); i++ {
-		rand := field.RandomFieldElement()
-		randSum := field.Multiply(rand, sum)
-		res[i].Share = field.Add(slots[i].Share, randSum)
-		sum = field.Add(sum, slots[i].Share)
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// checkNumResults returns the number of non-empty buckets to reveal (0 is treated as 1)
+// or an error if it exceeds the number of candidate buckets
+func checkNumResults(numResults, numCandidates int) (int, error) {
+	if numResults == 0 {
+		numResults = 1
+	}
+	if numResults < 0 || numResults > numCandidates {
+		return 0, errors.New("number of results should be between 1 and the number of candidate buckets")
+	}
+	return numResults, nil
+}
+
+// TODO(sss): figure where this should live, not great to have it as a function in server
+//
+// obliviousMasking masks the candidate records such that only the first numResults non-empty
+// records (in probe order) are revealed. Since counting non-empty records is not linear in the
+// shares, each record is returned in numResults versions (concatenated): version c of record i
+// is the record plus a random multiple of (n_i - c), where n_i is the number of non-empty
+// records preceding it (the sum of the Found shares, see pir.SecretSharedQueryResult; each
+// Found is 0 or 1 since the servers checked that the DPF outputs 1 on at most one point, see queryCheck).
+// Version c is thus the record itself iff exactly c non-empty records precede it and random
+// otherwise, so the client (who knows n_i once it has read the records preceding record i)
+// recovers record i iff n_i < numResults. Each server subtracts c/2 so that the shares of
+// both servers add up to n_i - c.
+func obliviousMasking(rnd *rand.Rand, records []*pir.SecretSharedQueryResult, numResults int) []*pir.SecretSharedQueryResult {
+
+	res := make([]*pir.SecretSharedQueryResult, len(records))
+
+	// share of the number of non-empty preceding records
+	count := field.FP(0)
+	for i := 0; i < len(records); i++ {
+		size := len(records[i].Shares)
+		res[i] = &pir.SecretSharedQueryResult{Shares: make([]field.FP, numResults*size)}
+		for c := 0; c < numResults; c++ {
+			diff := field.Add(count, field.Negate(field.Multiply(field.FP(c), field.Half)))
+			for e, share := range records[i].Shares {
+				r := field.RandomFieldElementFrom(rnd)
+				res[i].Shares[c*size+e] = field.Add(share, field.Multiply(r, diff))
+			}
+		}
+		count = field.Add(count, records[i].Found)
 	}
 
 	return res
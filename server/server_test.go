@@ -2,12 +2,230 @@ package server
 
 import (
 	"math/rand"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/sachaservan/private-ann/ann"
+	"github.com/sachaservan/private-ann/cmd/api"
 	"github.com/sachaservan/private-ann/pir"
 	"github.com/sachaservan/private-ann/pir/field"
+	"github.com/sachaservan/vec"
 )
 
+// generateTestServers returns two servers holding the same random tables
+// where each bucket holds up to bucketSize (encoded) ids
+func generateTestServers(numTables, numKeys, numPartitions, bucketSize, keyBits int) ([]*Server, [][]uint64, [][][]field.FP) {
+	tableKeys := make([][]uint64, numTables)
+	tableValues := make([][][]field.FP, numTables)
+	for t := 0; t < numTables; t++ {
+		seen := make(map[uint64]bool)
+		for len(tableKeys[t]) < numKeys {
+			k := uint64(rand.Intn(1 << keyBits))
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+
+			bucket := make([]field.FP, bucketSize)
+			for l := 0; l < 1+rand.Intn(bucketSize); l++ {
+				bucket[l] = ann.EncodeID(uint32(rand.Intn(1 << 20)))
+			}
+			tableKeys[t] = append(tableKeys[t], k)
+			tableValues[t] = append(tableValues[t], bucket)
+		}
+	}
+
+	servers := make([]*Server, 2)
+	for s := range servers {
+		servers[s] = &Server{
+			NumTables:         numTables,
+			NumProbes:         numPartitions,
+			BucketSize:        bucketSize,
+			HashFunctionRange: keyBits,
+			MaskingSeed:       []byte("test"),
+			PeerTimeout:       5 * time.Second,
+			Scheduler:         NewScheduler(2, 4),
+			Sessions:          NewSessionManager(time.Minute),
+		}
+		tableDBs := make([]*pir.Database, numTables)
+		for t := 0; t < numTables; t++ {
+			keys := append([]uint64{}, tableKeys[t]...)
+			values := append([][]field.FP{}, tableValues[t]...)
+			starts, stops := ann.ComputeBucketDivisions(ann.NewPartitions(numPartitions, keyBits), keys, values)
+
+			db := pir.NewDatabase()
+			db.BuildForKeysAndRecords(keys, values)
+			db.SetBatchingParameters(numPartitions, starts, stops)
+			tableDBs[t] = db
+		}
+		servers[s].SetSnapshot(&Snapshot{TableDBs: tableDBs})
+	}
+	LinkPeers(servers[0], servers[1])
+
+	return servers, tableKeys, tableValues
+}
+
+// onBothServers sends the requests to both servers concurrently (the servers
+// check the queries together before answering) and returns the error of each server
+func onBothServers(request func(s int) error) []error {
+	errs := make([]error, 2)
+	wg := sync.WaitGroup{}
+	for s := range errs {
+		wg.Add(1)
+		go func(s int) {
+			defer wg.Done()
+			errs[s] = request(s)
+		}(s)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// queryBothServers sends the ANN queries to both servers
+func queryBothServers(t *testing.T, servers []*Server, args []*api.ANNQueryArgs, replies []*api.ANNQueryResponse) {
+	errs := onBothServers(func(s int) error {
+		return servers[s].PrivateANNQuery(args[s], replies[s])
+	})
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newTestANNQuery returns the query shares (for both servers) of a key present in the last table;
+// every other partition is queried with absent keys. Returns the index of the queried key.
+func newTestANNQuery(t *testing.T, servers []*Server, tableKeys [][]uint64, numPartitions, keyBits int) ([]*api.ANNQueryArgs, int) {
+	numTables := len(tableKeys)
+	pbr := ann.NewPartitions(numPartitions, keyBits)
+
+	target := rand.Intn(len(tableKeys[numTables-1]))
+	targetKey := tableKeys[numTables-1][target]
+	targetPartition := int(pbr.FindBucket(targetKey))
+
+	args := []*api.ANNQueryArgs{{SessionID: openTestSession(t, servers[0])}, {SessionID: openTestSession(t, servers[1])}}
+	for t := 0; t < numTables; t++ {
+		present := make(map[uint64]bool)
+		for _, k := range tableKeys[t] {
+			present[k] = true
+		}
+
+		batchA := &pir.BatchQueryShare{}
+		batchB := &pir.BatchQueryShare{}
+		for b := 0; b < numPartitions; b++ {
+			key := targetKey
+			if t != numTables-1 || b != targetPartition {
+				key = pbr.Buckets[b][0] + uint64(rand.Intn(int(pbr.Buckets[b][1]-pbr.Buckets[b][0])))
+				for present[key] {
+					key = pbr.Buckets[b][0] + uint64(rand.Intn(int(pbr.Buckets[b][1]-pbr.Buckets[b][0])))
+				}
+			}
+			shares := servers[0].Snapshot().TableDBs[t].NewVerifiableKeywordQueryShares(key, 2, uint(keyBits))
+			batchA.Queries = append(batchA.Queries, shares[0])
+			batchB.Queries = append(batchB.Queries, shares[1])
+		}
+		args[0].SecretShared = append(args[0].SecretShared, batchA)
+		args[1].SecretShared = append(args[1].SecretShared, batchB)
+	}
+
+	return args, target
+}
+
+// checkTestANNReplies checks that the replies to newTestANNQuery reveal the target bucket
+// (and that all buckets before it are empty)
+func checkTestANNReplies(t *testing.T, replies []*api.ANNQueryResponse, tableKeys [][]uint64, tableValues [][][]field.FP, target, numPartitions, keyBits int) {
+	numTables := len(tableKeys)
+	bucketSize := len(tableValues[numTables-1][target])
+	pbr := ann.NewPartitions(numPartitions, keyBits)
+	targetPartition := int(pbr.FindBucket(tableKeys[numTables-1][target]))
+
+	targetRecord := (numTables-1)*numPartitions + targetPartition
+	for i := 0; i < numTables*numPartitions; i++ {
+		res := pir.Recover([]*pir.SecretSharedQueryResult{replies[0].ResSecretShared[i], replies[1].ResSecretShared[i]})
+		for l := 0; l < bucketSize; l++ {
+			if i < targetRecord && res[l] != 0 {
+				t.Fatalf("non-zero slot in record %v before target %v", i, targetRecord)
+			}
+
+			if i == targetRecord && res[l] != tableValues[numTables-1][target][l] {
+				t.Fatalf("wrong slot %v in target record: %v != %v", l, res[l], tableValues[numTables-1][target][l])
+			}
+		}
+	}
+}
+
+func TestPrivateANNQueryMultiSlot(t *testing.T) {
+
+	numTables := 3
+	numPartitions := 4
+	bucketSize := 3
+	keyBits := 20
+
+	servers, tableKeys, tableValues := generateTestServers(numTables, 100, numPartitions, bucketSize, keyBits)
+	args, target := newTestANNQuery(t, servers, tableKeys, numPartitions, keyBits)
+
+	replies := []*api.ANNQueryResponse{{}, {}}
+	queryBothServers(t, servers, args, replies)
+
+	checkTestANNReplies(t, replies, tableKeys, tableValues, target, numPartitions, keyBits)
+}
+
+func TestPrivateANNQueryBatched(t *testing.T) {
+
+	numTables := 3
+	numPartitions := 4
+	bucketSize := 2
+	keyBits := 20
+	numClients := 6
+
+	servers, tableKeys, tableValues := generateTestServers(numTables, 100, numPartitions, bucketSize, keyBits)
+	for _, server := range servers {
+		server.Scheduler = NewScheduler(2, numClients)
+		server.StartQueryBatching(4, 20*time.Millisecond)
+	}
+
+	args := make([][]*api.ANNQueryArgs, numClients)
+	targets := make([]int, numClients)
+	for c := range args {
+		args[c], targets[c] = newTestANNQuery(t, servers, tableKeys, numPartitions, keyBits)
+	}
+
+	// one client sends a malformed query; both servers reject it and answer the other clients
+	args[0][0].SecretShared[0].Queries[0].ShareNumber = 1
+
+	replies := make([][]*api.ANNQueryResponse, numClients)
+	errs := make([][]error, numClients)
+	wg := sync.WaitGroup{}
+	for c := range args {
+		replies[c] = []*api.ANNQueryResponse{{}, {}}
+		errs[c] = make([]error, 2)
+		for s := range servers {
+			wg.Add(1)
+			go func(c, s int) {
+				defer wg.Done()
+				errs[c][s] = servers[s].PrivateANNQuery(args[c][s], replies[c][s])
+			}(c, s)
+		}
+	}
+	wg.Wait()
+
+	if errs[0][0] == nil || errs[0][1] == nil {
+		t.Fatalf("malformed query was not rejected by both servers")
+	}
+
+	for c := 1; c < numClients; c++ {
+		for s := range servers {
+			if errs[c][s] != nil {
+				t.Fatalf("query of client %v failed: %v", c, errs[c][s])
+			}
+		}
+		checkTestANNReplies(t, replies[c], tableKeys, tableValues, targets[c], numPartitions, keyBits)
+	}
+}
+
 func TestObliviousMasking(t *testing.T) {
 
 	nslots := 10
@@ -45,28 +263,28 @@ func TestObliviousMasking(t *testing.T) {
 		queryRes := make([]*pir.SecretSharedQueryResult, nslots)
 		for i := 0; i < nslots; i++ {
 			queryRes[i] = &pir.SecretSharedQueryResult{}
-			queryRes[i].Share = slots[i]
+			queryRes[i].Shares = []field.FP{slots[i]}
 
-			// fmt.Println(queryRes[i].Share.Data)
 		}
+		setFound(queryRes)
 
-		masked := obliviousMasking(queryRes)
+		masked := obliviousMasking(rand.New(rand.NewSource(0)), queryRes, 1)
 
 		for i := 0; i < nslots; i++ {
 
-			if i < index && masked[i].Share != 0 {
+			if i < index && masked[i].Shares[0] != 0 {
 				t.Fatalf("non-zero slot at index %v < %v", i, index)
 			}
 
-			if i == index && masked[i].Share != specialSlot {
+			if i == index && masked[i].Shares[0] != specialSlot {
 				t.Fatalf("wrong slot at index %v == %v", i, index)
 			}
 
-			if i > index && masked[i].Share == 0 {
+			if i > index && masked[i].Shares[0] == 0 {
 				t.Fatalf("non-random slot at index %v > %v", i, index)
 			}
 
-			if i > index && masked[i].Share == specialSlot {
+			if i > index && masked[i].Shares[0] == specialSlot {
 				t.Fatalf("non-random slot at index %v > %v", i, index)
 			}
 		}
@@ -74,6 +292,267 @@ func TestObliviousMasking(t *testing.T) {
 
 }
 
+func TestObliviousMaskingSecretShared(t *testing.T) {
+
+	nslots := 10
+	index := rand.Intn(nslots)
+
+	// secret share the slots, where the first non-zero slot is at index
+	sharesA := make([]*pir.SecretSharedQueryResult, nslots)
+	sharesB := make([]*pir.SecretSharedQueryResult, nslots)
+	values := make([]field.FP, nslots)
+	for i := 0; i < nslots; i++ {
+		if i >= index {
+			values[i] = field.RandomFieldElement()
+		}
+		if i == index && values[i] == 0 {
+			values[i] = 1
+		}
+
+		found := field.FP(0)
+		if values[i] != 0 {
+			found = 1
+		}
+
+		r, f := field.RandomFieldElement(), field.RandomFieldElement()
+		sharesA[i] = &pir.SecretSharedQueryResult{Shares: []field.FP{r}, Found: f}
+		sharesB[i] = &pir.SecretSharedQueryResult{Shares: []field.FP{field.Add(values[i], field.Negate(r))}, Found: field.Add(found, field.Negate(f))}
+	}
+
+	// both servers use the same masking randomness
+	maskedA := obliviousMasking(rand.New(rand.NewSource(1)), sharesA, 1)
+	maskedB := obliviousMasking(rand.New(rand.NewSource(1)), sharesB, 1)
+
+	for i := 0; i < nslots; i++ {
+		res := pir.Recover([]*pir.SecretSharedQueryResult{maskedA[i], maskedB[i]})[0]
+		if i < index && res != 0 {
+			t.Fatalf("non-zero slot at index %v < %v", i, index)
+		}
+
+		if i == index && res != values[index] {
+			t.Fatalf("wrong slot at index %v == %v", i, index)
+		}
+	}
+}
+
+func TestObliviousMaskingMultiSlot(t *testing.T) {
+
+	nrecords := 10
+	slotSize := 4
+
+	for trial := 0; trial < 100; trial++ {
+		index := rand.Intn(nrecords)
+
+		// records before index are empty, the record at index has
+		// a random number of filled slots and all other records are random
+		records := make([]*pir.SecretSharedQueryResult, nrecords)
+		for i := 0; i < nrecords; i++ {
+			filled := 0
+			if i == index {
+				filled = 1 + rand.Intn(slotSize)
+			} else if i > index {
+				filled = rand.Intn(slotSize + 1)
+			}
+
+			records[i] = &pir.SecretSharedQueryResult{Shares: make([]field.FP, slotSize)}
+			for l := 0; l < filled; l++ {
+				records[i].Shares[l] = 1 + field.FP(rand.Intn(1000))
+			}
+		}
+		setFound(records)
+
+		masked := obliviousMasking(rand.New(rand.NewSource(0)), records, 1)
+
+		for i := 0; i < nrecords; i++ {
+			for l := 0; l < slotSize; l++ {
+				if i < index && masked[i].Shares[l] != 0 {
+					t.Fatalf("non-zero slot in record %v < %v", i, index)
+				}
+
+				if i == index && masked[i].Shares[l] != records[i].Shares[l] {
+					t.Fatalf("wrong slot in record %v == %v", i, index)
+				}
+
+				if i > index && masked[i].Shares[l] == records[i].Shares[l] {
+					t.Fatalf("non-random slot in record %v > %v", i, index)
+				}
+			}
+		}
+	}
+}
+
+// setFound sets the Found indicator of records held in the clear
+// (i.e., as if the shares of the other server were all zero)
+func setFound(records []*pir.SecretSharedQueryResult) {
+	for _, record := range records {
+		record.Found = 0
+		if record.Shares[0] != 0 {
+			record.Found = 1
+		}
+	}
+}
+
+// maskRecords masks the records (held in the clear by server A, server B
+// holding shares of zero) and returns the recovered versions of each record
+func maskRecords(records []*pir.SecretSharedQueryResult, k int) [][]field.FP {
+	zeros := make([]*pir.SecretSharedQueryResult, len(records))
+	for i := range records {
+		zeros[i] = &pir.SecretSharedQueryResult{Shares: make([]field.FP, len(records[i].Shares))}
+	}
+
+	maskedA := obliviousMasking(rand.New(rand.NewSource(0)), records, k)
+	maskedB := obliviousMasking(rand.New(rand.NewSource(0)), zeros, k)
+
+	versions := make([][]field.FP, len(records))
+	for i := range records {
+		versions[i] = pir.Recover([]*pir.SecretSharedQueryResult{maskedA[i], maskedB[i]})
+	}
+	return versions
+}
+
+// checkFirstK checks that version c of each record is the record itself iff exactly c (< k)
+// non-empty records precede it and returns the non-empty records that are revealed
+func checkFirstK(t *testing.T, records [][]field.FP, masked [][]field.FP, k int) []int {
+	var revealed []int
+	preceding := 0
+	for i, record := range records {
+		size := len(record)
+		if len(masked[i]) != k*size {
+			t.Fatalf("record %v has %v elements (expected %v versions of %v)", i, len(masked[i]), k, size)
+		}
+
+		for c := 0; c < k; c++ {
+			equal := reflect.DeepEqual(masked[i][c*size:(c+1)*size], record)
+			if c == preceding && !equal {
+				t.Fatalf("version %v of record %v should not be masked", c, i)
+			}
+			if c != preceding && equal {
+				t.Fatalf("version %v of record %v (preceded by %v non-empty records) should be masked", c, i, preceding)
+			}
+		}
+
+		if record[0] != 0 {
+			if preceding < k {
+				revealed = append(revealed, i)
+			}
+			preceding++
+		}
+	}
+	return revealed
+}
+
+func TestObliviousMaskingFirstK(t *testing.T) {
+
+	nrecords := 20
+	k := 3
+
+	for trial := 0; trial < 100; trial++ {
+
+		// each record is empty with probability 1/2
+		records := make([]*pir.SecretSharedQueryResult, nrecords)
+		values := make([][]field.FP, nrecords)
+		for i := range records {
+			records[i] = &pir.SecretSharedQueryResult{Shares: make([]field.FP, 2)}
+			if rand.Intn(2) == 0 {
+				records[i].Shares[0] = 1 + field.FP(rand.Intn(1000))
+				records[i].Shares[1] = field.FP(rand.Intn(1000))
+			}
+			values[i] = records[i].Shares
+		}
+		setFound(records)
+
+		if revealed := checkFirstK(t, values, maskRecords(records, k), k); len(revealed) > k {
+			t.Fatalf("revealed %v records, expected at most %v", len(revealed), k)
+		}
+	}
+
+	// the non-empty records are consecutive: the first k are all revealed
+	// (and not only the first one, as they would all fall in the same group
+	// if the records were interleaved into k groups)
+	records := make([]*pir.SecretSharedQueryResult, nrecords)
+	values := make([][]field.FP, nrecords)
+	for i := range records {
+		records[i] = &pir.SecretSharedQueryResult{Shares: make([]field.FP, 1)}
+		if i%k == 1 {
+			records[i].Shares[0] = field.FP(i)
+		}
+		values[i] = records[i].Shares
+	}
+	setFound(records)
+
+	if revealed := checkFirstK(t, values, maskRecords(records, k), k); !reflect.DeepEqual(revealed, []int{1, 4, 7}) {
+		t.Fatalf("revealed records %v (expected [1 4 7])", revealed)
+	}
+}
+
+func TestPrivateItemQuery(t *testing.T) {
+
+	numItems := 100
+	dim := 10
+	maxPayloadBytes := 16
+
+	data := make([]*vec.Vec, numItems)
+	payloads := make([][]byte, numItems)
+	for i := range data {
+		coords := make([]float64, dim)
+		for j := range coords {
+			coords[j] = float64(rand.Intn(256))
+		}
+		data[i] = vec.NewVec(coords)
+		payloads[i] = make([]byte, rand.Intn(maxPayloadBytes+1))
+		rand.Read(payloads[i])
+	}
+
+	matrix, err := ann.MatrixFromVecs(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemDB, err := NewItemDatabase(matrix, payloads, maxPayloadBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := []*Server{{Sessions: NewSessionManager(time.Minute)}, {Sessions: NewSessionManager(time.Minute)}}
+	for _, server := range servers {
+		server.MaskingSeed = []byte("test")
+		server.SetSnapshot(&Snapshot{ItemDB: itemDB})
+	}
+	LinkPeers(servers[0], servers[1])
+	params := itemDB.Metadata()
+
+	ids := []int{rand.Intn(numItems), rand.Intn(numItems)}
+	args := []*api.ItemQueryArgs{{SessionID: openTestSession(t, servers[0])}, {SessionID: openTestSession(t, servers[1])}}
+	for _, id := range ids {
+		shares := params.NewVerifiableIndexQueryShares(uint64(id), 2, uint(params.IndexBits))
+		args[0].Queries = append(args[0].Queries, shares[0])
+		args[1].Queries = append(args[1].Queries, shares[1])
+	}
+
+	replies := []*api.ItemQueryResponse{{}, {}}
+	for _, err := range onBothServers(func(s int) error { return servers[s].PrivateItemQuery(args[s], replies[s]) }) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	resA, resB := replies[0], replies[1]
+
+	for i, id := range ids {
+		res := []*pir.SecretSharedQueryResult{resA.ResSecretShared[i], resB.ResSecretShared[i]}
+
+		v, payload, err := ann.DecodeItem(pir.RecoverBytes(res, params.RecordBytes), params.Dimension)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if vec.EuclideanDistance(v, data[id]) != 0 {
+			t.Fatalf("wrong vector for item %v: %v != %v", id, v, data[id])
+		}
+
+		if string(payload) != string(payloads[id]) {
+			t.Fatalf("wrong payload for item %v", id)
+		}
+	}
+}
+
 func BenchmarkObliviousMasking(b *testing.B) {
 	nslots := 10000
 	slots := make([]field.FP, nslots)
@@ -108,29 +587,30 @@ func BenchmarkObliviousMasking(b *testing.B) {
 	queryRes := make([]*pir.SecretSharedQueryResult, nslots)
 	for i := 0; i < nslots; i++ {
 		queryRes[i] = &pir.SecretSharedQueryResult{}
-		queryRes[i].Share = slots[i]
+		queryRes[i].Shares = []field.FP{slots[i]}
 
-		// fmt.Println(queryRes[i].Share.Data)
 	}
+	setFound(queryRes)
+
 	for i := 0; i < b.N; i++ {
 
-		masked := obliviousMasking(queryRes)
+		masked := obliviousMasking(rand.New(rand.NewSource(0)), queryRes, 1)
 
 		for i := 0; i < nslots; i++ {
 
-			if i < index && masked[i].Share != 0 {
+			if i < index && masked[i].Shares[0] != 0 {
 				b.Fatalf("non-zero slot at index %v < %v", i, index)
 			}
 
-			if i == index && masked[i].Share != specialSlot {
+			if i == index && masked[i].Shares[0] != specialSlot {
 				b.Fatalf("wrong slot at index %v == %v", i, index)
 			}
 
-			if i > index && masked[i].Share == 0 {
+			if i > index && masked[i].Shares[0] == 0 {
 				b.Fatalf("non-random slot at index %v > %v", i, index)
 			}
 
-			if i > index && masked[i].Share == specialSlot {
+			if i > index && masked[i].Shares[0] == specialSlot {
 				b.Fatalf("non-random slot at index %v > %v", i, index)
 			}
 		}
@@ -1,9 +1,18 @@
 package server
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/sachaservan/private-ann/cmd/api"
+	"github.com/sachaservan/private-ann/hash"
 	"github.com/sachaservan/private-ann/pir"
 )
 
@@ -11,6 +20,174 @@ import (
 // and keeps the state until the client is done
 type ClientSession struct {
 	SessionID int64
+	Params    *api.SessionParameters // parameters sent to the client when the session was created
+	Snapshot  *Snapshot              // tables queried in the session (see Params.Version)
+	Expires   time.Time              // the session expires if no request is received before then
+}
+
+// SessionManager issues (random) session IDs and keeps track of the open sessions
+type SessionManager struct {
+	Timeout time.Duration // sessions expire after Timeout without requests (0: never)
+
+	mu       sync.Mutex
+	sessions map[int64]*ClientSession
+}
+
+// NewSessionManager returns a manager whose sessions expire after timeout without requests
+func NewSessionManager(timeout time.Duration) *SessionManager {
+	return &SessionManager{
+		Timeout:  timeout,
+		sessions: make(map[int64]*ClientSession),
+	}
+}
+
+// Open creates a new session on the snapshot with a fresh (non-zero) random ID
+func (m *SessionManager) Open(params *api.SessionParameters, snapshot *Snapshot) (*ClientSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.removeExpired(now)
+
+	var buf [8]byte
+	for {
+		if _, err := rand.Read(buf[:]); err != nil {
+			return nil, err
+		}
+		// positive IDs only; zero is never issued
+		id := int64(binary.LittleEndian.Uint64(buf[:]) >> 1)
+		if id == 0 || m.sessions[id] != nil {
+			continue
+		}
+
+		session := &ClientSession{SessionID: id, Params: params, Snapshot: snapshot}
+		m.touch(session, now)
+		m.sessions[id] = session
+		return session, nil
+	}
+}
+
+// Lookup returns the open session with the ID and extends its expiry
+func (m *SessionManager) Lookup(id int64) (*ClientSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, err := m.get(id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	m.touch(session, time.Now())
+	return session, nil
+}
+
+// Close ends the session with the ID
+func (m *SessionManager) Close(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.get(id, time.Now()); err != nil {
+		return err
+	}
+	delete(m.sessions, id)
+	return nil
+}
+
+// EndBefore ends the sessions opened on versions of the tables older than version
+// and returns the number of sessions ended
+func (m *SessionManager) EndBefore(version int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ended := 0
+	for id, session := range m.sessions {
+		if session.Snapshot != nil && session.Snapshot.Version < version {
+			delete(m.sessions, id)
+			ended++
+		}
+	}
+	return ended
+}
+
+// Snapshots returns the (distinct) snapshots that the open sessions query
+func (m *SessionManager) Snapshots() []*Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.removeExpired(time.Now())
+
+	var snapshots []*Snapshot
+	seen := make(map[*Snapshot]bool)
+	for _, session := range m.sessions {
+		if session.Snapshot != nil && !seen[session.Snapshot] {
+			seen[session.Snapshot] = true
+			snapshots = append(snapshots, session.Snapshot)
+		}
+	}
+	return snapshots
+}
+
+// NumSessions returns the number of open (unexpired) sessions
+func (m *SessionManager) NumSessions() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.removeExpired(time.Now())
+	return len(m.sessions)
+}
+
+func (m *SessionManager) get(id int64, now time.Time) (*ClientSession, error) {
+	session := m.sessions[id]
+	if session == nil {
+		return nil, errors.New("unknown session ID")
+	}
+	if m.expired(session, now) {
+		delete(m.sessions, id)
+		return nil, errors.New("session expired")
+	}
+	return session, nil
+}
+
+func (m *SessionManager) touch(session *ClientSession, now time.Time) {
+	if m.Timeout > 0 {
+		session.Expires = now.Add(m.Timeout)
+	}
+}
+
+func (m *SessionManager) expired(session *ClientSession, now time.Time) bool {
+	return m.Timeout > 0 && now.After(session.Expires)
+}
+
+func (m *SessionManager) removeExpired(now time.Time) {
+	for id, session := range m.sessions {
+		if m.expired(session, now) {
+			delete(m.sessions, id)
+		}
+	}
+}
+
+// session returns the open client session with the ID
+func (server *Server) session(id int64) (*ClientSession, error) {
+	if server.Sessions == nil {
+		return nil, errors.New("server does not accept sessions")
+	}
+	return server.Sessions.Lookup(id)
+}
+
+// sessionSnapshot returns the tables of the open client session with the ID.
+// The query must be for the version of the session so that both servers
+// answer it with the same tables (the client checks that the versions match)
+func (server *Server) sessionSnapshot(id int64, version int) (*Snapshot, error) {
+	session, err := server.session(id)
+	if err != nil {
+		return nil, err
+	}
+	if session.Snapshot == nil {
+		return nil, errors.New("session has no tables")
+	}
+	if version != session.Snapshot.Version {
+		return nil, fmt.Errorf("session queries version %v of the tables (not version %v)", session.Snapshot.Version, version)
+	}
+	return session.Snapshot, nil
 }
 
 // InitSession initializes a new KNN query session for the client
@@ -18,32 +195,118 @@ func (server *Server) InitSession(args api.InitSessionArgs, reply *api.InitSessi
 
 	log.Printf("[Server]: received request to InitSession")
 
-	// session ID
-	sessionID := int64(0)
+	if server.Sessions == nil {
+		return errors.New("server does not accept sessions")
+	}
+
+	if err := server.beginRequest(); err != nil {
+		return err
+	}
+	defer server.endRequest()
+
+	snapshot, err := server.currentSnapshot()
+	if err != nil {
+		return err
+	}
 
-	dbmd := make([]*pir.DBMetadata, len(server.TableDBs))
-	for i := 0; i < len(server.TableDBs); i++ {
-		dbmd[i] = &server.TableDBs[i].DBMetadata
+	dbmd := make([]*pir.DBMetadata, len(snapshot.TableDBs))
+	for i := 0; i < len(snapshot.TableDBs); i++ {
+		dbmd[i] = &snapshot.TableDBs[i].DBMetadata
 	}
 
-	reply.SessionID = sessionID
-	reply.HashFunctions = server.HashFunctions
-	reply.HashFunctionRange = server.HashFunctionRange
-	reply.TableBucketMetadata = dbmd
-	reply.NumProbes = server.NumProbes
-	reply.NumTables = server.NumTables
-	reply.TestQuery = server.TestQuery
+	digest, err := snapshot.HashFunctions.Digest()
+	if err != nil {
+		return err
+	}
+
+	probing := server.Probing()
+	params := api.SessionParameters{
+		HashBundleDigest:    digest,
+		HashFunctionRange:   server.HashFunctionRange,
+		DistanceMetric:      server.DistanceMetric,
+		TableBucketMetadata: dbmd,
+		NumProbes:           probing.NumProbes,
+		NumPartitions:       probing.NumPartitions,
+		ProbeStrategy:       probing.Strategy,
+		BucketSize:          server.BucketSize,
+		NumTables:           server.NumTables,
+		TestQuery:           snapshot.TestQuery,
+		Version:             snapshot.Version,
+	}
+	if snapshot.ItemDB != nil {
+		params.ItemDB = snapshot.ItemDB.Metadata()
+	}
+	if snapshot.SlotTables != nil {
+		params.SlotTables = snapshot.SlotTables.Metadata()
+	}
+
+	session, err := server.Sessions.Open(&params, snapshot)
+	if err != nil {
+		return err
+	}
+	params.SessionID = session.SessionID
+
+	reply.SessionParameters = params
+	reply.TableDigests = snapshot.TableDigests()
 	reply.StatsDatasetName = server.DatasetName
-	reply.StatsDatasetSize = server.DBSize
-	reply.StatsPreprocessingTimeInMS = server.StatsTotalPreprocessingTime
-	reply.StatsNumFeatures = server.StatsDatasetNumFeatures
+	reply.StatsDatasetSize = snapshot.DBSize
+	reply.StatsPreprocessingTimeInMS = snapshot.StatsTotalPreprocessingTime
+	reply.StatsNumFeatures = snapshot.StatsDatasetNumFeatures
 	reply.StatsNumServerProcs = server.NumProcs
 
+	log.Printf("[Server]: opened session %v on version %v (%v open sessions)", session.SessionID, snapshot.Version, server.Sessions.NumSessions())
+
 	return nil
 }
 
-// TerminateSession kills the server
+// HashBundle returns the (encoded) hash functions with the digest sent in the session parameters
+func (server *Server) HashBundle(args *api.HashBundleArgs, reply *api.HashBundleResponse) error {
+	if err := server.beginRequest(); err != nil {
+		return err
+	}
+	defer server.endRequest()
+
+	current, err := server.currentSnapshot()
+	if err != nil {
+		return err
+	}
+
+	// bundles are content-addressed: the client may have opened its session on a version
+	// of the tables that was replaced since (but that the server still retains for it)
+	snapshots := []*Snapshot{current}
+	if server.Sessions != nil {
+		snapshots = append(snapshots, server.Sessions.Snapshots()...)
+	}
+
+	for _, snapshot := range snapshots {
+		bundle, err := hash.EncodeBundle(snapshot.HashFunctions)
+		if err != nil {
+			return err
+		}
+
+		digest := sha256.Sum256(bundle)
+		if bytes.Equal(digest[:], args.Digest) {
+			reply.Bundle = bundle
+			return nil
+		}
+	}
+
+	return errors.New("unknown hash bundle")
+}
+
+// TerminateSession ends the client's session; the server keeps running
+// (only the admin can shut it down, see Admin.Shutdown)
 func (server *Server) TerminateSession(args *api.TerminateSessionArgs, reply *api.TerminateSessionResponse) error {
-	server.Killed = true
+	if server.Sessions == nil {
+		return errors.New("server does not accept sessions")
+	}
+
+	if err := server.Sessions.Close(args.SessionID); err != nil {
+		log.Printf("[Server]: rejected TerminateSession request: %v", err)
+		return err
+	}
+
+	log.Printf("[Server]: closed session %v", args.SessionID)
+
 	return nil
 }
@@ -0,0 +1,235 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/sachaservan/private-ann/cmd/api"
+	"github.com/sachaservan/private-ann/hash"
+)
+
+// openTestSession opens a session on the server and returns its ID
+func openTestSession(t *testing.T, server *Server) int64 {
+	reply := &api.InitSessionResponse{}
+	if err := server.InitSession(api.InitSessionArgs{}, reply); err != nil {
+		t.Fatal(err)
+	}
+	return reply.SessionID
+}
+
+func TestSessionManager(t *testing.T) {
+	m := NewSessionManager(time.Minute)
+
+	params := &api.SessionParameters{NumTables: 3}
+	a, err := m.Open(params, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := m.Open(&api.SessionParameters{NumTables: 5}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.SessionID == 0 || a.SessionID == b.SessionID {
+		t.Fatalf("session IDs are not unique: %v, %v", a.SessionID, b.SessionID)
+	}
+
+	session, err := m.Lookup(a.SessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Params.NumTables != 3 {
+		t.Fatalf("session parameters were not kept")
+	}
+
+	if _, err := m.Lookup(0); err == nil {
+		t.Fatalf("unknown session ID was accepted")
+	}
+
+	// closing a session does not affect the other sessions
+	if err := m.Close(a.SessionID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Lookup(a.SessionID); err == nil {
+		t.Fatalf("closed session was accepted")
+	}
+	if err := m.Close(a.SessionID); err == nil {
+		t.Fatalf("closed session was closed again")
+	}
+	if _, err := m.Lookup(b.SessionID); err != nil {
+		t.Fatalf("other session was closed: %v", err)
+	}
+	if m.NumSessions() != 1 {
+		t.Fatalf("expected 1 open session but got %v", m.NumSessions())
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	m := NewSessionManager(50 * time.Millisecond)
+
+	a, _ := m.Open(nil, nil)
+	b, _ := m.Open(nil, nil)
+
+	// requests extend the expiry
+	for i := 0; i < 4; i++ {
+		time.Sleep(20 * time.Millisecond)
+		if _, err := m.Lookup(a.SessionID); err != nil {
+			t.Fatalf("active session expired: %v", err)
+		}
+	}
+
+	if _, err := m.Lookup(b.SessionID); err == nil {
+		t.Fatalf("idle session did not expire")
+	}
+	if m.NumSessions() != 1 {
+		t.Fatalf("expected 1 open session but got %v", m.NumSessions())
+	}
+}
+
+func TestTerminateSession(t *testing.T) {
+	servers, _, _ := generateTestServers(1, 10, 2, 1, 8)
+	server := servers[0]
+
+	a := openTestSession(t, server)
+	b := openTestSession(t, server)
+
+	if err := server.TerminateSession(&api.TerminateSessionArgs{SessionID: a}, &api.TerminateSessionResponse{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// queries with unknown or terminated sessions are rejected
+	for _, id := range []int64{0, a} {
+		args := &api.ANNQueryArgs{SessionID: id}
+		if err := server.PrivateANNQuery(args, &api.ANNQueryResponse{}); err == nil {
+			t.Fatalf("query with session %v was accepted", id)
+		}
+	}
+
+	// the server keeps serving the other sessions
+	if _, err := server.session(b); err != nil {
+		t.Fatalf("other session was terminated: %v", err)
+	}
+
+	// ending the last session does not kill the server
+	if err := server.TerminateSession(&api.TerminateSessionArgs{SessionID: b}, &api.TerminateSessionResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	if server.Killed() {
+		t.Fatalf("server was killed by a client")
+	}
+}
+
+func TestSessionsOnOldVersionsEnd(t *testing.T) {
+	servers, _, _ := generateTestServers(1, 10, 2, 1, 8)
+	server := servers[0]
+	server.MaxVersions = 2
+
+	// swaps in the next version of the same tables
+	nextVersion := func() {
+		current := server.Snapshot()
+		server.SetSnapshot(&Snapshot{
+			Version:       current.Version + 1,
+			DBSize:        current.DBSize,
+			TableDBs:      current.TableDBs,
+			TestQuery:     current.TestQuery,
+			HashFunctions: current.HashFunctions,
+		})
+	}
+
+	a := openTestSession(t, server)
+	nextVersion()
+	b := openTestSession(t, server)
+
+	// the previous version is kept
+	for _, id := range []int64{a, b} {
+		if _, err := server.session(id); err != nil {
+			t.Fatalf("session %v was ended: %v", id, err)
+		}
+	}
+
+	nextVersion()
+	if _, err := server.session(a); err == nil {
+		t.Fatalf("session on a version older than MaxVersions was not ended")
+	}
+	if _, err := server.session(b); err != nil {
+		t.Fatalf("session on the previous version was ended: %v", err)
+	}
+	if server.Sessions.NumSessions() != 1 {
+		t.Fatalf("expected 1 open session but got %v", server.Sessions.NumSessions())
+	}
+}
+
+func TestTableDigests(t *testing.T) {
+	numTables := 3
+	servers, _, _ := generateTestServers(numTables, 20, 4, 2, 10)
+
+	replies := []*api.InitSessionResponse{{}, {}}
+	for s := range servers {
+		if err := servers[s].InitSession(api.InitSessionArgs{}, replies[s]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(replies[0].TableDigests) != numTables {
+		t.Fatalf("expected one digest per table")
+	}
+	for i := range replies[0].TableDigests {
+		if !bytes.Equal(replies[0].TableDigests[i], replies[1].TableDigests[i]) {
+			t.Fatalf("servers with identical tables have different digests")
+		}
+	}
+
+	// the digests change with the buckets and the hash functions
+	snapshot := servers[1].Snapshot()
+	snapshot.TableDBs[1].Data[0]++
+	snapshot.digests = nil
+	digests := snapshot.TableDigests()
+	if bytes.Equal(digests[1], replies[0].TableDigests[1]) || !bytes.Equal(digests[0], replies[0].TableDigests[0]) {
+		t.Fatalf("only the digest of the modified table should change")
+	}
+
+	snapshot = servers[0].Snapshot()
+	snapshot.HashFunctions = &hash.Bundle{Descriptions: []*hash.Description{{Digest: []byte("parameters")}}}
+	snapshot.digests = nil
+	digests = snapshot.TableDigests()
+	if bytes.Equal(digests[0], replies[0].TableDigests[0]) || !bytes.Equal(digests[2], replies[0].TableDigests[2]) {
+		t.Fatalf("only the digest of the table with a different hash function should change")
+	}
+}
+
+func TestHashBundleOfRetainedVersions(t *testing.T) {
+	servers, _, _ := generateTestServers(1, 10, 2, 1, 8)
+	server := servers[0]
+	server.MaxVersions = 2
+
+	// swaps in the next version of the tables with other hash functions
+	nextVersion := func() {
+		current := server.Snapshot()
+		server.SetSnapshot(&Snapshot{
+			Version:  current.Version + 1,
+			TableDBs: current.TableDBs,
+			HashFunctions: &hash.Bundle{Descriptions: []*hash.Description{
+				{Kind: hash.Hyperplane, Seed: hash.DeriveSeed([]byte("test"), current.Version+1), Dimension: 5, NumPlanes: 8},
+			}},
+		})
+	}
+
+	nextVersion()
+	reply := &api.InitSessionResponse{}
+	if err := server.InitSession(api.InitSessionArgs{}, reply); err != nil {
+		t.Fatal(err)
+	}
+	digest := reply.HashBundleDigest
+
+	// the bundle of the session is served while the server retains its version
+	nextVersion()
+	if err := server.HashBundle(&api.HashBundleArgs{Digest: digest}, &api.HashBundleResponse{}); err != nil {
+		t.Fatalf("bundle of a retained version was not served: %v", err)
+	}
+
+	nextVersion()
+	if err := server.HashBundle(&api.HashBundleArgs{Digest: digest}, &api.HashBundleResponse{}); err == nil {
+		t.Fatalf("bundle of a version that is no longer retained was served")
+	}
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/paillier"
+	"github.com/sachaservan/private-ann/ann"
+	"github.com/sachaservan/private-ann/cmd/api"
+	"github.com/sachaservan/private-ann/pir"
+	"github.com/sachaservan/private-ann/pir/field"
+)
+
+// SlotTables stores the hash tables laid out for single-server queries
+// Each partition of a table is made of ann.SlotChoices dense ranges of slots (see
+// ann.ComputeSlotTable) that the client queries with encrypted selection vectors
+type SlotTables struct {
+	DBs               []*pir.Database // one database per hash table (each record is a bucket)
+	NumPartitions     int
+	SlotsPerPartition int // slots of each of the ann.SlotChoices ranges of a partition
+	RejectedKeys      int // keys (of all tables) that are not served to single-server queries
+}
+
+// NewSlotTables lays out the hash tables (keys and buckets of each table) into slot tables
+// with the given partitions (see ann.Probing.Partitions).
+// Each range of a partition has a quarter more slots than the largest partition of any table
+// has keys so that the keys fit with cuckoo hashing (the keys that do not fit are rejected
+// and counted in RejectedKeys, see ann.ComputeSlotTable)
+func NewSlotTables(partitions *ann.PBRBuckets, keys [][]uint64, values [][][]field.FP, bucketSize int) (*SlotTables, error) {
+	if len(keys) == 0 || len(keys) != len(values) {
+		return nil, errors.New("number of keys and buckets do not match")
+	}
+
+	st := &SlotTables{
+		DBs:               make([]*pir.Database, len(keys)),
+		NumPartitions:     partitions.NumBuckets,
+		SlotsPerPartition: 1,
+	}
+
+	for t := range keys {
+		max := ann.MaxPartitionSize(partitions, keys[t])
+		if size := max + max/4 + 1; size > st.SlotsPerPartition {
+			st.SlotsPerPartition = size
+		}
+	}
+
+	numBatches := st.NumPartitions * ann.SlotChoices
+	starts := make([]int, numBatches)
+	stops := make([]int, numBatches)
+	for b := range starts {
+		starts[b] = b * st.SlotsPerPartition
+		stops[b] = (b + 1) * st.SlotsPerPartition
+	}
+
+	for t := range st.DBs {
+		records, rejected := ann.ComputeSlotTable(partitions, st.SlotsPerPartition, keys[t], values[t], bucketSize)
+		if rejected > 0 {
+			log.Printf("[Server]: %v of %v keys of table %v do not fit in the slot table and are not served to single-server queries\n", rejected, len(keys[t]), t)
+		}
+		st.RejectedKeys += rejected
+
+		db := pir.NewDatabase()
+		if err := db.BuildForRecords(records); err != nil {
+			return nil, err
+		}
+		if err := db.SetBatchingParameters(numBatches, starts, stops); err != nil {
+			return nil, err
+		}
+		st.DBs[t] = db
+	}
+
+	return st, nil
+}
+
+// Metadata returns the parameters the client needs to query the slot tables
+func (st *SlotTables) Metadata() *api.SlotTableParameters {
+	return &api.SlotTableParameters{
+		DBMetadata:        st.DBs[0].DBMetadata,
+		SlotsPerPartition: st.SlotsPerPartition,
+	}
+}
+
+// PrivateEncryptedANNQuery performs single-server PIR queries (encrypted selection
+// vectors) for buckets in the slot tables; the results are obliviously masked like the
+// results of PrivateANNQuery (see encryptedMasking)
+func (server *Server) PrivateEncryptedANNQuery(args *api.EncryptedANNQueryArgs, reply *api.EncryptedANNQueryResponse) error {
+	return server.privateEncryptedANNQuery(context.Background(), args, reply)
+}
+
+// privateEncryptedANNQuery is PrivateEncryptedANNQuery for a request that is abandoned once ctx is done
+func (server *Server) privateEncryptedANNQuery(ctx context.Context, args *api.EncryptedANNQueryArgs, reply *api.EncryptedANNQueryResponse) error {
+
+	log.Printf("[Server]: received request to PrivateEncryptedANNQuery")
+
+	if err := server.beginRequest(); err != nil {
+		return err
+	}
+	defer server.endRequest()
+
+	snapshot, err := server.sessionSnapshot(args.SessionID, args.Version)
+	if err != nil {
+		log.Printf("[Server]: rejected PrivateEncryptedANNQuery request: %v", err)
+		return err
+	}
+
+	start := time.Now()
+
+	if snapshot.SlotTables == nil {
+		return errors.New("server does not serve single-server queries")
+	}
+
+	if len(args.Encrypted) != server.NumTables {
+		return errors.New("query should contain one batch query per table")
+	}
+
+	pk := args.PublicKey
+	if err := pir.CheckPublicKey(pk); err != nil {
+		return err
+	}
+
+	// cache N^2 before the tables are queried concurrently
+	pk.GetN2()
+
+	// one candidate bucket per candidate slot of each partition
+	numBatches := snapshot.SlotTables.NumPartitions * ann.SlotChoices
+	numResults, err := checkNumResults(args.NumResults, numBatches*server.NumTables)
+	if err != nil {
+		return err
+	}
+
+	candidates := make([]*pir.EncryptedQueryResult, numBatches*server.NumTables)
+	errs := make([]error, server.NumTables)
+
+	err = server.runTasks(ctx, server.NumTables, func(t int) {
+		res, err := snapshot.SlotTables.DBs[t].PrivateEncryptedBatchQuery(pk, args.Encrypted[t])
+		if err != nil {
+			errs[t] = err
+			return
+		}
+
+		copy(candidates[t*numBatches:(t+1)*numBatches], res)
+	})
+	if err != nil {
+		log.Printf("[Server]: rejected PrivateEncryptedANNQuery request: %v", err)
+		return err
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			log.Printf("[Server]: rejected PrivateEncryptedANNQuery request: %v", err)
+			return err
+		}
+	}
+
+	reply.StatsQueryTimeInMS = time.Since(start).Milliseconds()
+
+	start = time.Now()
+	reply.ResEncrypted = encryptedMasking(pk, candidates, numResults)
+	reply.StatsMaskingTimeInUS = time.Since(start).Microseconds()
+	reply.SessionID = args.SessionID
+
+	log.Printf("[Server]: processed PrivateEncryptedANNQuery request in %v ms", reply.StatsQueryTimeInMS)
+
+	return nil
+}
+
+// encryptedMasking is the homomorphic analogue of obliviousMasking: each record is returned in
+// numResults versions (concatenated) and each (packed) plaintext of version c of record i is
+// masked with a random multiple of (n_i - c), where n_i is the number of non-empty preceding
+// records (the sum of the Found plaintexts, see pir.EncryptedQueryResult).
+// The masks are uniformly random as long as n_i - c is non-zero (and invertible mod N).
+// The records already contain a fresh encryption of zero (see pir.PrivateEncryptedQueryInRange)
+// so the client cannot learn anything from the randomness of the ciphertexts.
+func encryptedMasking(pk *paillier.PublicKey, records []*pir.EncryptedQueryResult, numResults int) []*pir.EncryptedQueryResult {
+
+	res := make([]*pir.EncryptedQueryResult, len(records))
+
+	// encryptions of the versions c
+	versions := make([]*paillier.Ciphertext, numResults)
+	for c := range versions {
+		versions[c] = pk.Encrypt(gmp.NewInt(int64(c)))
+	}
+
+	// encryption of the number of non-empty preceding records
+	count := pk.EncryptZero()
+	for i := 0; i < len(records); i++ {
+		size := len(records[i].Ciphertexts)
+		res[i] = &pir.EncryptedQueryResult{Ciphertexts: make([]*paillier.Ciphertext, numResults*size)}
+		for c := 0; c < numResults; c++ {
+			diff := pk.Sub(count, versions[c])
+			for e, ct := range records[i].Ciphertexts {
+				res[i].Ciphertexts[c*size+e] = pk.Add(ct, pk.ConstMult(diff, randomPlaintext(pk)))
+			}
+		}
+		count = pk.Add(count, records[i].Found)
+	}
+
+	return res
+}
+
+// randomPlaintext returns a uniformly random plaintext
+func randomPlaintext(pk *paillier.PublicKey) *gmp.Int {
+	for {
+		r, err := paillier.GetRandomNumber(pk.N, rand.Reader)
+		if err == nil {
+			return r
+		}
+	}
+}
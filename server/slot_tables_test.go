@@ -0,0 +1,214 @@
+package server
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sachaservan/paillier"
+	"github.com/sachaservan/private-ann/ann"
+	"github.com/sachaservan/private-ann/cmd/api"
+	"github.com/sachaservan/private-ann/hash"
+	"github.com/sachaservan/private-ann/pir"
+	"github.com/sachaservan/private-ann/pir/field"
+)
+
+// small keys keep the tests fast (not secure)
+const testPaillierBits = 512
+
+func TestPrivateEncryptedANNQuery(t *testing.T) {
+
+	numTables := 2
+	numPartitions := 4
+	bucketSize := 2
+
+	keys := make([][]uint64, numTables)
+	values := make([][][]field.FP, numTables)
+	for tbl := range keys {
+		for i := 0; i < 30; i++ {
+			keys[tbl] = append(keys[tbl], 1+rand.Uint64()%(hash.Prime-1))
+			bucket := make([]field.FP, bucketSize)
+			for l := 0; l < 1+rand.Intn(bucketSize); l++ {
+				bucket[l] = ann.EncodeID(uint32(rand.Intn(1 << 20)))
+			}
+			values[tbl] = append(values[tbl], bucket)
+		}
+	}
+
+	slotTables, err := NewSlotTables(ann.NewPartitions(numPartitions, 64), keys, values, bucketSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &Server{NumTables: numTables, NumProbes: numPartitions, BucketSize: bucketSize, Sessions: NewSessionManager(time.Minute)}
+	server.SetSnapshot(&Snapshot{SlotTables: slotTables})
+	params := slotTables.Metadata()
+
+	// query a key present in the last table (that was not rejected); both candidate slots
+	// of the key are queried and every other partition is queried with empty queries
+	var targetKey uint64
+	var targetPartition, targetChoice int
+	for _, key := range keys[numTables-1] {
+		targetKey = key
+		targetPartition = int(ann.NewPartitions(numPartitions, 64).FindBucket(targetKey))
+		for targetChoice = 0; targetChoice < ann.SlotChoices; targetChoice++ {
+			if ann.SlotMatches(slotTables.DBs[numTables-1].Record(ann.SlotRecord(ann.NewPartitions(numPartitions, 64), key, targetChoice, params.SlotsPerPartition)), key) {
+				break
+			}
+		}
+		if targetChoice < ann.SlotChoices {
+			break
+		}
+	}
+
+	sk, pk := paillier.KeyGen(testPaillierBits)
+	args := &api.EncryptedANNQueryArgs{SessionID: openTestSession(t, server), PublicKey: pk}
+	for tbl := 0; tbl < numTables; tbl++ {
+		batch := &pir.EncryptedBatchQuery{}
+		for b := 0; b < numPartitions; b++ {
+			for c := 0; c < ann.SlotChoices; c++ {
+				if tbl == numTables-1 && b == targetPartition {
+					slot := ann.SlotIndex(targetKey, c, params.SlotsPerPartition)
+					batch.Queries = append(batch.Queries, params.NewEncryptedIndexQuery(pk, slot, params.SlotsPerPartition))
+				} else {
+					batch.Queries = append(batch.Queries, params.NewEncryptedEmptyQuery(pk, params.SlotsPerPartition))
+				}
+			}
+		}
+		args.Encrypted = append(args.Encrypted, batch)
+	}
+
+	reply := &api.EncryptedANNQueryResponse{}
+	if err := server.PrivateEncryptedANNQuery(args, reply); err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.ResEncrypted) != numTables*numPartitions*ann.SlotChoices {
+		t.Fatalf("expected %v buckets but got %v", numTables*numPartitions*ann.SlotChoices, len(reply.ResEncrypted))
+	}
+
+	// the first candidate slot of the key is revealed (the first non-empty bucket)
+	expected := slotTables.DBs[numTables-1].Record(ann.SlotRecord(ann.NewPartitions(numPartitions, 64), targetKey, 0, params.SlotsPerPartition))
+	targetRecord := ((numTables-1)*numPartitions + targetPartition) * ann.SlotChoices
+	for i := 0; i <= targetRecord; i++ {
+		res := pir.RecoverEncrypted(sk, reply.ResEncrypted[i], params.SlotSize)
+		for l := range res {
+			if i < targetRecord && res[l] != 0 {
+				t.Fatalf("non-zero slot in record %v before target %v", i, targetRecord)
+			}
+
+			if i == targetRecord && res[l] != expected[l] {
+				t.Fatalf("wrong slot %v in target record: %v != %v", l, res[l], expected[l])
+			}
+		}
+	}
+
+	if targetChoice == ann.SlotChoices {
+		t.Fatalf("no candidate slot holds the bucket of the target key")
+	}
+
+	// selection vectors must match the partition sizes
+	args.Encrypted[0].Queries[0] = params.NewEncryptedEmptyQuery(pk, 1)
+	if err := server.PrivateEncryptedANNQuery(args, &api.EncryptedANNQueryResponse{}); err == nil {
+		t.Fatalf("selection vector of the wrong size was accepted")
+	}
+}
+
+func TestComputeSlotTable(t *testing.T) {
+	numPartitions := 3
+	bucketSize := 2
+	partitions := ann.NewPartitions(numPartitions, 64)
+
+	// as many keys in each partition as a partition of the server's slot tables is sized for
+	keysPerPartition := 200
+	slotsPerPartition := keysPerPartition + keysPerPartition/4 + 1
+	rnd := rand.New(rand.NewSource(1))
+	var keys []uint64
+	var values [][]field.FP
+	for b := 0; b < numPartitions; b++ {
+		for i := 0; i < keysPerPartition; i++ {
+			keys = append(keys, partitions.Buckets[b][0]+rnd.Uint64()%partitions.Size)
+			values = append(values, []field.FP{field.FP(len(keys)), 0})
+		}
+	}
+
+	records, rejected := ann.ComputeSlotTable(partitions, slotsPerPartition, keys, values, bucketSize)
+	if len(records) != numPartitions*ann.SlotChoices*slotsPerPartition {
+		t.Fatalf("expected %v records but got %v", numPartitions*ann.SlotChoices*slotsPerPartition, len(records))
+	}
+	if rejected != 0 {
+		t.Fatalf("%v keys were rejected", rejected)
+	}
+
+	// each key is stored in one of its candidate slots (and only there)
+	for i, key := range keys {
+		found := 0
+		for c := 0; c < ann.SlotChoices; c++ {
+			record := records[ann.SlotRecord(partitions, key, c, slotsPerPartition)]
+			if ann.SlotMatches(record, key) {
+				found++
+				if !reflect.DeepEqual(record[:bucketSize], values[i]) {
+					t.Fatalf("slot of key %v holds %v (expected %v)", i, record[:bucketSize], values[i])
+				}
+			}
+		}
+		if found != 1 {
+			t.Fatalf("key %v is stored in %v of its candidate slots", i, found)
+		}
+	}
+
+	// a key 0 is stored like any other key
+	records, _ = ann.ComputeSlotTable(partitions, slotsPerPartition, []uint64{0}, [][]field.FP{{7, 0}}, bucketSize)
+	if !ann.SlotMatches(records[ann.SlotRecord(partitions, 0, 0, slotsPerPartition)], 0) {
+		t.Fatalf("key 0 is not stored in its first candidate slot")
+	}
+
+	// the keys that do not fit are counted
+	records, rejected = ann.ComputeSlotTable(partitions, 1, keys[:3], values[:3], bucketSize)
+	if rejected != 1 {
+		t.Fatalf("expected 1 rejected key but got %v", rejected)
+	}
+	for slot, record := range records {
+		if slot >= ann.SlotChoices && !reflect.DeepEqual(record, make([]field.FP, bucketSize+ann.SlotTagElements)) {
+			t.Fatalf("record %v of an empty partition is %v", slot, record)
+		}
+	}
+}
+
+func TestEncryptedMasking(t *testing.T) {
+	sk, pk := paillier.KeyGen(testPaillierBits)
+
+	db := pir.NewDatabase()
+	records := [][]field.FP{{0, 0}, {0, 0}, {5, 7}, {0, 0}, {9, 2}, {3, 0}}
+	if err := db.BuildForRecords(records); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range []int{1, 2, 3} {
+		encrypted := make([]*pir.EncryptedQueryResult, len(records))
+		for i := range records {
+			res, err := db.PrivateEncryptedQuery(pk, db.NewEncryptedIndexQuery(pk, i, len(records)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			encrypted[i] = res
+		}
+
+		masked := encryptedMasking(pk, encrypted, k)
+
+		// decrypt each version of each record
+		versions := make([][]field.FP, len(masked))
+		for i := range masked {
+			size := len(masked[i].Ciphertexts) / k
+			for c := 0; c < k; c++ {
+				version := &pir.EncryptedQueryResult{Ciphertexts: masked[i].Ciphertexts[c*size : (c+1)*size]}
+				versions[i] = append(versions[i], pir.RecoverEncrypted(sk, version, 2)...)
+			}
+		}
+
+		// the non-empty records are 2, 4 and 5
+		expected := []int{2, 4, 5}[:k]
+		if revealed := checkFirstK(t, records, versions, k); !reflect.DeepEqual(revealed, expected) {
+			t.Fatalf("revealed records %v (expected %v)", revealed, expected)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/sachaservan/private-ann/cmd/api"
+	"github.com/sachaservan/private-ann/hash"
+	"github.com/sachaservan/private-ann/pir"
+	"github.com/sachaservan/vec"
+)
+
+// Snapshot is an immutable version of the tables of the server. Reloads and updates
+// build a new snapshot and swap it in atomically (see SetSnapshot) so that requests never
+// observe tables that are being rebuilt: each session queries the snapshot it was opened on
+// until it ends, and both servers answer the queries of a session with the same version
+type Snapshot struct {
+	Version int // increases with every reload and update (see Admin.Reload and Admin.Update)
+	DBSize  int // number of ids (deleted ids included)
+
+	// PIR databases containing the LSH tables
+	// each record of TableDBs[t] is a bucket of table t (BucketSize field elements)
+	TableDBs      []*pir.Database
+	TestQuery     *vec.Vec     // query that the client can use to test
+	HashFunctions *hash.Bundle // descriptions of the LSH hash functions used to make the tables
+
+	// PIR database containing the vector and payload of each item (optional)
+	ItemDB *ItemDatabase
+
+	// hash tables laid out for single-server (encrypted) queries (optional)
+	SlotTables *SlotTables
+
+	StatsTotalPreprocessingTime int64 // time taken to build the hash tables
+	StatsDatasetNumFeatures     int
+
+	// computed on first use (the tables are never modified)
+	mu      sync.Mutex
+	digests [][]byte    // digest of each table (see TableDigests)
+	hashes  []hash.Hash // hash functions of HashFunctions
+}
+
+// Snapshot returns the tables served to new sessions (nil until the tables are loaded)
+func (server *Server) Snapshot() *Snapshot {
+	snapshot, _ := server.snapshot.Load().(*Snapshot)
+	return snapshot
+}
+
+// SetSnapshot atomically replaces the tables served to new sessions; the open sessions
+// keep querying the snapshot they were opened on, unless it is more than MaxVersions old
+// (so that every reload or update does not keep another copy of the tables alive)
+func (server *Server) SetSnapshot(snapshot *Snapshot) {
+	server.snapshot.Store(snapshot)
+
+	if server.Sessions != nil && server.MaxVersions > 0 {
+		oldest := snapshot.Version - server.MaxVersions + 1
+		if ended := server.Sessions.EndBefore(oldest); ended > 0 {
+			log.Printf("[Server]: ended %v sessions opened on versions older than %v", ended, oldest)
+		}
+	}
+}
+
+// currentSnapshot returns the tables served to new sessions or an error if they are not loaded
+func (server *Server) currentSnapshot() (*Snapshot, error) {
+	snapshot := server.Snapshot()
+	if snapshot == nil {
+		return nil, errors.New("server has not loaded its tables")
+	}
+	return snapshot, nil
+}
+
+// TableDigests returns the digest of each table: the SHA-256 digest of the Merkle root
+// of the table (its keys and buckets, see pir.Database.MerkleRoot) and of the parameters
+// of its hash function. Servers return the same digests iff they hold identical tables,
+// which the clients check before querying them
+func (snapshot *Snapshot) TableDigests() [][]byte {
+	snapshot.mu.Lock()
+	defer snapshot.mu.Unlock()
+
+	if snapshot.digests != nil {
+		return snapshot.digests
+	}
+
+	digests := make([][]byte, len(snapshot.TableDBs))
+	for t, db := range snapshot.TableDBs {
+		h := sha256.New()
+		h.Write(db.MerkleRoot())
+		if snapshot.HashFunctions != nil && t < len(snapshot.HashFunctions.Descriptions) {
+			h.Write(snapshot.HashFunctions.Descriptions[t].Digest)
+		}
+		digests[t] = h.Sum(nil)
+	}
+	snapshot.digests = digests
+
+	return digests
+}
+
+// digest returns the SHA-256 digest of the table digests (see TableDigests)
+// and of the records of the item database
+func (snapshot *Snapshot) digest() []byte {
+	h := sha256.New()
+	for _, digest := range snapshot.TableDigests() {
+		h.Write(digest)
+	}
+	if snapshot.ItemDB != nil {
+		binary.Write(h, binary.LittleEndian, snapshot.ItemDB.DB.Data)
+	}
+	return h.Sum(nil)
+}
+
+// status returns the version of the tables and their digest
+func (snapshot *Snapshot) status() *api.AdminStatusResponse {
+	return &api.AdminStatusResponse{
+		Version: snapshot.Version,
+		DBSize:  snapshot.DBSize,
+		Digest:  snapshot.digest(),
+	}
+}
+
+// hashFunctions returns the hash functions of the tables
+// (reconstructed from their descriptions on first use)
+func (snapshot *Snapshot) hashFunctions() ([]hash.Hash, error) {
+	snapshot.mu.Lock()
+	defer snapshot.mu.Unlock()
+
+	if snapshot.hashes != nil {
+		return snapshot.hashes, nil
+	}
+
+	if snapshot.HashFunctions == nil || len(snapshot.HashFunctions.Descriptions) != len(snapshot.TableDBs) {
+		return nil, errors.New("server should have one hash function per table")
+	}
+
+	hashes, err := snapshot.HashFunctions.HashFunctions()
+	if err != nil {
+		return nil, err
+	}
+	snapshot.hashes = hashes
+
+	return hashes, nil
+}
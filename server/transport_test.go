@@ -0,0 +1,273 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sachaservan/private-ann/client"
+	"github.com/sachaservan/private-ann/cmd/api"
+	"github.com/sachaservan/private-ann/cmd/api/pb"
+	"github.com/sachaservan/private-ann/hash"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// serveTestGRPC serves the server over an in-memory gRPC connection
+// (with TLS when serverConfig and clientConfig are set)
+func serveTestGRPC(t *testing.T, server *Server, serverConfig, clientConfig *tls.Config) pb.PrivateANNClient {
+	listener := bufconn.Listen(1 << 20)
+
+	var opts []grpc.ServerOption
+	creds := grpc.WithInsecure()
+	if serverConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(serverConfig)))
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(clientConfig))
+	}
+
+	grpcServer := grpc.NewServer(opts...)
+	pb.RegisterPrivateANNServer(grpcServer, pb.NewServer(server.GRPCHandler()))
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	dial := func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.Dial()
+	}
+	conn, err := grpc.Dial("bufnet", grpc.WithContextDialer(dial), creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewPrivateANNClient(conn)
+}
+
+func TestGRPCTransport(t *testing.T) {
+	numTables := 2
+	numPartitions := 4
+	bucketSize := 2
+	keyBits := 20
+
+	servers, tableKeys, tableValues := generateTestServers(numTables, 50, numPartitions, bucketSize, keyBits)
+
+	// the session parameters (including the hash functions) survive the round trip
+	for _, server := range servers {
+		server.Snapshot().Version = 2
+	}
+	servers[0].Snapshot().HashFunctions = &hash.Bundle{Descriptions: []*hash.Description{
+		{Kind: hash.Hyperplane, Seed: hash.DeriveSeed([]byte("test"), 0), Dimension: 5, NumPlanes: 8},
+		{Kind: hash.Hyperplane, Seed: hash.DeriveSeed([]byte("test"), 1), Dimension: 5, NumPlanes: 8},
+	}}
+
+	clients := []pb.PrivateANNClient{serveTestGRPC(t, servers[0], nil, nil), serveTestGRPC(t, servers[1], nil, nil)}
+	ctx := context.Background()
+
+	session := &api.InitSessionResponse{}
+	if err := pb.Invoke(ctx, clients[0], "Server.InitSession", &api.InitSessionArgs{}, session); err != nil {
+		t.Fatal(err)
+	}
+	if session.SessionID == 0 || session.NumTables != numTables || session.BucketSize != bucketSize || session.Version != 2 {
+		t.Fatalf("session parameters do not match: %+v", session.SessionParameters)
+	}
+	if len(session.TableBucketMetadata) != numTables || session.TableBucketMetadata[0].DBSize != servers[0].Snapshot().TableDBs[0].DBSize {
+		t.Fatalf("table metadata does not match")
+	}
+	if len(session.TableDigests) != numTables || string(session.TableDigests[1]) != string(servers[0].Snapshot().TableDigests()[1]) {
+		t.Fatalf("table digests do not match")
+	}
+	bundle := &api.HashBundleResponse{}
+	if err := pb.Invoke(ctx, clients[0], "Server.HashBundle", &api.HashBundleArgs{Digest: session.HashBundleDigest}, bundle); err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := hash.EncodeBundle(servers[0].Snapshot().HashFunctions)
+	if string(expected) != string(bundle.Bundle) {
+		t.Fatalf("hash bundle does not match")
+	}
+	if err := pb.Invoke(ctx, clients[1], "Server.HashBundle", &api.HashBundleArgs{Digest: session.HashBundleDigest}, bundle); err == nil {
+		t.Fatalf("unknown hash bundle was returned")
+	}
+
+	args, target := newTestANNQuery(t, servers, tableKeys, numPartitions, keyBits)
+	replies := []*api.ANNQueryResponse{{}, {}}
+	for s := range servers {
+		if err := pb.Invoke(ctx, clients[s], "Server.PrivateANNQuery", args[s], replies[s]); err == nil {
+			t.Fatalf("query for another version than the version of the session was accepted")
+		}
+		args[s].Version = 2
+	}
+	errs := onBothServers(func(s int) error {
+		return pb.Invoke(ctx, clients[s], "Server.PrivateANNQuery", args[s], replies[s])
+	})
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkTestANNReplies(t, replies, tableKeys, tableValues, target, numPartitions, keyBits)
+
+	// malformed queries are rejected
+	args[0].SecretShared[0].Queries[0].PrfKey[0] ^= 1
+	args[0].SecretShared[0].Queries = args[0].SecretShared[0].Queries[1:]
+	if err := pb.Invoke(ctx, clients[0], "Server.PrivateANNQuery", args[0], &api.ANNQueryResponse{}); err == nil {
+		t.Fatalf("malformed query was accepted")
+	}
+
+	terminate := &api.TerminateSessionArgs{SessionID: session.SessionID}
+	if err := pb.Invoke(ctx, clients[0], "Server.TerminateSession", terminate, &api.TerminateSessionResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := pb.Invoke(ctx, clients[0], "Server.TerminateSession", terminate, &api.TerminateSessionResponse{}); err == nil {
+		t.Fatalf("terminated session was terminated again")
+	}
+}
+
+func TestGRPCCancellation(t *testing.T) {
+	servers, params := generateTestItemServers(t, 50, 4)
+	client := serveTestGRPC(t, servers[0], nil, nil)
+
+	// the server is not ready: the client stops waiting
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := pb.Invoke(ctx, client, "Server.WaitForExperiment", &api.WaitForExperimentArgs{}, &api.WaitForExperimentResponse{}); err == nil {
+		t.Fatalf("server was not ready")
+	}
+
+	// the peer never receives the query: the server stops waiting for it once the client gives up
+	args := newTestItemQuery(t, servers, params, 2)
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := pb.Invoke(ctx, client, "Server.PrivateItemQuery", args[0], &api.ItemQueryResponse{}); err == nil {
+		t.Fatalf("query answered without the peer")
+	}
+	if elapsed := time.Since(start); elapsed > servers[0].PeerTimeout/2 {
+		t.Fatalf("calls were not abandoned with the client (took %v)", elapsed)
+	}
+
+	// once the server abandoned the request, the same request is answered
+	for servers[0].peerInbox.numInflight() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	errs := onBothServers(func(s int) error {
+		if s == 0 {
+			return pb.Invoke(context.Background(), client, "Server.PrivateItemQuery", args[0], &api.ItemQueryResponse{})
+		}
+		return servers[1].PrivateItemQuery(args[1], &api.ItemQueryResponse{})
+	})
+	if errs[0] != nil || errs[1] != nil {
+		t.Fatalf("request failed after the client abandoned it: %v", errs)
+	}
+}
+
+// writeTestCertificate writes a certificate for name (signed by parent, self-signed if nil)
+// and its key to dir and returns the certificate, its key, and the certificate file
+func writeTestCertificate(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, name+".crt")
+	err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, key, certFile
+}
+
+func TestGRPCTransportTLS(t *testing.T) {
+	servers, _, _ := generateTestServers(2, 50, 4, 2, 20)
+
+	dir := t.TempDir()
+	ca, caKey, caFile := writeTestCertificate(t, dir, "ca", nil, nil)
+	serverCert, _, serverCertFile := writeTestCertificate(t, dir, "localhost", ca, caKey)
+	_, _, clientCertFile := writeTestCertificate(t, dir, "client", ca, caKey)
+	rogueCA, rogueKey, _ := writeTestCertificate(t, dir, "rogue", nil, nil)
+	_, _, rogueCertFile := writeTestCertificate(t, dir, "rogueclient", rogueCA, rogueKey)
+
+	serverConfig, err := NewTLSConfig(serverCertFile, filepath.Join(dir, "localhost.key"), caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initSession := func(clientConfig *tls.Config) error {
+		clientConfig.ServerName = "localhost"
+		cli := serveTestGRPC(t, servers[0], serverConfig, clientConfig)
+		return pb.Invoke(context.Background(), cli, "Server.InitSession", &api.InitSessionArgs{}, &api.InitSessionResponse{})
+	}
+
+	clientConfig, err := client.NewTLSConfig(caFile, clientCertFile, filepath.Join(dir, "client.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := initSession(client.PinServer(clientConfig, client.PublicKeyPin(serverCert))); err != nil {
+		t.Fatalf("mutually authenticated connection failed: %v", err)
+	}
+
+	// clients must present a certificate signed by the client CA
+	anonymousConfig, err := client.NewTLSConfig(caFile, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := initSession(anonymousConfig); err == nil {
+		t.Fatalf("client without a certificate was accepted")
+	}
+
+	rogueConfig, err := client.NewTLSConfig(caFile, rogueCertFile, filepath.Join(dir, "rogueclient.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := initSession(rogueConfig); err == nil {
+		t.Fatalf("client with a certificate from another CA was accepted")
+	}
+
+	// the server must match the pin even if its certificate is valid
+	if err := initSession(client.PinServer(clientConfig, client.PublicKeyPin(ca))); err == nil {
+		t.Fatalf("server that does not match the pin was accepted")
+	}
+}
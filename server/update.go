@@ -0,0 +1,203 @@
+package server
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/sachaservan/private-ann/ann"
+	"github.com/sachaservan/private-ann/cmd/api"
+	"github.com/sachaservan/private-ann/pir"
+	"github.com/sachaservan/private-ann/pir/field"
+)
+
+// update applies the update to a copy of the tables (see Admin.Update) and swaps it in;
+// queries are answered with the current tables in the meantime
+func (server *Server) update(args *api.UpdateArgs) (*api.UpdateResponse, error) {
+	// shutdowns wait for the update
+	if err := server.beginRequest(); err != nil {
+		return nil, err
+	}
+	defer server.endRequest()
+
+	server.updates.Lock()
+	defer server.updates.Unlock()
+
+	current, err := server.currentSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	if args.Version != current.Version+1 {
+		return nil, fmt.Errorf("expected version %v (got version %v)", current.Version+1, args.Version)
+	}
+
+	// the update is applied to copies of the tables that are only swapped in once the whole
+	// update succeeded, so that it is either fully applied or not at all (otherwise the tables
+	// of the servers could diverge); it is validated first to fail before copying the tables
+	hashes, err := current.hashFunctions()
+	if err != nil {
+		return nil, err
+	}
+
+	dimension := current.HashFunctions.Descriptions[0].Dimension
+	for _, v := range args.Insert {
+		if v == nil || v.Size() != dimension {
+			return nil, fmt.Errorf("inserted vectors should have dimension %v", dimension)
+		}
+	}
+	if current.DBSize+len(args.Insert)-1 > ann.MaxID {
+		return nil, fmt.Errorf("the tables cannot hold more than %v ids", ann.MaxID+1)
+	}
+	// vectors are stored like the dataset (see ann.ReadDatasetMatrix)
+	vectors, err := ann.MatrixFromVecs(args.Insert)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make(map[field.FP]bool, len(args.Delete))
+	for _, id := range args.Delete {
+		if id < 0 || id >= current.DBSize {
+			return nil, fmt.Errorf("id %v is not in the database", id)
+		}
+		deleted[ann.EncodeID(uint32(id))] = true
+	}
+
+	// vectors are hashed in the same (normalized) space as the dataset
+	server.DistanceMetric.NormalizeMatrix(vectors)
+
+	ids := make([]int, vectors.Rows)
+	for i := range ids {
+		ids[i] = current.DBSize + i
+	}
+
+	snapshot := &Snapshot{
+		Version:                     args.Version,
+		DBSize:                      current.DBSize + vectors.Rows,
+		TableDBs:                    make([]*pir.Database, len(current.TableDBs)),
+		TestQuery:                   current.TestQuery,
+		HashFunctions:               current.HashFunctions,
+		StatsTotalPreprocessingTime: current.StatsTotalPreprocessingTime,
+		StatsDatasetNumFeatures:     current.StatsDatasetNumFeatures,
+		hashes:                      hashes,
+	}
+
+	partitions := server.Probing().Partitions()
+	mask := ann.KeyMask(server.HashFunctionRange)
+	dropped := 0
+	for t, db := range current.TableDBs {
+		db = db.Copy()
+		if err := deleteIDs(db, deleted); err != nil {
+			return nil, err
+		}
+
+		for i := range ids {
+			key := hashes[t].HashRow(vectors.Row(i, nil)) & mask
+			ok, err := insertID(db, partitions, key, ann.EncodeID(uint32(ids[i])))
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				dropped++
+			}
+		}
+		snapshot.TableDBs[t] = db
+	}
+
+	if current.ItemDB != nil {
+		snapshot.ItemDB = current.ItemDB.copy()
+		for _, id := range args.Delete {
+			snapshot.ItemDB.delete(id)
+		}
+		for i := range ids {
+			if err := snapshot.ItemDB.insert(vectors.Vec(i)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if current.SlotTables != nil {
+		snapshot.SlotTables, err = rebuildSlotTables(snapshot.TableDBs, partitions, server.BucketSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	server.SetSnapshot(snapshot)
+
+	log.Printf("[Server]: updated tables to version %v (%v inserted, %v deleted, %v dropped from full buckets)",
+		snapshot.Version, vectors.Rows, len(deleted), dropped)
+
+	reply := &api.UpdateResponse{IDs: ids}
+	reply.AdminStatusResponse = *snapshot.status()
+	return reply, nil
+}
+
+// deleteIDs removes the (encoded) ids from the buckets of the table;
+// the remaining ids of each bucket are moved to its first slots and empty buckets are removed
+func deleteIDs(db *pir.Database, deleted map[field.FP]bool) error {
+	if len(deleted) == 0 {
+		return nil
+	}
+
+	var empty []uint64
+	for i := 0; i < db.DBSize; i++ {
+		record := db.Record(i)
+		kept := 0
+		for _, v := range record {
+			if v != 0 && !deleted[v] {
+				record[kept] = v
+				kept++
+			}
+		}
+		for j := kept; j < len(record); j++ {
+			record[j] = 0
+		}
+		if kept == 0 {
+			empty = append(empty, db.Keywords[i])
+		}
+	}
+
+	for _, key := range empty {
+		if err := db.DeleteKeyword(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insertID adds the (encoded) id to the bucket of the key, which is created if needed.
+// Returns false if the bucket is full (the id is not added to the table)
+func insertID(db *pir.Database, partitions *ann.PBRBuckets, key uint64, id field.FP) (bool, error) {
+	i, found := db.KeywordIndex(key)
+	if !found {
+		record := make([]field.FP, db.SlotSize)
+		record[0] = id
+		return true, db.InsertKeyword(key, record, int(partitions.FindBucket(key)))
+	}
+
+	record := db.Record(i)
+	for j := range record {
+		if record[j] == 0 {
+			record[j] = id
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// rebuildSlotTables lays out the (updated) tables into new slot tables
+func rebuildSlotTables(tables []*pir.Database, partitions *ann.PBRBuckets, bucketSize int) (*SlotTables, error) {
+	keys := make([][]uint64, len(tables))
+	values := make([][][]field.FP, len(tables))
+	for t, db := range tables {
+		keys[t] = db.Keywords
+		values[t] = make([][]field.FP, db.DBSize)
+		for i := range values[t] {
+			values[t][i] = db.Record(i)
+		}
+	}
+
+	return NewSlotTables(partitions, keys, values, bucketSize)
+}
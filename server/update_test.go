@@ -0,0 +1,186 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sachaservan/private-ann/ann"
+	"github.com/sachaservan/private-ann/cmd/api"
+	"github.com/sachaservan/private-ann/hash"
+	"github.com/sachaservan/private-ann/pir/field"
+	"github.com/sachaservan/vec"
+)
+
+// tableContents returns the keys and buckets of each table of the server
+func tableContents(server *Server) ([][]uint64, [][][]field.FP) {
+	tables := server.Snapshot().TableDBs
+	keys := make([][]uint64, len(tables))
+	values := make([][][]field.FP, len(tables))
+	for t, db := range tables {
+		keys[t] = db.Keywords
+		for i := 0; i < db.DBSize; i++ {
+			values[t] = append(values[t], db.Record(i))
+		}
+	}
+	return keys, values
+}
+
+// findID returns the number of tables with a bucket that contains the id
+func findID(server *Server, id int) int {
+	found := 0
+	keys, values := tableContents(server)
+	for t := range keys {
+		for _, bucket := range values[t] {
+			for _, v := range bucket {
+				if v == ann.EncodeID(uint32(id)) {
+					found++
+				}
+			}
+		}
+	}
+	return found
+}
+
+func TestUpdate(t *testing.T) {
+	numTables := 2
+	numPartitions := 4
+	bucketSize := 2
+	keyBits := 12
+	dim := 5
+
+	servers, _, _ := generateTestServers(numTables, 50, numPartitions, bucketSize, keyBits)
+	for _, server := range servers {
+		snapshot := server.Snapshot()
+		snapshot.DBSize = 1 << 20
+		snapshot.HashFunctions = &hash.Bundle{}
+		for i := 0; i < numTables; i++ {
+			snapshot.HashFunctions.Descriptions = append(snapshot.HashFunctions.Descriptions,
+				&hash.Description{Kind: hash.Hyperplane, Seed: hash.DeriveSeed([]byte("test"), i), Dimension: dim, NumPlanes: 8})
+		}
+	}
+
+	hashes, err := servers[0].Snapshot().HashFunctions.HashFunctions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rnd := hash.NewSeededRand([]byte("vectors"))
+	// queries of sessions opened before the update are answered with the previous version
+	tableKeys, tableValues := tableContents(servers[0])
+	previous := servers[0].Snapshot()
+	previousDigest := previous.digest()
+	queries, target := newTestANNQuery(t, servers, tableKeys, numPartitions, keyBits)
+
+	args := &api.UpdateArgs{Version: 1}
+	for i := 0; i < 20; i++ {
+		args.Insert = append(args.Insert, hash.Normals(rnd, dim))
+	}
+
+	replies := make([]*api.UpdateResponse, len(servers))
+	for s, server := range servers {
+		if replies[s], err = server.update(args); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(replies[0].IDs) != len(args.Insert) || replies[0].IDs[0] != 1<<20 || servers[0].Snapshot().DBSize != 1<<20+len(args.Insert) {
+		t.Fatalf("inserted vectors were assigned ids %v", replies[0].IDs)
+	}
+	if replies[0].Version != 1 || !bytes.Equal(replies[0].Digest, replies[1].Digest) {
+		t.Fatalf("servers have different tables after the update")
+	}
+	if !bytes.Equal(previous.digest(), previousDigest) {
+		t.Fatalf("update modified the previous version of the tables")
+	}
+
+	queryReplies := []*api.ANNQueryResponse{{}, {}}
+	queryBothServers(t, servers, queries, queryReplies)
+	checkTestANNReplies(t, queryReplies, tableKeys, tableValues, target, numPartitions, keyBits)
+
+	// each vector is in the bucket of its key (unless the bucket is full)
+	for i, v := range args.Insert {
+		for table := range hashes {
+			key := hashes[table].Hash(v) & ann.KeyMask(keyBits)
+			index, found := servers[0].Snapshot().TableDBs[table].KeywordIndex(key)
+			if !found {
+				t.Fatalf("key of vector %v is not in table %v", i, table)
+			}
+
+			inBucket := false
+			for _, id := range servers[0].Snapshot().TableDBs[table].Record(index) {
+				inBucket = inBucket || id == ann.EncodeID(uint32(replies[0].IDs[i]))
+			}
+			if !inBucket && servers[0].Snapshot().TableDBs[table].Record(index)[bucketSize-1] == 0 {
+				t.Fatalf("vector %v was dropped from a bucket that is not full", i)
+			}
+		}
+	}
+
+	// the batches are consistent with the updated tables; sessions opened after
+	// the update query the new version (and must say so in their queries)
+	tableKeys, updatedValues := tableContents(servers[0])
+	queries, target = newTestANNQuery(t, servers, tableKeys, numPartitions, keyBits)
+	if err := servers[0].PrivateANNQuery(queries[0], queryReplies[0]); err == nil {
+		t.Fatalf("query for the previous version was accepted by a session of the new version")
+	}
+	for s := range servers {
+		queries[s].Version = 1
+	}
+	queryBothServers(t, servers, queries, queryReplies)
+	checkTestANNReplies(t, queryReplies, tableKeys, updatedValues, target, numPartitions, keyBits)
+
+	// updates are applied once and in order, and invalid updates are rejected
+	digest := servers[0].Snapshot().digest()
+	for _, invalid := range []*api.UpdateArgs{
+		{Version: 1, Delete: []int{0}},
+		{Version: 3, Delete: []int{0}},
+		{Version: 2, Insert: []*vec.Vec{hash.Normals(rnd, dim+1)}},
+		{Version: 2, Delete: []int{servers[0].Snapshot().DBSize}},
+	} {
+		if _, err := servers[0].update(invalid); err == nil {
+			t.Fatalf("invalid update %+v was applied", invalid)
+		}
+	}
+
+	// ids above ann.MaxID cannot be encoded
+	snapshot := servers[0].Snapshot()
+	dbSize := snapshot.DBSize
+	snapshot.DBSize = ann.MaxID
+	overflow := &api.UpdateArgs{Version: 2, Insert: []*vec.Vec{hash.Normals(rnd, dim), hash.Normals(rnd, dim)}}
+	if _, err := servers[0].update(overflow); err == nil {
+		t.Fatalf("update inserting id %v was applied", ann.MaxID+1)
+	}
+	snapshot.DBSize = dbSize
+
+	if servers[0].Snapshot().Version != 1 || !bytes.Equal(digest, servers[0].Snapshot().digest()) {
+		t.Fatalf("rejected update modified the tables")
+	}
+
+	// delete an inserted id and an id of the original tables
+	original, _ := ann.DecodeID(tableValues[0][0][0])
+	args = &api.UpdateArgs{Version: 2, Delete: []int{replies[0].IDs[0], original}}
+	for s, server := range servers {
+		if replies[s], err = server.update(args); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(replies[0].Digest, replies[1].Digest) {
+		t.Fatalf("servers have different tables after the update")
+	}
+
+	for _, id := range args.Delete {
+		if findID(servers[0], id) != 0 {
+			t.Fatalf("deleted id %v is still in the tables", id)
+		}
+	}
+
+	// buckets without ids are removed
+	_, values := tableContents(servers[0])
+	for table := range values {
+		for _, bucket := range values[table] {
+			if bucket[0] == 0 {
+				t.Fatalf("empty bucket in table %v", table)
+			}
+		}
+	}
+}